@@ -0,0 +1,32 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+/*
+
+Multi-call binary providing all Nagios plugins from this project.
+
+PURPOSE
+
+Provide a single binary in place of the standalone plugin binaries. The
+plugin to execute is selected using a subcommand matching the plugin type
+(e.g., check_vmware snapshots-age --server ...) or, if the binary is invoked
+via a symlink named after a standalone plugin binary (e.g.,
+check_vmware_snapshots_age), using the name of the symlink.
+
+PROJECT HOME
+
+See our GitHub repo (https://github.com/atc0005/check-vmware) for the latest
+code, to file an issue or submit improvements for review and potential
+inclusion into the project.
+
+USAGE
+
+Run check_vmware --help for a list of supported subcommands. See our main
+README for supported settings and examples.
+
+*/
+package main
@@ -0,0 +1,93 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package main
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"text/tabwriter"
+
+	"github.com/atc0005/go-nagios"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/plugins"
+)
+
+func main() {
+
+	// Invoked via symlink (or copy) named after a plugin binary or type.
+	if plugin, ok := plugins.Lookup(filepath.Base(os.Args[0])); ok {
+		plugin.Run()
+		return
+	}
+
+	if len(os.Args) < 2 {
+		usage(os.Stdout)
+		fmt.Printf(
+			"\n%s: plugin subcommand not specified\n",
+			output.StateLabel(nagios.StateUNKNOWNExitCode),
+		)
+		os.Exit(nagios.StateUNKNOWNExitCode)
+	}
+
+	subcommand := os.Args[1]
+
+	switch subcommand {
+	case "-h", "-help", "--help", "help":
+		usage(os.Stdout)
+		return
+
+	case "-v", "-version", "--version", "version":
+		fmt.Println(config.Version())
+		return
+	}
+
+	plugin, ok := plugins.Lookup(subcommand)
+	if !ok {
+		usage(os.Stdout)
+		fmt.Printf(
+			"\n%s: unknown plugin subcommand %q\n",
+			output.StateLabel(nagios.StateUNKNOWNExitCode),
+			subcommand,
+		)
+		os.Exit(nagios.StateUNKNOWNExitCode)
+	}
+
+	// Remove the subcommand so that plugin flags are parsed as they would be
+	// for the standalone plugin binary. The subcommand is retained in the
+	// program name for use in help output.
+	os.Args = append(
+		[]string{os.Args[0] + " " + subcommand},
+		os.Args[2:]...,
+	)
+
+	plugin.Run()
+}
+
+// usage writes the list of supported plugin subcommands to w.
+func usage(w io.Writer) {
+
+	fmt.Fprintf(w, "\n%s\n\n", config.Version())
+	fmt.Fprintf(w, "Usage: %s PLUGIN [flags]\n\n", filepath.Base(os.Args[0]))
+	fmt.Fprintln(w, "Plugins:")
+
+	tw := tabwriter.NewWriter(w, 0, 8, 2, ' ', 0)
+	for _, plugin := range plugins.All() {
+		fmt.Fprintf(tw, "  %s\t(%s)\n", plugin.Name, plugin.Binary)
+	}
+	_ = tw.Flush()
+
+	fmt.Fprintf(
+		w,
+		"\nRun %s PLUGIN --help for the flags supported by a plugin.\n",
+		filepath.Base(os.Args[0]),
+	)
+}
@@ -188,7 +188,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine, Exclude VM CPU usage",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -219,7 +219,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine, Exclude VM CPU and memory usage",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -247,7 +247,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Pretend no alarms",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -276,7 +276,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude datastore usage, tacos on sale",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -308,7 +308,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Evaluate all",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -343,7 +343,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Evaluate all unacknowledged",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -377,7 +377,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include Tacos on sale",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -407,7 +407,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include Tacos on sale, evaluate acknowledged alarms",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -437,7 +437,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore usage",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -468,7 +468,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore usage, eval previously acknowledged",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -500,7 +500,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine type, eval previously acknowledged, exclude VM CPU usage, include red status",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -532,7 +532,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine type, exclude VM CPU usage, include yellow status",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -564,7 +564,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine type, include VM name",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -596,7 +596,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VM name substring",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -627,7 +627,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore name",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -657,7 +657,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore name HUSVM substring",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -687,7 +687,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore name RES substring",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -717,7 +717,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include datastore name RES substring, eval acknowledged",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -748,7 +748,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include VirtualMachine type, exclude VM CPU usage, include yellow status, exclude node1 substring",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -779,7 +779,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include yellow status, include development resource pool",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -810,7 +810,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include development resource pool",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -840,7 +840,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include production resource pool",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -871,7 +871,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include production resource pool, exclude development resource pool",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -902,7 +902,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Include development resource pool, exclude production resource pool",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -932,7 +932,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -964,7 +964,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools, eval acknowledged",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -996,7 +996,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools, eval acknowledged, exclude datastore type",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -1024,7 +1024,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools, exclude datastore type",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -1052,7 +1052,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools, exclude virtualmachine type",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -1083,7 +1083,7 @@ func TestFilters(t *testing.T) {
 		{
 			testName: "Exclude development, production resource pools, include virtualmachine type, include yellow status",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
@@ -1115,7 +1115,7 @@ func TestFilters(t *testing.T) {
 			// GH-220
 			testName: "Include vm type, exclude specific ds, exclude specific alarm name, include development, production rps, include red status",
 			cfg: config.Config{
-				Server:                           "vc1.example.com",
+				Servers:                          []string{"vc1.example.com"},
 				Username:                         "vc1-read-only-service-account",
 				Password:                         "placeholder",
 				Domain:                           "example",
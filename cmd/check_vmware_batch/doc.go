@@ -0,0 +1,30 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+/*
+
+Batch mode executing multiple checks using a single vSphere session.
+
+PURPOSE
+
+Execute the checks defined in a checks file (plugin type, Nagios host and
+service and plugin settings) using a single login to the ESXi host or vCenter
+instance and submit the results to Nagios as passive check results using the
+external command file or the check result spool directory.
+
+PROJECT HOME
+
+See our GitHub repo (https://github.com/atc0005/check-vmware) for the latest
+code, to file an issue or submit improvements for review and potential
+inclusion into the project.
+
+USAGE
+
+See our main README for supported settings and examples.
+
+*/
+package main
@@ -0,0 +1,18 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package main
+
+import (
+	"os"
+
+	"github.com/atc0005/check-vmware/internal/plugins"
+)
+
+func main() {
+	os.Exit(plugins.Batch())
+}
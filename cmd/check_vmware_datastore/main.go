@@ -7,264 +7,8 @@
 
 package main
 
-import (
-	"context"
-	"errors"
-	"fmt"
-
-	"github.com/atc0005/go-nagios"
-	"github.com/vmware/govmomi/units"
-
-	"github.com/atc0005/check-vmware/internal/config"
-	"github.com/atc0005/check-vmware/internal/vsphere"
-
-	zlog "github.com/rs/zerolog/log"
-)
+import "github.com/atc0005/check-vmware/internal/plugins"
 
 func main() {
-
-	// Set initial "state" as valid, adjust as we go.
-	var nagiosExitState = nagios.ExitState{
-		LastError:      nil,
-		ExitStatusCode: nagios.StateOKExitCode,
-	}
-
-	// defer this from the start so it is the last deferred function to run
-	defer nagiosExitState.ReturnCheckResults()
-
-	// Disable library debug logging output by default
-	// vsphere.EnableLogging()
-	vsphere.DisableLogging()
-
-	// Setup configuration by parsing user-provided flags. Note plugin type so
-	// that only applicable CLI flags are exposed and any plugin-specific
-	// settings are applied.
-	cfg, cfgErr := config.New(config.PluginType{DatastoresSize: true})
-	switch {
-	case errors.Is(cfgErr, config.ErrVersionRequested):
-		fmt.Println(config.Version())
-
-		return
-
-	case cfgErr != nil:
-		// We're using the standalone Err function from rs/zerolog/log as we
-		// do not have a working configuration.
-		zlog.Err(cfgErr).Msg("Error initializing application")
-		nagiosExitState.ServiceOutput = fmt.Sprintf(
-			"%s: Error initializing application",
-			nagios.StateCRITICALLabel,
-		)
-		nagiosExitState.LastError = cfgErr
-		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
-
-		return
-	}
-
-	// Enable library-level logging if debug logging level is enabled app-wide
-	if cfg.LoggingLevel == config.LogLevelDebug {
-		vsphere.EnableLogging()
-	}
-
-	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout())
-	defer cancel()
-
-	// Record thresholds for use as Nagios "Long Service Output" content. This
-	// content is shown in the detailed web UI and in notifications generated
-	// by Nagios.
-	nagiosExitState.CriticalThreshold = fmt.Sprintf(
-		"%d%% datastore usage",
-		cfg.DatastoreUsageCritical,
-	)
-
-	nagiosExitState.WarningThreshold = fmt.Sprintf(
-		"%d%% datastore usage",
-		cfg.DatastoreUsageWarning,
-	)
-
-	if cfg.EmitBranding {
-		// If enabled, show application details at end of notification
-		nagiosExitState.BrandingCallback = config.Branding("Notification generated by ")
-	}
-
-	dcName := cfg.DatacenterName
-	if dcName == "" {
-		dcName = "not provided"
-	}
-
-	log := cfg.Log.With().
-		Str("datastore_name", cfg.DatastoreName).
-		Str("datacenter_name", dcName).
-		Int("datastore_critical_usage", cfg.DatastoreUsageCritical).
-		Int("datastore_warning_usage", cfg.DatastoreUsageWarning).
-		Logger()
-
-	log.Debug().Msg("Logging into vSphere environment")
-	c, loginErr := vsphere.Login(
-		ctx, cfg.Server, cfg.Port, cfg.TrustCert,
-		cfg.Username, cfg.Domain, cfg.Password,
-		cfg.UserAgent(),
-	)
-	if loginErr != nil {
-		log.Error().Err(loginErr).Msgf("error logging into %s", cfg.Server)
-
-		nagiosExitState.LastError = loginErr
-		nagiosExitState.ServiceOutput = fmt.Sprintf(
-			"%s: Error logging into %q",
-			nagios.StateCRITICALLabel,
-			cfg.Server,
-		)
-		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
-
-		return
-	}
-	log.Debug().Msg("Successfully logged into vSphere environment")
-
-	defer func() {
-		if err := c.Logout(ctx); err != nil {
-			log.Error().
-				Err(err).
-				Msg("failed to logout")
-		}
-	}()
-
-	// At this point we're logged in, ready to retrieve the requested
-	// datastore.
-
-	log.Debug().Msg("Retrieving datastore by name")
-	datastore, dsFetchErr := vsphere.GetDatastoreByName(
-		ctx,
-		c.Client,
-		cfg.DatastoreName,
-		cfg.DatacenterName,
-		true,
-	)
-	if dsFetchErr != nil {
-		log.Error().Err(dsFetchErr).Msg(
-			"error retrieving requested datastore",
-		)
-
-		nagiosExitState.LastError = dsFetchErr
-		nagiosExitState.ServiceOutput = fmt.Sprintf(
-			"%s: Error retrieving datastore %q",
-			nagios.StateCRITICALLabel,
-			cfg.DatastoreName,
-		)
-		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
-
-		return
-	}
-
-	log.Debug().Msg("Successfully retrieved datastore by name")
-
-	log.Debug().Msg("Generating datastore usage summary")
-	dsUsage := vsphere.NewDatastoreUsageSummary(
-		datastore,
-		cfg.DatastoreUsageCritical,
-		cfg.DatastoreUsageWarning,
-	)
-
-	log.Debug().
-		Str("datastore_name", datastore.Name).
-		Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
-		Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
-		Str("datastore_storage_total", units.ByteSize(dsUsage.StorageTotal).String()).
-		Str("datastore_storage_used", units.ByteSize(dsUsage.StorageUsed).String()).
-		Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
-		Int("datastore_critical_threshold", dsUsage.CriticalThreshold).
-		Int("datastore_warning_threshold", dsUsage.WarningThreshold).
-		Msg("Datastore usage summary")
-
-	log.Debug().Msg("Retrieving VMs for datastore")
-	dsVMs, dsVMsFetchErr := vsphere.GetVMsFromDatastore(ctx, c.Client, datastore, true)
-	if dsVMsFetchErr != nil {
-		log.Error().Err(dsFetchErr).Msg(
-			"error retrieving VirtualMachines from datastore",
-		)
-
-		nagiosExitState.LastError = dsVMsFetchErr
-		nagiosExitState.ServiceOutput = fmt.Sprintf(
-			"%s: Error retrieving VirtualMachines from datastore %q",
-			nagios.StateCRITICALLabel,
-			cfg.DatastoreName,
-		)
-		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
-
-		return
-	}
-
-	log.Debug().Msg("Evaluating datastore usage state")
-	switch {
-	case dsUsage.IsCriticalState():
-
-		log.Error().
-			Str("datastore_name", datastore.Name).
-			Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
-			Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
-			Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
-			Msg("Datastore usage CRITICAL")
-
-		nagiosExitState.LastError = vsphere.ErrDatastoreUsageThresholdCrossed
-
-		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
-			nagios.StateCRITICALLabel,
-			dsUsage,
-		)
-
-		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
-			c.Client,
-			dsVMs,
-			dsUsage,
-		)
-
-		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
-
-		return
-
-	case dsUsage.IsWarningState():
-
-		log.Error().
-			Str("datastore_name", datastore.Name).
-			Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
-			Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
-			Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
-			Msg("Datastore usage CRITICAL")
-
-		nagiosExitState.LastError = vsphere.ErrDatastoreUsageThresholdCrossed
-
-		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
-			nagios.StateWARNINGLabel,
-			dsUsage,
-		)
-
-		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
-			c.Client,
-			dsVMs,
-			dsUsage,
-		)
-
-		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
-
-		return
-
-	default:
-
-		nagiosExitState.LastError = nil
-
-		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
-			nagios.StateOKLabel,
-			dsUsage,
-		)
-
-		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
-			c.Client,
-			dsVMs,
-			dsUsage,
-		)
-
-		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
-
-		return
-
-	}
-
+	plugins.DatastoresSize()
 }
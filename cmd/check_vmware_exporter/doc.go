@@ -0,0 +1,30 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+/*
+
+Prometheus exporter providing metrics for a vSphere environment.
+
+PURPOSE
+
+Expose datastore, host, Resource Pool, snapshot, VMware Tools, virtual
+hardware, triggered alarm and VirtualMachine uptime metrics for scraping by
+Prometheus. The exporter runs as a long-running service, logs in once and
+keeps the vSphere session active between scrape requests.
+
+PROJECT HOME
+
+See our GitHub repo (https://github.com/atc0005/check-vmware) for the latest
+code, to file an issue or submit improvements for review and potential
+inclusion into the project.
+
+USAGE
+
+See our main README for supported settings and examples.
+
+*/
+package main
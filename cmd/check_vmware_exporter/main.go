@@ -0,0 +1,126 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package main
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"os"
+	"os/signal"
+	"syscall"
+	"time"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/exporter"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+
+	zlog "github.com/rs/zerolog/log"
+)
+
+// shutdownTimeout is the time allowed for in-progress scrape requests to
+// complete and for the vSphere session to be logged out during shutdown.
+const shutdownTimeout = 30 * time.Second
+
+// landingPage is served for requests to the root path.
+const landingPage string = `<html>
+<head><title>%[1]s</title></head>
+<body>
+<h1>%[1]s</h1>
+<p><a href="%[2]s">Metrics</a></p>
+</body>
+</html>
+`
+
+func main() {
+
+	// Disable library debug logging output by default
+	vsphere.DisableLogging()
+
+	cfg, cfgErr := config.New(config.PluginType{Exporter: true})
+	switch {
+	case errors.Is(cfgErr, config.ErrVersionRequested):
+		fmt.Println(config.Version())
+
+		return
+
+	case cfgErr != nil:
+		// We're using the standalone Err function from rs/zerolog/log as we
+		// do not have a working configuration.
+		zlog.Err(cfgErr).Msg("Error initializing application")
+
+		os.Exit(1)
+	}
+
+	// Enable library-level logging if debug logging level is enabled app-wide
+	if cfg.LoggingLevel == config.LogLevelDebug {
+		vsphere.EnableLogging()
+	}
+
+	log := cfg.Log.With().
+		Str("listen_address", cfg.ListenAddress).
+		Str("metrics_path", cfg.MetricsPath).
+		Dur("cache_ttl", cfg.CacheTTL()).
+		Logger()
+
+	exp := exporter.New(cfg)
+
+	mux := http.NewServeMux()
+	mux.Handle(cfg.MetricsPath, exp)
+	mux.HandleFunc("/", func(w http.ResponseWriter, r *http.Request) {
+		if r.URL.Path != "/" {
+			http.NotFound(w, r)
+			return
+		}
+
+		w.Header().Set("Content-Type", "text/html; charset=utf-8")
+		if _, err := fmt.Fprintf(w, landingPage, config.Version(), cfg.MetricsPath); err != nil {
+			log.Error().Err(err).Msg("failed to write landing page")
+		}
+	})
+
+	server := &http.Server{
+		Addr:              cfg.ListenAddress,
+		Handler:           mux,
+		ReadHeaderTimeout: 10 * time.Second,
+	}
+
+	// Listen for shutdown signals so that the vSphere session can be logged
+	// out before the application exits.
+	done := make(chan struct{})
+	go func() {
+		sigs := make(chan os.Signal, 1)
+		signal.Notify(sigs, os.Interrupt, syscall.SIGTERM)
+		sig := <-sigs
+
+		log.Info().Str("signal", sig.String()).Msg("Shutting down")
+
+		ctx, cancel := context.WithTimeout(context.Background(), shutdownTimeout)
+		defer cancel()
+
+		if err := server.Shutdown(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to shutdown HTTP server")
+		}
+
+		if err := exp.Close(ctx); err != nil {
+			log.Error().Err(err).Msg("failed to logout")
+		}
+
+		close(done)
+	}()
+
+	log.Info().Msg("Starting exporter")
+	if err := server.ListenAndServe(); err != nil && !errors.Is(err, http.ErrServerClosed) {
+		log.Error().Err(err).Msg("HTTP server failed")
+
+		os.Exit(1)
+	}
+
+	<-done
+}
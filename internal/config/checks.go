@@ -0,0 +1,329 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"bytes"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+)
+
+// Checks file section names. Settings in the defaults section apply to all
+// checks, settings in a check section apply only to that check.
+const (
+	checksFileDefaultsSection    string = "defaults"
+	checksFileCheckSectionPrefix string = "check."
+)
+
+// Checks file settings which describe a check instead of providing a value
+// for a plugin flag.
+const (
+	checkSettingPlugin  string = "plugin"
+	checkSettingHost    string = "host"
+	checkSettingService string = "service"
+)
+
+// CheckDefinition describes a check executed in batch mode.
+type CheckDefinition struct {
+
+	// Name is the name of the check as specified in the checks file.
+	Name string
+
+	// Plugin is the plugin type label (e.g., snapshots-age) of the plugin
+	// used to perform the check.
+	Plugin string
+
+	// HostName is the name of the Nagios host associated with the passive
+	// check result.
+	HostName string
+
+	// ServiceDescription is the description of the Nagios service associated
+	// with the passive check result. The check name is used if not
+	// specified.
+	ServiceDescription string
+
+	// settings are the plugin flag settings for the check.
+	settings []configFileSetting
+
+	// path is the path to the checks file where the check was defined.
+	path string
+}
+
+// sharedCheckSettings are the flags which cannot be specified for individual
+// checks in batch mode. These settings are used to create the single vSphere
+// session shared by all checks or otherwise apply to the batch as a whole.
+var sharedCheckSettings = map[string]bool{
+	"server":            true,
+	"s":                 true,
+	"port":              true,
+	"p":                 true,
+	"username":          true,
+	"u":                 true,
+	"password":          true,
+	"pw":                true,
+	"password-file":     true,
+	"domain":            true,
+	"trust-cert":        true,
+	"ca-file":           true,
+	"thumbprint":        true,
+	"proxy":             true,
+	"connect-timeout":   true,
+	"retrieve-timeout":  true,
+	"retries":           true,
+	"retry-delay":       true,
+	"session-cache":     true,
+	"session-cache-dir": true,
+	"config":            true,
+	"profile":           true,
+	"output":            true,
+	"v":                 true,
+	"version":           true,
+	"h":                 true,
+	"help":              true,
+}
+
+// LoadCheckDefinitions reads the checks executed in batch mode from the
+// specified checks file. The checks file uses the same format as the
+// configuration file; each check is defined in a section named check.NAME
+// and settings in a defaults section apply to all checks. Checks are
+// returned in the order they were defined.
+func LoadCheckDefinitions(path string) ([]CheckDefinition, error) {
+
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		return nil, fmt.Errorf("failed to read checks file: %w", err)
+	}
+
+	cf, err := parseConfigFile(bytes.NewReader(content), path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse checks file: %w", err)
+	}
+
+	defaults := cf.sections[checksFileDefaultsSection]
+
+	var checks []CheckDefinition
+	for _, section := range cf.sectionNames {
+
+		if section == checksFileDefaultsSection {
+			continue
+		}
+
+		if !strings.HasPrefix(section, checksFileCheckSectionPrefix) {
+			return nil, fmt.Errorf(
+				"%s: unsupported section %q; expected %q or %q",
+				path,
+				section,
+				checksFileDefaultsSection,
+				checksFileCheckSectionPrefix+"NAME",
+			)
+		}
+
+		check, err := newCheckDefinition(
+			path,
+			strings.TrimPrefix(section, checksFileCheckSectionPrefix),
+			defaults,
+			cf.sections[section],
+		)
+		if err != nil {
+			return nil, err
+		}
+
+		checks = append(checks, check)
+	}
+
+	if len(checks) == 0 {
+		return nil, fmt.Errorf("no checks defined in checks file %s", path)
+	}
+
+	return checks, nil
+}
+
+// newCheckDefinition creates a check definition from the settings in the
+// defaults section and the section for the check. Settings from the check
+// section override all values for the same setting from the defaults
+// section.
+func newCheckDefinition(
+	path string,
+	name string,
+	defaults []configFileSetting,
+	settings []configFileSetting,
+) (CheckDefinition, error) {
+
+	check := CheckDefinition{
+		Name:               name,
+		ServiceDescription: name,
+		path:               path,
+	}
+
+	specified := make(map[string]bool)
+	for _, setting := range settings {
+		specified[setting.key] = true
+	}
+
+	merged := make([]configFileSetting, 0, len(defaults)+len(settings))
+	for _, setting := range defaults {
+		if !specified[setting.key] {
+			merged = append(merged, setting)
+		}
+	}
+	merged = append(merged, settings...)
+
+	for _, setting := range merged {
+		switch {
+		case setting.key == checkSettingPlugin:
+			check.Plugin = setting.value
+
+		case setting.key == checkSettingHost:
+			check.HostName = setting.value
+
+		case setting.key == checkSettingService:
+			check.ServiceDescription = setting.value
+
+		case sharedCheckSettings[setting.key]:
+			return CheckDefinition{}, fmt.Errorf(
+				"%s:%d: setting %q applies to all checks and is not supported in checks file",
+				path,
+				setting.line,
+				setting.key,
+			)
+
+		default:
+			check.settings = append(check.settings, setting)
+		}
+	}
+
+	switch {
+	case check.Plugin == "":
+		return CheckDefinition{}, fmt.Errorf(
+			"%s: check %q does not specify required setting %q",
+			path,
+			name,
+			checkSettingPlugin,
+		)
+
+	case check.HostName == "":
+		return CheckDefinition{}, fmt.Errorf(
+			"%s: check %q does not specify required setting %q",
+			path,
+			name,
+			checkSettingHost,
+		)
+	}
+
+	return check, nil
+}
+
+// CheckConfig creates the configuration for a check executed in batch mode
+// using the specified plugin type. Plugin flag settings from the check
+// definition are applied first, followed by settings from the configuration
+// file for any flags not specified for the check. Connection settings are
+// shared with the batch configuration so that all checks use the same
+// vSphere session.
+func (c Config) CheckConfig(pluginType PluginType, check CheckDefinition) (*Config, error) {
+	var config Config
+
+	fs := flag.NewFlagSet(check.Name, flag.ContinueOnError)
+	config.defineFlags(fs, pluginType)
+
+	// these settings are inherited from the batch unless specified for the
+	// check
+	config.timeout = c.timeout
+	config.LoggingLevel = c.LoggingLevel
+	config.EmitBranding = c.EmitBranding
+
+	for _, setting := range check.settings {
+		if fs.Lookup(setting.key) == nil {
+			return nil, fmt.Errorf(
+				"%s:%d: setting %q is not supported by plugin %q",
+				check.path,
+				setting.line,
+				setting.key,
+				pluginTypeLabel(pluginType),
+			)
+		}
+
+		if err := fs.Set(setting.key, setting.value); err != nil {
+			return nil, fmt.Errorf(
+				"%s:%d: invalid value %q for setting %q: %w",
+				check.path,
+				setting.line,
+				setting.value,
+				setting.key,
+				err,
+			)
+		}
+	}
+
+	config.App = AppInfo{
+		Name:    myAppName,
+		Version: version,
+		URL:     myAppURL,
+		Plugin:  pluginTypeLabel(pluginType),
+	}
+
+	config.ConfigFile = c.ConfigFile
+	config.Profile = c.Profile
+	if err := config.loadConfigFile(pluginType); err != nil {
+		return nil, fmt.Errorf("failed to load configuration file: %w", err)
+	}
+
+	// connection settings are shared by all checks
+	config.Servers = c.Servers
+	config.Port = c.Port
+	config.Username = c.Username
+	config.Password = c.Password
+	config.Domain = c.Domain
+	config.TrustCert = c.TrustCert
+	config.CAFile = c.CAFile
+	config.Thumbprint = c.Thumbprint
+	config.Proxy = c.Proxy
+	config.connectTimeout = c.connectTimeout
+	config.retrieveTimeout = c.retrieveTimeout
+	config.Retries = c.Retries
+	config.retryDelay = c.retryDelay
+	config.SessionCache = c.SessionCache
+	config.sessionCacheDir = c.sessionCacheDir
+	config.passwordSource = c.passwordSource
+	config.OutputFormat = OutputFormatText
+
+	if err := config.validate(pluginType); err != nil {
+		return nil, fmt.Errorf("configuration validation failed: %w", err)
+	}
+
+	if err := config.setupLogging(pluginType); err != nil {
+		return nil, fmt.Errorf(
+			"failed to set logging configuration: %w",
+			err,
+		)
+	}
+
+	// merge exclusions from the ignore file after logging is initialized so
+	// that expired entries may be logged
+	if err := config.loadIgnoreFile(); err != nil {
+		return nil, fmt.Errorf("failed to load ignore file: %w", err)
+	}
+
+	if err := config.setAlarmStatuses(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to evaluate provided triggered alarm status keywords: %w",
+			err,
+		)
+	}
+
+	if err := config.setHostStateMappings(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to evaluate provided host state mappings: %w",
+			err,
+		)
+	}
+
+	return &config, nil
+}
@@ -0,0 +1,90 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"testing"
+)
+
+func TestLoadCheckDefinitions(t *testing.T) {
+
+	content := `
+[defaults]
+host = vc1.example.com
+include-rp = Default
+
+[check.VMware Snapshots Age]
+plugin = snapshots-age
+age-warning = 2
+include-rp = Production
+include-rp = Staging
+
+[check.tools]
+plugin = vmware-tools
+host = vc2.example.com
+service = VMware Tools
+`
+
+	path := filepath.Join(t.TempDir(), "checks.ini")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	checks, err := LoadCheckDefinitions(path)
+	if err != nil {
+		t.Fatalf("LoadCheckDefinitions returned unexpected error: %v", err)
+	}
+
+	if len(checks) != 2 {
+		t.Fatalf("got %d checks; want 2", len(checks))
+	}
+
+	first := checks[0]
+	if first.Plugin != PluginTypeSnapshotsAge ||
+		first.HostName != "vc1.example.com" ||
+		first.ServiceDescription != "VMware Snapshots Age" {
+		t.Errorf("unexpected first check: %+v", first)
+	}
+
+	// check settings replace all values for the same setting from defaults
+	var includeRPs []string
+	for _, setting := range first.settings {
+		if setting.key == "include-rp" {
+			includeRPs = append(includeRPs, setting.value)
+		}
+	}
+	if len(includeRPs) != 2 || includeRPs[0] != "Production" || includeRPs[1] != "Staging" {
+		t.Errorf("got include-rp values %v; want [Production Staging]", includeRPs)
+	}
+
+	second := checks[1]
+	if second.HostName != "vc2.example.com" || second.ServiceDescription != "VMware Tools" {
+		t.Errorf("unexpected second check: %+v", second)
+	}
+
+	invalid := map[string]string{
+		"shared setting":  "[check.a]\nplugin = alarms\nhost = h\nserver = vc1",
+		"missing plugin":  "[check.a]\nhost = h",
+		"missing host":    "[check.a]\nplugin = alarms",
+		"unknown section": "[profile.a]\nplugin = alarms\nhost = h",
+		"no checks":       "[defaults]\nhost = h",
+	}
+
+	for name, content := range invalid {
+		path := filepath.Join(t.TempDir(), "checks.ini")
+		if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+			t.Fatal(err)
+		}
+
+		if _, err := LoadCheckDefinitions(path); err == nil {
+			t.Errorf("%s: expected error", name)
+		}
+	}
+}
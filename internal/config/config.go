@@ -43,10 +43,13 @@ type PluginType struct {
 	Host2Datastores2VMs            bool
 	HostSystemMemory               bool
 	HostSystemCPU                  bool
+	HostSystemStatus               bool
 	VirtualMachinePowerCycleUptime bool
 	DiskConsolidation              bool
 	InteractiveQuestion            bool
 	Alarms                         bool
+	Exporter                       bool
+	Batch                          bool
 
 	// TODO:
 	// - vCenter/server time (NTP)
@@ -113,9 +116,11 @@ func (mvs *multiValueStringFlag) Set(value string) error {
 // command-line flags.
 type Config struct {
 
-	// Server is the fully-qualified domain name of the system running a
-	// certificate-enabled service.
-	Server string
+	// Servers is the list of fully-qualified domain names (or IP Addresses)
+	// of the ESXi hosts or vCenter instances evaluated by the plugin. Only
+	// plugins which aggregate results across servers support multiple
+	// servers.
+	Servers multiValueStringFlag
 
 	// Username is the user account used to login to the ESXi host or vCenter
 	// instance.
@@ -125,6 +130,15 @@ type Config struct {
 	// or vCenter instance.
 	Password string
 
+	// PasswordFile is the path to a file containing the password associated
+	// with the account used to login to the ESXi host or vCenter instance. If
+	// specified as "-", the password is read from standard input.
+	PasswordFile string
+
+	// passwordSource records how the password was provided (e.g., flag,
+	// file, environment variable) for troubleshooting purposes.
+	passwordSource string
+
 	// Domain is the domain for the user account used to login to the ESXi
 	// host or vCenter instance.
 	Domain string
@@ -136,6 +150,22 @@ type Config struct {
 	// LoggingLevel is the supported logging level for this application.
 	LoggingLevel string
 
+	// OutputFormat is the format used to emit plugin results.
+	OutputFormat string
+
+	// ServiceName is the name of the Checkmk service used when emitting
+	// Checkmk local check output.
+	ServiceName string
+
+	// ConfigFile is the fully-qualified path to a configuration file
+	// providing default settings. If not specified, a default set of paths
+	// is searched.
+	ConfigFile string
+
+	// Profile is the name of the configuration file profile (e.g., a
+	// specific vCenter instance) whose settings should be applied.
+	Profile string
+
 	// hostCustomAttributeName is a Custom Attribute name specific to hosts.
 	// If specified, the user must also specify the Custom Attribute name
 	// specific to datastores.
@@ -196,6 +226,11 @@ type Config struct {
 	// vSphere inventory.
 	HostSystemName string
 
+	// HostSystemNames lists ESXi hosts/servers (by name as found in the
+	// vSphere inventory) used to limit evaluation to VirtualMachine objects
+	// running on those hosts.
+	HostSystemNames multiValueStringFlag
+
 	// IncludedResourcePools lists resource pools that are explicitly
 	// monitored. Specifying list values automatically excludes VirtualMachine
 	// objects outside a Resource Pool.
@@ -205,6 +240,32 @@ type Config struct {
 	// or excluded from being monitored.
 	ExcludedResourcePools multiValueStringFlag
 
+	// IncludedFolders lists VM folders (by name or inventory path) that are
+	// explicitly monitored. Specifying list values automatically excludes
+	// VirtualMachine objects outside a folder.
+	IncludedFolders multiValueStringFlag
+
+	// ExcludedFolders lists VM folders (by name or inventory path) that are
+	// explicitly ignored or excluded from being monitored.
+	ExcludedFolders multiValueStringFlag
+
+	// IncludedTags lists vSphere tags (in category:tag format) attached to
+	// objects which are explicitly monitored.
+	IncludedTags multiValueStringFlag
+
+	// ExcludedTags lists vSphere tags (in category:tag format) attached to
+	// objects which are explicitly ignored or excluded from being monitored.
+	ExcludedTags multiValueStringFlag
+
+	// IncludedCustomAttributes lists Custom Attribute filters (in name=value
+	// format) matching VirtualMachines which are explicitly monitored.
+	IncludedCustomAttributes multiValueStringFlag
+
+	// ExcludedCustomAttributes lists Custom Attribute filters (in name=value
+	// format) matching VirtualMachines which are explicitly ignored or
+	// excluded from being monitored.
+	ExcludedCustomAttributes multiValueStringFlag
+
 	// IgnoredVM is a list of VMs that are explicitly ignored or excluded
 	// from being monitored.
 	IgnoredVMs multiValueStringFlag
@@ -214,6 +275,17 @@ type Config struct {
 	// with its current host.
 	IgnoredDatastores multiValueStringFlag
 
+	// IgnoreFile is the fully-qualified path to a file listing VM, datastore
+	// and alarm name exclusions along with optional expiration dates and
+	// reasons for each exclusion.
+	IgnoreFile string
+
+	// IgnoreEntries is the collection of entries from the ignore file
+	// applicable to the current plugin type, including expired entries.
+	// Names from active entries are merged into the IgnoredVMs,
+	// IgnoredDatastores and ExcludedAlarmNames lists.
+	IgnoreEntries []IgnoreEntry
+
 	// IncludedAlarmEntityTypes is a list of entity types for Alarms that will
 	// be explicitly included for evaluation. Unless included by later
 	// filtering logic, unmatched Triggered Alarms will be excluded from final
@@ -298,6 +370,17 @@ type Config struct {
 	// keywords. See the exported field of the same name for more information.
 	excludedAlarmStatuses multiValueStringFlag
 
+	// hostStateMappings is a list of user-specified host state to Nagios
+	// state mappings in state=nagios-state format. This list will be
+	// validated and then merged with the default mappings. See the exported
+	// field of the same name for more information.
+	hostStateMappings multiValueStringFlag
+
+	// HostStateMappings maps each supported host state keyword (e.g.,
+	// maintenance) to the Nagios state keyword (e.g., warning) used when a
+	// host is found in that state.
+	HostStateMappings map[string]string
+
 	// IncludedAlarmNames is a list of statuses for Triggered Alarms that will
 	// be explicitly included for evaluation. Unless included by later
 	// filtering logic, unmatched Triggered Alarms will be excluded from final
@@ -318,25 +401,30 @@ type Config struct {
 	// Log is an embedded zerolog Logger initialized via config.New().
 	Log zerolog.Logger
 
+	// flags is the flag set used to define and parse the configuration
+	// flags. This is used to determine which flags were explicitly specified
+	// by the user.
+	flags *flag.FlagSet
+
 	// HostSystemMemoryUseWarning specifies the percentage of memory use (as a
 	// whole number) for the specified ESXi host when a WARNING threshold is
 	// reached.
-	HostSystemMemoryUseWarning int
+	HostSystemMemoryUseWarning Range
 
 	// HostSystemMemoryUseCritical specifies the percentage of memory use (as
 	// a whole number) for the specified ESXi host when a CRITICAL threshold
 	// is reached.
-	HostSystemMemoryUseCritical int
+	HostSystemMemoryUseCritical Range
 
 	// HostSystemCPUUseWarning specifies the percentage of CPU use (as a whole
 	// number) for the specified ESXi host when a WARNING threshold is
 	// reached.
-	HostSystemCPUUseWarning int
+	HostSystemCPUUseWarning Range
 
 	// HostSystemCPUUseCritical specifies the percentage of CPU use (as a
 	// whole number) for the specified ESXi host when a CRITICAL threshold is
 	// reached.
-	HostSystemCPUUseCritical int
+	HostSystemCPUUseCritical Range
 
 	// Port is the TCP port used by the certifcate-enabled service.
 	Port int
@@ -346,13 +434,55 @@ type Config struct {
 	// returned.
 	timeout int
 
+	// connectTimeout is the number of seconds allowed for logging into the
+	// ESXi host or vCenter instance. If zero, only the plugin execution
+	// timeout applies.
+	connectTimeout int
+
+	// retrieveTimeout is the number of seconds allowed for each vSphere API
+	// request made after logging in. If zero, only the plugin execution
+	// timeout applies.
+	retrieveTimeout int
+
+	// Retries is the maximum number of retries for vSphere API requests
+	// failing with a transient error.
+	Retries int
+
+	// retryDelay is the number of seconds before the first retry of a
+	// failed vSphere API request.
+	retryDelay int
+
+	// cacheTTL is the number of seconds that metrics collected by the
+	// exporter are reused before being collected again.
+	cacheTTL int
+
+	// ListenAddress is the network address (e.g., ":9879") used by the
+	// exporter to listen for scrape requests.
+	ListenAddress string
+
+	// MetricsPath is the path (e.g., "/metrics") used by the exporter to
+	// expose metrics.
+	MetricsPath string
+
+	// ChecksFile is the path to the file defining the checks executed in
+	// batch mode.
+	ChecksFile string
+
+	// CommandFile is the path to the Nagios external command file used to
+	// submit passive check results in batch mode.
+	CommandFile string
+
+	// SpoolDir is the path to the Nagios check result spool directory used
+	// to submit passive check results in batch mode.
+	SpoolDir string
+
 	// VCPUsAllocatedWarning specifies the percentage of vCPUs allocation (as
 	// a whole number) when a WARNING threshold is reached.
-	VCPUsAllocatedWarning int
+	VCPUsAllocatedWarning Range
 
 	// VCPUsAllocatedCritical specifies the percentage of vCPUs allocation (as
 	// a whole number) when a CRITICAL threshold is reached.
-	VCPUsAllocatedCritical int
+	VCPUsAllocatedCritical Range
 
 	// VCPUsMaxAllowed specifies the maximum amount of virtual CPUs (as a
 	// whole number) that we are allowed to allocate in the target VMware
@@ -362,12 +492,12 @@ type Config struct {
 	// ResourcePoolsMemoryUseWarning specifies the percentage of memory use
 	// (as a whole number) across all specified Resource Pools when a WARNING
 	// threshold is reached.
-	ResourcePoolsMemoryUseWarning int
+	ResourcePoolsMemoryUseWarning Range
 
 	// ResourcePoolsMemoryUseCritical specifies the percentage of memory use
 	// (as a whole number) across all specified Resource Pools when a CRITICAL
 	// threshold is reached.
-	ResourcePoolsMemoryUseCritical int
+	ResourcePoolsMemoryUseCritical Range
 
 	// ResourcePoolsMemoryMaxAllowed specifies the maximum amount of memory
 	// that we are allowed to consume in GB (as a whole number) in the target
@@ -378,43 +508,43 @@ type Config struct {
 
 	// DatastoreUsageWarning specifies the percentage of a datastore's storage
 	// usage (as a whole number) when a WARNING threshold is reached.
-	DatastoreUsageWarning int
+	DatastoreUsageWarning Range
 
 	// DatastoreUsageCritical specifies the percentage of a datastore's storage
 	// usage (as a whole number) when a CRITICAL threshold is reached.
-	DatastoreUsageCritical int
+	DatastoreUsageCritical Range
 
 	// SnapshotsSizeCritical specifies the cumulative size in GB of all
 	// snapshots for a VM when a WARNING threshold is reached.
-	SnapshotsSizeWarning int
+	SnapshotsSizeWarning Range
 
 	// SnapshotsSizeCritical specifies the cumulative size in GB of all
 	// snapshots for a VM when a CRITICAL threshold is reached.
-	SnapshotsSizeCritical int
+	SnapshotsSizeCritical Range
 
 	// SnapshotsAgeWarning specifies the age of a snapshot in days when a
 	// WARNING threshold is reached.
-	SnapshotsAgeWarning int
+	SnapshotsAgeWarning Range
 
 	// SnapshotsAgeCritical specifies the age of a snapshot in days when a
 	// CRITICAL threshold is reached.
-	SnapshotsAgeCritical int
+	SnapshotsAgeCritical Range
 
 	// SnapshotsCountWarning specifies the number of snapshots per VM when a
 	// WARNING threshold is reached.
-	SnapshotsCountWarning int
+	SnapshotsCountWarning Range
 
 	// SnapshotsCountCritical specifies the number of snapshots per VM when a
 	// CRITICAL threshold is reached.
-	SnapshotsCountCritical int
+	SnapshotsCountCritical Range
 
 	// VMPowerCycleUptimeWarning specifies the power cycle (off/on) uptime in
 	// days per VM when a WARNING threshold is reached.
-	VMPowerCycleUptimeWarning int
+	VMPowerCycleUptimeWarning Range
 
 	// VMPowerCycleUptimeCritical specifies the power cycle (off/on) uptime in
 	// days per VM when a CRITICAL threshold is reached.
-	VMPowerCycleUptimeCritical int
+	VMPowerCycleUptimeCritical Range
 
 	// VirtualHardwareMinimumVersion is the minimum virtual hardware version
 	// accepted for each Virtual Machine. Any Virtual Machine not meeting this
@@ -446,13 +576,77 @@ type Config struct {
 	// to powered on VMs.
 	PoweredOff bool
 
+	// RecursiveFolders indicates whether VMs within subfolders of the
+	// included or excluded VM folders are also matched.
+	RecursiveFolders bool
+
+	// RecursiveResourcePools indicates whether child Resource Pools and
+	// vApps of the included or excluded Resource Pools are also matched.
+	RecursiveResourcePools bool
+
 	// EvaluateAcknowledgedAlarms indicates whether acknowledged triggered
 	// alarms are evaluated in addition to unacknowledged ones.
 	EvaluateAcknowledgedAlarms bool
 
+	// CollectDatastores indicates whether the exporter collects datastore
+	// usage metrics.
+	CollectDatastores bool
+
+	// CollectHostSystems indicates whether the exporter collects host CPU
+	// and memory usage metrics.
+	CollectHostSystems bool
+
+	// CollectResourcePools indicates whether the exporter collects Resource
+	// Pool memory usage metrics.
+	CollectResourcePools bool
+
+	// CollectSnapshots indicates whether the exporter collects snapshot age,
+	// size and count metrics.
+	CollectSnapshots bool
+
+	// CollectVMTools indicates whether the exporter collects VMware Tools
+	// status metrics.
+	CollectVMTools bool
+
+	// CollectHardwareVersions indicates whether the exporter collects
+	// virtual hardware version metrics.
+	CollectHardwareVersions bool
+
+	// CollectAlarms indicates whether the exporter collects triggered alarm
+	// metrics.
+	CollectAlarms bool
+
+	// CollectVMUptime indicates whether the exporter collects VirtualMachine
+	// (power cycle) uptime metrics.
+	CollectVMUptime bool
+
 	// Whether the certificate should be trusted as-is without validation.
 	TrustCert bool
 
+	// CAFile is the fully-qualified path to a file containing PEM-encoded CA
+	// certificates used to validate the server certificate instead of the
+	// system certificate store.
+	CAFile string
+
+	// Thumbprint is the expected SHA-1 or SHA-256 fingerprint of the server
+	// certificate as colon-delimited hex digits. If specified, the server
+	// certificate is pinned instead of validating the certificate chain.
+	Thumbprint string
+
+	// Proxy is the URL of the proxy used to connect to the server. If not
+	// specified, the proxy is determined from the HTTPS_PROXY and NO_PROXY
+	// environment variables.
+	Proxy string
+
+	// SessionCache indicates whether an authenticated session should be
+	// cached and reused by later plugin executions instead of logging in and
+	// out each time the plugin is executed.
+	SessionCache bool
+
+	// sessionCacheDir is the user-specified directory used to store cached
+	// sessions. A default location is used if not specified.
+	sessionCacheDir string
+
 	// EmitBranding controls whether "generated by" text is included at the
 	// bottom of application output. This output is included in the Nagios
 	// dashboard and notifications. This output may not mix well with branding
@@ -524,6 +718,8 @@ func pluginTypeLabel(pluginType PluginType) string {
 
 	case pluginType.HostSystemCPU:
 		label = PluginTypeHostSystemCPU
+	case pluginType.HostSystemStatus:
+		label = PluginTypeHostSystemStatus
 
 	case pluginType.VirtualMachinePowerCycleUptime:
 		label = PluginTypeVirtualMachinePowerCycleUptime
@@ -537,6 +733,12 @@ func pluginTypeLabel(pluginType PluginType) string {
 	case pluginType.Alarms:
 		label = PluginTypeAlarms
 
+	case pluginType.Exporter:
+		label = PluginTypeExporter
+
+	case pluginType.Batch:
+		label = PluginTypeBatch
+
 	case pluginType.Tools:
 		label = PluginTypeTools
 
@@ -569,6 +771,17 @@ func New(pluginType PluginType) (*Config, error) {
 		return nil, ErrVersionRequested
 	}
 
+	// apply configuration file settings for any flags not specified by the
+	// user so that validation is performed against the merged result
+	if err := config.loadConfigFile(pluginType); err != nil {
+		return nil, fmt.Errorf("failed to load configuration file: %w", err)
+	}
+
+	// resolve credentials provided via file, stdin or environment variables
+	if err := config.loadCredentials(); err != nil {
+		return nil, fmt.Errorf("failed to load credentials: %w", err)
+	}
+
 	if err := config.validate(pluginType); err != nil {
 		return nil, fmt.Errorf("configuration validation failed: %w", err)
 	}
@@ -581,6 +794,16 @@ func New(pluginType PluginType) (*Config, error) {
 		)
 	}
 
+	config.Log.Debug().
+		Str("password_source", config.passwordSource).
+		Msg("Loaded credentials")
+
+	// merge exclusions from the ignore file after logging is initialized so
+	// that expired entries may be logged
+	if err := config.loadIgnoreFile(); err != nil {
+		return nil, fmt.Errorf("failed to load ignore file: %w", err)
+	}
+
 	// initialize exported TriggeredAlarm status inclusion and exclusion lists
 	// based on user-provided keywords after validation is complete
 	if err := config.setAlarmStatuses(); err != nil {
@@ -590,6 +813,15 @@ func New(pluginType PluginType) (*Config, error) {
 		)
 	}
 
+	// initialize exported host state mappings based on user-provided
+	// mappings after validation is complete
+	if err := config.setHostStateMappings(); err != nil {
+		return nil, fmt.Errorf(
+			"failed to evaluate provided host state mappings: %w",
+			err,
+		)
+	}
+
 	return &config, nil
 
 }
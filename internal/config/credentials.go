@@ -0,0 +1,183 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"bufio"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+)
+
+// Environment variables used as a fallback if credentials are not provided
+// via flag, password file or configuration file.
+const (
+	usernameEnvVar string = "CHECK_VMWARE_USERNAME"
+	passwordEnvVar string = "CHECK_VMWARE_PASSWORD"
+)
+
+// passwordFileStdin is the password file value used to indicate that the
+// password should be read from standard input.
+const passwordFileStdin string = "-"
+
+// Sources for the password used to login to the ESXi host or vCenter
+// instance. These values are recorded in debug logging in place of the
+// password itself.
+const (
+	passwordSourceFlag       string = "flag"
+	passwordSourceConfigFile string = "config-file"
+	passwordSourceFile       string = "password-file"
+	passwordSourceStdin      string = "stdin"
+	passwordSourceEnvVar     string = "environment"
+)
+
+// ErrInsecureFilePermissions indicates that a file containing secrets is
+// accessible by users other than the owner.
+var ErrInsecureFilePermissions = errors.New("insecure file permissions")
+
+// checkSecretFilePermissions asserts that the specified file is not
+// accessible by group members or other users. This check is skipped on
+// Windows where file permission bits are not meaningful.
+func checkSecretFilePermissions(path string) error {
+
+	if runtime.GOOS == "windows" {
+		return nil
+	}
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	if perm := info.Mode().Perm(); perm&0o077 != 0 {
+		return fmt.Errorf(
+			"%w: %s has mode %#o; access must be limited to the owner (e.g., 0600)",
+			ErrInsecureFilePermissions,
+			path,
+			perm,
+		)
+	}
+
+	return nil
+}
+
+// readPassword reads the password from the first line of r. Trailing
+// newline characters are removed.
+func readPassword(r io.Reader) (string, error) {
+
+	line, err := bufio.NewReader(r).ReadString('\n')
+	if err != nil && !errors.Is(err, io.EOF) {
+		return "", err
+	}
+
+	password := strings.TrimRight(line, "\r\n")
+	if password == "" {
+		return "", fmt.Errorf("empty password")
+	}
+
+	return password, nil
+}
+
+// readPasswordFile reads the password from the specified file, or from
+// standard input if the file is specified as "-". Files accessible by users
+// other than the owner are rejected.
+func readPasswordFile(path string) (string, error) {
+
+	if path == passwordFileStdin {
+		return readPassword(os.Stdin)
+	}
+
+	path = filepath.Clean(path)
+
+	if err := checkSecretFilePermissions(path); err != nil {
+		return "", err
+	}
+
+	fh, err := os.Open(path)
+	if err != nil {
+		return "", err
+	}
+
+	password, readErr := readPassword(fh)
+	if err := fh.Close(); err != nil && readErr == nil {
+		readErr = err
+	}
+
+	return password, readErr
+}
+
+// loadCredentials resolves the username and password used to login to the
+// ESXi host or vCenter instance. A password provided via flag, password file
+// or configuration file is used if available, otherwise environment
+// variables are checked. The source of the password is recorded for later
+// logging.
+func (c *Config) loadCredentials() error {
+
+	if c.Username == "" {
+		c.Username = os.Getenv(usernameEnvVar)
+	}
+
+	var passwordFlagSet, passwordFileFlagSet bool
+	c.flags.Visit(func(f *flag.Flag) {
+		switch f.Name {
+		case "password", "pw":
+			passwordFlagSet = true
+		case "password-file":
+			passwordFileFlagSet = true
+		}
+	})
+
+	// A password or password file specified via command-line flag takes
+	// precedence over the other option specified via configuration file.
+	switch {
+	case passwordFlagSet && !passwordFileFlagSet:
+		c.PasswordFile = ""
+	case passwordFileFlagSet && !passwordFlagSet:
+		c.Password = ""
+	}
+
+	switch {
+	case c.Password != "" && c.PasswordFile != "":
+		return fmt.Errorf(
+			"only one of %q or %q may be specified",
+			"password",
+			"password-file",
+		)
+
+	case c.PasswordFile != "":
+		password, err := readPasswordFile(c.PasswordFile)
+		if err != nil {
+			return fmt.Errorf("failed to read password file: %w", err)
+		}
+
+		c.Password = password
+		c.passwordSource = passwordSourceFile
+		if c.PasswordFile == passwordFileStdin {
+			c.passwordSource = passwordSourceStdin
+		}
+
+	case c.Password != "":
+		c.passwordSource = passwordSourceConfigFile
+		if passwordFlagSet {
+			c.passwordSource = passwordSourceFlag
+		}
+
+	default:
+		c.Password = os.Getenv(passwordEnvVar)
+		if c.Password != "" {
+			c.passwordSource = passwordSourceEnvVar
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,57 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"errors"
+	"io/ioutil"
+	"path/filepath"
+	"runtime"
+	"testing"
+)
+
+func TestReadPasswordFile(t *testing.T) {
+
+	dir := t.TempDir()
+
+	secure := filepath.Join(dir, "secure")
+	if err := ioutil.WriteFile(secure, []byte("s3cr3t value\r\nignored\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	got, err := readPasswordFile(secure)
+	if err != nil {
+		t.Fatalf("readPasswordFile returned unexpected error: %v", err)
+	}
+
+	if want := "s3cr3t value"; got != want {
+		t.Errorf("got password %q; want %q", got, want)
+	}
+
+	empty := filepath.Join(dir, "empty")
+	if err := ioutil.WriteFile(empty, []byte("\n"), 0600); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readPasswordFile(empty); err == nil {
+		t.Error("expected error for empty password file")
+	}
+
+	if runtime.GOOS == "windows" {
+		return
+	}
+
+	insecure := filepath.Join(dir, "insecure")
+	if err := ioutil.WriteFile(insecure, []byte("s3cr3t\n"), 0644); err != nil {
+		t.Fatal(err)
+	}
+
+	if _, err := readPasswordFile(insecure); !errors.Is(err, ErrInsecureFilePermissions) {
+		t.Errorf("got error %v; want %v", err, ErrInsecureFilePermissions)
+	}
+}
@@ -0,0 +1,345 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"flag"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"reflect"
+	"strings"
+)
+
+// Configuration file section names. Settings in the defaults section apply
+// to all plugins, settings in a plugin section apply to the plugin type with
+// the matching label and settings in a profile section apply only when the
+// profile is selected.
+const (
+	configFileDefaultsSection      string = "defaults"
+	configFilePluginSectionPrefix  string = "plugin."
+	configFileProfileSectionPrefix string = "profile."
+)
+
+// defaultConfigFileName is the name of the configuration file searched for
+// within the default configuration directories.
+const defaultConfigFileName string = "config.ini"
+
+// ErrConfigFileNotFound indicates that a configuration file specified by the
+// user could not be found.
+var ErrConfigFileNotFound = errors.New("configuration file not found")
+
+// configFileSetting is a single key/value pair from a configuration file
+// along with the line number where it was specified.
+type configFileSetting struct {
+	key   string
+	value string
+	line  int
+}
+
+// configFile represents the settings parsed from a configuration file,
+// grouped by section name in the order they were specified. Section names
+// are also recorded in the order they were first specified.
+type configFile struct {
+	path         string
+	sections     map[string][]configFileSetting
+	sectionNames []string
+}
+
+// defaultConfigFilePaths returns the paths searched, in order, for a
+// configuration file if one is not specified by the user.
+func defaultConfigFilePaths() []string {
+
+	var paths []string
+
+	if userConfigDir, err := os.UserConfigDir(); err == nil {
+		paths = append(paths, filepath.Join(userConfigDir, myAppName, defaultConfigFileName))
+	}
+
+	return append(paths, filepath.Join("/etc", myAppName, defaultConfigFileName))
+}
+
+// parseConfigFile parses configuration file content in a simple INI format.
+// Blank lines and lines beginning with '#' or ';' are ignored. Section names
+// are enclosed in square brackets and settings are specified as key = value
+// pairs where key is the name of a supported flag. Values may optionally be
+// enclosed in double quotes.
+func parseConfigFile(r io.Reader, path string) (*configFile, error) {
+
+	cf := configFile{
+		path:     path,
+		sections: make(map[string][]configFileSetting),
+	}
+
+	var section string
+	var lineNum int
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		switch {
+		case line == "",
+			strings.HasPrefix(line, "#"),
+			strings.HasPrefix(line, ";"):
+			continue
+
+		case strings.HasPrefix(line, "["):
+			if !strings.HasSuffix(line, "]") {
+				return nil, fmt.Errorf(
+					"%s:%d: invalid section header %q",
+					path,
+					lineNum,
+					line,
+				)
+			}
+
+			section = strings.TrimSpace(line[1 : len(line)-1])
+			if section == "" {
+				return nil, fmt.Errorf("%s:%d: empty section name", path, lineNum)
+			}
+
+			// record empty sections so that they may still be selected
+			if _, ok := cf.sections[section]; !ok {
+				cf.sections[section] = nil
+				cf.sectionNames = append(cf.sectionNames, section)
+			}
+
+			continue
+		}
+
+		if section == "" {
+			return nil, fmt.Errorf(
+				"%s:%d: setting specified outside of a section",
+				path,
+				lineNum,
+			)
+		}
+
+		idx := strings.Index(line, "=")
+		if idx < 0 {
+			return nil, fmt.Errorf(
+				"%s:%d: invalid setting %q; expected key = value",
+				path,
+				lineNum,
+				line,
+			)
+		}
+
+		key := strings.TrimSpace(line[:idx])
+		if key == "" {
+			return nil, fmt.Errorf("%s:%d: missing setting name", path, lineNum)
+		}
+
+		value := strings.TrimSpace(line[idx+1:])
+		if len(value) >= 2 && strings.HasPrefix(value, `"`) && strings.HasSuffix(value, `"`) {
+			value = value[1 : len(value)-1]
+		}
+
+		cf.sections[section] = append(cf.sections[section], configFileSetting{
+			key:   key,
+			value: value,
+			line:  lineNum,
+		})
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read %s: %w", path, err)
+	}
+
+	return &cf, nil
+}
+
+// settings returns the settings applicable to the specified plugin type and
+// profile. Settings from the defaults section are overridden by settings
+// from the plugin section which are in turn overridden by settings from the
+// profile section. Each setting key is associated with all values specified
+// for it within the section with the highest precedence. If provided, the
+// normalize function is used to map each setting key to the key used when
+// merging sections so that different names for the same setting (e.g., the
+// shorthand and long form of a flag) override each other.
+func (cf configFile) settings(
+	pluginLabel string,
+	profile string,
+	normalize func(key string) string,
+) (map[string][]configFileSetting, error) {
+
+	if normalize == nil {
+		normalize = func(key string) string { return key }
+	}
+
+	sections := []string{
+		configFileDefaultsSection,
+		configFilePluginSectionPrefix + pluginLabel,
+	}
+
+	if profile != "" {
+		profileSection := configFileProfileSectionPrefix + profile
+		if _, ok := cf.sections[profileSection]; !ok {
+			return nil, fmt.Errorf(
+				"profile %q not found in configuration file %s",
+				profile,
+				cf.path,
+			)
+		}
+		sections = append(sections, profileSection)
+	}
+
+	merged := make(map[string][]configFileSetting)
+	for _, section := range sections {
+		sectionSettings := make(map[string][]configFileSetting)
+		for _, setting := range cf.sections[section] {
+			key := normalize(setting.key)
+			sectionSettings[key] = append(sectionSettings[key], setting)
+		}
+
+		for key, values := range sectionSettings {
+			merged[key] = values
+		}
+	}
+
+	return merged, nil
+}
+
+// flagDestination returns an identifier for the variable where the value for
+// the given flag is stored. Shorthand and long flag names for the same
+// setting share the same destination.
+func flagDestination(f *flag.Flag) uintptr {
+	return reflect.ValueOf(f.Value).Pointer()
+}
+
+// flagKeyNormalizer returns a function which maps a configuration file
+// setting key to the long name of the flag sharing the same destination.
+// Keys which do not match a flag in the given flag set are returned as-is.
+func flagKeyNormalizer(fs *flag.FlagSet) func(key string) string {
+
+	names := make(map[uintptr]string)
+	fs.VisitAll(func(f *flag.Flag) {
+		dest := flagDestination(f)
+		if len(f.Name) > len(names[dest]) {
+			names[dest] = f.Name
+		}
+	})
+
+	return func(key string) string {
+		if f := fs.Lookup(key); f != nil {
+			return names[flagDestination(f)]
+		}
+
+		return key
+	}
+}
+
+// loadConfigFile applies settings from the configuration file specified by
+// the user (or found in the default search path) for any flag not explicitly
+// specified on the command line. Settings for flags not supported by the
+// specified plugin type are ignored so that profiles may be shared between
+// plugins.
+func (c *Config) loadConfigFile(pluginType PluginType) error {
+
+	path := c.ConfigFile
+	if path == "" {
+		for _, candidate := range defaultConfigFilePaths() {
+			if _, err := os.Stat(candidate); err == nil {
+				path = candidate
+				break
+			}
+		}
+	}
+
+	if path == "" {
+		if c.Profile != "" {
+			return fmt.Errorf(
+				"profile %q specified, but no configuration file found in %s",
+				c.Profile,
+				strings.Join(defaultConfigFilePaths(), ", "),
+			)
+		}
+
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(filepath.Clean(path))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrConfigFileNotFound, path)
+		}
+		return fmt.Errorf("failed to read configuration file: %w", err)
+	}
+
+	cf, err := parseConfigFile(bytes.NewReader(content), path)
+	if err != nil {
+		return fmt.Errorf("failed to parse configuration file: %w", err)
+	}
+
+	// settings for the shorthand and long form of the same flag are merged
+	// using the long flag name
+	settings, err := cf.settings(
+		pluginTypeLabel(pluginType),
+		c.Profile,
+		flagKeyNormalizer(c.flags),
+	)
+	if err != nil {
+		return err
+	}
+
+	// Command-line flags take precedence over configuration file settings.
+	// Track the destination of each flag explicitly set so that settings for
+	// the shorthand or long form of the same flag are also skipped.
+	setByUser := make(map[uintptr]bool)
+	c.flags.Visit(func(f *flag.Flag) {
+		setByUser[flagDestination(f)] = true
+	})
+
+	for key, values := range settings {
+
+		switch values[0].key {
+		case "config", "profile", "v", "version", "h", "help":
+			return fmt.Errorf(
+				"%s:%d: setting %q is not supported in configuration file",
+				path,
+				values[0].line,
+				values[0].key,
+			)
+		}
+
+		f := c.flags.Lookup(key)
+		if f == nil || setByUser[flagDestination(f)] {
+			continue
+		}
+
+		// configuration files providing passwords are subject to the same
+		// permission requirements as password files
+		if f.Name == "password" || f.Name == "pw" {
+			if err := checkSecretFilePermissions(path); err != nil {
+				return err
+			}
+		}
+
+		for _, setting := range values {
+			if err := f.Value.Set(setting.value); err != nil {
+				return fmt.Errorf(
+					"%s:%d: invalid value %q for setting %q: %w",
+					path,
+					setting.line,
+					setting.value,
+					setting.key,
+					err,
+				)
+			}
+		}
+	}
+
+	return nil
+}
@@ -0,0 +1,185 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+func TestConfigFileSettings(t *testing.T) {
+
+	content := `
+# settings applied to all plugins
+[defaults]
+port = 443
+trust-cert = true
+include-rp = Default
+
+[plugin.snapshots-age]
+age-warning = 2
+age-critical = 4
+
+[profile.vc1]
+server = "vc1.example.com"
+username = monitor
+include-rp = Production
+include-rp = Staging, Testing
+`
+
+	cf, err := parseConfigFile(strings.NewReader(content), "config.ini")
+	if err != nil {
+		t.Fatalf("parseConfigFile returned unexpected error: %v", err)
+	}
+
+	settings, err := cf.settings(PluginTypeSnapshotsAge, "vc1", nil)
+	if err != nil {
+		t.Fatalf("settings returned unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"port":         {"443"},
+		"trust-cert":   {"true"},
+		"age-warning":  {"2"},
+		"age-critical": {"4"},
+		"server":       {"vc1.example.com"},
+		"username":     {"monitor"},
+		"include-rp":   {"Production", "Staging, Testing"},
+	}
+
+	if len(settings) != len(want) {
+		t.Errorf("got %d settings; want %d", len(settings), len(want))
+	}
+
+	for key, wantValues := range want {
+		gotValues := settings[key]
+		if len(gotValues) != len(wantValues) {
+			t.Errorf("%s: got %d values; want %d", key, len(gotValues), len(wantValues))
+			continue
+		}
+		for i := range wantValues {
+			if gotValues[i].value != wantValues[i] {
+				t.Errorf("%s: got value %q; want %q", key, gotValues[i].value, wantValues[i])
+			}
+		}
+	}
+
+	// plugin sections for other plugin types are not applied
+	settings, err = cf.settings(PluginTypeDatastoresSize, "", nil)
+	if err != nil {
+		t.Fatalf("settings returned unexpected error: %v", err)
+	}
+
+	if _, ok := settings["age-warning"]; ok {
+		t.Error("settings for another plugin type were applied")
+	}
+
+	if _, err := cf.settings(PluginTypeSnapshotsAge, "missing", nil); err == nil {
+		t.Error("expected error for missing profile")
+	}
+}
+
+func TestConfigFileSettingsFlagForms(t *testing.T) {
+
+	content := `
+[defaults]
+s = vc1.example.com
+p = 8443
+u = monitor
+
+[profile.vc2]
+server = vc2.example.com
+username = nagios
+`
+
+	cf, err := parseConfigFile(strings.NewReader(content), "config.ini")
+	if err != nil {
+		t.Fatalf("parseConfigFile returned unexpected error: %v", err)
+	}
+
+	var c Config
+	c.defineFlags(flag.NewFlagSet("test", flag.ContinueOnError), PluginType{SnapshotsAge: true})
+
+	settings, err := cf.settings(PluginTypeSnapshotsAge, "vc2", flagKeyNormalizer(c.flags))
+	if err != nil {
+		t.Fatalf("settings returned unexpected error: %v", err)
+	}
+
+	want := map[string][]string{
+		"server":   {"vc2.example.com"},
+		"port":     {"8443"},
+		"username": {"nagios"},
+	}
+
+	if len(settings) != len(want) {
+		t.Errorf("got %d settings; want %d", len(settings), len(want))
+	}
+
+	for key, wantValues := range want {
+		gotValues := settings[key]
+		if len(gotValues) != len(wantValues) {
+			t.Errorf("%s: got %d values; want %d", key, len(gotValues), len(wantValues))
+			continue
+		}
+		for i := range wantValues {
+			if gotValues[i].value != wantValues[i] {
+				t.Errorf("%s: got value %q; want %q", key, gotValues[i].value, wantValues[i])
+			}
+		}
+	}
+
+	// the profile overrides the default server instead of adding to it
+	dir, err := ioutil.TempDir("", "check-vmware-config")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "config.ini")
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write configuration file: %v", err)
+	}
+
+	c = Config{}
+	c.defineFlags(flag.NewFlagSet("test", flag.ContinueOnError), PluginType{SnapshotsAge: true})
+	c.ConfigFile = path
+	c.Profile = "vc2"
+
+	if err := c.loadConfigFile(PluginType{SnapshotsAge: true}); err != nil {
+		t.Fatalf("loadConfigFile returned unexpected error: %v", err)
+	}
+
+	if len(c.Servers) != 1 || c.Servers[0] != "vc2.example.com" {
+		t.Errorf("got servers %v; want [vc2.example.com]", c.Servers)
+	}
+
+	if c.Port != 8443 {
+		t.Errorf("got port %d; want 8443", c.Port)
+	}
+}
+
+func TestParseConfigFileInvalid(t *testing.T) {
+
+	tests := []string{
+		"server = vc1.example.com",
+		"[defaults\nport = 443",
+		"[]",
+		"[defaults]\nserver",
+		"[defaults]\n= vc1.example.com",
+	}
+
+	for _, content := range tests {
+		if _, err := parseConfigFile(strings.NewReader(content), "config.ini"); err == nil {
+			t.Errorf("parseConfigFile(%q) returned nil error", content)
+		}
+	}
+}
@@ -9,6 +9,14 @@ package config
 
 import "flag"
 
+// rangeVar defines a threshold range flag within the given flag set with the
+// specified name, default value and usage string. The default value is used
+// as the initial value of the flag.
+func rangeVar(fs *flag.FlagSet, r *Range, name string, value string, usage string) {
+	*r = mustParseRange(value)
+	fs.Var(r, name, usage)
+}
+
 // handleFlagsConfig handles toggling the exposure of specific configuration
 // flags to the user. This behavior is controlled via the specified plugin
 // type as set by each cmd. Based on the plugin type, a smaller subset of
@@ -16,20 +24,61 @@ import "flag"
 // plugin types.
 func (c *Config) handleFlagsConfig(pluginType PluginType) {
 
+	c.defineFlags(flag.CommandLine, pluginType)
+
+	// Allow our function to override the default Help output
+	flag.Usage = Usage
+
+	// parse flag definitions from the argument list
+	flag.Parse()
+
+}
+
+// defineFlags defines the flags applicable to the specified plugin type
+// within the given flag set. The flag set is recorded so that flags
+// explicitly specified by the user can be identified later.
+func (c *Config) defineFlags(fs *flag.FlagSet, pluginType PluginType) {
+
+	c.flags = fs
+
 	// Flags specific to one plugin type or the other
 	switch {
 	case pluginType.Tools:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
-		flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
+		fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
 	case pluginType.SnapshotsAge:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
 		// NOTE: This plugin is hard-coded to evaluate powered off and powered
 		// on VMs equally. I'm not sure whether ignoring powered off VMs by
@@ -38,19 +87,31 @@ func (c *Config) handleFlagsConfig(pluginType PluginType) {
 		// Please share your feedback here if you feel differently:
 		// https://github.com/atc0005/check-vmware/discussions/177
 		//
-		// flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		// fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
-		flag.IntVar(&c.SnapshotsAgeWarning, "age-warning", defaultSnapshotsAgeWarning, snapshotsAgeWarningFlagHelp)
-		flag.IntVar(&c.SnapshotsAgeWarning, "aw", defaultSnapshotsAgeWarning, snapshotsAgeWarningFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsAgeWarning, "age-warning", defaultSnapshotsAgeWarning, snapshotsAgeWarningFlagHelp)
+		rangeVar(fs, &c.SnapshotsAgeWarning, "aw", defaultSnapshotsAgeWarning, snapshotsAgeWarningFlagHelp+" (shorthand)")
 
-		flag.IntVar(&c.SnapshotsAgeCritical, "age-critical", defaultSnapshotsAgeCritical, snapshotsAgeCriticalFlagHelp)
-		flag.IntVar(&c.SnapshotsAgeCritical, "ac", defaultSnapshotsAgeCritical, snapshotsAgeCriticalFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsAgeCritical, "age-critical", defaultSnapshotsAgeCritical, snapshotsAgeCriticalFlagHelp)
+		rangeVar(fs, &c.SnapshotsAgeCritical, "ac", defaultSnapshotsAgeCritical, snapshotsAgeCriticalFlagHelp+" (shorthand)")
 
 	case pluginType.SnapshotsCount:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
 		// NOTE: This plugin is hard-coded to evaluate powered off and powered
 		// on VMs equally. I'm not sure whether ignoring powered off VMs by
@@ -59,19 +120,31 @@ func (c *Config) handleFlagsConfig(pluginType PluginType) {
 		// Please share your feedback here if you feel differently:
 		// https://github.com/atc0005/check-vmware/discussions/177
 		//
-		// flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		// fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
-		flag.IntVar(&c.SnapshotsCountWarning, "count-warning", defaultSnapshotsCountWarning, snapshotsCountWarningFlagHelp)
-		flag.IntVar(&c.SnapshotsCountWarning, "cw", defaultSnapshotsCountWarning, snapshotsCountWarningFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsCountWarning, "count-warning", defaultSnapshotsCountWarning, snapshotsCountWarningFlagHelp)
+		rangeVar(fs, &c.SnapshotsCountWarning, "cw", defaultSnapshotsCountWarning, snapshotsCountWarningFlagHelp+" (shorthand)")
 
-		flag.IntVar(&c.SnapshotsCountCritical, "count-critical", defaultSnapshotsCountCritical, snapshotsCountCriticalFlagHelp)
-		flag.IntVar(&c.SnapshotsCountCritical, "cc", defaultSnapshotsCountCritical, snapshotsCountCriticalFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsCountCritical, "count-critical", defaultSnapshotsCountCritical, snapshotsCountCriticalFlagHelp)
+		rangeVar(fs, &c.SnapshotsCountCritical, "cc", defaultSnapshotsCountCritical, snapshotsCountCriticalFlagHelp+" (shorthand)")
 
 	case pluginType.SnapshotsSize:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
 		// NOTE: This plugin is hard-coded to evaluate powered off and powered
 		// on VMs equally. I'm not sure whether ignoring powered off VMs by
@@ -80,31 +153,55 @@ func (c *Config) handleFlagsConfig(pluginType PluginType) {
 		// Please share your feedback here if you feel differently:
 		// https://github.com/atc0005/check-vmware/discussions/177
 		//
-		// flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		// fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
-		flag.IntVar(&c.SnapshotsSizeWarning, "size-warning", defaultSnapshotsSizeWarning, snapshotsSizeWarningFlagHelp)
-		flag.IntVar(&c.SnapshotsSizeWarning, "sw", defaultSnapshotsSizeWarning, snapshotsSizeWarningFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsSizeWarning, "size-warning", defaultSnapshotsSizeWarning, snapshotsSizeWarningFlagHelp)
+		rangeVar(fs, &c.SnapshotsSizeWarning, "sw", defaultSnapshotsSizeWarning, snapshotsSizeWarningFlagHelp+" (shorthand)")
 
-		flag.IntVar(&c.SnapshotsSizeCritical, "size-critical", defaultSnapshotsSizeCritical, snapshotsSizeCriticalFlagHelp)
-		flag.IntVar(&c.SnapshotsSizeCritical, "sc", defaultSnapshotsSizeCritical, snapshotsSizeCriticalFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.SnapshotsSizeCritical, "size-critical", defaultSnapshotsSizeCritical, snapshotsSizeCriticalFlagHelp)
+		rangeVar(fs, &c.SnapshotsSizeCritical, "sc", defaultSnapshotsSizeCritical, snapshotsSizeCriticalFlagHelp+" (shorthand)")
 
 	case pluginType.VirtualMachinePowerCycleUptime:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
-
-		flag.IntVar(&c.VMPowerCycleUptimeWarning, "uptime-warning", defaultVMPowerCycleUptimeWarning, vmPowerCycleUptimeWarningFlagHelp)
-		flag.IntVar(&c.VMPowerCycleUptimeWarning, "uw", defaultVMPowerCycleUptimeWarning, vmPowerCycleUptimeWarningFlagHelp+" (shorthand)")
-
-		flag.IntVar(&c.VMPowerCycleUptimeCritical, "uptime-critical", defaultVMPowerCycleUptimeCritical, vmPowerCycleUptimeCriticalFlagHelp)
-		flag.IntVar(&c.VMPowerCycleUptimeCritical, "uc", defaultVMPowerCycleUptimeCritical, vmPowerCycleUptimeCriticalFlagHelp+" (shorthand)")
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
+
+		rangeVar(fs, &c.VMPowerCycleUptimeWarning, "uptime-warning", defaultVMPowerCycleUptimeWarning, vmPowerCycleUptimeWarningFlagHelp)
+		rangeVar(fs, &c.VMPowerCycleUptimeWarning, "uw", defaultVMPowerCycleUptimeWarning, vmPowerCycleUptimeWarningFlagHelp+" (shorthand)")
+
+		rangeVar(fs, &c.VMPowerCycleUptimeCritical, "uptime-critical", defaultVMPowerCycleUptimeCritical, vmPowerCycleUptimeCriticalFlagHelp)
+		rangeVar(fs, &c.VMPowerCycleUptimeCritical, "uc", defaultVMPowerCycleUptimeCritical, vmPowerCycleUptimeCriticalFlagHelp+" (shorthand)")
 
 	case pluginType.DiskConsolidation:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
 		// NOTE: This plugin is hard-coded to evaluate powered off and powered
 		// on VMs equally. I'm not sure whether ignoring powered off VMs by
@@ -115,181 +212,288 @@ func (c *Config) handleFlagsConfig(pluginType PluginType) {
 		//
 		// Please expand on some use cases for ignoring powered off VMs by default.
 		//
-		// flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		// fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
 	case pluginType.InteractiveQuestion:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
 	case pluginType.Alarms:
 
-		flag.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
-		flag.Var(&c.IncludedAlarmEntityTypes, "include-entity-type", includedAlarmEntityTypesFlagHelp)
-		flag.Var(&c.ExcludedAlarmEntityTypes, "exclude-entity-type", excludedAlarmEntityTypesFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.Var(&c.IncludedAlarmEntityTypes, "include-entity-type", includedAlarmEntityTypesFlagHelp)
+		fs.Var(&c.ExcludedAlarmEntityTypes, "exclude-entity-type", excludedAlarmEntityTypesFlagHelp)
 
-		flag.BoolVar(&c.EvaluateAcknowledgedAlarms, "eval-acknowledged", defaultEvaluateAcknowledgedAlarms, evaluateAcknowledgedTriggeredAlarmFlagHelp)
+		fs.BoolVar(&c.EvaluateAcknowledgedAlarms, "eval-acknowledged", defaultEvaluateAcknowledgedAlarms, evaluateAcknowledgedTriggeredAlarmFlagHelp)
 
-		flag.Var(&c.IncludedAlarmNames, "include-name", includedAlarmNamesFlagHelp)
-		flag.Var(&c.ExcludedAlarmNames, "exclude-name", excludedAlarmNamesFlagHelp)
+		fs.Var(&c.IncludedAlarmNames, "include-name", includedAlarmNamesFlagHelp)
+		fs.Var(&c.ExcludedAlarmNames, "exclude-name", excludedAlarmNamesFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
-		flag.Var(&c.IncludedAlarmDescriptions, "include-desc", includedAlarmDescriptionsFlagHelp)
-		flag.Var(&c.ExcludedAlarmDescriptions, "exclude-desc", excludedAlarmDescriptionsFlagHelp)
+		fs.Var(&c.IncludedAlarmDescriptions, "include-desc", includedAlarmDescriptionsFlagHelp)
+		fs.Var(&c.ExcludedAlarmDescriptions, "exclude-desc", excludedAlarmDescriptionsFlagHelp)
 
-		flag.Var(&c.includedAlarmStatuses, "include-status", includedAlarmStatusesFlagHelp)
-		flag.Var(&c.excludedAlarmStatuses, "exclude-status", excludedAlarmStatusesFlagHelp)
+		fs.Var(&c.includedAlarmStatuses, "include-status", includedAlarmStatusesFlagHelp)
+		fs.Var(&c.excludedAlarmStatuses, "exclude-status", excludedAlarmStatusesFlagHelp)
 
-		flag.Var(&c.IncludedAlarmEntityNames, "include-entity-name", includedAlarmEntityNamesFlagHelp)
-		flag.Var(&c.ExcludedAlarmEntityNames, "exclude-entity-name", excludedAlarmEntityNamesFlagHelp)
+		fs.Var(&c.IncludedAlarmEntityNames, "include-entity-name", includedAlarmEntityNamesFlagHelp)
+		fs.Var(&c.ExcludedAlarmEntityNames, "exclude-entity-name", excludedAlarmEntityNamesFlagHelp)
 
-		flag.Var(&c.IncludedAlarmEntityResourcePools, "include-entity-rp", includedAlarmEntityResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedAlarmEntityResourcePools, "exclude-entity-rp", excludedAlarmEntityResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedAlarmEntityResourcePools, "include-entity-rp", includedAlarmEntityResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedAlarmEntityResourcePools, "exclude-entity-rp", excludedAlarmEntityResourcePoolsFlagHelp)
 
-	case pluginType.DatastoresSize:
+	case pluginType.Exporter:
 
-		flag.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
+		fs.StringVar(&c.ListenAddress, "listen-address", defaultListenAddress, listenAddressFlagHelp)
+		fs.StringVar(&c.MetricsPath, "metrics-path", defaultMetricsPath, metricsPathFlagHelp)
+		fs.IntVar(&c.cacheTTL, "cache-ttl", defaultCacheTTL, cacheTTLFlagHelp)
 
-		flag.StringVar(&c.DatastoreName, "ds-name", defaultDatastoreName, datastoreNameFlagHelp)
+		fs.BoolVar(&c.CollectDatastores, "collect-datastores", defaultCollectorEnabled, collectDatastoresFlagHelp)
+		fs.BoolVar(&c.CollectHostSystems, "collect-hosts", defaultCollectorEnabled, collectHostSystemsFlagHelp)
+		fs.BoolVar(&c.CollectResourcePools, "collect-rps", defaultCollectorEnabled, collectResourcePoolsFlagHelp)
+		fs.BoolVar(&c.CollectSnapshots, "collect-snapshots", defaultCollectorEnabled, collectSnapshotsFlagHelp)
+		fs.BoolVar(&c.CollectVMTools, "collect-tools", defaultCollectorEnabled, collectVMToolsFlagHelp)
+		fs.BoolVar(&c.CollectHardwareVersions, "collect-vhw", defaultCollectorEnabled, collectHardwareVersionsFlagHelp)
+		fs.BoolVar(&c.CollectAlarms, "collect-alarms", defaultCollectorEnabled, collectAlarmsFlagHelp)
+		fs.BoolVar(&c.CollectVMUptime, "collect-vm-uptime", defaultCollectorEnabled, collectVMUptimeFlagHelp)
 
-		flag.IntVar(&c.DatastoreUsageWarning, "ds-usage-warning", defaultDatastoreUsageWarning, datastoreUsageWarningFlagHelp)
-		flag.IntVar(&c.DatastoreUsageWarning, "dsuw", defaultDatastoreUsageWarning, datastoreUsageWarningFlagHelp+" (shorthand)")
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
 
-		flag.IntVar(&c.DatastoreUsageCritical, "ds-usage-critical", defaultDatastoreUsageCritical, datastoreUsageCriticalFlagHelp)
-		flag.IntVar(&c.DatastoreUsageCritical, "dsuc", defaultDatastoreUsageCritical, datastoreUsageCriticalFlagHelp+" (shorthand)")
+	case pluginType.Batch:
 
-	case pluginType.HostSystemMemory:
+		fs.StringVar(&c.ChecksFile, "checks-file", defaultChecksFile, checksFileFlagHelp)
+		fs.StringVar(&c.CommandFile, "command-file", defaultCommandFile, commandFileFlagHelp)
+		fs.StringVar(&c.SpoolDir, "spool-dir", defaultSpoolDir, spoolDirFlagHelp)
 
-		flag.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
+	case pluginType.DatastoresSize:
 
-		flag.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
+		fs.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
 
-		flag.IntVar(&c.HostSystemMemoryUseWarning, "memory-usage-warning", defaultMemoryUseWarning, hostSystemMemoryUseWarningFlagHelp)
-		flag.IntVar(&c.HostSystemMemoryUseWarning, "mw", defaultMemoryUseWarning, hostSystemMemoryUseWarningFlagHelp+" (shorthand)")
+		fs.StringVar(&c.DatastoreName, "ds-name", defaultDatastoreName, datastoreNameFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
 
-		flag.IntVar(&c.HostSystemMemoryUseCritical, "memory-usage-critical", defaultMemoryUseCritical, hostSystemMemoryUseCriticalFlagHelp)
-		flag.IntVar(&c.HostSystemMemoryUseCritical, "mc", defaultMemoryUseCritical, hostSystemMemoryUseCriticalFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.DatastoreUsageWarning, "ds-usage-warning", defaultDatastoreUsageWarning, datastoreUsageWarningFlagHelp)
+		rangeVar(fs, &c.DatastoreUsageWarning, "dsuw", defaultDatastoreUsageWarning, datastoreUsageWarningFlagHelp+" (shorthand)")
 
-	case pluginType.HostSystemCPU:
+		rangeVar(fs, &c.DatastoreUsageCritical, "ds-usage-critical", defaultDatastoreUsageCritical, datastoreUsageCriticalFlagHelp)
+		rangeVar(fs, &c.DatastoreUsageCritical, "dsuc", defaultDatastoreUsageCritical, datastoreUsageCriticalFlagHelp+" (shorthand)")
 
-		flag.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
+	case pluginType.HostSystemMemory:
 
-		flag.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
+		fs.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
 
-		flag.IntVar(&c.HostSystemCPUUseWarning, "cpu-usage-warning", defaultCPUUseWarning, hostSystemCPUUseWarningFlagHelp)
-		flag.IntVar(&c.HostSystemCPUUseWarning, "cw", defaultCPUUseWarning, hostSystemCPUUseWarningFlagHelp+" (shorthand)")
+		fs.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
 
-		flag.IntVar(&c.HostSystemCPUUseCritical, "cpu-usage-critical", defaultCPUUseCritical, hostSystemCPUUseCriticalFlagHelp)
-		flag.IntVar(&c.HostSystemCPUUseCritical, "cc", defaultCPUUseCritical, hostSystemCPUUseCriticalFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.HostSystemMemoryUseWarning, "memory-usage-warning", defaultMemoryUseWarning, hostSystemMemoryUseWarningFlagHelp)
+		rangeVar(fs, &c.HostSystemMemoryUseWarning, "mw", defaultMemoryUseWarning, hostSystemMemoryUseWarningFlagHelp+" (shorthand)")
 
-	case pluginType.ResourcePoolsMemory:
+		rangeVar(fs, &c.HostSystemMemoryUseCritical, "memory-usage-critical", defaultMemoryUseCritical, hostSystemMemoryUseCriticalFlagHelp)
+		rangeVar(fs, &c.HostSystemMemoryUseCritical, "mc", defaultMemoryUseCritical, hostSystemMemoryUseCriticalFlagHelp+" (shorthand)")
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+	case pluginType.HostSystemCPU:
 
-		flag.IntVar(&c.ResourcePoolsMemoryUseWarning, "memory-use-warning", defaultMemoryUseWarning, resourcePoolsMemoryUseWarningFlagHelp)
-		flag.IntVar(&c.ResourcePoolsMemoryUseWarning, "mw", defaultMemoryUseWarning, resourcePoolsMemoryUseWarningFlagHelp+" (shorthand)")
+		fs.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
 
-		flag.IntVar(&c.ResourcePoolsMemoryUseCritical, "memory-use-critical", defaultMemoryUseCritical, resourcePoolsMemoryUseCriticalFlagHelp)
-		flag.IntVar(&c.ResourcePoolsMemoryUseCritical, "mc", defaultMemoryUseCritical, resourcePoolsMemoryUseCriticalFlagHelp+" (shorthand)")
+		fs.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
 
-		flag.IntVar(&c.ResourcePoolsMemoryMaxAllowed, "memory-max-allowed", defaultResourcePoolsMemoryMaxAllowed, resourcePoolsMemoryMaxAllowedFlagHelp)
-		flag.IntVar(&c.ResourcePoolsMemoryMaxAllowed, "mma", defaultResourcePoolsMemoryMaxAllowed, resourcePoolsMemoryMaxAllowedFlagHelp+" (shorthand)")
+		rangeVar(fs, &c.HostSystemCPUUseWarning, "cpu-usage-warning", defaultCPUUseWarning, hostSystemCPUUseWarningFlagHelp)
+		rangeVar(fs, &c.HostSystemCPUUseWarning, "cw", defaultCPUUseWarning, hostSystemCPUUseWarningFlagHelp+" (shorthand)")
 
-	case pluginType.VirtualCPUsAllocation:
+		rangeVar(fs, &c.HostSystemCPUUseCritical, "cpu-usage-critical", defaultCPUUseCritical, hostSystemCPUUseCriticalFlagHelp)
+		rangeVar(fs, &c.HostSystemCPUUseCritical, "cc", defaultCPUUseCritical, hostSystemCPUUseCriticalFlagHelp+" (shorthand)")
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
-		flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+	case pluginType.HostSystemStatus:
 
-		flag.IntVar(&c.VCPUsAllocatedWarning, "vcpus-warning", defaultVCPUsAllocatedWarning, vCPUsAllocatedWarningFlagHelp)
-		flag.IntVar(&c.VCPUsAllocatedWarning, "vw", defaultVCPUsAllocatedWarning, vCPUsAllocatedWarningFlagHelp+" (shorthand)")
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", hostStatusHostSystemNamesFlagHelp)
+		fs.Var(&c.hostStateMappings, "state-map", hostStateMappingsFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
 
-		flag.IntVar(&c.VCPUsAllocatedCritical, "vcpus-critical", defaultVCPUsAllocatedCritical, vCPUsAllocatedCriticalFlagHelp)
-		flag.IntVar(&c.VCPUsAllocatedCritical, "vc", defaultVCPUsAllocatedCritical, vCPUsAllocatedCriticalFlagHelp+" (shorthand)")
+	case pluginType.ResourcePoolsMemory:
 
-		flag.IntVar(&c.VCPUsMaxAllowed, "vcpus-max-allowed", defaultVCPUsMaxAllowed, vCPUsAllocatedMaxAllowedFlagHelp)
-		flag.IntVar(&c.VCPUsMaxAllowed, "vcma", defaultVCPUsMaxAllowed, vCPUsAllocatedMaxAllowedFlagHelp+" (shorthand)")
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
 
-	case pluginType.VirtualHardwareVersion:
+		rangeVar(fs, &c.ResourcePoolsMemoryUseWarning, "memory-use-warning", defaultMemoryUseWarning, resourcePoolsMemoryUseWarningFlagHelp)
+		rangeVar(fs, &c.ResourcePoolsMemoryUseWarning, "mw", defaultMemoryUseWarning, resourcePoolsMemoryUseWarningFlagHelp+" (shorthand)")
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
-		flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		rangeVar(fs, &c.ResourcePoolsMemoryUseCritical, "memory-use-critical", defaultMemoryUseCritical, resourcePoolsMemoryUseCriticalFlagHelp)
+		rangeVar(fs, &c.ResourcePoolsMemoryUseCritical, "mc", defaultMemoryUseCritical, resourcePoolsMemoryUseCriticalFlagHelp+" (shorthand)")
 
-		flag.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
-		flag.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
-		flag.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterNameFlagHelp)
+		fs.IntVar(&c.ResourcePoolsMemoryMaxAllowed, "memory-max-allowed", defaultResourcePoolsMemoryMaxAllowed, resourcePoolsMemoryMaxAllowedFlagHelp)
+		fs.IntVar(&c.ResourcePoolsMemoryMaxAllowed, "mma", defaultResourcePoolsMemoryMaxAllowed, resourcePoolsMemoryMaxAllowedFlagHelp+" (shorthand)")
 
-		flag.IntVar(&c.VirtualHardwareOutdatedByWarning, "outdated-by-warning", defaultVirtualHardwareOutdatedByWarning, virtualHardwareOutdatedByWarningFlagHelp)
-		flag.IntVar(&c.VirtualHardwareOutdatedByWarning, "obw", defaultVirtualHardwareOutdatedByWarning, virtualHardwareOutdatedByWarningFlagHelp+" (shorthand)")
+	case pluginType.VirtualCPUsAllocation:
 
-		flag.IntVar(&c.VirtualHardwareOutdatedByCritical, "outdated-by-critical", defaultVirtualHardwareOutdatedByCritical, virtualHardwareOutdatedByCriticalFlagHelp)
-		flag.IntVar(&c.VirtualHardwareOutdatedByCritical, "obc", defaultVirtualHardwareOutdatedByCritical, virtualHardwareOutdatedByCriticalFlagHelp+" (shorthand)")
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
+		fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+
+		rangeVar(fs, &c.VCPUsAllocatedWarning, "vcpus-warning", defaultVCPUsAllocatedWarning, vCPUsAllocatedWarningFlagHelp)
+		rangeVar(fs, &c.VCPUsAllocatedWarning, "vw", defaultVCPUsAllocatedWarning, vCPUsAllocatedWarningFlagHelp+" (shorthand)")
+
+		rangeVar(fs, &c.VCPUsAllocatedCritical, "vcpus-critical", defaultVCPUsAllocatedCritical, vCPUsAllocatedCriticalFlagHelp)
+		rangeVar(fs, &c.VCPUsAllocatedCritical, "vc", defaultVCPUsAllocatedCritical, vCPUsAllocatedCriticalFlagHelp+" (shorthand)")
+
+		fs.IntVar(&c.VCPUsMaxAllowed, "vcpus-max-allowed", defaultVCPUsMaxAllowed, vCPUsAllocatedMaxAllowedFlagHelp)
+		fs.IntVar(&c.VCPUsMaxAllowed, "vcma", defaultVCPUsMaxAllowed, vCPUsAllocatedMaxAllowedFlagHelp+" (shorthand)")
 
-		flag.IntVar(&c.VirtualHardwareMinimumVersion, "minimum-version", defaultVirtualHardwareMinimumVersion, virtualHardwareMinimumVersionFlagHelp)
-		flag.IntVar(&c.VirtualHardwareMinimumVersion, "mv", defaultVirtualHardwareMinimumVersion, virtualHardwareMinimumVersionFlagHelp+" (shorthand)")
+	case pluginType.VirtualHardwareVersion:
 
-		flag.BoolVar(&c.VirtualHardwareDefaultVersionIsMinimum, "default-is-min-version", defaultVirtualHardwareDefaultIsMinimum, virtualHardwareDefaultIsMinimumFlagHelp)
-		flag.BoolVar(&c.VirtualHardwareDefaultVersionIsMinimum, "dimv", defaultVirtualHardwareDefaultIsMinimum, virtualHardwareDefaultIsMinimumFlagHelp+" (shorthand)")
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
+		fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+
+		fs.StringVar(&c.DatacenterName, "dc-name", defaultDatacenterName, datacenterNameFlagHelp)
+		fs.StringVar(&c.HostSystemName, "host-name", defaultHostSystemName, hostSystemNameFlagHelp)
+		fs.Var(&c.HostSystemNames, "vm-host-name", vmHostSystemNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterNameFlagHelp)
+
+		fs.IntVar(&c.VirtualHardwareOutdatedByWarning, "outdated-by-warning", defaultVirtualHardwareOutdatedByWarning, virtualHardwareOutdatedByWarningFlagHelp)
+		fs.IntVar(&c.VirtualHardwareOutdatedByWarning, "obw", defaultVirtualHardwareOutdatedByWarning, virtualHardwareOutdatedByWarningFlagHelp+" (shorthand)")
+
+		fs.IntVar(&c.VirtualHardwareOutdatedByCritical, "outdated-by-critical", defaultVirtualHardwareOutdatedByCritical, virtualHardwareOutdatedByCriticalFlagHelp)
+		fs.IntVar(&c.VirtualHardwareOutdatedByCritical, "obc", defaultVirtualHardwareOutdatedByCritical, virtualHardwareOutdatedByCriticalFlagHelp+" (shorthand)")
+
+		fs.IntVar(&c.VirtualHardwareMinimumVersion, "minimum-version", defaultVirtualHardwareMinimumVersion, virtualHardwareMinimumVersionFlagHelp)
+		fs.IntVar(&c.VirtualHardwareMinimumVersion, "mv", defaultVirtualHardwareMinimumVersion, virtualHardwareMinimumVersionFlagHelp+" (shorthand)")
+
+		fs.BoolVar(&c.VirtualHardwareDefaultVersionIsMinimum, "default-is-min-version", defaultVirtualHardwareDefaultIsMinimum, virtualHardwareDefaultIsMinimumFlagHelp)
+		fs.BoolVar(&c.VirtualHardwareDefaultVersionIsMinimum, "dimv", defaultVirtualHardwareDefaultIsMinimum, virtualHardwareDefaultIsMinimumFlagHelp+" (shorthand)")
 
 	case pluginType.Host2Datastores2VMs:
 
-		flag.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
-		flag.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
-		flag.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
-		flag.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
+		fs.Var(&c.DatacenterNames, "dc-name", datacenterNamesFlagHelp)
+		fs.StringVar(&c.ClusterName, "cluster-name", defaultClusterName, clusterScopeFlagHelp)
+		fs.Var(&c.HostSystemNames, "host-name", vmHostSystemNamesFlagHelp)
+		fs.Var(&c.IncludedResourcePools, "include-rp", vmIncludedResourcePoolsFlagHelp)
+		fs.Var(&c.ExcludedResourcePools, "exclude-rp", vmExcludedResourcePoolsFlagHelp)
+		fs.BoolVar(&c.RecursiveResourcePools, "recursive-rp", defaultRecursiveResourcePools, recursiveResourcePoolsFlagHelp)
+		fs.Var(&c.IncludedFolders, "include-folder", vmIncludedFoldersFlagHelp)
+		fs.Var(&c.ExcludedFolders, "exclude-folder", vmExcludedFoldersFlagHelp)
+		fs.BoolVar(&c.RecursiveFolders, "recursive-folder", defaultRecursiveFolders, recursiveFoldersFlagHelp)
+		fs.Var(&c.IncludedTags, "include-tag", includedTagsFlagHelp)
+		fs.Var(&c.ExcludedTags, "exclude-tag", excludedTagsFlagHelp)
+		fs.Var(&c.IncludedCustomAttributes, "include-ca", includedCustomAttributesFlagHelp)
+		fs.Var(&c.ExcludedCustomAttributes, "exclude-ca", excludedCustomAttributesFlagHelp)
+		fs.Var(&c.IgnoredVMs, "ignore-vm", ignoreVMsFlagHelp)
+		fs.BoolVar(&c.PoweredOff, "powered-off", defaultPoweredOff, poweredOffFlagHelp)
 
-		flag.Var(&c.IgnoredDatastores, "ignore-ds", ignoreDatastoreFlagHelp)
+		fs.Var(&c.IgnoredDatastores, "ignore-ds", ignoreDatastoreFlagHelp)
+		fs.StringVar(&c.IgnoreFile, "ignore-file", defaultIgnoreFile, ignoreFileFlagHelp)
 
-		flag.StringVar(&c.sharedCustomAttributeName, "ca-name", defaultCustomAttributeName, sharedCustomAttributeNameFlagHelp)
-		flag.StringVar(&c.sharedCustomAttributePrefixSeparator, "ca-prefix-sep", defaultCustomAttributePrefixSeparator, sharedCustomAttributePrefixSeparatorFlagHelp)
+		fs.StringVar(&c.sharedCustomAttributeName, "ca-name", defaultCustomAttributeName, sharedCustomAttributeNameFlagHelp)
+		fs.StringVar(&c.sharedCustomAttributePrefixSeparator, "ca-prefix-sep", defaultCustomAttributePrefixSeparator, sharedCustomAttributePrefixSeparatorFlagHelp)
 
-		flag.StringVar(&c.hostCustomAttributeName, "host-ca-name", defaultCustomAttributeName, hostCustomAttributeNameFlagHelp)
-		flag.StringVar(&c.hostCustomAttributePrefixSeparator, "host-ca-prefix-sep", defaultCustomAttributePrefixSeparator, hostCustomAttributePrefixSeparatorFlagHelp)
+		fs.StringVar(&c.hostCustomAttributeName, "host-ca-name", defaultCustomAttributeName, hostCustomAttributeNameFlagHelp)
+		fs.StringVar(&c.hostCustomAttributePrefixSeparator, "host-ca-prefix-sep", defaultCustomAttributePrefixSeparator, hostCustomAttributePrefixSeparatorFlagHelp)
 
-		flag.StringVar(&c.datastoreCustomAttributeName, "ds-ca-name", defaultCustomAttributeName, datastoreCustomAttributeNameFlagHelp)
-		flag.StringVar(&c.datastoreCustomAttributePrefixSeparator, "ds-ca-prefix-sep", defaultCustomAttributePrefixSeparator, datastoreCustomAttributePrefixSeparatorFlagHelp)
+		fs.StringVar(&c.datastoreCustomAttributeName, "ds-ca-name", defaultCustomAttributeName, datastoreCustomAttributeNameFlagHelp)
+		fs.StringVar(&c.datastoreCustomAttributePrefixSeparator, "ds-ca-prefix-sep", defaultCustomAttributePrefixSeparator, datastoreCustomAttributePrefixSeparatorFlagHelp)
 
-		flag.BoolVar(&c.IgnoreMissingCustomAttribute, "ignore-missing-ca", defaultIgnoreMissingCustomAttribute, ignoreMissingCustomAttributeFlagHelp)
+		fs.BoolVar(&c.IgnoreMissingCustomAttribute, "ignore-missing-ca", defaultIgnoreMissingCustomAttribute, ignoreMissingCustomAttributeFlagHelp)
 
 	}
 
 	// Shared flags for all plugin types
 
-	flag.StringVar(&c.Username, "username", defaultUsername, usernameFlagHelp)
-	flag.StringVar(&c.Username, "u", defaultUsername, usernameFlagHelp+" (shorthand)")
-	flag.StringVar(&c.Password, "password", defaultPassword, passwordFlagHelp)
-	flag.StringVar(&c.Password, "pw", defaultPassword, passwordFlagHelp+" (shorthand)")
+	fs.StringVar(&c.Username, "username", defaultUsername, usernameFlagHelp)
+	fs.StringVar(&c.Username, "u", defaultUsername, usernameFlagHelp+" (shorthand)")
+	fs.StringVar(&c.Password, "password", defaultPassword, passwordFlagHelp)
+	fs.StringVar(&c.Password, "pw", defaultPassword, passwordFlagHelp+" (shorthand)")
+	fs.StringVar(&c.PasswordFile, "password-file", defaultPasswordFile, passwordFileFlagHelp)
 
 	// TODO: Is this actually needed?
-	flag.StringVar(&c.Domain, "domain", defaultUserDomain, userDomainFlagHelp)
+	fs.StringVar(&c.Domain, "domain", defaultUserDomain, userDomainFlagHelp)
 
-	flag.BoolVar(&c.TrustCert, "trust-cert", defaultTrustCert, trustCertFlagHelp)
+	fs.BoolVar(&c.TrustCert, "trust-cert", defaultTrustCert, trustCertFlagHelp)
+	fs.StringVar(&c.CAFile, "ca-file", defaultCAFile, caFileFlagHelp)
+	fs.StringVar(&c.Thumbprint, "thumbprint", defaultThumbprint, thumbprintFlagHelp)
+	fs.StringVar(&c.Proxy, "proxy", defaultProxy, proxyFlagHelp)
 
-	flag.BoolVar(&c.EmitBranding, "branding", defaultBranding, brandingFlagHelp)
+	fs.BoolVar(&c.SessionCache, "session-cache", defaultSessionCache, sessionCacheFlagHelp)
+	fs.StringVar(&c.sessionCacheDir, "session-cache-dir", defaultSessionCacheDir, sessionCacheDirFlagHelp)
 
-	flag.StringVar(&c.Server, "s", defaultServer, serverFlagHelp+" (shorthand)")
-	flag.StringVar(&c.Server, "server", defaultServer, serverFlagHelp)
+	fs.BoolVar(&c.EmitBranding, "branding", defaultBranding, brandingFlagHelp)
 
-	flag.IntVar(&c.Port, "p", defaultPort, portFlagHelp+" (shorthand)")
-	flag.IntVar(&c.Port, "port", defaultPort, portFlagHelp)
+	fs.Var(&c.Servers, "s", serverFlagHelp+" (shorthand)")
+	fs.Var(&c.Servers, "server", serverFlagHelp)
 
-	flag.IntVar(&c.timeout, "t", defaultConnectTimeout, timeoutConnectFlagHelp)
-	flag.IntVar(&c.timeout, "timeout", defaultConnectTimeout, timeoutConnectFlagHelp)
+	fs.IntVar(&c.Port, "p", defaultPort, portFlagHelp+" (shorthand)")
+	fs.IntVar(&c.Port, "port", defaultPort, portFlagHelp)
 
-	flag.StringVar(&c.LoggingLevel, "ll", defaultLogLevel, logLevelFlagHelp)
-	flag.StringVar(&c.LoggingLevel, "log-level", defaultLogLevel, logLevelFlagHelp)
+	fs.IntVar(&c.timeout, "t", defaultConnectTimeout, timeoutConnectFlagHelp)
+	fs.IntVar(&c.timeout, "timeout", defaultConnectTimeout, timeoutConnectFlagHelp)
+	fs.IntVar(&c.connectTimeout, "connect-timeout", defaultLoginTimeout, connectTimeoutFlagHelp)
+	fs.IntVar(&c.retrieveTimeout, "retrieve-timeout", defaultRetrieveTimeout, retrieveTimeoutFlagHelp)
+	fs.IntVar(&c.Retries, "retries", defaultRetries, retriesFlagHelp)
+	fs.IntVar(&c.retryDelay, "retry-delay", defaultRetryDelay, retryDelayFlagHelp)
 
-	flag.BoolVar(&c.ShowVersion, "v", defaultDisplayVersionAndExit, versionFlagHelp)
-	flag.BoolVar(&c.ShowVersion, "version", defaultDisplayVersionAndExit, versionFlagHelp)
+	fs.StringVar(&c.LoggingLevel, "ll", defaultLogLevel, logLevelFlagHelp)
+	fs.StringVar(&c.LoggingLevel, "log-level", defaultLogLevel, logLevelFlagHelp)
 
-	// Allow our function to override the default Help output
-	flag.Usage = Usage
+	fs.StringVar(&c.OutputFormat, "output", defaultOutputFormat, outputFormatFlagHelp)
+	fs.StringVar(&c.ServiceName, "service-name", defaultServiceName, serviceNameFlagHelp)
 
-	// parse flag definitions from the argument list
-	flag.Parse()
+	fs.StringVar(&c.ConfigFile, "config", defaultConfigFile, configFileFlagHelp)
+	fs.StringVar(&c.Profile, "profile", defaultProfile, profileFlagHelp)
+
+	fs.BoolVar(&c.ShowVersion, "v", defaultDisplayVersionAndExit, versionFlagHelp)
+	fs.BoolVar(&c.ShowVersion, "version", defaultDisplayVersionAndExit, versionFlagHelp)
 
 }
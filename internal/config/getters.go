@@ -9,9 +9,26 @@ package config
 
 import (
 	"fmt"
+	"os"
+	"path/filepath"
 	"time"
 )
 
+// Server returns the first (or only) user-specified server. This is the
+// server evaluated by plugins which do not support multiple servers.
+func (c Config) Server() string {
+	if len(c.Servers) == 0 {
+		return ""
+	}
+
+	return c.Servers[0]
+}
+
+// MultipleServers indicates whether multiple servers were specified.
+func (c Config) MultipleServers() bool {
+	return len(c.Servers) > 1
+}
+
 // Timeout converts the user-specified connection timeout value in
 // seconds to an appropriate time duration value for use with setting
 // initial connection attempt timeout value.
@@ -19,6 +36,55 @@ func (c Config) Timeout() time.Duration {
 	return time.Duration(c.timeout) * time.Second
 }
 
+// ConnectTimeout converts the user-specified login timeout value in seconds
+// to a time duration value. The plugin execution timeout is returned if a
+// login timeout was not specified.
+func (c Config) ConnectTimeout() time.Duration {
+	if c.connectTimeout == 0 {
+		return c.Timeout()
+	}
+
+	return time.Duration(c.connectTimeout) * time.Second
+}
+
+// RetrieveTimeout converts the user-specified vSphere API request timeout
+// value in seconds to a time duration value. A zero value indicates that
+// requests are limited only by the plugin execution timeout.
+func (c Config) RetrieveTimeout() time.Duration {
+	return time.Duration(c.retrieveTimeout) * time.Second
+}
+
+// RetryDelay converts the user-specified delay before the first retry of a
+// failed vSphere API request in seconds to a time duration value.
+func (c Config) RetryDelay() time.Duration {
+	return time.Duration(c.retryDelay) * time.Second
+}
+
+// CacheTTL converts the user-specified exporter cache TTL value in seconds to
+// a time duration value. A zero value indicates that caching is disabled.
+func (c Config) CacheTTL() time.Duration {
+	return time.Duration(c.cacheTTL) * time.Second
+}
+
+// SessionCacheDir returns the directory used to store cached sessions or an
+// empty string if session caching is disabled. The user cache directory is
+// used if a directory was not specified by the user.
+func (c Config) SessionCacheDir() string {
+	if !c.SessionCache {
+		return ""
+	}
+
+	if c.sessionCacheDir != "" {
+		return c.sessionCacheDir
+	}
+
+	// config validation asserts that the user cache directory is available
+	// if a session cache directory is not specified
+	userCacheDir, _ := os.UserCacheDir()
+
+	return filepath.Join(userCacheDir, myAppName)
+}
+
 // add getters to indicate whether user has specified a shared custom
 // attribute or whether separate host and datastore attributes are used.
 
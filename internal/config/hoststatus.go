@@ -0,0 +1,89 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DefaultHostStateMappings returns the Nagios state keyword used for each
+// supported host state keyword unless overridden by the user.
+func DefaultHostStateMappings() map[string]string {
+	return map[string]string{
+		HostStateConnected:     NagiosStateOK,
+		HostStateMaintenance:   NagiosStateOK,
+		HostStateQuarantine:    NagiosStateWarning,
+		HostStateStandby:       NagiosStateWarning,
+		HostStatePoweredOff:    NagiosStateCritical,
+		HostStateDisconnected:  NagiosStateCritical,
+		HostStateNotResponding: NagiosStateCritical,
+		HostStateUnknown:       NagiosStateUnknown,
+	}
+}
+
+// getNagiosStates is a helper function that returns a map of supported
+// Nagios state keywords. This is used to provide keyword validation for host
+// state mappings.
+func getNagiosStates() map[string]struct{} {
+	return map[string]struct{}{
+		NagiosStateOK:       {},
+		NagiosStateWarning:  {},
+		NagiosStateCritical: {},
+		NagiosStateUnknown:  {},
+	}
+}
+
+// setHostStateMappings evaluates user-provided host state mappings and
+// assigns the result of merging them with the default mappings to the
+// exported field for later use. This method should be called *after* config
+// validation has been performed.
+func (c *Config) setHostStateMappings() error {
+
+	mappings := DefaultHostStateMappings()
+	nagiosStates := getNagiosStates()
+
+	for _, mapping := range c.hostStateMappings {
+		if mapping == "" {
+			continue
+		}
+
+		parts := strings.SplitN(mapping, "=", 2)
+		if len(parts) != 2 {
+			return fmt.Errorf(
+				"invalid host state mapping %q; expected state=nagios-state",
+				mapping,
+			)
+		}
+
+		hostState := strings.ToLower(strings.TrimSpace(parts[0]))
+		if _, ok := mappings[hostState]; !ok {
+			return fmt.Errorf(
+				"invalid host state %q in mapping %q",
+				parts[0],
+				mapping,
+			)
+		}
+
+		nagiosState := strings.ToLower(strings.TrimSpace(parts[1]))
+		if _, ok := nagiosStates[nagiosState]; !ok {
+			return fmt.Errorf(
+				"invalid Nagios state %q in mapping %q",
+				parts[1],
+				mapping,
+			)
+		}
+
+		mappings[hostState] = nagiosState
+	}
+
+	c.HostStateMappings = mappings
+
+	return nil
+
+}
@@ -0,0 +1,49 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import "testing"
+
+func TestSetHostStateMappings(t *testing.T) {
+
+	c := Config{
+		hostStateMappings: multiValueStringFlag{
+			"maintenance=warning",
+			" Standby = Critical ",
+		},
+	}
+
+	if err := c.setHostStateMappings(); err != nil {
+		t.Fatalf("setHostStateMappings returned unexpected error: %v", err)
+	}
+
+	want := DefaultHostStateMappings()
+	want[HostStateMaintenance] = NagiosStateWarning
+	want[HostStateStandby] = NagiosStateCritical
+
+	if len(c.HostStateMappings) != len(want) {
+		t.Fatalf("got %d mappings; want %d", len(c.HostStateMappings), len(want))
+	}
+
+	for state, nagiosState := range want {
+		if got := c.HostStateMappings[state]; got != nagiosState {
+			t.Errorf("mapping for %q: got %q; want %q", state, got, nagiosState)
+		}
+	}
+
+	for _, invalid := range []string{
+		"maintenance",
+		"rebooting=warning",
+		"maintenance=pending",
+	} {
+		c := Config{hostStateMappings: multiValueStringFlag{invalid}}
+		if err := c.setHostStateMappings(); err == nil {
+			t.Errorf("setHostStateMappings(%q) did not return expected error", invalid)
+		}
+	}
+}
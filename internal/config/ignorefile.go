@@ -0,0 +1,277 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"bufio"
+	"bytes"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"time"
+
+	"github.com/atc0005/check-vmware/internal/textutils"
+)
+
+// Supported ignore file entry kinds. The kind determines which list of
+// exclusions the entry name is applied to.
+const (
+	IgnoreEntryKindVM        string = "vm"
+	IgnoreEntryKindDatastore string = "datastore"
+	IgnoreEntryKindAlarm     string = "alarm"
+)
+
+// ignoreEntryUntilPrefix is the prefix for the optional expiration date
+// specified at the end of an ignore file entry.
+const ignoreEntryUntilPrefix string = "until="
+
+// ignoreEntryDateLayout is the layout used for ignore file entry expiration
+// dates.
+const ignoreEntryDateLayout string = "2006-01-02"
+
+// ignoreEntryCommentRegex matches the start of a comment following an
+// ignore file entry. The '#' character must be surrounded by whitespace (or
+// end the line) to be treated as the start of a comment.
+var ignoreEntryCommentRegex = regexp.MustCompile(`\s#(\s|$)`)
+
+// ErrIgnoreFileNotFound indicates that the ignore file specified by the user
+// could not be found.
+var ErrIgnoreFileNotFound = errors.New("ignore file not found")
+
+// IgnoreEntry is a single exclusion from an ignore file.
+type IgnoreEntry struct {
+
+	// Kind is the type of object excluded by this entry.
+	Kind string
+
+	// Name is the name (or name pattern) of the excluded object.
+	Name string
+
+	// Until is the last day that this entry applies. The entry does not
+	// expire if not set.
+	Until time.Time
+
+	// Reason is the comment recorded for this entry.
+	Reason string
+
+	// Line is the line number in the ignore file where this entry was
+	// specified.
+	Line int
+}
+
+// Expired indicates whether this entry no longer applies as of the given
+// time. Entries apply through the end of the day specified as the
+// expiration date.
+func (e IgnoreEntry) Expired(now time.Time) bool {
+	if e.Until.IsZero() {
+		return false
+	}
+
+	return !now.Before(e.Until.AddDate(0, 0, 1))
+}
+
+// String returns the entry in the kind:name format used by the ignore file.
+func (e IgnoreEntry) String() string {
+	return e.Kind + ":" + e.Name
+}
+
+// ignoreEntryKinds returns a map of supported entry kind keywords to entry
+// kinds.
+func ignoreEntryKinds() map[string]string {
+	return map[string]string{
+		IgnoreEntryKindVM:        IgnoreEntryKindVM,
+		IgnoreEntryKindDatastore: IgnoreEntryKindDatastore,
+		"ds":                     IgnoreEntryKindDatastore,
+		IgnoreEntryKindAlarm:     IgnoreEntryKindAlarm,
+	}
+}
+
+// parseIgnoreFile parses ignore file content. Blank lines and lines
+// beginning with '#' are ignored. Each entry is specified in kind:name
+// format, optionally followed by an until=YYYY-MM-DD expiration date and a
+// ' # ' comment recording the reason for the exclusion. Names may contain
+// spaces.
+func parseIgnoreFile(r io.Reader, path string) ([]IgnoreEntry, error) {
+
+	var entries []IgnoreEntry
+	var lineNum int
+
+	kinds := ignoreEntryKinds()
+
+	scanner := bufio.NewScanner(r)
+	for scanner.Scan() {
+		lineNum++
+		line := strings.TrimSpace(scanner.Text())
+
+		if line == "" || strings.HasPrefix(line, "#") {
+			continue
+		}
+
+		entry := IgnoreEntry{Line: lineNum}
+
+		// comments must be separated from the entry by whitespace so that
+		// names containing a '#' character are supported
+		if loc := ignoreEntryCommentRegex.FindStringIndex(line); loc != nil {
+			entry.Reason = strings.TrimSpace(line[loc[1]:])
+			line = strings.TrimSpace(line[:loc[0]])
+		}
+
+		if fields := strings.Fields(line); len(fields) > 1 {
+			last := fields[len(fields)-1]
+			if strings.HasPrefix(last, ignoreEntryUntilPrefix) {
+				until, err := time.ParseInLocation(
+					ignoreEntryDateLayout,
+					strings.TrimPrefix(last, ignoreEntryUntilPrefix),
+					time.Local,
+				)
+				if err != nil {
+					return nil, fmt.Errorf(
+						"%s:%d: invalid expiration date %q; expected %sYYYY-MM-DD",
+						path,
+						lineNum,
+						last,
+						ignoreEntryUntilPrefix,
+					)
+				}
+
+				entry.Until = until
+				line = strings.TrimSpace(strings.TrimSuffix(line, last))
+			}
+		}
+
+		idx := strings.Index(line, ":")
+		if idx < 0 {
+			return nil, fmt.Errorf(
+				"%s:%d: invalid entry %q; expected kind:name",
+				path,
+				lineNum,
+				line,
+			)
+		}
+
+		kind, ok := kinds[strings.ToLower(strings.TrimSpace(line[:idx]))]
+		if !ok {
+			return nil, fmt.Errorf(
+				"%s:%d: unsupported entry kind %q; expected one of %s, %s or %s",
+				path,
+				lineNum,
+				strings.TrimSpace(line[:idx]),
+				IgnoreEntryKindVM,
+				IgnoreEntryKindDatastore,
+				IgnoreEntryKindAlarm,
+			)
+		}
+		entry.Kind = kind
+
+		entry.Name = strings.TrimSpace(line[idx+1:])
+		if entry.Name == "" {
+			return nil, fmt.Errorf("%s:%d: entry does not specify a name", path, lineNum)
+		}
+
+		// VM and datastore names are name patterns and are subject to the
+		// same validation as patterns specified via command-line flags;
+		// alarm names are matched as substrings
+		if entry.Kind != IgnoreEntryKindAlarm {
+			if err := textutils.ValidatePattern(entry.Name); err != nil {
+				return nil, fmt.Errorf(
+					"%s:%d: invalid pattern %q: %w",
+					path,
+					lineNum,
+					entry.Name,
+					err,
+				)
+			}
+		}
+
+		entries = append(entries, entry)
+	}
+
+	if err := scanner.Err(); err != nil {
+		return nil, fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	return entries, nil
+}
+
+// loadIgnoreFile parses the ignore file specified by the user and merges
+// the names from active entries into the exclusion lists supported by the
+// specified plugin type. Entries for exclusions not supported by the plugin
+// type are skipped so that the same file may be shared between plugins.
+// Alarm entries are rejected if alarm names are explicitly included as only
+// one of the inclusion or exclusion lists is applied. This method should be
+// called *after* config validation has been performed.
+func (c *Config) loadIgnoreFile() error {
+
+	if c.IgnoreFile == "" {
+		return nil
+	}
+
+	content, err := ioutil.ReadFile(filepath.Clean(c.IgnoreFile))
+	if err != nil {
+		if errors.Is(err, os.ErrNotExist) {
+			return fmt.Errorf("%w: %s", ErrIgnoreFileNotFound, c.IgnoreFile)
+		}
+		return fmt.Errorf("failed to read ignore file: %w", err)
+	}
+
+	entries, err := parseIgnoreFile(bytes.NewReader(content), c.IgnoreFile)
+	if err != nil {
+		return err
+	}
+
+	lists := map[string]*multiValueStringFlag{}
+	if c.flags.Lookup("ignore-vm") != nil {
+		lists[IgnoreEntryKindVM] = &c.IgnoredVMs
+	}
+	if c.flags.Lookup("ignore-ds") != nil {
+		lists[IgnoreEntryKindDatastore] = &c.IgnoredDatastores
+	}
+	if c.flags.Lookup("exclude-name") != nil {
+		lists[IgnoreEntryKindAlarm] = &c.ExcludedAlarmNames
+	}
+
+	now := time.Now()
+	for _, entry := range entries {
+		list, ok := lists[entry.Kind]
+		if !ok {
+			continue
+		}
+
+		if entry.Kind == IgnoreEntryKindAlarm && len(c.IncludedAlarmNames) > 0 {
+			return fmt.Errorf(
+				"%s:%d: alarm entry %q may not be used with the %q flag",
+				c.IgnoreFile,
+				entry.Line,
+				entry.Name,
+				"include-name",
+			)
+		}
+
+		c.IgnoreEntries = append(c.IgnoreEntries, entry)
+
+		if entry.Expired(now) {
+			c.Log.Warn().
+				Str("ignore_file", c.IgnoreFile).
+				Int("line", entry.Line).
+				Str("entry", entry.String()).
+				Str("until", entry.Until.Format(ignoreEntryDateLayout)).
+				Msg("ignore file entry has expired and no longer applies")
+
+			continue
+		}
+
+		*list = append(*list, entry.Name)
+	}
+
+	return nil
+}
@@ -0,0 +1,141 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"flag"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestParseIgnoreFile(t *testing.T) {
+
+	content := `
+# exclusions shared by all plugins
+vm:sql01 until=2026-12-01 # vendor upgrade
+vm:citrix-pool-*
+ds:datastore #2 # decommission pending
+alarm:Virtual machine CPU usage until=2021-01-31
+`
+
+	entries, err := parseIgnoreFile(strings.NewReader(content), "ignore.txt")
+	if err != nil {
+		t.Fatalf("parseIgnoreFile returned unexpected error: %v", err)
+	}
+
+	want := []struct {
+		kind   string
+		name   string
+		until  string
+		reason string
+		line   int
+	}{
+		{kind: IgnoreEntryKindVM, name: "sql01", until: "2026-12-01", reason: "vendor upgrade", line: 3},
+		{kind: IgnoreEntryKindVM, name: "citrix-pool-*", line: 4},
+		{kind: IgnoreEntryKindDatastore, name: "datastore #2", reason: "decommission pending", line: 5},
+		{kind: IgnoreEntryKindAlarm, name: "Virtual machine CPU usage", until: "2021-01-31", line: 6},
+	}
+
+	if len(entries) != len(want) {
+		t.Fatalf("got %d entries; want %d", len(entries), len(want))
+	}
+
+	for i, w := range want {
+		got := entries[i]
+
+		var gotUntil string
+		if !got.Until.IsZero() {
+			gotUntil = got.Until.Format(ignoreEntryDateLayout)
+		}
+
+		if got.Kind != w.kind || got.Name != w.name || gotUntil != w.until ||
+			got.Reason != w.reason || got.Line != w.line {
+			t.Errorf(
+				"entry %d: got {%s %q %s %q %d}; want {%s %q %s %q %d}",
+				i, got.Kind, got.Name, gotUntil, got.Reason, got.Line,
+				w.kind, w.name, w.until, w.reason, w.line,
+			)
+		}
+	}
+
+	for _, invalid := range []string{
+		"sql01",
+		"host:esx01",
+		"vm:",
+		"vm:sql01 until=12/01/2026",
+		"vm:re:sql[0-9",
+		"ds:datastore[",
+	} {
+		if _, err := parseIgnoreFile(strings.NewReader(invalid), "ignore.txt"); err == nil {
+			t.Errorf("parseIgnoreFile(%q) did not return expected error", invalid)
+		}
+	}
+}
+
+func TestIgnoreEntryExpired(t *testing.T) {
+
+	until := time.Date(2026, time.December, 1, 0, 0, 0, 0, time.Local)
+	entry := IgnoreEntry{Kind: IgnoreEntryKindVM, Name: "sql01", Until: until}
+
+	if entry.Expired(until.Add(23 * time.Hour)) {
+		t.Error("entry expired before the end of the expiration date")
+	}
+
+	if !entry.Expired(until.AddDate(0, 0, 1)) {
+		t.Error("entry not expired after the expiration date")
+	}
+
+	if (IgnoreEntry{Kind: IgnoreEntryKindVM, Name: "sql01"}).Expired(until.AddDate(10, 0, 0)) {
+		t.Error("entry without expiration date reported as expired")
+	}
+}
+
+func TestLoadIgnoreFileAlarmEntries(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "check-vmware-ignore")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	path := filepath.Join(dir, "ignore.txt")
+	content := "vm:sql01\nalarm:Host connection state\n"
+	if err := ioutil.WriteFile(path, []byte(content), 0600); err != nil {
+		t.Fatalf("failed to write ignore file: %v", err)
+	}
+
+	newConfig := func() *Config {
+		c := Config{IgnoreFile: path, flags: flag.NewFlagSet("test", flag.ContinueOnError)}
+		c.flags.Var(&c.IncludedAlarmNames, "include-name", "")
+		c.flags.Var(&c.ExcludedAlarmNames, "exclude-name", "")
+		return &c
+	}
+
+	c := newConfig()
+	if err := c.loadIgnoreFile(); err != nil {
+		t.Fatalf("loadIgnoreFile returned unexpected error: %v", err)
+	}
+
+	if len(c.ExcludedAlarmNames) != 1 || c.ExcludedAlarmNames[0] != "Host connection state" {
+		t.Errorf("got excluded alarm names %v; want [Host connection state]", c.ExcludedAlarmNames)
+	}
+
+	if len(c.IgnoreEntries) != 1 {
+		t.Errorf("got %d applicable entries; want 1", len(c.IgnoreEntries))
+	}
+
+	c = newConfig()
+	c.IncludedAlarmNames = multiValueStringFlag{"Host"}
+	if err := c.loadIgnoreFile(); err == nil {
+		t.Error("loadIgnoreFile did not reject alarm entries used with include-name")
+	}
+}
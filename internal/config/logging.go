@@ -9,6 +9,7 @@ package config
 
 import (
 	"fmt"
+	"net/url"
 	"os"
 
 	"github.com/rs/zerolog"
@@ -83,7 +84,10 @@ func (c *Config) setupLogging(pluginType PluginType) error {
 		Str("username", c.Username).
 		Str("user_domain", c.Domain).
 		Bool("trust_cert", c.TrustCert).
-		Str("server", c.Server).
+		Str("ca_file", c.CAFile).
+		Str("thumbprint", c.Thumbprint).
+		Str("proxy", redactURL(c.Proxy)).
+		Str("server", c.Servers.String()).
 		Int("port", c.Port).
 		Logger()
 
@@ -94,3 +98,18 @@ func (c *Config) setupLogging(pluginType PluginType) error {
 	return nil
 
 }
+
+// redactURL returns the given URL with any password replaced by "xxxxx" so
+// that the URL may be safely logged. An empty string is returned as-is.
+func redactURL(s string) string {
+	if s == "" {
+		return s
+	}
+
+	u, err := url.Parse(s)
+	if err != nil {
+		return "(invalid URL)"
+	}
+
+	return u.Redacted()
+}
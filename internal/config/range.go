@@ -0,0 +1,187 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// ErrInvalidRange indicates that a threshold value does not conform to the
+// Nagios threshold range format.
+var ErrInvalidRange = errors.New("invalid threshold range")
+
+// Range represents a Nagios threshold range as described by the Nagios
+// Plugin Development Guidelines:
+//
+// https://nagios-plugins.org/doc/guidelines.html#THRESHOLDFORMAT
+//
+// Supported formats:
+//
+//	10      alert if value < 0 or > 10
+//	10:     alert if value < 10
+//	~:10    alert if value > 10
+//	10:20   alert if value < 10 or > 20
+//	@10:20  alert if value >= 10 and <= 20
+//
+// Range satisfies the flag.Value interface so that threshold flags may be
+// specified using any of the supported formats. The zero value is an unset
+// range which never triggers an alert.
+type Range struct {
+
+	// raw is the original value provided by the user.
+	raw string
+
+	// start is the lower bound of the range.
+	start float64
+
+	// end is the upper bound of the range.
+	end float64
+
+	// inside indicates whether an alert is triggered if a value is inside
+	// the range (inclusive of endpoints) instead of outside the range.
+	inside bool
+}
+
+// ParseRange parses the given value as a Nagios threshold range.
+func ParseRange(value string) (Range, error) {
+
+	raw := strings.TrimSpace(value)
+	if raw == "" {
+		return Range{}, fmt.Errorf("%w: empty value", ErrInvalidRange)
+	}
+
+	r := Range{
+		raw: raw,
+		end: math.Inf(1),
+	}
+
+	s := raw
+	if strings.HasPrefix(s, "@") {
+		r.inside = true
+		s = s[1:]
+	}
+
+	if s == "" || s == ":" {
+		return Range{}, fmt.Errorf("%w: %q: missing range values", ErrInvalidRange, raw)
+	}
+
+	var startStr, endStr string
+	switch i := strings.Index(s, ":"); {
+	case i < 0:
+		endStr = s
+	default:
+		startStr = s[:i]
+		endStr = s[i+1:]
+	}
+
+	switch startStr {
+	case "":
+	case "~":
+		r.start = math.Inf(-1)
+	default:
+		start, err := strconv.ParseFloat(startStr, 64)
+		if err != nil || math.IsNaN(start) {
+			return Range{}, fmt.Errorf("%w: %q: invalid start value", ErrInvalidRange, raw)
+		}
+		r.start = start
+	}
+
+	if endStr != "" {
+		end, err := strconv.ParseFloat(endStr, 64)
+		if err != nil || math.IsNaN(end) {
+			return Range{}, fmt.Errorf("%w: %q: invalid end value", ErrInvalidRange, raw)
+		}
+		r.end = end
+	}
+
+	if r.start > r.end {
+		return Range{}, fmt.Errorf(
+			"%w: %q: start value greater than end value",
+			ErrInvalidRange,
+			raw,
+		)
+	}
+
+	return r, nil
+}
+
+// mustParseRange is a helper function used to parse threshold range values
+// known to be valid (e.g., default values). This function panics if the
+// value cannot be parsed.
+func mustParseRange(value string) Range {
+	r, err := ParseRange(value)
+	if err != nil {
+		panic(err)
+	}
+
+	return r
+}
+
+// String returns the threshold range as originally specified.
+func (r Range) String() string {
+	return r.raw
+}
+
+// Set is called by the flag package to parse the user-specified threshold
+// range.
+func (r *Range) Set(value string) error {
+	parsed, err := ParseRange(value)
+	if err != nil {
+		return err
+	}
+
+	*r = parsed
+
+	return nil
+}
+
+// IsSet indicates whether a threshold range value has been provided.
+func (r Range) IsSet() bool {
+	return r.raw != ""
+}
+
+// IsSimple indicates whether the threshold range was specified as a single
+// value (e.g., 10), the format used by earlier releases of this project. A
+// simple range triggers an alert if a value is negative or greater than the
+// given value.
+func (r Range) IsSimple() bool {
+	return r.IsSet() && !r.inside && r.start == 0 && !math.IsInf(r.end, 1)
+}
+
+// Start returns the lower bound of the threshold range. Negative infinity is
+// returned if the range has no lower bound.
+func (r Range) Start() float64 {
+	return r.start
+}
+
+// End returns the upper bound of the threshold range. Positive infinity is
+// returned if the range has no upper bound.
+func (r Range) End() float64 {
+	return r.end
+}
+
+// Alert indicates whether the given value triggers an alert for the
+// threshold range. An unset threshold range never triggers an alert.
+func (r Range) Alert(value float64) bool {
+
+	if !r.IsSet() {
+		return false
+	}
+
+	inRange := value >= r.start && value <= r.end
+
+	if r.inside {
+		return inRange
+	}
+
+	return !inRange
+}
@@ -0,0 +1,65 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"errors"
+	"testing"
+)
+
+func TestRangeAlert(t *testing.T) {
+
+	tests := []struct {
+		threshold string
+		value     float64
+		want      bool
+	}{
+		{threshold: "10", value: -1, want: true},
+		{threshold: "10", value: 0, want: false},
+		{threshold: "10", value: 10, want: false},
+		{threshold: "10", value: 10.5, want: true},
+		{threshold: "10:", value: 9, want: true},
+		{threshold: "10:", value: 1000, want: false},
+		{threshold: "~:10", value: -1000, want: false},
+		{threshold: "~:10", value: 11, want: true},
+		{threshold: "10:20", value: 9, want: true},
+		{threshold: "10:20", value: 15, want: false},
+		{threshold: "10:20", value: 21, want: true},
+		{threshold: "@10:20", value: 10, want: true},
+		{threshold: "@10:20", value: 20, want: true},
+		{threshold: "@10:20", value: 21, want: false},
+	}
+
+	for _, tt := range tests {
+		r, err := ParseRange(tt.threshold)
+		if err != nil {
+			t.Fatalf("ParseRange(%q) returned unexpected error: %v", tt.threshold, err)
+		}
+
+		if got := r.Alert(tt.value); got != tt.want {
+			t.Errorf("Range(%q).Alert(%v) = %t; want %t", tt.threshold, tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestParseRangeInvalid(t *testing.T) {
+
+	for _, threshold := range []string{"", "abc", "10:abc", "20:10", "@", "~"} {
+		if _, err := ParseRange(threshold); !errors.Is(err, ErrInvalidRange) {
+			t.Errorf("ParseRange(%q) error = %v; want %v", threshold, err, ErrInvalidRange)
+		}
+	}
+}
+
+func TestRangeZeroValue(t *testing.T) {
+
+	var r Range
+	if r.IsSet() || r.Alert(100) {
+		t.Errorf("zero value Range should be unset and never alert")
+	}
+}
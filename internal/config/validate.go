@@ -8,8 +8,14 @@
 package config
 
 import (
+	"encoding/hex"
 	"fmt"
+	"net/url"
+	"os"
+	"regexp"
 	"strings"
+
+	"github.com/atc0005/check-vmware/internal/textutils"
 )
 
 // validate verifies all Config struct fields have been provided acceptable
@@ -40,21 +46,7 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.SnapshotsAgeWarning < 0 {
-			return fmt.Errorf(
-				"invalid snapshot age WARNING threshold number: %d",
-				c.SnapshotsAgeWarning,
-			)
-		}
-
-		if c.SnapshotsAgeCritical < 0 {
-			return fmt.Errorf(
-				"invalid snapshot age CRITICAL threshold number: %d",
-				c.SnapshotsAgeCritical,
-			)
-		}
-
-		if c.SnapshotsAgeCritical <= c.SnapshotsAgeWarning {
+		if thresholdsOverlap(c.SnapshotsAgeCritical, c.SnapshotsAgeWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -71,21 +63,7 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.SnapshotsCountWarning < 0 {
-			return fmt.Errorf(
-				"invalid snapshot count WARNING threshold number: %d",
-				c.SnapshotsCountWarning,
-			)
-		}
-
-		if c.SnapshotsCountCritical < 0 {
-			return fmt.Errorf(
-				"invalid snapshot count CRITICAL threshold number: %d",
-				c.SnapshotsCountCritical,
-			)
-		}
-
-		if c.SnapshotsCountCritical <= c.SnapshotsCountWarning {
+		if thresholdsOverlap(c.SnapshotsCountCritical, c.SnapshotsCountWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -102,21 +80,7 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.SnapshotsSizeWarning < 0 {
-			return fmt.Errorf(
-				"invalid snapshot size WARNING threshold number: %d",
-				c.SnapshotsSizeWarning,
-			)
-		}
-
-		if c.SnapshotsSizeCritical < 0 {
-			return fmt.Errorf(
-				"invalid snapshot size CRITICAL threshold number: %d",
-				c.SnapshotsSizeCritical,
-			)
-		}
-
-		if c.SnapshotsSizeCritical <= c.SnapshotsSizeWarning {
+		if thresholdsOverlap(c.SnapshotsSizeCritical, c.SnapshotsSizeWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -133,21 +97,7 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.VMPowerCycleUptimeWarning < 0 {
-			return fmt.Errorf(
-				"invalid VM power cycle uptime WARNING threshold number: %d",
-				c.VMPowerCycleUptimeWarning,
-			)
-		}
-
-		if c.VMPowerCycleUptimeCritical < 0 {
-			return fmt.Errorf(
-				"invalid VM power cycle uptime CRITICAL threshold number: %d",
-				c.VMPowerCycleUptimeCritical,
-			)
-		}
-
-		if c.VMPowerCycleUptimeCritical <= c.VMPowerCycleUptimeWarning {
+		if thresholdsOverlap(c.VMPowerCycleUptimeCritical, c.VMPowerCycleUptimeWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -159,21 +109,21 @@ func (c Config) validate(pluginType PluginType) error {
 			return fmt.Errorf("datastore name not provided")
 		}
 
-		if c.DatastoreUsageCritical < 1 {
+		if c.DatastoreUsageCritical.IsSimple() && c.DatastoreUsageCritical.End() < 1 {
 			return fmt.Errorf(
-				"invalid datastore usage (percentage as whole number) CRITICAL threshold number: %d",
+				"invalid datastore usage (percentage as whole number) CRITICAL threshold number: %s",
 				c.DatastoreUsageCritical,
 			)
 		}
 
-		if c.DatastoreUsageWarning < 1 {
+		if c.DatastoreUsageWarning.IsSimple() && c.DatastoreUsageWarning.End() < 1 {
 			return fmt.Errorf(
-				"invalid datastore usage (percentage as whole number) WARNING threshold number: %d",
+				"invalid datastore usage (percentage as whole number) WARNING threshold number: %s",
 				c.DatastoreUsageWarning,
 			)
 		}
 
-		if c.DatastoreUsageCritical <= c.DatastoreUsageWarning {
+		if thresholdsOverlap(c.DatastoreUsageCritical, c.DatastoreUsageWarning) {
 			return fmt.Errorf(
 				"datastore critical threshold set lower than or equal to warning threshold",
 			)
@@ -185,21 +135,21 @@ func (c Config) validate(pluginType PluginType) error {
 			return fmt.Errorf("host name not provided")
 		}
 
-		if c.HostSystemMemoryUseCritical < 1 {
+		if c.HostSystemMemoryUseCritical.IsSimple() && c.HostSystemMemoryUseCritical.End() < 1 {
 			return fmt.Errorf(
-				"invalid host memory usage (percentage as whole number) CRITICAL threshold number: %d",
+				"invalid host memory usage (percentage as whole number) CRITICAL threshold number: %s",
 				c.HostSystemMemoryUseCritical,
 			)
 		}
 
-		if c.HostSystemMemoryUseWarning < 1 {
+		if c.HostSystemMemoryUseWarning.IsSimple() && c.HostSystemMemoryUseWarning.End() < 1 {
 			return fmt.Errorf(
-				"invalid host memory usage (percentage as whole number) WARNING threshold number: %d",
+				"invalid host memory usage (percentage as whole number) WARNING threshold number: %s",
 				c.HostSystemMemoryUseWarning,
 			)
 		}
 
-		if c.HostSystemMemoryUseCritical <= c.HostSystemMemoryUseWarning {
+		if thresholdsOverlap(c.HostSystemMemoryUseCritical, c.HostSystemMemoryUseWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -211,21 +161,21 @@ func (c Config) validate(pluginType PluginType) error {
 			return fmt.Errorf("host name not provided")
 		}
 
-		if c.HostSystemCPUUseCritical < 1 {
+		if c.HostSystemCPUUseCritical.IsSimple() && c.HostSystemCPUUseCritical.End() < 1 {
 			return fmt.Errorf(
-				"invalid host CPU usage (percentage as whole number) CRITICAL threshold number: %d",
+				"invalid host CPU usage (percentage as whole number) CRITICAL threshold number: %s",
 				c.HostSystemCPUUseCritical,
 			)
 		}
 
-		if c.HostSystemCPUUseWarning < 1 {
+		if c.HostSystemCPUUseWarning.IsSimple() && c.HostSystemCPUUseWarning.End() < 1 {
 			return fmt.Errorf(
-				"invalid host CPU usage (percentage as whole number) WARNING threshold number: %d",
+				"invalid host CPU usage (percentage as whole number) WARNING threshold number: %s",
 				c.HostSystemCPUUseWarning,
 			)
 		}
 
-		if c.HostSystemCPUUseCritical <= c.HostSystemCPUUseWarning {
+		if thresholdsOverlap(c.HostSystemCPUUseCritical, c.HostSystemCPUUseWarning) {
 			return fmt.Errorf(
 				"critical threshold set lower than or equal to warning threshold",
 			)
@@ -249,21 +199,21 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.ResourcePoolsMemoryUseCritical < 1 {
+		if c.ResourcePoolsMemoryUseCritical.IsSimple() && c.ResourcePoolsMemoryUseCritical.End() < 1 {
 			return fmt.Errorf(
-				"invalid memory usage CRITICAL threshold number: %d",
+				"invalid memory usage CRITICAL threshold number: %s",
 				c.ResourcePoolsMemoryUseCritical,
 			)
 		}
 
-		if c.ResourcePoolsMemoryUseWarning < 1 {
+		if c.ResourcePoolsMemoryUseWarning.IsSimple() && c.ResourcePoolsMemoryUseWarning.End() < 1 {
 			return fmt.Errorf(
-				"invalid memory usage WARNING threshold number: %d",
+				"invalid memory usage WARNING threshold number: %s",
 				c.ResourcePoolsMemoryUseWarning,
 			)
 		}
 
-		if c.ResourcePoolsMemoryUseCritical <= c.ResourcePoolsMemoryUseWarning {
+		if thresholdsOverlap(c.ResourcePoolsMemoryUseCritical, c.ResourcePoolsMemoryUseWarning) {
 			return fmt.Errorf(
 				"memory usage critical threshold set lower than or equal to warning threshold",
 			)
@@ -287,21 +237,21 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		if c.VCPUsAllocatedCritical < 1 {
+		if c.VCPUsAllocatedCritical.IsSimple() && c.VCPUsAllocatedCritical.End() < 1 {
 			return fmt.Errorf(
-				"invalid vCPUs allocation CRITICAL threshold number: %d",
+				"invalid vCPUs allocation CRITICAL threshold number: %s",
 				c.VCPUsAllocatedCritical,
 			)
 		}
 
-		if c.VCPUsAllocatedWarning < 1 {
+		if c.VCPUsAllocatedWarning.IsSimple() && c.VCPUsAllocatedWarning.End() < 1 {
 			return fmt.Errorf(
-				"invalid vCPUs allocation WARNING threshold number: %d",
+				"invalid vCPUs allocation WARNING threshold number: %s",
 				c.VCPUsAllocatedWarning,
 			)
 		}
 
-		if c.VCPUsAllocatedCritical <= c.VCPUsAllocatedWarning {
+		if thresholdsOverlap(c.VCPUsAllocatedCritical, c.VCPUsAllocatedWarning) {
 			return fmt.Errorf(
 				"vCPUs allocation critical threshold set lower than or equal to warning threshold",
 			)
@@ -406,17 +356,6 @@ func (c Config) validate(pluginType PluginType) error {
 			)
 		}
 
-		// optional flag; if not default value, assert known requirements
-		if c.ClusterName != defaultClusterName {
-			if len(c.ClusterName) > MaxClusterNameChars {
-				return fmt.Errorf(
-					"invalid cluster name specified; max supported length is %d, received %d",
-					MaxClusterNameChars,
-					len(c.ClusterName),
-				)
-			}
-		}
-
 		// both are optional flags, but only one at a time is supported
 		if c.ClusterName != defaultClusterName && c.HostSystemName != defaultHostSystemName {
 			return fmt.Errorf(
@@ -503,6 +442,61 @@ func (c Config) validate(pluginType PluginType) error {
 
 		}
 
+	case pluginType.Exporter:
+
+		// only one of these options may be used
+		if len(c.ExcludedResourcePools) > 0 && len(c.IncludedResourcePools) > 0 {
+			return fmt.Errorf(
+				"only one of %q or %q flags may be specified",
+				"include-rp",
+				"exclude-rp",
+			)
+		}
+
+		if c.ListenAddress == "" {
+			return fmt.Errorf("listen address not provided")
+		}
+
+		if !strings.HasPrefix(c.MetricsPath, "/") {
+			return fmt.Errorf(
+				"invalid metrics path %q; path must begin with /",
+				c.MetricsPath,
+			)
+		}
+
+		if c.cacheTTL < 0 {
+			return fmt.Errorf("invalid cache TTL value %d provided", c.cacheTTL)
+		}
+
+		if !(c.CollectDatastores || c.CollectHostSystems ||
+			c.CollectResourcePools || c.CollectSnapshots ||
+			c.CollectVMTools || c.CollectHardwareVersions ||
+			c.CollectAlarms || c.CollectVMUptime) {
+			return fmt.Errorf("all collectors disabled; at least one collector must be enabled")
+		}
+
+	case pluginType.Batch:
+
+		if c.ChecksFile == "" {
+			return fmt.Errorf("checks file not provided")
+		}
+
+		// exactly one of these options must be used
+		switch {
+		case c.CommandFile == "" && c.SpoolDir == "":
+			return fmt.Errorf(
+				"one of %q or %q flags must be specified",
+				"command-file",
+				"spool-dir",
+			)
+		case c.CommandFile != "" && c.SpoolDir != "":
+			return fmt.Errorf(
+				"only one of %q or %q flags may be specified",
+				"command-file",
+				"spool-dir",
+			)
+		}
+
 	case pluginType.Alarms:
 
 		// only one of these options may be used
@@ -604,10 +598,31 @@ func (c Config) validate(pluginType PluginType) error {
 
 	// shared validation checks
 
-	if c.Server == "" {
+	if len(c.Servers) == 0 {
 		return fmt.Errorf("server FQDN or IP Address not provided")
 	}
 
+	seenServers := make(map[string]bool, len(c.Servers))
+	for _, server := range c.Servers {
+		if server == "" {
+			return fmt.Errorf("empty server FQDN or IP Address provided")
+		}
+
+		if seenServers[strings.ToLower(server)] {
+			return fmt.Errorf("server %q specified more than once", server)
+		}
+		seenServers[strings.ToLower(server)] = true
+	}
+
+	// only plugins which aggregate results across servers support evaluating
+	// multiple servers
+	if c.MultipleServers() && !(pluginType.VirtualCPUsAllocation || pluginType.ResourcePoolsMemory) {
+		return fmt.Errorf(
+			"multiple servers specified, but plugin %q supports only one",
+			pluginTypeLabel(pluginType),
+		)
+	}
+
 	if c.Username == "" {
 		return fmt.Errorf("username not provided")
 	}
@@ -616,6 +631,158 @@ func (c Config) validate(pluginType PluginType) error {
 		return fmt.Errorf("password not provided")
 	}
 
+	// only one certificate validation option may be used
+	switch {
+	case c.TrustCert && c.CAFile != "":
+		return fmt.Errorf(
+			"only one of %q or %q flags may be specified",
+			"trust-cert",
+			"ca-file",
+		)
+
+	case c.TrustCert && c.Thumbprint != "":
+		return fmt.Errorf(
+			"only one of %q or %q flags may be specified",
+			"trust-cert",
+			"thumbprint",
+		)
+
+	case c.CAFile != "" && c.Thumbprint != "":
+		return fmt.Errorf(
+			"only one of %q or %q flags may be specified",
+			"ca-file",
+			"thumbprint",
+		)
+	}
+
+	if c.Thumbprint != "" {
+		if err := validateThumbprint(c.Thumbprint); err != nil {
+			return err
+		}
+	}
+
+	if c.Proxy != "" {
+		proxyURL, err := url.Parse(c.Proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+
+		switch {
+		case proxyURL.Scheme != "http" && proxyURL.Scheme != "https" && proxyURL.Scheme != "socks5":
+			return fmt.Errorf(
+				"unsupported proxy URL scheme %q; expected http, https or socks5",
+				proxyURL.Scheme,
+			)
+
+		case proxyURL.Host == "":
+			return fmt.Errorf("proxy URL %q does not specify a host", redactURL(c.Proxy))
+		}
+	}
+
+	if c.SessionCache && c.sessionCacheDir == "" {
+		if _, err := os.UserCacheDir(); err != nil {
+			return fmt.Errorf(
+				"unable to determine default session cache directory, %q flag required: %w",
+				"session-cache-dir",
+				err,
+			)
+		}
+	}
+
+	// optional flags for VM plugin types; only one of these options may be
+	// used
+	if len(c.ExcludedFolders) > 0 && len(c.IncludedFolders) > 0 {
+		return fmt.Errorf(
+			"only one of %q or %q flags may be specified",
+			"include-folder",
+			"exclude-folder",
+		)
+	}
+
+	// optional flags for VM, Datastore and HostSystem plugin types and the
+	// exporter; only one of these options may be used and each tag must
+	// specify the category
+	if len(c.ExcludedTags) > 0 && len(c.IncludedTags) > 0 {
+		return fmt.Errorf(
+			"only one of %q or %q flags may be specified",
+			"include-tag",
+			"exclude-tag",
+		)
+	}
+
+	for _, tag := range append(append([]string{}, c.IncludedTags...), c.ExcludedTags...) {
+		parts := strings.SplitN(tag, ":", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+			return fmt.Errorf(
+				"invalid tag %q specified; expected format is category:tag",
+				tag,
+			)
+		}
+	}
+
+	// optional name filters for several plugin types; each entry is a glob
+	// pattern or a regular expression (if prefixed with "re:")
+	namePatterns := []struct {
+		flagName string
+		patterns []string
+	}{
+		{"ignore-vm", c.IgnoredVMs},
+		{"ignore-ds", c.IgnoredDatastores},
+		{"include-rp", c.IncludedResourcePools},
+		{"exclude-rp", c.ExcludedResourcePools},
+		{"include-folder", c.IncludedFolders},
+		{"exclude-folder", c.ExcludedFolders},
+		{"include-entity-rp", c.IncludedAlarmEntityResourcePools},
+		{"exclude-entity-rp", c.ExcludedAlarmEntityResourcePools},
+	}
+	for _, filter := range namePatterns {
+		for _, pattern := range filter.patterns {
+			if err := textutils.ValidatePattern(pattern); err != nil {
+				return fmt.Errorf(
+					"invalid pattern %q specified for %q flag: %w",
+					pattern,
+					filter.flagName,
+					err,
+				)
+			}
+		}
+	}
+
+	// optional flags for VM plugin types; these options may be combined, but
+	// each filter must specify the Custom Attribute name and any regular
+	// expression used to match values must be valid
+	for _, filter := range append(append([]string{}, c.IncludedCustomAttributes...), c.ExcludedCustomAttributes...) {
+		parts := strings.SplitN(filter, "=", 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return fmt.Errorf(
+				"invalid Custom Attribute filter %q specified; expected format is name=value",
+				filter,
+			)
+		}
+
+		if strings.HasPrefix(parts[1], "re:") {
+			if _, err := regexp.Compile(strings.TrimPrefix(parts[1], "re:")); err != nil {
+				return fmt.Errorf(
+					"invalid regular expression specified for Custom Attribute filter %q: %w",
+					filter,
+					err,
+				)
+			}
+		}
+	}
+
+	// optional flag for several plugin types; if not default value, assert
+	// known requirements
+	if c.ClusterName != defaultClusterName {
+		if len(c.ClusterName) > MaxClusterNameChars {
+			return fmt.Errorf(
+				"invalid cluster name specified; max supported length is %d, received %d",
+				MaxClusterNameChars,
+				len(c.ClusterName),
+			)
+		}
+	}
+
 	if c.Port < 0 {
 		return fmt.Errorf("invalid TCP port number %d", c.Port)
 	}
@@ -624,12 +791,78 @@ func (c Config) validate(pluginType PluginType) error {
 		return fmt.Errorf("invalid timeout value %d provided", c.Timeout())
 	}
 
+	if c.connectTimeout < 0 {
+		return fmt.Errorf("invalid connect timeout value %d provided", c.connectTimeout)
+	}
+
+	if c.retrieveTimeout < 0 {
+		return fmt.Errorf("invalid retrieve timeout value %d provided", c.retrieveTimeout)
+	}
+
+	if c.Retries < 0 {
+		return fmt.Errorf("invalid retries value %d provided", c.Retries)
+	}
+
+	if c.retryDelay < 0 {
+		return fmt.Errorf("invalid retry delay value %d provided", c.retryDelay)
+	}
+
 	requestedLoggingLevel := strings.ToLower(c.LoggingLevel)
 	if _, ok := loggingLevels[requestedLoggingLevel]; !ok {
 		return fmt.Errorf("invalid logging level %q", c.LoggingLevel)
 	}
 
+	switch c.OutputFormat {
+	case OutputFormatText, OutputFormatJSON, OutputFormatCheckmk, OutputFormatIcinga2:
+	default:
+		return fmt.Errorf("invalid output format %q", c.OutputFormat)
+	}
+
+	if strings.ContainsAny(c.ServiceName, "\"\r\n") {
+		return fmt.Errorf(
+			"invalid service name %q; double quotes and line breaks are not supported",
+			c.ServiceName,
+		)
+	}
+
 	// Optimist
 	return nil
 
 }
+
+// validateThumbprint asserts that the given certificate thumbprint is a
+// colon-delimited hex encoded SHA-1 (20 byte) or SHA-256 (32 byte)
+// fingerprint.
+func validateThumbprint(thumbprint string) error {
+
+	fingerprint, err := hex.DecodeString(strings.ReplaceAll(thumbprint, ":", ""))
+	if err != nil {
+		return fmt.Errorf(
+			"invalid certificate thumbprint %q; expected colon-delimited hex digits: %w",
+			thumbprint,
+			err,
+		)
+	}
+
+	switch len(fingerprint) {
+	case thumbprintSHA1Length, thumbprintSHA256Length:
+		return nil
+	default:
+		return fmt.Errorf(
+			"invalid certificate thumbprint %q; decoded length of %d bytes does not match a SHA-1 (%d bytes) or SHA-256 (%d bytes) fingerprint",
+			thumbprint,
+			len(fingerprint),
+			thumbprintSHA1Length,
+			thumbprintSHA256Length,
+		)
+	}
+}
+
+// thresholdsOverlap indicates whether the critical threshold is set lower than
+// or equal to the warning threshold. Overlapping thresholds are only rejected
+// if both were specified using the single value format; other range formats
+// may overlap.
+func thresholdsOverlap(critical Range, warning Range) bool {
+	return critical.IsSimple() && warning.IsSimple() &&
+		critical.End() <= warning.End()
+}
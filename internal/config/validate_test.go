@@ -0,0 +1,45 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package config
+
+import (
+	"strings"
+	"testing"
+)
+
+func TestValidateThumbprint(t *testing.T) {
+
+	sha1 := strings.TrimSuffix(strings.Repeat("3B:", 20), ":")
+	sha256 := strings.TrimSuffix(strings.Repeat("a1:", 32), ":")
+
+	tests := map[string]struct {
+		thumbprint string
+		valid      bool
+	}{
+		"SHA-1":            {thumbprint: sha1, valid: true},
+		"SHA-256":          {thumbprint: sha256, valid: true},
+		"without colons":   {thumbprint: strings.ReplaceAll(sha256, ":", ""), valid: true},
+		"MD5 length":       {thumbprint: strings.TrimSuffix(strings.Repeat("3B:", 16), ":"), valid: false},
+		"truncated SHA-1":  {thumbprint: sha1[:len(sha1)-3], valid: false},
+		"invalid hex":      {thumbprint: "ZZ" + sha1[2:], valid: false},
+		"odd digit number": {thumbprint: sha1 + ":A", valid: false},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := validateThumbprint(tt.thumbprint)
+			switch {
+			case tt.valid && err != nil:
+				t.Errorf("validateThumbprint(%q) returned unexpected error: %v", tt.thumbprint, err)
+			case !tt.valid && err == nil:
+				t.Errorf("validateThumbprint(%q) did not return expected error", tt.thumbprint)
+			}
+		})
+	}
+}
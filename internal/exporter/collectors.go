@@ -0,0 +1,440 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package exporter
+
+import (
+	"context"
+	"fmt"
+	"sort"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// secondsPerDay is used to convert snapshot age in days to seconds.
+const secondsPerDay float64 = 24 * 60 * 60
+
+// Collector names used when reporting collector status metrics.
+const (
+	collectorDatastores       string = "datastores"
+	collectorHostSystems      string = "hosts"
+	collectorResourcePools    string = "resource_pools"
+	collectorSnapshots        string = "snapshots"
+	collectorVMTools          string = "tools"
+	collectorHardwareVersions string = "hardware_versions"
+	collectorAlarms           string = "alarms"
+	collectorVMUptime         string = "vm_uptime"
+)
+
+// collector retrieves a related set of metrics from the vSphere environment.
+type collector struct {
+	name    string
+	collect func(ctx context.Context, s *scrape) ([]*metricFamily, error)
+}
+
+// scrape holds values shared by collectors during a single collection. This
+// allows the (potentially expensive) retrieval of VirtualMachines to be
+// performed once for all collectors which need them.
+type scrape struct {
+	client *govmomi.Client
+	cfg    *config.Config
+
+	rps     []mo.ResourcePool
+	vms     []mo.VirtualMachine
+	vmsErr  error
+	vmsDone bool
+
+	tagsErr  error
+	tagsDone bool
+}
+
+// enabledCollectors returns the collectors enabled by the user.
+func enabledCollectors(cfg *config.Config) []collector {
+
+	all := []struct {
+		enabled bool
+		collector
+	}{
+		{cfg.CollectDatastores, collector{collectorDatastores, collectDatastores}},
+		{cfg.CollectHostSystems, collector{collectorHostSystems, collectHostSystems}},
+		{cfg.CollectResourcePools, collector{collectorResourcePools, collectResourcePools}},
+		{cfg.CollectSnapshots, collector{collectorSnapshots, collectSnapshots}},
+		{cfg.CollectVMTools, collector{collectorVMTools, collectVMTools}},
+		{cfg.CollectHardwareVersions, collector{collectorHardwareVersions, collectHardwareVersions}},
+		{cfg.CollectAlarms, collector{collectorAlarms, collectAlarms}},
+		{cfg.CollectVMUptime, collector{collectorVMUptime, collectVMUptime}},
+	}
+
+	collectors := make([]collector, 0, len(all))
+	for _, c := range all {
+		if c.enabled {
+			collectors = append(collectors, c.collector)
+		}
+	}
+
+	return collectors
+}
+
+// resourcePools retrieves the Resource Pools eligible for evaluation based on
+// the user-specified include and exclude lists.
+func (s *scrape) resourcePools(ctx context.Context) ([]mo.ResourcePool, error) {
+
+	if s.rps != nil {
+		return s.rps, nil
+	}
+
+	if err := vsphere.ValidateRPs(
+		ctx,
+		s.client.Client,
+		s.cfg.IncludedResourcePools,
+		s.cfg.ExcludedResourcePools,
+	); err != nil {
+		return nil, fmt.Errorf("error validating include/exclude lists: %w", err)
+	}
+
+	rps, err := vsphere.GetEligibleRPs(
+		ctx,
+		s.client.Client,
+		s.cfg.IncludedResourcePools,
+		s.cfg.ExcludedResourcePools,
+		s.cfg.RecursiveResourcePools,
+		true,
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving list of resource pools: %w", err)
+	}
+
+	s.rps = rps
+
+	return s.rps, nil
+}
+
+// validateTags asserts that the user-specified vSphere tags used to filter
+// VirtualMachines, hosts and datastores are present. Validation is performed
+// once per scrape.
+func (s *scrape) validateTags(ctx context.Context) error {
+
+	if !s.tagsDone {
+		s.tagsDone = true
+
+		if err := vsphere.ValidateTags(
+			ctx,
+			s.client.Client,
+			s.cfg.IncludedTags,
+			s.cfg.ExcludedTags,
+		); err != nil {
+			s.tagsErr = fmt.Errorf("error validating include/exclude tag lists: %w", err)
+		}
+	}
+
+	return s.tagsErr
+}
+
+// virtualMachines retrieves the VirtualMachines from eligible Resource Pools
+// which are not explicitly ignored by the user (by name or vSphere tag). Powered off VirtualMachines
+// are included if requested by the user. The result is retrieved once per
+// scrape and shared by all collectors.
+func (s *scrape) virtualMachines(ctx context.Context) ([]mo.VirtualMachine, error) {
+
+	if s.vmsDone {
+		return s.vms, s.vmsErr
+	}
+	s.vmsDone = true
+
+	if err := s.validateTags(ctx); err != nil {
+		s.vmsErr = err
+		return nil, s.vmsErr
+	}
+
+	rps, err := s.resourcePools(ctx)
+	if err != nil {
+		s.vmsErr = err
+		return nil, s.vmsErr
+	}
+
+	vms, err := vsphere.GetVMsFromRPs(ctx, s.client.Client, true, rps...)
+	if err != nil {
+		s.vmsErr = fmt.Errorf("error retrieving list of VMs from resource pools list: %w", err)
+		return nil, s.vmsErr
+	}
+
+	vms = vsphere.ExcludeVMsByName(vms, s.cfg.IgnoredVMs)
+
+	vms, err = vsphere.FilterVMsByTag(ctx, s.client.Client, vms, s.cfg.IncludedTags, s.cfg.ExcludedTags)
+	if err != nil {
+		s.vmsErr = err
+		return nil, s.vmsErr
+	}
+
+	s.vms = vsphere.FilterVMsByPowerState(vms, s.cfg.PoweredOff)
+
+	return s.vms, nil
+}
+
+// collectDatastores collects usage metrics for all datastores not excluded
+// by the user-specified vSphere tags.
+func collectDatastores(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	if err := s.validateTags(ctx); err != nil {
+		return nil, err
+	}
+
+	dss, err := vsphere.GetDatastores(ctx, s.client.Client, true)
+	if err != nil {
+		return nil, err
+	}
+
+	dss, err = vsphere.FilterDatastoresByTag(ctx, s.client.Client, dss, s.cfg.IncludedTags, s.cfg.ExcludedTags)
+	if err != nil {
+		return nil, err
+	}
+
+	capacity := newGauge("datastore_capacity_bytes", "Datastore capacity in bytes.")
+	used := newGauge("datastore_used_bytes", "Datastore storage used in bytes.")
+	free := newGauge("datastore_free_bytes", "Datastore storage remaining in bytes.")
+	usage := newGauge("datastore_usage_percent", "Percentage of datastore storage used.")
+
+	for _, ds := range dss {
+		summary := vsphere.NewDatastoreUsageSummary(ds, config.Range{}, config.Range{})
+
+		capacity.add(float64(summary.StorageTotal), "datastore", ds.Name)
+		used.add(float64(summary.StorageUsed), "datastore", ds.Name)
+		free.add(float64(summary.StorageRemaining), "datastore", ds.Name)
+		usage.add(summary.StorageUsedPercent, "datastore", ds.Name)
+	}
+
+	return []*metricFamily{capacity, used, free, usage}, nil
+}
+
+// collectHostSystems collects CPU and memory usage metrics for all hosts not
+// excluded by the user-specified vSphere tags.
+func collectHostSystems(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	if err := s.validateTags(ctx); err != nil {
+		return nil, err
+	}
+
+	hss, err := vsphere.GetHostSystems(ctx, s.client.Client, true)
+	if err != nil {
+		return nil, err
+	}
+
+	hss, err = vsphere.FilterHostSystemsByTag(ctx, s.client.Client, hss, s.cfg.IncludedTags, s.cfg.ExcludedTags)
+	if err != nil {
+		return nil, err
+	}
+
+	memTotal := newGauge("host_memory_total_bytes", "Host memory capacity in bytes.")
+	memUsed := newGauge("host_memory_used_bytes", "Host memory used in bytes.")
+	memUsage := newGauge("host_memory_usage_percent", "Percentage of host memory used.")
+	cpuTotal := newGauge("host_cpu_total_hertz", "Host CPU capacity in Hz.")
+	cpuUsed := newGauge("host_cpu_used_hertz", "Host CPU used in Hz.")
+	cpuUsage := newGauge("host_cpu_usage_percent", "Percentage of host CPU capacity used.")
+
+	for _, hs := range hss {
+		memSummary := vsphere.NewHostSystemMemoryUsageSummary(hs, config.Range{}, config.Range{})
+		memTotal.add(float64(memSummary.MemoryTotal), "host", hs.Name)
+		memUsed.add(float64(memSummary.MemoryUsed), "host", hs.Name)
+		memUsage.add(memSummary.MemoryUsedPercent, "host", hs.Name)
+
+		cpuSummary := vsphere.NewHostSystemCPUUsageSummary(hs, config.Range{}, config.Range{})
+		cpuTotal.add(cpuSummary.CPUTotal, "host", hs.Name)
+		cpuUsed.add(cpuSummary.CPUUsed, "host", hs.Name)
+		cpuUsage.add(cpuSummary.CPUUsedPercent, "host", hs.Name)
+	}
+
+	return []*metricFamily{memTotal, memUsed, memUsage, cpuTotal, cpuUsed, cpuUsage}, nil
+}
+
+// collectResourcePools collects memory usage metrics for eligible Resource
+// Pools along with aggregate usage of the cluster memory capacity.
+func collectResourcePools(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	rps, err := s.resourcePools(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	rpMemUsed := newGauge("resource_pool_memory_used_bytes", "Resource Pool host memory used in bytes.")
+	clusterMemUsage := newGauge(
+		"resource_pools_memory_usage_percent",
+		"Percentage of cluster memory capacity used by all eligible Resource Pools.",
+	)
+
+	var aggregateMemoryUsage int64
+	for _, rp := range rps {
+		// Per vSphere API docs, `rp.Runtime.Memory.OverallUsage` was
+		// deprecated in v6.5, so we use `hostMemoryUsage` instead.
+		rpMemoryUsage := rp.Summary.GetResourcePoolSummary().QuickStats.HostMemoryUsage * units.MB
+		aggregateMemoryUsage += rpMemoryUsage
+		rpMemUsed.add(float64(rpMemoryUsage), "resource_pool", rp.Name)
+	}
+
+	clusterMemory, err := vsphere.GetHostSystemsTotalMemory(ctx, s.client.Client, false)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving hosts memory capacity: %w", err)
+	}
+
+	clusterMemUsage.add(vsphere.MemoryUsedPercentage(
+		aggregateMemoryUsage,
+		int(clusterMemory/units.GB),
+	))
+
+	return []*metricFamily{rpMemUsed, clusterMemUsage}, nil
+}
+
+// collectSnapshots collects snapshot count, size and age metrics for each
+// VirtualMachine with one or more snapshots.
+func collectSnapshots(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	vms, err := s.virtualMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	count := newGauge("vm_snapshots", "Number of snapshots for the VirtualMachine.")
+	size := newGauge("vm_snapshots_size_bytes", "Cumulative size of all snapshots for the VirtualMachine in bytes.")
+	age := newGauge("vm_snapshots_max_age_seconds", "Age of the oldest snapshot for the VirtualMachine in seconds.")
+
+	for _, vm := range vsphere.FilterVMsWithSnapshots(vms) {
+		set := vsphere.NewSnapshotSummarySet(vm, vsphere.SnapshotThresholds{})
+		if len(set.Snapshots) == 0 {
+			continue
+		}
+
+		var maxAgeDays float64
+		for _, snap := range set.Snapshots {
+			if snap.AgeDays() > maxAgeDays {
+				maxAgeDays = snap.AgeDays()
+			}
+		}
+
+		count.add(float64(len(set.Snapshots)), "vm", vm.Name)
+		size.add(float64(set.Size()), "vm", vm.Name)
+		age.add(maxAgeDays*secondsPerDay, "vm", vm.Name)
+	}
+
+	return []*metricFamily{count, size, age}, nil
+}
+
+// collectVMTools collects the VMware Tools status for each VirtualMachine.
+func collectVMTools(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	vms, err := s.virtualMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	status := newGauge(
+		"vm_tools_status",
+		"VMware Tools status for the VirtualMachine. The value is always 1; the status is provided by the status label.",
+	)
+	ok := newGauge("vm_tools_ok", "Whether VMware Tools status for the VirtualMachine is OK (1) or not (0).")
+
+	for _, vm := range vms {
+		status.add(1, "vm", vm.Name, "status", string(vm.Guest.ToolsStatus))
+		ok.add(
+			boolToFloat(vm.Guest.ToolsStatus == types.VirtualMachineToolsStatusToolsOk),
+			"vm", vm.Name,
+		)
+	}
+
+	return []*metricFamily{status, ok}, nil
+}
+
+// collectHardwareVersions collects the number of VirtualMachines using each
+// virtual hardware version.
+func collectHardwareVersions(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	vms, err := s.virtualMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	hardwareVersionsIdx := make(vsphere.HardwareVersionsIndex)
+	for _, vm := range vms {
+		if vm.Config == nil {
+			continue
+		}
+		hardwareVersionsIdx[vm.Config.Version]++
+	}
+
+	versions := newGauge("vm_hardware_version_vms", "Number of VirtualMachines using the virtual hardware version.")
+	for _, hv := range hardwareVersionsIdx.Versions() {
+		versions.add(float64(hv.Count()), "version", hv.String())
+	}
+
+	return []*metricFamily{versions}, nil
+}
+
+// collectAlarms collects triggered alarms from the user-specified
+// datacenters (or all datacenters if not specified).
+func collectAlarms(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	if err := vsphere.RequireVCenter(s.client.Client, "alarms"); err != nil {
+		return nil, err
+	}
+
+	dcs, err := vsphere.GetDatacenters(ctx, s.client.Client, s.cfg.DatacenterNames, true)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving datacenters: %w", err)
+	}
+
+	triggeredAlarms, err := vsphere.GetTriggeredAlarms(ctx, s.client, dcs, true)
+	if err != nil {
+		return nil, fmt.Errorf("error retrieving triggered alarms: %w", err)
+	}
+
+	sort.Slice(triggeredAlarms, func(i, j int) bool {
+		return triggeredAlarms[i].Key < triggeredAlarms[j].Key
+	})
+
+	alarms := newGauge(
+		"triggered_alarm",
+		"Triggered alarm. The value is always 1; details are provided by labels.",
+	)
+
+	for _, ta := range triggeredAlarms {
+		alarms.add(
+			1,
+			"datacenter", ta.Datacenter,
+			"alarm", ta.Name,
+			"entity", ta.Entity.Name,
+			"entity_type", ta.Entity.MOID.Type,
+			"status", string(ta.OverallStatus),
+			"acknowledged", fmt.Sprintf("%t", ta.Acknowledged),
+		)
+	}
+
+	return []*metricFamily{alarms}, nil
+}
+
+// collectVMUptime collects the (power cycle) uptime of each powered on
+// VirtualMachine.
+func collectVMUptime(ctx context.Context, s *scrape) ([]*metricFamily, error) {
+
+	vms, err := s.virtualMachines(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	uptime := newGauge("vm_uptime_seconds", "VirtualMachine (power cycle) uptime in seconds.")
+	for _, vm := range vms {
+		if vm.Runtime.PowerState != types.VirtualMachinePowerStatePoweredOn {
+			continue
+		}
+		uptime.add(float64(vm.Summary.QuickStats.UptimeSeconds), "vm", vm.Name)
+	}
+
+	return []*metricFamily{uptime}, nil
+}
@@ -0,0 +1,11 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+// Package exporter provides a Prometheus exporter exposing metrics for a
+// vSphere environment using the same vsphere package functions as the
+// Nagios plugins provided by this project.
+package exporter
@@ -0,0 +1,194 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package exporter
+
+import (
+	"bytes"
+	"context"
+	"fmt"
+	"net/http"
+	"sync"
+	"time"
+
+	"github.com/rs/zerolog"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session/keepalive"
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// keepAliveInterval is the idle time between requests used to keep the
+// vSphere session active between scrape requests.
+const keepAliveInterval = 5 * time.Minute
+
+// contentType is the Content-Type of the Prometheus text-based exposition
+// format.
+const contentType string = "text/plain; version=0.0.4; charset=utf-8"
+
+// Exporter collects metrics from a vSphere environment and exposes them for
+// scraping by Prometheus. A single vSphere session is used for all scrape
+// requests; the session is kept alive between requests and recreated if it
+// expires.
+type Exporter struct {
+	cfg *config.Config
+	log zerolog.Logger
+
+	// mu guards the client and cached metrics. Scrape requests are
+	// serialized so that concurrent requests do not trigger duplicate
+	// collection.
+	mu          sync.Mutex
+	client      *govmomi.Client
+	keepAlive   *keepalive.HandlerSOAP
+	cached      []byte
+	collectedAt time.Time
+}
+
+// New creates a new Exporter using the provided configuration. The vSphere
+// session is created when the first scrape request is received.
+func New(cfg *config.Config) *Exporter {
+	return &Exporter{
+		cfg: cfg,
+		log: cfg.Log,
+	}
+}
+
+// ServeHTTP handles scrape requests. Collected metrics are reused for later
+// requests until the user-specified cache TTL has elapsed.
+func (e *Exporter) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.cached == nil || time.Since(e.collectedAt) >= e.cfg.CacheTTL() {
+		e.cached = e.collect(r.Context())
+		e.collectedAt = time.Now()
+	}
+
+	w.Header().Set("Content-Type", contentType)
+	if _, err := w.Write(e.cached); err != nil {
+		e.log.Error().Err(err).Msg("failed to write metrics response")
+	}
+}
+
+// Close stops the session keep alive handler and logs out of the vSphere
+// environment. The session is left active if session caching is enabled.
+func (e *Exporter) Close(ctx context.Context) error {
+
+	e.mu.Lock()
+	defer e.mu.Unlock()
+
+	if e.client == nil {
+		return nil
+	}
+
+	e.keepAlive.Stop()
+
+	// cached sessions are left active for reuse by later executions
+	if e.cfg.SessionCache {
+		e.log.Debug().Msg("Session caching enabled, skipping logout")
+		return nil
+	}
+
+	return e.client.Logout(ctx)
+}
+
+// connect returns a client with an active vSphere session, logging in if a
+// session has not yet been created or has expired.
+func (e *Exporter) connect(ctx context.Context) (*govmomi.Client, error) {
+
+	if e.client != nil {
+		userSession, err := e.client.SessionManager.UserSession(ctx)
+		if err == nil && userSession != nil {
+			return e.client, nil
+		}
+
+		e.log.Debug().Err(err).Msg("Session is no longer active")
+		e.keepAlive.Stop()
+		e.client = nil
+	}
+
+	loginCtx, loginCancel := context.WithTimeout(ctx, e.cfg.ConnectTimeout())
+	defer loginCancel()
+
+	e.log.Debug().Msg("Logging into vSphere environment")
+	c, err := vsphere.Login(
+		loginCtx, e.cfg.Server(), e.cfg.Port, e.cfg.TrustCert,
+		e.cfg.CAFile, e.cfg.Thumbprint, e.cfg.Proxy,
+		e.cfg.Username, e.cfg.Domain, e.cfg.Password,
+		e.cfg.UserAgent(), e.cfg.SessionCacheDir(),
+		e.cfg.Retries, e.cfg.RetryDelay(), e.cfg.RetrieveTimeout(),
+	)
+	if err != nil {
+		return nil, fmt.Errorf("error logging into %s: %w", e.cfg.Server(), err)
+	}
+	e.log.Debug().Msg("Successfully logged into vSphere environment")
+
+	// The keep alive handler is explicitly started as the session was
+	// created (or resumed from cache) before the handler was installed.
+	vsphere.WrapRoundTripper(c.Client, func(rt soap.RoundTripper) soap.RoundTripper {
+		e.keepAlive = keepalive.NewHandlerSOAP(rt, keepAliveInterval, nil)
+		return e.keepAlive
+	})
+	e.keepAlive.Start()
+
+	e.client = c
+
+	return e.client, nil
+}
+
+// collect runs all enabled collectors and returns the collected metrics in
+// the Prometheus text-based exposition format.
+func (e *Exporter) collect(parent context.Context) []byte {
+
+	ctx, cancel := context.WithTimeout(parent, e.cfg.Timeout())
+	defer cancel()
+
+	up := newGauge("up", "Whether the vSphere environment could be reached (1) or not (0).")
+	success := newGauge("exporter_collector_success", "Whether the collector succeeded (1) or not (0).")
+	duration := newGauge("exporter_collector_duration_seconds", "Time taken by the collector in seconds.")
+
+	families := []*metricFamily{up, success, duration}
+
+	c, err := e.connect(ctx)
+	up.add(boolToFloat(err == nil))
+
+	if err != nil {
+		e.log.Error().Err(err).Msg("failed to connect to vSphere environment")
+	} else {
+		s := scrape{client: c, cfg: e.cfg}
+
+		for _, col := range enabledCollectors(e.cfg) {
+			start := time.Now()
+			collected, err := col.collect(ctx, &s)
+			elapsed := time.Since(start)
+
+			if err != nil {
+				e.log.Error().
+					Err(err).
+					Str("collector", col.name).
+					Msg("collector failed")
+			}
+
+			success.add(boolToFloat(err == nil), "collector", col.name)
+			duration.add(elapsed.Seconds(), "collector", col.name)
+
+			if err == nil {
+				families = append(families, collected...)
+			}
+		}
+	}
+
+	var buf bytes.Buffer
+	if err := writeMetrics(&buf, families); err != nil {
+		e.log.Error().Err(err).Msg("failed to format metrics")
+	}
+
+	return buf.Bytes()
+}
@@ -0,0 +1,138 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package exporter
+
+import (
+	"io"
+	"math"
+	"strconv"
+	"strings"
+)
+
+// metricNamespace is the prefix used for all metrics exposed by the exporter.
+const metricNamespace string = "vmware_"
+
+// Metric types used by the exporter. See the Prometheus text-based exposition
+// format documentation for details.
+const (
+	metricTypeGauge string = "gauge"
+)
+
+// label is a metric label name/value pair.
+type label struct {
+	name  string
+	value string
+}
+
+// sample is a single metric value along with its labels.
+type sample struct {
+	labels []label
+	value  float64
+}
+
+// metricFamily is a collection of samples sharing the same metric name.
+type metricFamily struct {
+	name    string
+	help    string
+	typ     string
+	samples []sample
+}
+
+// newGauge creates a new gauge metric family with the specified name (without
+// namespace) and help text.
+func newGauge(name string, help string) *metricFamily {
+	return &metricFamily{
+		name: metricNamespace + name,
+		help: help,
+		typ:  metricTypeGauge,
+	}
+}
+
+// add records a sample with the specified value and label name/value pairs.
+func (mf *metricFamily) add(value float64, labelPairs ...string) {
+
+	labels := make([]label, 0, len(labelPairs)/2)
+	for i := 0; i+1 < len(labelPairs); i += 2 {
+		labels = append(labels, label{name: labelPairs[i], value: labelPairs[i+1]})
+	}
+
+	mf.samples = append(mf.samples, sample{labels: labels, value: value})
+}
+
+// writeMetrics writes the given metric families to w using the Prometheus
+// text-based exposition format. Metric families without samples are
+// omitted.
+func writeMetrics(w io.Writer, families []*metricFamily) error {
+
+	var sb strings.Builder
+
+	for _, mf := range families {
+		if len(mf.samples) == 0 {
+			continue
+		}
+
+		sb.WriteString("# HELP " + mf.name + " " + escapeHelp(mf.help) + "\n")
+		sb.WriteString("# TYPE " + mf.name + " " + mf.typ + "\n")
+
+		for _, s := range mf.samples {
+			sb.WriteString(mf.name)
+
+			if len(s.labels) > 0 {
+				sb.WriteString("{")
+				for i, l := range s.labels {
+					if i > 0 {
+						sb.WriteString(",")
+					}
+					sb.WriteString(l.name + `="` + escapeLabelValue(l.value) + `"`)
+				}
+				sb.WriteString("}")
+			}
+
+			sb.WriteString(" " + formatValue(s.value) + "\n")
+		}
+	}
+
+	_, err := io.WriteString(w, sb.String())
+
+	return err
+}
+
+// escapeHelp escapes backslash and newline characters in metric help text.
+func escapeHelp(s string) string {
+	return strings.NewReplacer(`\`, `\\`, "\n", `\n`).Replace(s)
+}
+
+// escapeLabelValue escapes backslash, double-quote and newline characters in
+// label values.
+func escapeLabelValue(s string) string {
+	return strings.NewReplacer(`\`, `\\`, `"`, `\"`, "\n", `\n`).Replace(s)
+}
+
+// formatValue formats a sample value using the representations expected by
+// Prometheus for special values.
+func formatValue(v float64) string {
+	switch {
+	case math.IsNaN(v):
+		return "NaN"
+	case math.IsInf(v, 1):
+		return "+Inf"
+	case math.IsInf(v, -1):
+		return "-Inf"
+	default:
+		return strconv.FormatFloat(v, 'g', -1, 64)
+	}
+}
+
+// boolToFloat converts a boolean value to the 1 or 0 value used by metrics.
+func boolToFloat(b bool) float64 {
+	if b {
+		return 1
+	}
+
+	return 0
+}
@@ -0,0 +1,45 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package exporter
+
+import (
+	"math"
+	"strings"
+	"testing"
+)
+
+func TestWriteMetrics(t *testing.T) {
+
+	usage := newGauge("datastore_usage_percent", "Percentage of datastore\nstorage used.")
+	usage.add(42.5, "datastore", `ds "01"`)
+	usage.add(math.NaN(), "datastore", `C:\ds02`)
+
+	up := newGauge("up", "Up.")
+	up.add(1)
+
+	// families without samples are omitted
+	empty := newGauge("empty", "Empty.")
+
+	var sb strings.Builder
+	if err := writeMetrics(&sb, []*metricFamily{usage, empty, up}); err != nil {
+		t.Fatalf("writeMetrics returned unexpected error: %v", err)
+	}
+
+	want := `# HELP vmware_datastore_usage_percent Percentage of datastore\nstorage used.
+# TYPE vmware_datastore_usage_percent gauge
+vmware_datastore_usage_percent{datastore="ds \"01\""} 42.5
+vmware_datastore_usage_percent{datastore="C:\\ds02"} NaN
+# HELP vmware_up Up.
+# TYPE vmware_up gauge
+vmware_up 1
+`
+
+	if got := sb.String(); got != want {
+		t.Errorf("got:\n%s\nwant:\n%s", got, want)
+	}
+}
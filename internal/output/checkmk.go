@@ -0,0 +1,199 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package output
+
+import (
+	"io"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+
+	"github.com/atc0005/check-vmware/internal/perfdata"
+)
+
+// checkmkServiceNamePrefix is the prefix used to generate a Checkmk service
+// name from the plugin type if a service name is not specified.
+const checkmkServiceNamePrefix string = "vmware_"
+
+// checkmkNoMetrics is the metrics field value used by Checkmk local checks
+// when no metrics are provided.
+const checkmkNoMetrics string = "-"
+
+// checkmkInvalidMetricChars matches characters not supported in Checkmk
+// metric names.
+var checkmkInvalidMetricChars = regexp.MustCompile(`[^a-zA-Z0-9_]`)
+
+// checkmkLineBreaks replaces line breaks with the literal \n sequence used by
+// Checkmk local checks to separate the summary from details.
+var checkmkLineBreaks = strings.NewReplacer(
+	"\r\n", `\n`,
+	"\n", `\n`,
+	"\r", `\n`,
+)
+
+// serviceState returns the service state for the given Nagios exit code. The
+// DEPENDENT state is not supported by Checkmk or Icinga 2 and is reported as
+// UNKNOWN.
+func serviceState(exitCode int) int {
+	switch exitCode {
+	case nagios.StateOKExitCode,
+		nagios.StateWARNINGExitCode,
+		nagios.StateCRITICALExitCode:
+		return exitCode
+	default:
+		return nagios.StateUNKNOWNExitCode
+	}
+}
+
+// Object is the evaluated result for a single object (e.g., VirtualMachine,
+// Datastore or HostSystem) evaluated by a plugin. Each object is reported as
+// a separate Checkmk local check service.
+type Object struct {
+
+	// Name is the name of the evaluated object. This value is appended to the
+	// service name to form the Checkmk service name for the object.
+	Name string
+
+	// State is the Nagios exit code for the evaluated object.
+	State int
+
+	// PerfData is the collection of performance data for the evaluated
+	// object.
+	PerfData []perfdata.PerformanceData
+
+	// Text is the summary of the evaluated object. The one-line summary for
+	// the plugin along with any details is used if not set.
+	Text string
+}
+
+// Checkmk returns the collected plugin results as Checkmk local check lines.
+// One line is emitted for each object evaluated by the plugin. A single line
+// consisting of the state, service name, metrics and the one-line summary
+// followed by any details is emitted if per-object results are not available
+// (e.g., plugins evaluating aggregate usage or plugins which failed before
+// evaluating any objects).
+func (p *Plugin) Checkmk() string {
+
+	serviceName := p.ServiceName
+	if serviceName == "" {
+		serviceName = strings.ReplaceAll(p.App.Plugin, "-", "_")
+		if !strings.HasPrefix(serviceName, checkmkServiceNamePrefix) {
+			serviceName = checkmkServiceNamePrefix + serviceName
+		}
+	}
+
+	text := p.ExitState.ServiceOutput + p.details("\n")
+
+	if len(p.Objects) == 0 {
+		return checkmkLine(
+			p.ExitState.ExitStatusCode,
+			serviceName,
+			p.PerfData,
+			text,
+		)
+	}
+
+	var lines strings.Builder
+	for _, object := range p.Objects {
+		objectText := object.Text
+		if objectText == "" {
+			objectText = text
+		}
+
+		lines.WriteString(checkmkLine(
+			object.State,
+			serviceName+" "+object.Name,
+			object.PerfData,
+			objectText,
+		))
+	}
+
+	return lines.String()
+}
+
+// checkmkLine returns a Checkmk local check line consisting of the state,
+// service name, metrics and text.
+func checkmkLine(exitCode int, serviceName string, pd []perfdata.PerformanceData, text string) string {
+
+	if strings.ContainsAny(serviceName, " \t") {
+		serviceName = `"` + serviceName + `"`
+	}
+
+	metrics := checkmkMetrics(pd)
+	if metrics == "" {
+		metrics = checkmkNoMetrics
+	}
+
+	text = checkmkLineBreaks.Replace(strings.TrimRight(text, "\r\n"))
+
+	return strconv.Itoa(serviceState(exitCode)) + " " +
+		serviceName + " " +
+		metrics + " " +
+		text + "\n"
+}
+
+// writeCheckmk writes the collected plugin results to w as Checkmk local
+// check lines.
+func (p *Plugin) writeCheckmk(w io.Writer) error {
+	_, err := io.WriteString(w, p.Checkmk())
+	return err
+}
+
+// checkmkMetrics returns the given performance data formatted as Checkmk
+// local check metrics. Checkmk does not support units of measurement or
+// Nagios threshold ranges, so units are dropped along with any threshold or
+// range values which are not plain numbers. Entries with an unknown value
+// are skipped.
+func checkmkMetrics(pd []perfdata.PerformanceData) string {
+
+	metrics := make([]string, 0, len(pd))
+
+	for _, entry := range perfdata.Valid(pd...) {
+
+		if !isNumber(entry.Value) {
+			continue
+		}
+
+		fields := []string{
+			entry.Value,
+			numberOrEmpty(entry.Warn),
+			numberOrEmpty(entry.Crit),
+			numberOrEmpty(entry.Min),
+			numberOrEmpty(entry.Max),
+		}
+
+		// drop trailing empty fields
+		last := len(fields)
+		for last > 1 && fields[last-1] == "" {
+			last--
+		}
+
+		name := checkmkInvalidMetricChars.ReplaceAllString(entry.Label, "_")
+		metrics = append(metrics, name+"="+strings.Join(fields[:last], ";"))
+	}
+
+	return strings.Join(metrics, "|")
+}
+
+// isNumber indicates whether the given value is a plain number.
+func isNumber(value string) bool {
+	_, err := strconv.ParseFloat(value, 64)
+	return err == nil
+}
+
+// numberOrEmpty returns the given value if it is a plain number or an empty
+// string otherwise.
+func numberOrEmpty(value string) string {
+	if isNumber(value) {
+		return value
+	}
+
+	return ""
+}
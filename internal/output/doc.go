@@ -0,0 +1,10 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+// Package output provides types and functions used to emit plugin results in
+// the output format requested by the user.
+package output
@@ -0,0 +1,60 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package output
+
+import (
+	"encoding/json"
+	"io"
+	"time"
+
+	"github.com/atc0005/check-vmware/internal/perfdata"
+)
+
+// Icinga2Result is the request body for the Icinga 2 API
+// process-check-result action. The target host or service is specified by
+// the client submitting the request.
+type Icinga2Result struct {
+	ExitStatus      int      `json:"exit_status"`
+	PluginOutput    string   `json:"plugin_output"`
+	PerformanceData []string `json:"performance_data"`
+	ExecutionStart  float64  `json:"execution_start"`
+	ExecutionEnd    float64  `json:"execution_end"`
+}
+
+// Icinga2Result returns the collected plugin results as an Icinga 2 API
+// process-check-result request body. The plugin output consists of the
+// one-line summary followed by any details; performance data is provided
+// separately.
+func (p *Plugin) Icinga2Result() Icinga2Result {
+
+	valid := perfdata.Valid(p.PerfData...)
+	pd := make([]string, 0, len(valid))
+	for _, entry := range valid {
+		pd = append(pd, entry.String())
+	}
+
+	return Icinga2Result{
+		ExitStatus:      serviceState(p.ExitState.ExitStatusCode),
+		PluginOutput:    p.ExitState.ServiceOutput + p.details("\n"),
+		PerformanceData: pd,
+		ExecutionStart:  unixSeconds(p.start),
+		ExecutionEnd:    unixSeconds(time.Now()),
+	}
+}
+
+// writeIcinga2 writes the collected plugin results to w as an Icinga 2 API
+// process-check-result request body.
+func (p *Plugin) writeIcinga2(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p.Icinga2Result())
+}
+
+// unixSeconds returns the given time as fractional seconds since the Unix
+// epoch.
+func unixSeconds(t time.Time) float64 {
+	return float64(t.UnixNano()) / float64(time.Second)
+}
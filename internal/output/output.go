@@ -0,0 +1,309 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package output
+
+import (
+	"encoding/json"
+	"fmt"
+	"io"
+	"os"
+	"runtime/debug"
+	"strings"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+)
+
+// SchemaVersion is the version of the JSON output schema. This value is
+// incremented whenever a backwards incompatible change is made to the schema
+// (e.g., a field is removed or renamed). New fields may be added without
+// incrementing this value. See docs/json-output.md for the schema.
+const SchemaVersion int = 1
+
+// Nagios state labels used in JSON output. The UNKNOWN label is provided here
+// as the go-nagios package misspells it.
+const (
+	stateOKLabel        string = "OK"
+	stateWARNINGLabel   string = "WARNING"
+	stateCRITICALLabel  string = "CRITICAL"
+	stateUNKNOWNLabel   string = "UNKNOWN"
+	stateDEPENDENTLabel string = "DEPENDENT"
+)
+
+// Plugin collects the results of a plugin execution and emits them in the
+// requested output format.
+type Plugin struct {
+
+	// ExitState is the Nagios exit state for the plugin. Fields on this value
+	// are set by client code as the plugin executes.
+	ExitState *nagios.ExitState
+
+	// App identifies the plugin and project version included in JSON output.
+	App config.AppInfo
+
+	// Format is the output format used to emit plugin results. Standard
+	// Nagios plugin output is used if not set.
+	Format string
+
+	// PerfData is the collection of performance data gathered as the plugin
+	// executes. An entry recording plugin runtime is added just before
+	// results are emitted.
+	PerfData []perfdata.PerformanceData
+
+	// ServiceName is the name of the Checkmk service used when emitting
+	// Checkmk local check output. A name based on the plugin type is used if
+	// not set.
+	ServiceName string
+
+	// Data is the plugin-specific evaluated data included in JSON output.
+	// This value is expected to be one of the vsphere package *Data types.
+	Data interface{}
+
+	// Objects is the evaluated result for each object evaluated by the
+	// plugin. Output formats which report each evaluated object separately
+	// (e.g., Checkmk local checks) use these results if provided.
+	Objects []Object
+
+	// start is when plugin execution began.
+	start time.Time
+}
+
+// Result is the top-level object emitted when the JSON output format is
+// used. See docs/json-output.md for details.
+type Result struct {
+	SchemaVersion int                        `json:"schema_version"`
+	Plugin        string                     `json:"plugin"`
+	Version       string                     `json:"version"`
+	State         string                     `json:"state"`
+	ExitCode      int                        `json:"exit_code"`
+	Summary       string                     `json:"summary"`
+	Error         string                     `json:"error,omitempty"`
+	Thresholds    Thresholds                 `json:"thresholds"`
+	PerfData      []perfdata.PerformanceData `json:"perfdata"`
+	Data          interface{}                `json:"data"`
+}
+
+// Thresholds is the display text for the thresholds used by a plugin.
+type Thresholds struct {
+	Critical string `json:"critical,omitempty"`
+	Warning  string `json:"warning,omitempty"`
+}
+
+// New creates a new Plugin value using the provided Nagios exit state and
+// records the current time as the start of plugin execution.
+func New(es *nagios.ExitState) *Plugin {
+	return &Plugin{
+		ExitState: es,
+		Format:    config.OutputFormatText,
+		start:     time.Now(),
+	}
+}
+
+// ReturnCheckResults emits the collected plugin results in the requested
+// output format and exits the application using the exit code from the
+// Nagios exit state. As with (nagios.ExitState).ReturnCheckResults, this
+// method should be registered as the first deferred function in client code
+// so that it runs last. Panics in client code are reported as a CRITICAL
+// state.
+func (p *Plugin) ReturnCheckResults() {
+
+	// Check for unhandled panic in client code. This is done here instead of
+	// relying on the nagios.ExitState method as recover only returns a value
+	// when called directly by the deferred function.
+	if err := recover(); err != nil {
+		p.setPanicState(err, debug.Stack())
+	}
+
+	p.PerfData = append(p.PerfData, perfdata.Runtime(p.start))
+
+	switch p.Format {
+	case config.OutputFormatJSON:
+		p.exit("JSON", p.writeJSON)
+
+	case config.OutputFormatCheckmk:
+		p.exit("Checkmk", p.writeCheckmk)
+
+	case config.OutputFormatIcinga2:
+		p.exit("Icinga 2", p.writeIcinga2)
+
+	default:
+		p.ExitState.ServiceOutput = perfdata.Append(
+			p.ExitState.ServiceOutput,
+			p.PerfData...,
+		)
+
+		p.ExitState.ReturnCheckResults()
+	}
+}
+
+// exit emits the collected plugin results to stdout using the given writer
+// and exits the application using the exit code from the Nagios exit state.
+// The application exits with an UNKNOWN state if the results could not be
+// written.
+func (p *Plugin) exit(format string, write func(w io.Writer) error) {
+	if err := write(os.Stdout); err != nil {
+		fmt.Printf(
+			"%s: failed to generate %s output: %v",
+			nagios.StateUNKNOWNLabel,
+			format,
+			err,
+		)
+		os.Exit(nagios.StateUNKNOWNExitCode)
+	}
+
+	os.Exit(p.ExitState.ExitStatusCode)
+}
+
+// Finish finalizes the collected plugin results without emitting them or
+// exiting the application. This is intended for client code which submits
+// results elsewhere (e.g., batch mode). As with ReturnCheckResults, this
+// method should be deferred so that panics in client code are reported as a
+// CRITICAL state.
+func (p *Plugin) Finish() {
+
+	if err := recover(); err != nil {
+		p.setPanicState(err, debug.Stack())
+	}
+
+	p.PerfData = append(p.PerfData, perfdata.Runtime(p.start))
+}
+
+// Text returns the collected plugin results as standard Nagios plugin output
+// including performance data. The output matches the format emitted by
+// (nagios.ExitState).ReturnCheckResults.
+func (p *Plugin) Text() string {
+	return perfdata.Append(p.ExitState.ServiceOutput, p.PerfData...) +
+		p.details(nagios.CheckOutputEOL)
+}
+
+// details returns the errors, thresholds, Long Service Output and branding
+// sections which follow the one-line summary in standard Nagios plugin
+// output. Lines are terminated using the given end of line sequence.
+func (p *Plugin) details(eol string) string {
+
+	es := p.ExitState
+
+	var sb strings.Builder
+
+	if es.LongServiceOutput != "" || es.LastError != nil {
+
+		fmt.Fprintf(&sb, "%s%s**ERRORS**%s", eol, eol, eol)
+
+		if es.LastError != nil {
+			fmt.Fprintf(&sb, "%s* %v%s", eol, es.LastError, eol)
+		} else {
+			fmt.Fprintf(&sb, "%s* None%s", eol, eol)
+		}
+
+		if es.LongServiceOutput != "" {
+
+			fmt.Fprintf(&sb, "%s**THRESHOLDS**%s", eol, eol)
+
+			if es.CriticalThreshold != "" || es.WarningThreshold != "" {
+
+				sb.WriteString(eol)
+
+				if es.CriticalThreshold != "" {
+					fmt.Fprintf(&sb, "* %s: %v%s", stateCRITICALLabel, es.CriticalThreshold, eol)
+				}
+
+				if es.WarningThreshold != "" {
+					fmt.Fprintf(&sb, "* %s: %v%s", stateWARNINGLabel, es.WarningThreshold, eol)
+				}
+			} else {
+				fmt.Fprintf(&sb, "%s* Not specified%s", eol, eol)
+			}
+
+			fmt.Fprintf(&sb, "%s**DETAILED INFO**%s", eol, eol)
+			fmt.Fprintf(&sb, "%s%v%s", eol, es.LongServiceOutput, eol)
+		}
+	}
+
+	if es.BrandingCallback != nil {
+		fmt.Fprintf(&sb, "%s%s%s", eol, es.BrandingCallback(), eol)
+	}
+
+	return sb.String()
+}
+
+// Result returns the collected plugin results using the JSON output schema.
+func (p *Plugin) Result() Result {
+
+	var errMsg string
+	if p.ExitState.LastError != nil {
+		errMsg = p.ExitState.LastError.Error()
+	}
+
+	return Result{
+		SchemaVersion: SchemaVersion,
+		Plugin:        p.App.Plugin,
+		Version:       p.App.Version,
+		State:         StateLabel(p.ExitState.ExitStatusCode),
+		ExitCode:      p.ExitState.ExitStatusCode,
+		Summary:       p.ExitState.ServiceOutput,
+		Error:         errMsg,
+		Thresholds: Thresholds{
+			Critical: p.ExitState.CriticalThreshold,
+			Warning:  p.ExitState.WarningThreshold,
+		},
+		PerfData: perfdata.Valid(p.PerfData...),
+		Data:     p.Data,
+	}
+}
+
+// StateLabel returns the Nagios state label for the given exit code.
+func StateLabel(exitCode int) string {
+	switch exitCode {
+	case nagios.StateOKExitCode:
+		return stateOKLabel
+	case nagios.StateWARNINGExitCode:
+		return stateWARNINGLabel
+	case nagios.StateCRITICALExitCode:
+		return stateCRITICALLabel
+	case nagios.StateDEPENDENTExitCode:
+		return stateDEPENDENTLabel
+	default:
+		return stateUNKNOWNLabel
+	}
+}
+
+// writeJSON writes the collected plugin results to w as a JSON document.
+func (p *Plugin) writeJSON(w io.Writer) error {
+	return json.NewEncoder(w).Encode(p.Result())
+}
+
+// setPanicState overrides the Nagios exit state to report details of a panic
+// in client code. This mirrors the behavior of the go-nagios package.
+func (p *Plugin) setPanicState(err interface{}, stackTrace []byte) {
+
+	p.ExitState.LastError = fmt.Errorf("plugin crash/panic detected: %s", err)
+
+	p.ExitState.ServiceOutput = fmt.Sprintf(
+		"%s: plugin crash detected. See details via web UI or run plugin manually via CLI.",
+		nagios.StateCRITICALLabel,
+	)
+
+	p.ExitState.LongServiceOutput = fmt.Sprintf(
+		"```%s%s%s%s%s%s```",
+		nagios.CheckOutputEOL,
+		err,
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+		stackTrace,
+		nagios.CheckOutputEOL,
+	)
+
+	// Any data collected prior to the panic is incomplete.
+	p.Data = nil
+	p.Objects = nil
+
+	p.ExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+}
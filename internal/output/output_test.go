@@ -0,0 +1,230 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package output
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"strings"
+	"testing"
+
+	"github.com/atc0005/go-nagios"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+)
+
+func TestPluginWriteJSON(t *testing.T) {
+
+	es := nagios.ExitState{
+		LastError:         errors.New("datastore usage threshold crossed"),
+		ExitStatusCode:    nagios.StateCRITICALExitCode,
+		ServiceOutput:     "CRITICAL: Datastore usage high",
+		CriticalThreshold: "95% datastore usage",
+	}
+
+	p := New(&es)
+	p.App = config.AppInfo{Plugin: config.PluginTypeDatastoresSize}
+	p.Format = config.OutputFormatJSON
+	p.PerfData = []perfdata.PerformanceData{
+		{Label: "datastore_usage", Value: "96.00", UnitOfMeasurement: perfdata.UOMPercentage},
+		{Label: "invalid", Value: "NaN"},
+	}
+	p.Data = map[string]int{"vms": 3}
+
+	var buf bytes.Buffer
+	if err := p.writeJSON(&buf); err != nil {
+		t.Fatalf("writeJSON returned unexpected error: %v", err)
+	}
+
+	var got map[string]interface{}
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode JSON output %q: %v", buf.String(), err)
+	}
+
+	want := map[string]interface{}{
+		"schema_version": float64(SchemaVersion),
+		"plugin":         config.PluginTypeDatastoresSize,
+		"state":          "CRITICAL",
+		"exit_code":      float64(nagios.StateCRITICALExitCode),
+		"summary":        es.ServiceOutput,
+		"error":          es.LastError.Error(),
+	}
+
+	for key, value := range want {
+		if got[key] != value {
+			t.Errorf("%s = %v; want %v", key, got[key], value)
+		}
+	}
+
+	if pd, ok := got["perfdata"].([]interface{}); !ok || len(pd) != 1 {
+		t.Errorf("perfdata = %v; want single valid entry", got["perfdata"])
+	}
+
+	if data, ok := got["data"].(map[string]interface{}); !ok || data["vms"] != float64(3) {
+		t.Errorf("data = %v; want plugin data", got["data"])
+	}
+}
+
+func TestStateLabel(t *testing.T) {
+
+	tests := map[int]string{
+		nagios.StateOKExitCode:        "OK",
+		nagios.StateWARNINGExitCode:   "WARNING",
+		nagios.StateCRITICALExitCode:  "CRITICAL",
+		nagios.StateUNKNOWNExitCode:   "UNKNOWN",
+		nagios.StateDEPENDENTExitCode: "DEPENDENT",
+		42:                            "UNKNOWN",
+	}
+
+	for exitCode, want := range tests {
+		if got := StateLabel(exitCode); got != want {
+			t.Errorf("StateLabel(%d) = %q; want %q", exitCode, got, want)
+		}
+	}
+}
+
+func TestPluginFinishText(t *testing.T) {
+
+	es := nagios.ExitState{
+		ExitStatusCode: nagios.StateOKExitCode,
+	}
+
+	p := New(&es)
+
+	func() {
+		defer p.Finish()
+		panic("unexpected failure")
+	}()
+
+	if es.ExitStatusCode != nagios.StateCRITICALExitCode {
+		t.Errorf("got exit code %d; want %d", es.ExitStatusCode, nagios.StateCRITICALExitCode)
+	}
+
+	text := p.Text()
+
+	if want := "CRITICAL: plugin crash detected."; !strings.HasPrefix(text, want) {
+		t.Errorf("output %q does not begin with %q", text, want)
+	}
+
+	for _, want := range []string{" | time=", "**ERRORS**", "unexpected failure"} {
+		if !strings.Contains(text, want) {
+			t.Errorf("output %q does not contain %q", text, want)
+		}
+	}
+}
+
+func TestPluginCheckmk(t *testing.T) {
+
+	es := nagios.ExitState{
+		LastError:      errors.New("snapshots exceed age threshold"),
+		ExitStatusCode: nagios.StateWARNINGExitCode,
+		ServiceOutput:  "WARNING: 2 snapshots older than 2 days detected",
+	}
+
+	p := New(&es)
+	p.App = config.AppInfo{Plugin: config.PluginTypeSnapshotsAge}
+	p.PerfData = []perfdata.PerformanceData{
+		{Label: "snapshot_age_max_days", Value: "2", Warn: "1", Crit: "3:"},
+		{Label: "time", Value: "1520", UnitOfMeasurement: perfdata.UOMMilliseconds},
+		{Label: "unknown", Value: "U"},
+	}
+
+	want := `1 vmware_snapshots_age snapshot_age_max_days=2;1|time=1520 ` +
+		`WARNING: 2 snapshots older than 2 days detected\n\n**ERRORS**\n\n* snapshots exceed age threshold` + "\n"
+
+	if got := p.Checkmk(); got != want {
+		t.Errorf("Checkmk() = %q; want %q", got, want)
+	}
+
+	p.PerfData = nil
+	es.LastError = nil
+	p.App = config.AppInfo{Plugin: config.PluginTypeTools}
+	es.ServiceOutput = "OK: No VMware Tools issues detected"
+	es.ExitStatusCode = nagios.StateOKExitCode
+
+	want = `0 vmware_tools - OK: No VMware Tools issues detected` + "\n"
+
+	if got := p.Checkmk(); got != want {
+		t.Errorf("Checkmk() = %q; want %q", got, want)
+	}
+
+	es.ServiceOutput = "WARNING: 2 snapshots older than 2 days detected"
+	p.ServiceName = "VMware Snapshots"
+	es.ExitStatusCode = nagios.StateDEPENDENTExitCode
+
+	want = `3 "VMware Snapshots" - WARNING: 2 snapshots older than 2 days detected` + "\n"
+
+	if got := p.Checkmk(); got != want {
+		t.Errorf("Checkmk() = %q; want %q", got, want)
+	}
+
+	es.ExitStatusCode = nagios.StateWARNINGExitCode
+	p.ServiceName = ""
+	p.App = config.AppInfo{Plugin: config.PluginTypeSnapshotsAge}
+	p.Objects = []Object{
+		{
+			Name:     "vm1",
+			State:    nagios.StateWARNINGExitCode,
+			PerfData: []perfdata.PerformanceData{{Label: "snapshots", Value: "2"}},
+			Text:     "WARNING: 2 snapshots, oldest 2.50 days",
+		},
+		{
+			Name:  "vm2",
+			State: nagios.StateOKExitCode,
+		},
+	}
+
+	want = `1 "vmware_snapshots_age vm1" snapshots=2 WARNING: 2 snapshots, oldest 2.50 days` + "\n" +
+		`0 "vmware_snapshots_age vm2" - WARNING: 2 snapshots older than 2 days detected` + "\n"
+
+	if got := p.Checkmk(); got != want {
+		t.Errorf("Checkmk() = %q; want %q", got, want)
+	}
+}
+
+func TestPluginWriteIcinga2(t *testing.T) {
+
+	es := nagios.ExitState{
+		ExitStatusCode: nagios.StateCRITICALExitCode,
+		ServiceOutput:  "CRITICAL: Datastore usage high",
+	}
+
+	p := New(&es)
+	p.PerfData = []perfdata.PerformanceData{
+		{Label: "datastore_usage", Value: "96.00", UnitOfMeasurement: perfdata.UOMPercentage},
+		{Label: "invalid", Value: "NaN"},
+	}
+
+	var buf bytes.Buffer
+	if err := p.writeIcinga2(&buf); err != nil {
+		t.Fatalf("writeIcinga2 returned unexpected error: %v", err)
+	}
+
+	var got Icinga2Result
+	if err := json.Unmarshal(buf.Bytes(), &got); err != nil {
+		t.Fatalf("failed to decode JSON output %q: %v", buf.String(), err)
+	}
+
+	if got.ExitStatus != nagios.StateCRITICALExitCode {
+		t.Errorf("exit_status = %d; want %d", got.ExitStatus, nagios.StateCRITICALExitCode)
+	}
+
+	if got.PluginOutput != es.ServiceOutput {
+		t.Errorf("plugin_output = %q; want %q", got.PluginOutput, es.ServiceOutput)
+	}
+
+	if len(got.PerformanceData) != 1 || got.PerformanceData[0] != "datastore_usage=96.00%" {
+		t.Errorf("performance_data = %v; want single valid entry", got.PerformanceData)
+	}
+
+	if got.ExecutionStart <= 0 || got.ExecutionEnd < got.ExecutionStart {
+		t.Errorf("execution_start = %v, execution_end = %v; want valid timestamps", got.ExecutionStart, got.ExecutionEnd)
+	}
+}
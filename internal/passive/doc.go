@@ -0,0 +1,11 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+// Package passive provides types and functions used to submit passive check
+// results to Nagios via the external command file or the check result spool
+// directory.
+package passive
@@ -0,0 +1,232 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package passive
+
+import (
+	"crypto/rand"
+	"errors"
+	"fmt"
+	"math/big"
+	"os"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// spoolFileChars are the characters used for the random portion of check
+// result spool file names.
+const spoolFileChars string = "abcdefghijklmnopqrstuvwxyzABCDEFGHIJKLMNOPQRSTUVWXYZ0123456789"
+
+// spoolFileRandomLength is the length of the random portion of check result
+// spool file names. Nagios only processes files named using a single 'c'
+// followed by six characters.
+const spoolFileRandomLength int = 6
+
+// spoolFileAttempts is the number of attempts made to create a uniquely
+// named check result spool file.
+const spoolFileAttempts int = 10
+
+// CheckResult is the result of a service check submitted to Nagios as a
+// passive check result.
+type CheckResult struct {
+
+	// HostName is the name of the Nagios host associated with the service.
+	HostName string
+
+	// ServiceDescription is the description of the Nagios service.
+	ServiceDescription string
+
+	// ReturnCode is the Nagios state exit code for the check.
+	ReturnCode int
+
+	// Output is the plugin output for the check including long service
+	// output and performance data.
+	Output string
+
+	// Start is when the check began.
+	Start time.Time
+
+	// Finish is when the check completed.
+	Finish time.Time
+}
+
+// escapeOutput escapes newline characters in plugin output so that multiline
+// output is submitted as a single line. Nagios restores the newlines when
+// processing the check result.
+func escapeOutput(s string) string {
+	return strings.NewReplacer("\r\n", `\n`, "\n", `\n`, "\r", "").Replace(s)
+}
+
+// ExternalCommand returns the PROCESS_SERVICE_CHECK_RESULT external command
+// used to submit the check result via the Nagios external command file.
+func (cr CheckResult) ExternalCommand() string {
+	return fmt.Sprintf(
+		"[%d] PROCESS_SERVICE_CHECK_RESULT;%s;%s;%d;%s\n",
+		cr.Finish.Unix(),
+		cr.HostName,
+		cr.ServiceDescription,
+		cr.ReturnCode,
+		escapeOutput(cr.Output),
+	)
+}
+
+// formatTime formats t using the seconds.microseconds format used in Nagios
+// check result files.
+func formatTime(t time.Time) string {
+	return fmt.Sprintf("%d.%06d", t.Unix(), t.Nanosecond()/int(time.Microsecond))
+}
+
+// spoolEntry returns the check result formatted as an entry within a Nagios
+// check result spool file.
+func (cr CheckResult) spoolEntry() string {
+
+	var sb strings.Builder
+
+	sb.WriteString("### Nagios Service Check Result ###\n")
+	fmt.Fprintf(&sb, "# Time: %s\n", cr.Finish.Format(time.ANSIC))
+	fmt.Fprintf(&sb, "host_name=%s\n", cr.HostName)
+	fmt.Fprintf(&sb, "service_description=%s\n", cr.ServiceDescription)
+	sb.WriteString("check_type=1\n")
+	sb.WriteString("check_options=0\n")
+	sb.WriteString("scheduled_check=0\n")
+	sb.WriteString("reschedule_check=0\n")
+	sb.WriteString("latency=0.000000\n")
+	fmt.Fprintf(&sb, "start_time=%s\n", formatTime(cr.Start))
+	fmt.Fprintf(&sb, "finish_time=%s\n", formatTime(cr.Finish))
+	sb.WriteString("early_timeout=0\n")
+	sb.WriteString("exited_ok=1\n")
+	fmt.Fprintf(&sb, "return_code=%d\n", cr.ReturnCode)
+	fmt.Fprintf(&sb, "output=%s\n", escapeOutput(cr.Output))
+	sb.WriteString("\n")
+
+	return sb.String()
+}
+
+// spoolFile returns the content of a Nagios check result spool file
+// containing the given check results.
+func spoolFile(results []CheckResult, now time.Time) string {
+
+	var sb strings.Builder
+
+	sb.WriteString("### Passive Check Result File ###\n")
+	fmt.Fprintf(&sb, "# Time: %s\n", now.Format(time.ANSIC))
+	fmt.Fprintf(&sb, "file_time=%d\n\n", now.Unix())
+
+	for _, cr := range results {
+		sb.WriteString(cr.spoolEntry())
+	}
+
+	return sb.String()
+}
+
+// WriteCommandFile submits the given check results using the Nagios external
+// command file at the specified path. The command file is expected to exist
+// (it is created by Nagios when external commands are enabled).
+func WriteCommandFile(path string, results []CheckResult) error {
+
+	fh, err := os.OpenFile(filepath.Clean(path), os.O_WRONLY|os.O_APPEND, 0)
+	if err != nil {
+		return fmt.Errorf("failed to open command file: %w", err)
+	}
+
+	// each command is written separately so that commands from other
+	// processes writing to the command file are not interleaved
+	for _, cr := range results {
+		if _, err := fh.WriteString(cr.ExternalCommand()); err != nil {
+			_ = fh.Close()
+			return fmt.Errorf("failed to write to command file: %w", err)
+		}
+	}
+
+	if err := fh.Close(); err != nil {
+		return fmt.Errorf("failed to close command file: %w", err)
+	}
+
+	return nil
+}
+
+// WriteSpoolDir submits the given check results by creating a check result
+// file within the Nagios check result spool directory at the specified path.
+// A matching .ok file is created once the check result file is complete to
+// indicate to Nagios that the file is ready for processing.
+func WriteSpoolDir(dir string, results []CheckResult) error {
+
+	fh, err := createSpoolFile(dir)
+	if err != nil {
+		return fmt.Errorf("failed to create check result file: %w", err)
+	}
+
+	if _, err := fh.WriteString(spoolFile(results, time.Now())); err != nil {
+		_ = fh.Close()
+		_ = os.Remove(fh.Name())
+		return fmt.Errorf("failed to write check result file: %w", err)
+	}
+
+	if err := fh.Close(); err != nil {
+		_ = os.Remove(fh.Name())
+		return fmt.Errorf("failed to close check result file: %w", err)
+	}
+
+	okFile, err := os.OpenFile(fh.Name()+".ok", os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+	if err != nil {
+		_ = os.Remove(fh.Name())
+		return fmt.Errorf("failed to create check result ok file: %w", err)
+	}
+
+	if err := okFile.Close(); err != nil {
+		return fmt.Errorf("failed to close check result ok file: %w", err)
+	}
+
+	return nil
+}
+
+// createSpoolFile creates a uniquely named check result file within the
+// specified spool directory using the naming convention required by Nagios.
+func createSpoolFile(dir string) (*os.File, error) {
+
+	for i := 0; i < spoolFileAttempts; i++ {
+		name, err := randomSpoolFileName()
+		if err != nil {
+			return nil, err
+		}
+
+		path := filepath.Join(filepath.Clean(dir), name)
+		fh, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_EXCL, 0600)
+		switch {
+		case errors.Is(err, os.ErrExist):
+			continue
+		case err != nil:
+			return nil, err
+		}
+
+		return fh, nil
+	}
+
+	return nil, fmt.Errorf(
+		"failed to create uniquely named file in %s after %d attempts",
+		dir,
+		spoolFileAttempts,
+	)
+}
+
+// randomSpoolFileName returns a random check result file name.
+func randomSpoolFileName() (string, error) {
+
+	name := make([]byte, 0, spoolFileRandomLength+1)
+	name = append(name, 'c')
+
+	for i := 0; i < spoolFileRandomLength; i++ {
+		n, err := rand.Int(rand.Reader, big.NewInt(int64(len(spoolFileChars))))
+		if err != nil {
+			return "", err
+		}
+		name = append(name, spoolFileChars[n.Int64()])
+	}
+
+	return string(name), nil
+}
@@ -0,0 +1,77 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package passive
+
+import (
+	"io/ioutil"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+)
+
+func TestExternalCommand(t *testing.T) {
+
+	cr := CheckResult{
+		HostName:           "vc1.example.com",
+		ServiceDescription: "VMware Snapshots Age",
+		ReturnCode:         1,
+		Output:             "WARNING: 1 snapshot | time=5ms\r\n\r\n**ERRORS**\r\n",
+		Finish:             time.Unix(1600000000, 0),
+	}
+
+	want := `[1600000000] PROCESS_SERVICE_CHECK_RESULT;vc1.example.com;VMware Snapshots Age;1;WARNING: 1 snapshot | time=5ms\n\n**ERRORS**\n` + "\n"
+
+	if got := cr.ExternalCommand(); got != want {
+		t.Errorf("got %q; want %q", got, want)
+	}
+}
+
+func TestWriteSpoolDir(t *testing.T) {
+
+	dir := t.TempDir()
+
+	results := []CheckResult{
+		{
+			HostName:           "vc1.example.com",
+			ServiceDescription: "VMware Tools",
+			ReturnCode:         0,
+			Output:             "OK: No VMware Tools issues",
+			Start:              time.Unix(1600000000, 500000000),
+			Finish:             time.Unix(1600000001, 0),
+		},
+	}
+
+	if err := WriteSpoolDir(dir, results); err != nil {
+		t.Fatalf("WriteSpoolDir returned unexpected error: %v", err)
+	}
+
+	okFiles, err := filepath.Glob(filepath.Join(dir, "c??????.ok"))
+	if err != nil || len(okFiles) != 1 {
+		t.Fatalf("got ok files %v (error: %v); want one", okFiles, err)
+	}
+
+	content, err := ioutil.ReadFile(strings.TrimSuffix(okFiles[0], ".ok"))
+	if err != nil {
+		t.Fatal(err)
+	}
+
+	for _, want := range []string{
+		"### Passive Check Result File ###\n",
+		"host_name=vc1.example.com\n",
+		"service_description=VMware Tools\n",
+		"start_time=1600000000.500000\n",
+		"finish_time=1600000001.000000\n",
+		"return_code=0\n",
+		"output=OK: No VMware Tools issues\n",
+	} {
+		if !strings.Contains(string(content), want) {
+			t.Errorf("check result file does not contain %q:\n%s", want, content)
+		}
+	}
+}
@@ -0,0 +1,10 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+// Package perfdata provides types and functions used to generate Nagios
+// performance data for plugins provided by this module.
+package perfdata
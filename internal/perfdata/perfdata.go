@@ -0,0 +1,234 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package perfdata
+
+import (
+	"errors"
+	"fmt"
+	"math"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// ErrInvalidPerformanceData indicates that a performance data entry does not
+// meet the format requirements set by the Nagios Plugin API.
+var ErrInvalidPerformanceData = errors.New("invalid performance data")
+
+// Valid Unit of Measurement (UOM) values for performance data. An empty
+// string (no unit specified) is also valid and indicates a number of things
+// (e.g., users, processes, load averages).
+const (
+	UOMNone         string = ""
+	UOMSeconds      string = "s"
+	UOMMilliseconds string = "ms"
+	UOMMicroseconds string = "us"
+	UOMPercentage   string = "%"
+	UOMBytes        string = "B"
+	UOMKilobytes    string = "KB"
+	UOMMegabytes    string = "MB"
+	UOMGigabytes    string = "GB"
+	UOMTerabytes    string = "TB"
+	UOMCounter      string = "c"
+)
+
+// valueUnknown is the value used to indicate that the actual value could not
+// be determined.
+const valueUnknown string = "U"
+
+// PerformanceData represents a single performance data metric emitted by a
+// plugin. The format is:
+//
+// 'label'=value[UOM];[warn];[crit];[min];[max]
+//
+// See https://nagios-plugins.org/doc/guidelines.html#AEN200 for additional
+// details. Field names used when serializing to JSON are part of the
+// versioned JSON output schema.
+type PerformanceData struct {
+
+	// Label is the text string used as a label for the performance data. The
+	// label may contain any characters except the equals sign or single
+	// quote.
+	Label string `json:"label"`
+
+	// Value is the numeric value of the metric or "U" if the actual value
+	// could not be determined.
+	Value string `json:"value"`
+
+	// UnitOfMeasurement is one of the supported UOM values. An empty string
+	// indicates that no unit is specified.
+	UnitOfMeasurement string `json:"uom,omitempty"`
+
+	// Warn is the WARNING threshold for the metric, if applicable.
+	Warn string `json:"warn,omitempty"`
+
+	// Crit is the CRITICAL threshold for the metric, if applicable.
+	Crit string `json:"crit,omitempty"`
+
+	// Min is the minimum possible value for the metric, if applicable.
+	Min string `json:"min,omitempty"`
+
+	// Max is the maximum possible value for the metric, if applicable.
+	Max string `json:"max,omitempty"`
+}
+
+// Validate asserts that the performance data entry meets the format
+// requirements set by the Nagios Plugin API.
+func (pd PerformanceData) Validate() error {
+
+	switch {
+	case strings.TrimSpace(pd.Label) == "":
+		return fmt.Errorf("%w: empty label", ErrInvalidPerformanceData)
+
+	case strings.ContainsAny(pd.Label, "='"):
+		return fmt.Errorf(
+			"%w: label %q contains equals sign or single quote",
+			ErrInvalidPerformanceData,
+			pd.Label,
+		)
+	}
+
+	if pd.Value != valueUnknown {
+		v, err := strconv.ParseFloat(pd.Value, 64)
+		if err != nil || math.IsNaN(v) || math.IsInf(v, 0) {
+			return fmt.Errorf(
+				"%w: non-numeric value %q for label %q",
+				ErrInvalidPerformanceData,
+				pd.Value,
+				pd.Label,
+			)
+		}
+	}
+
+	switch pd.UnitOfMeasurement {
+	case UOMNone, UOMSeconds, UOMMilliseconds, UOMMicroseconds,
+		UOMPercentage, UOMBytes, UOMKilobytes, UOMMegabytes,
+		UOMGigabytes, UOMTerabytes, UOMCounter:
+	default:
+		return fmt.Errorf(
+			"%w: unsupported unit of measurement %q for label %q",
+			ErrInvalidPerformanceData,
+			pd.UnitOfMeasurement,
+			pd.Label,
+		)
+	}
+
+	for _, field := range []string{pd.Warn, pd.Crit, pd.Min, pd.Max} {
+		if field == "" {
+			continue
+		}
+		if strings.ContainsAny(field, "; ") {
+			return fmt.Errorf(
+				"%w: threshold or range value %q for label %q contains"+
+					" separator or whitespace",
+				ErrInvalidPerformanceData,
+				field,
+				pd.Label,
+			)
+		}
+	}
+
+	return nil
+
+}
+
+// String provides the performance data entry in the format expected by
+// Nagios. Trailing empty fields are omitted.
+func (pd PerformanceData) String() string {
+
+	label := pd.Label
+	if strings.ContainsAny(label, " \t") {
+		label = "'" + label + "'"
+	}
+
+	fields := []string{
+		pd.Value + pd.UnitOfMeasurement,
+		pd.Warn,
+		pd.Crit,
+		pd.Min,
+		pd.Max,
+	}
+
+	// drop trailing empty fields
+	last := len(fields)
+	for last > 1 && fields[last-1] == "" {
+		last--
+	}
+
+	return label + "=" + strings.Join(fields[:last], ";")
+
+}
+
+// Valid returns the provided performance data entries which pass
+// validation. Entries which fail validation are skipped so that one malformed
+// metric does not prevent the others from being recorded.
+func Valid(pd ...PerformanceData) []PerformanceData {
+
+	valid := make([]PerformanceData, 0, len(pd))
+	for i := range pd {
+		if err := pd[i].Validate(); err != nil {
+			continue
+		}
+		valid = append(valid, pd[i])
+	}
+
+	return valid
+
+}
+
+// Format returns the provided performance data entries as a single
+// space-separated string. Entries which fail validation are skipped.
+func Format(pd ...PerformanceData) string {
+
+	valid := Valid(pd...)
+
+	entries := make([]string, 0, len(valid))
+	for i := range valid {
+		entries = append(entries, valid[i].String())
+	}
+
+	return strings.Join(entries, " ")
+
+}
+
+// Append returns the provided one-line service check summary with the given
+// performance data entries appended using the pipe separator expected by
+// Nagios. The summary is returned unmodified if it is empty or if no valid
+// entries are provided.
+func Append(serviceOutput string, pd ...PerformanceData) string {
+
+	formatted := Format(pd...)
+	if serviceOutput == "" || formatted == "" {
+		return serviceOutput
+	}
+
+	return fmt.Sprintf("%s | %s", serviceOutput, formatted)
+
+}
+
+// Runtime returns a performance data entry recording the time elapsed
+// (in milliseconds) since the provided plugin start time.
+func Runtime(start time.Time) PerformanceData {
+	return PerformanceData{
+		Label:             "time",
+		Value:             strconv.FormatInt(time.Since(start).Milliseconds(), 10),
+		UnitOfMeasurement: UOMMilliseconds,
+	}
+}
+
+// Int returns the string representation of the provided integer value for
+// use with PerformanceData fields.
+func Int(i int64) string {
+	return strconv.FormatInt(i, 10)
+}
+
+// Float returns the string representation of the provided floating point
+// value, limited to two decimal places, for use with PerformanceData fields.
+func Float(f float64) string {
+	return strconv.FormatFloat(f, 'f', 2, 64)
+}
@@ -0,0 +1,85 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package perfdata
+
+import (
+	"testing"
+)
+
+func TestPerformanceDataString(t *testing.T) {
+
+	tests := []struct {
+		name string
+		pd   PerformanceData
+		want string
+	}{
+		{
+			name: "value only",
+			pd:   PerformanceData{Label: "vms", Value: "12"},
+			want: "vms=12",
+		},
+		{
+			name: "all fields",
+			pd: PerformanceData{
+				Label:             "datastore_usage",
+				Value:             "81.25",
+				UnitOfMeasurement: UOMPercentage,
+				Warn:              "80",
+				Crit:              "90",
+				Min:               "0",
+				Max:               "100",
+			},
+			want: "datastore_usage=81.25%;80;90;0;100",
+		},
+		{
+			name: "inner empty fields retained",
+			pd:   PerformanceData{Label: "vms_needing_response", Value: "0", Crit: "0", Min: "0"},
+			want: "vms_needing_response=0;;0;0",
+		},
+		{
+			name: "label with spaces is quoted",
+			pd:   PerformanceData{Label: "memory used", Value: "1024", UnitOfMeasurement: UOMBytes},
+			want: "'memory used'=1024B",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if err := tt.pd.Validate(); err != nil {
+				t.Fatalf("unexpected validation error: %v", err)
+			}
+			if got := tt.pd.String(); got != tt.want {
+				t.Errorf("got %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAppend(t *testing.T) {
+
+	valid := PerformanceData{Label: "time", Value: "15", UnitOfMeasurement: UOMMilliseconds}
+	invalid := PerformanceData{Label: "bad=label", Value: "1"}
+	notANumber := PerformanceData{Label: "vcpus_usage", Value: Float(0.0 / zero())}
+
+	got := Append("OK: All is well", valid, invalid, notANumber)
+	want := "OK: All is well | time=15ms"
+	if got != want {
+		t.Errorf("got %q, want %q", got, want)
+	}
+
+	if got := Append("OK: All is well", invalid); got != "OK: All is well" {
+		t.Errorf("summary modified when no valid entries provided: %q", got)
+	}
+
+	if got := Append("", valid); got != "" {
+		t.Errorf("empty summary modified: %q", got)
+	}
+}
+
+// zero is used to produce a NaN value at runtime.
+func zero() float64 { return 0 }
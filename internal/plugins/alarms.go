@@ -0,0 +1,307 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// Alarms executes the plugin used to monitor triggered alarms. This function
+// does not return; the application exits with the final plugin state.
+func Alarms() {
+	run(config.PluginType{Alarms: true}, checkAlarms)
+}
+
+// checkAlarms evaluates triggered alarms using an established vSphere
+// session and records the results in the provided plugin output.
+func checkAlarms(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	// https://vdc-download.vmware.com/vmwb-repository/dcr-public/a5f4000f-1ea8-48a9-9221-586adff3c557/7ff50256-2cf2-45ea-aacd-87d231ab1ac7/vim.ManagedEntity.html#overallStatus
+	nagiosExitState.CriticalThreshold = "One or more non-excluded alarms with a red status"
+	nagiosExitState.WarningThreshold = "One or more non-excluded alarms with a yellow status"
+
+	log := cfg.Log.With().
+		Str("datacenter_names", strings.Join(cfg.DatacenterNames, ", ")).
+		Bool("eval_acknowledged_alarms", cfg.EvaluateAcknowledgedAlarms).
+		Logger()
+
+	// At this point we're logged in. Alarms are provided by vCenter, so
+	// confirm that we're not connected to a standalone ESXi host.
+	log.Debug().Msg("Verifying vCenter connection")
+	if err := vsphere.RequireVCenter(c.Client, "alarms"); err != nil {
+		log.Error().Err(err).Msg("plugin requires vCenter connection")
+
+		nagiosExitState.LastError = err
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Plugin requires a vCenter connection; alarms are not provided by standalone ESXi hosts",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Ready to process alarms.
+
+	log.Debug().
+		Int("datacenters_specified", len(cfg.DatacenterNames)).
+		Msg("Validating datacenter names")
+	validateDCsErr := vsphere.ValidateDCs(ctx, c.Client, cfg.DatacenterNames)
+	if validateDCsErr != nil {
+		log.Error().Err(validateDCsErr).Msg("error validating datacenter names")
+
+		nagiosExitState.LastError = validateDCsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating requested datacenter names",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Retrieving Datacenters")
+	dcs, dcsFetchErr := vsphere.GetDatacenters(ctx, c.Client, cfg.DatacenterNames, true)
+	if dcsFetchErr != nil {
+		log.Error().Err(dcsFetchErr).Msg("error retrieving datacenters")
+
+		nagiosExitState.LastError = dcsFetchErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving datacenters",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	dcsEvalNames := func(dcs []mo.Datacenter) []string {
+		names := make([]string, len(dcs))
+		for i := range dcs {
+			names[i] = dcs[i].Name
+		}
+		return names
+	}(dcs)
+
+	log.Debug().
+		Int("datacenters_found", len(dcs)).
+		Str("datacenters", strings.Join(dcsEvalNames, ", ")).
+		Msg("Datacenters found")
+
+	if len(cfg.ExcludedAlarmEntityResourcePools) > 0 || len(cfg.IncludedAlarmEntityResourcePools) > 0 {
+		// If include/exclude lists for Resource Pools (associated with Triggered
+		// Alarm entities) were provided, validate those.
+		log.Debug().Msg("Validating provided resource pool names")
+		validateRPsErr := vsphere.ValidateRPs(
+			ctx,
+			c.Client,
+			cfg.IncludedAlarmEntityResourcePools,
+			cfg.ExcludedAlarmEntityResourcePools,
+		)
+		if validateRPsErr != nil {
+			log.Error().Err(validateRPsErr).Msg("error validating include/exclude lists")
+
+			nagiosExitState.LastError = validateRPsErr
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Error validating include/exclude lists",
+				nagios.StateCRITICALLabel,
+			)
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+		}
+	}
+
+	triggeredAlarms, fetchAlarmsErr := vsphere.GetTriggeredAlarms(
+		ctx,
+		c,
+		dcs,
+		true,
+	)
+
+	if fetchAlarmsErr != nil {
+		log.Error().Err(fetchAlarmsErr).Msg("error retrieving alarms")
+
+		nagiosExitState.LastError = fetchAlarmsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving alarms",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Int("total_triggered_alarms", len(triggeredAlarms)).Msg("")
+
+	// Collect all filtering options together for easy reference.
+	triggeredAlarmFilters := vsphere.TriggeredAlarmFilters{
+		IncludedAlarmEntityTypes:         cfg.IncludedAlarmEntityTypes,
+		ExcludedAlarmEntityTypes:         cfg.ExcludedAlarmEntityTypes,
+		IncludedAlarmEntityNames:         cfg.IncludedAlarmEntityNames,
+		ExcludedAlarmEntityNames:         cfg.ExcludedAlarmEntityNames,
+		IncludedAlarmEntityResourcePools: cfg.IncludedAlarmEntityResourcePools,
+		ExcludedAlarmEntityResourcePools: cfg.ExcludedAlarmEntityResourcePools,
+		IncludedAlarmNames:               cfg.IncludedAlarmNames,
+		ExcludedAlarmNames:               cfg.ExcludedAlarmNames,
+		IncludedAlarmDescriptions:        cfg.IncludedAlarmDescriptions,
+		ExcludedAlarmDescriptions:        cfg.ExcludedAlarmDescriptions,
+		IncludedAlarmStatuses:            cfg.IncludedAlarmStatuses,
+		ExcludedAlarmStatuses:            cfg.ExcludedAlarmStatuses,
+		EvaluateAcknowledgedAlarms:       cfg.EvaluateAcknowledgedAlarms,
+	}
+
+	switch {
+
+	case len(triggeredAlarms) > 0:
+
+		// Filter Triggered Alarms using requested settings.
+		triggeredAlarms.Filter(triggeredAlarmFilters)
+
+		numTriggeredAlarmsToReport := len(triggeredAlarms) - triggeredAlarms.NumExcluded()
+		log.Debug().
+			Int("remaining_triggered_alarms", numTriggeredAlarmsToReport).
+			Msg("triggered alarms remaining after filtering")
+
+		if !triggeredAlarms.IsOKState(false) {
+			log.Error().
+				Int("total_triggered_alarms", len(triggeredAlarms)).
+				Int("remaining_alarms", numTriggeredAlarmsToReport).
+				Int("excluded_triggered_alarms", triggeredAlarms.NumExcluded()).
+				Msg("Non-excluded alarms detected")
+		}
+
+		// Set state label and exit code based on most severe
+		// ManagedEntityStatus found in the TriggeredAlarms collection. Record
+		// error if any TriggeredAlarms remain after filtering.
+		stateLabel, stateExitCode := triggeredAlarmsState(triggeredAlarms)
+		nagiosExitState.ExitStatusCode = stateExitCode
+
+		switch {
+		case stateExitCode != nagios.StateOKExitCode:
+			nagiosExitState.LastError = vsphere.ErrAlarmNotExcludedFromEvaluation
+
+		// though we started off with triggered alarms, it's possible that we
+		// filtered all of them out by this point
+		default:
+
+			// success path
+
+			nagiosExitState.LastError = nil
+
+		}
+
+		plugin.PerfData = vsphere.AlarmsPerfData(triggeredAlarms, dcsEvalNames)
+		plugin.Data = vsphere.NewAlarmsData(triggeredAlarms, dcsEvalNames)
+		plugin.Objects = alarmObjects(triggeredAlarms, dcsEvalNames)
+
+		nagiosExitState.ServiceOutput = vsphere.AlarmsOneLineCheckSummary(
+			stateLabel,
+			triggeredAlarms,
+			dcsEvalNames,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.AlarmsReport(
+			c.Client,
+			triggeredAlarms,
+			triggeredAlarmFilters,
+			cfg.DatacenterNames,
+			dcsEvalNames,
+		) + vsphere.IgnoreFileReport(cfg.IgnoreFile, cfg.IgnoreEntries)
+
+		return
+
+	default:
+
+		// success path
+
+		log.Debug().Msg("No non-excluded alarms detected")
+
+		nagiosExitState.LastError = nil
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		plugin.PerfData = vsphere.AlarmsPerfData(triggeredAlarms, dcsEvalNames)
+		plugin.Data = vsphere.NewAlarmsData(triggeredAlarms, dcsEvalNames)
+
+		nagiosExitState.ServiceOutput = vsphere.AlarmsOneLineCheckSummary(
+			nagios.StateOKLabel,
+			triggeredAlarms,
+			dcsEvalNames,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.AlarmsReport(
+			c.Client,
+			triggeredAlarms,
+			triggeredAlarmFilters,
+			cfg.DatacenterNames,
+			dcsEvalNames,
+		) + vsphere.IgnoreFileReport(cfg.IgnoreFile, cfg.IgnoreEntries)
+
+		return
+
+	}
+
+}
+
+// triggeredAlarmsState returns the Nagios state label and exit code for the
+// most severe ManagedEntityStatus found in the given TriggeredAlarms
+// collection. Excluded TriggeredAlarms are not evaluated.
+func triggeredAlarmsState(triggeredAlarms vsphere.TriggeredAlarms) (string, int) {
+	switch {
+	case triggeredAlarms.HasCriticalState(false):
+		return nagios.StateCRITICALLabel, nagios.StateCRITICALExitCode
+
+	case triggeredAlarms.HasWarningState(false):
+		return nagios.StateWARNINGLabel, nagios.StateWARNINGExitCode
+
+	case triggeredAlarms.HasUnknownState(false):
+		return nagios.StateUNKNOWNLabel, nagios.StateUNKNOWNExitCode
+
+	default:
+		return nagios.StateOKLabel, nagios.StateOKExitCode
+	}
+}
+
+// alarmObjects generates the evaluated result for each of the given
+// TriggeredAlarms which are not excluded from evaluation.
+func alarmObjects(triggeredAlarms vsphere.TriggeredAlarms, datacentersEvaluated []string) []output.Object {
+
+	objects := make([]output.Object, 0, len(triggeredAlarms))
+	for _, ta := range triggeredAlarms {
+		if ta.Excluded() {
+			continue
+		}
+
+		alarm := vsphere.TriggeredAlarms{ta}
+		stateLabel, stateExitCode := triggeredAlarmsState(alarm)
+
+		objects = append(objects, output.Object{
+			Name:     ta.Entity.Name + " " + ta.Name,
+			State:    stateExitCode,
+			PerfData: vsphere.AlarmsPerfData(alarm, datacentersEvaluated),
+			Text:     vsphere.AlarmsOneLineCheckSummary(stateLabel, alarm, datacentersEvaluated),
+		})
+	}
+
+	return objects
+}
@@ -0,0 +1,233 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/passive"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+
+	zlog "github.com/rs/zerolog/log"
+)
+
+// Batch executes the checks defined in the user-specified checks file using
+// a single vSphere session and submits the results to Nagios as passive
+// check results. The exit code for the application is returned; a non-zero
+// exit code is returned if the checks could not be executed or the results
+// could not be submitted.
+func Batch() int {
+
+	// Disable library debug logging output by default
+	// vsphere.EnableLogging()
+	vsphere.DisableLogging()
+
+	cfg, cfgErr := config.New(config.PluginType{Batch: true})
+	switch {
+	case errors.Is(cfgErr, config.ErrVersionRequested):
+		fmt.Println(config.Version())
+
+		return 0
+
+	case cfgErr != nil:
+		// We're using the standalone Err function from rs/zerolog/log as we
+		// do not have a working configuration.
+		zlog.Err(cfgErr).Msg("Error initializing application")
+
+		return 1
+	}
+
+	// Enable library-level logging if debug logging level is enabled app-wide
+	if cfg.LoggingLevel == config.LogLevelDebug {
+		vsphere.EnableLogging()
+	}
+
+	log := cfg.Log.With().
+		Str("checks_file", cfg.ChecksFile).
+		Str("command_file", cfg.CommandFile).
+		Str("spool_dir", cfg.SpoolDir).
+		Logger()
+
+	checks, err := config.LoadCheckDefinitions(cfg.ChecksFile)
+	if err != nil {
+		log.Error().Err(err).Msg("Error loading check definitions")
+
+		return 1
+	}
+
+	loginCtx, loginCancel := context.WithTimeout(context.Background(), cfg.ConnectTimeout())
+
+	log.Debug().Msg("Logging into vSphere environment")
+	c, loginErr := vsphere.Login(
+		loginCtx, cfg.Server(), cfg.Port, cfg.TrustCert,
+		cfg.CAFile, cfg.Thumbprint, cfg.Proxy,
+		cfg.Username, cfg.Domain, cfg.Password,
+		cfg.UserAgent(), cfg.SessionCacheDir(),
+		cfg.Retries, cfg.RetryDelay(), cfg.RetrieveTimeout(),
+	)
+	loginCancel()
+	if loginErr != nil {
+		// Results are still submitted for each check so that the login
+		// failure is visible in Nagios.
+		log.Error().Err(loginErr).Msgf("error logging into %s", cfg.Server())
+	} else {
+		log.Debug().Msg("Successfully logged into vSphere environment")
+	}
+
+	results := make([]passive.CheckResult, 0, len(checks))
+	for _, check := range checks {
+		result := runBatchCheck(c, loginErr, cfg, check)
+
+		log.Debug().
+			Str("check", check.Name).
+			Str("plugin", check.Plugin).
+			Str("state", output.StateLabel(result.ReturnCode)).
+			Msg("Check completed")
+
+		results = append(results, result)
+	}
+
+	switch {
+	case loginErr != nil:
+		// a session was not created; there is nothing to logout
+
+	// cached sessions are left active for reuse by later executions
+	case cfg.SessionCache:
+		log.Debug().Msg("Session caching enabled, skipping logout")
+
+	default:
+		logoutCtx, logoutCancel := context.WithTimeout(context.Background(), cfg.Timeout())
+		if err := c.Logout(logoutCtx); err != nil {
+			log.Error().
+				Err(err).
+				Msg("failed to logout")
+		}
+		logoutCancel()
+	}
+
+	var submitErr error
+	switch {
+	case cfg.CommandFile != "":
+		submitErr = passive.WriteCommandFile(cfg.CommandFile, results)
+	default:
+		submitErr = passive.WriteSpoolDir(cfg.SpoolDir, results)
+	}
+
+	if submitErr != nil {
+		log.Error().Err(submitErr).Msg("Error submitting passive check results")
+
+		return 1
+	}
+
+	log.Debug().
+		Int("checks", len(results)).
+		Msg("Submitted passive check results")
+
+	return 0
+}
+
+// runBatchCheck executes the given check using the provided vSphere session
+// and returns the result. Configuration or login errors are reported as the
+// check result so that they are visible in Nagios.
+func runBatchCheck(
+	c *govmomi.Client,
+	loginErr error,
+	batchCfg *config.Config,
+	check config.CheckDefinition,
+) passive.CheckResult {
+
+	// Set initial "state" as valid, adjust as we go.
+	var nagiosExitState = nagios.ExitState{
+		LastError:      nil,
+		ExitStatusCode: nagios.StateOKExitCode,
+	}
+
+	plugin := output.New(&nagiosExitState)
+
+	result := passive.CheckResult{
+		HostName:           check.HostName,
+		ServiceDescription: check.ServiceDescription,
+		Start:              time.Now(),
+	}
+
+	func() {
+
+		// defer this from the start so that panics are reported as the
+		// check result
+		defer plugin.Finish()
+
+		p, ok := Lookup(check.Plugin)
+		if !ok {
+			nagiosExitState.LastError = fmt.Errorf("unknown plugin %q", check.Plugin)
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Unknown plugin %q specified for check %q",
+				output.StateLabel(nagios.StateUNKNOWNExitCode),
+				check.Plugin,
+				check.Name,
+			)
+			nagiosExitState.ExitStatusCode = nagios.StateUNKNOWNExitCode
+
+			return
+		}
+
+		cfg, cfgErr := batchCfg.CheckConfig(p.Type, check)
+		if cfgErr != nil {
+			batchCfg.Log.Error().
+				Err(cfgErr).
+				Str("check", check.Name).
+				Msg("Error initializing check")
+
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Error initializing check",
+				nagios.StateCRITICALLabel,
+			)
+			nagiosExitState.LastError = cfgErr
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+		}
+
+		plugin.App = cfg.App
+
+		if cfg.EmitBranding {
+			// If enabled, show application details at end of notification
+			nagiosExitState.BrandingCallback = config.Branding("Notification generated by ")
+		}
+
+		if loginErr != nil {
+			nagiosExitState.LastError = loginErr
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Error logging into %q",
+				nagios.StateCRITICALLabel,
+				cfg.Server(),
+			)
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+		}
+
+		ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout())
+		defer cancel()
+
+		p.Check(ctx, c, cfg, plugin)
+	}()
+
+	result.ReturnCode = nagiosExitState.ExitStatusCode
+	result.Output = plugin.Text()
+	result.Finish = time.Now()
+
+	return result
+}
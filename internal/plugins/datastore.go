@@ -0,0 +1,256 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// DatastoresSize executes the plugin used to monitor datastore usage. This
+// function does not return; the application exits with the final plugin
+// state.
+func DatastoresSize() {
+	run(config.PluginType{DatastoresSize: true}, checkDatastoresSize)
+}
+
+// checkDatastoresSize evaluates datastore usage using an established vSphere
+// session and records the results in the provided plugin output.
+func checkDatastoresSize(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s%% datastore usage",
+		cfg.DatastoreUsageCritical,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s%% datastore usage",
+		cfg.DatastoreUsageWarning,
+	)
+
+	dcName := cfg.DatacenterName
+	if dcName == "" {
+		dcName = "not provided"
+	}
+
+	log := cfg.Log.With().
+		Str("datastore_name", cfg.DatastoreName).
+		Str("datacenter_name", dcName).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("datastore_critical_usage", cfg.DatastoreUsageCritical.String()).
+		Str("datastore_warning_usage", cfg.DatastoreUsageWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve the requested
+	// datastore.
+
+	log.Debug().Msg("Retrieving datastore by name")
+	datastore, dsFetchErr := vsphere.GetDatastoreByName(
+		ctx,
+		c.Client,
+		cfg.DatastoreName,
+		cfg.DatacenterName,
+		true,
+	)
+	if dsFetchErr != nil {
+		log.Error().Err(dsFetchErr).Msg(
+			"error retrieving requested datastore",
+		)
+
+		nagiosExitState.LastError = dsFetchErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving datastore %q",
+			nagios.StateCRITICALLabel,
+			cfg.DatastoreName,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Successfully retrieved datastore by name")
+
+	log.Debug().Msg("Validating tags")
+	validateTagsErr := vsphere.ValidateTags(ctx, c.Client, cfg.IncludedTags, cfg.ExcludedTags)
+	if validateTagsErr != nil {
+		log.Error().Err(validateTagsErr).Msg("error validating include/exclude tag lists")
+
+		nagiosExitState.LastError = validateTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating include/exclude tag lists",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Filter datastore to specified tags")
+	tagged, filterTagsErr := vsphere.FilterDatastoresByTag(ctx, c.Client, []mo.Datastore{datastore}, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterTagsErr != nil {
+		log.Error().Err(filterTagsErr).Msg(
+			"error retrieving vSphere tags",
+		)
+
+		nagiosExitState.LastError = filterTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving vSphere tags",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	if len(tagged) == 0 {
+		log.Debug().Msg("Datastore excluded from evaluation by specified tags")
+
+		nagiosExitState.LastError = nil
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Datastore %q not evaluated; excluded by specified tags",
+			nagios.StateOKLabel,
+			datastore.Name,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Generating datastore usage summary")
+	dsUsage := vsphere.NewDatastoreUsageSummary(
+		datastore,
+		cfg.DatastoreUsageCritical,
+		cfg.DatastoreUsageWarning,
+	)
+
+	log.Debug().
+		Str("datastore_name", datastore.Name).
+		Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
+		Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
+		Str("datastore_storage_total", units.ByteSize(dsUsage.StorageTotal).String()).
+		Str("datastore_storage_used", units.ByteSize(dsUsage.StorageUsed).String()).
+		Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
+		Str("datastore_critical_threshold", dsUsage.CriticalThreshold.String()).
+		Str("datastore_warning_threshold", dsUsage.WarningThreshold.String()).
+		Msg("Datastore usage summary")
+
+	log.Debug().Msg("Retrieving VMs for datastore")
+	dsVMs, dsVMsFetchErr := vsphere.GetVMsFromDatastore(ctx, c.Client, datastore, true)
+	if dsVMsFetchErr != nil {
+		log.Error().Err(dsFetchErr).Msg(
+			"error retrieving VirtualMachines from datastore",
+		)
+
+		nagiosExitState.LastError = dsVMsFetchErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving VirtualMachines from datastore %q",
+			nagios.StateCRITICALLabel,
+			cfg.DatastoreName,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	plugin.PerfData = vsphere.DatastoreUsagePerfData(dsUsage, dsVMs)
+	plugin.Data = vsphere.NewDatastoreUsageData(dsUsage, dsVMs)
+	plugin.Objects = []output.Object{
+		usageObject(dsUsage.Datastore.Name, dsUsage.IsCriticalState(), dsUsage.IsWarningState(), plugin.PerfData),
+	}
+
+	log.Debug().Msg("Evaluating datastore usage state")
+	switch {
+	case dsUsage.IsCriticalState():
+
+		log.Error().
+			Str("datastore_name", datastore.Name).
+			Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
+			Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
+			Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
+			Msg("Datastore usage CRITICAL")
+
+		nagiosExitState.LastError = vsphere.ErrDatastoreUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			dsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
+			c.Client,
+			dsVMs,
+			dsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case dsUsage.IsWarningState():
+
+		log.Error().
+			Str("datastore_name", datastore.Name).
+			Float64("datastore_usage_used_percentage", dsUsage.StorageUsedPercent).
+			Float64("datastore_usage_remaining_percentage", dsUsage.StorageRemainingPercent).
+			Str("datastore_storage_remaining", units.ByteSize(dsUsage.StorageRemaining).String()).
+			Msg("Datastore usage CRITICAL")
+
+		nagiosExitState.LastError = vsphere.ErrDatastoreUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			dsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
+			c.Client,
+			dsVMs,
+			dsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.DatastoreUsageOneLineCheckSummary(
+			nagios.StateOKLabel,
+			dsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.DatastoreUsageReport(
+			c.Client,
+			dsVMs,
+			dsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
@@ -0,0 +1,153 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"crypto/tls"
+	"strconv"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/rs/zerolog"
+	"github.com/vmware/govmomi/find"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+
+	// register the vSphere Automation API (tags) endpoints
+	_ "github.com/vmware/govmomi/vapi/simulator"
+)
+
+func TestCheckDatastoresSizeTags(t *testing.T) {
+
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("failed to create simulator model: %v", err)
+	}
+	defer model.Remove()
+
+	// vsphere.Login connects using HTTPS and tags are provided by the
+	// vSphere Automation API endpoints.
+	model.Service.TLS = new(tls.Config)
+	model.Service.RegisterEndpoints = true
+
+	server := model.Service.NewServer()
+	defer server.Close()
+
+	port, err := strconv.Atoi(server.URL.Port())
+	if err != nil {
+		t.Fatalf("failed to parse simulator port: %v", err)
+	}
+
+	password, _ := server.URL.User.Password()
+	c, err := vsphere.Login(
+		ctx, server.URL.Hostname(), port, true, "", "", "",
+		server.URL.User.Username(), "", password,
+		"", "", 0, time.Second, time.Minute,
+	)
+	if err != nil {
+		t.Fatalf("failed to log in to simulator: %v", err)
+	}
+
+	const dsName string = "LocalDS_0"
+
+	// Attach the Environment:Test tag to the datastore.
+	rc := rest.NewClient(c.Client)
+	if err := rc.Login(ctx, server.URL.User); err != nil {
+		t.Fatalf("failed to log in to vSphere Automation API: %v", err)
+	}
+
+	tm := tags.NewManager(rc)
+	categoryID, err := tm.CreateCategory(ctx, &tags.Category{Name: "Environment"})
+	if err != nil {
+		t.Fatalf("failed to create tag category: %v", err)
+	}
+
+	if _, err := tm.CreateTag(ctx, &tags.Tag{Name: "Production", CategoryID: categoryID}); err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+
+	testTagID, err := tm.CreateTag(ctx, &tags.Tag{Name: "Test", CategoryID: categoryID})
+	if err != nil {
+		t.Fatalf("failed to create tag: %v", err)
+	}
+
+	ds, err := find.NewFinder(c.Client).Datastore(ctx, dsName)
+	if err != nil {
+		t.Fatalf("failed to find datastore: %v", err)
+	}
+
+	if err := tm.AttachTag(ctx, testTagID, ds.Reference()); err != nil {
+		t.Fatalf("failed to attach tag: %v", err)
+	}
+
+	critical, _ := config.ParseRange("95")
+	warning, _ := config.ParseRange("90")
+
+	tests := map[string]struct {
+		includedTags  []string
+		excludedTags  []string
+		wantEvaluated bool
+	}{
+		"no tags specified": {
+			wantEvaluated: true,
+		},
+		"included tag attached": {
+			includedTags:  []string{"Environment:Test"},
+			wantEvaluated: true,
+		},
+		"included tag not attached": {
+			includedTags:  []string{"Environment:Production"},
+			wantEvaluated: false,
+		},
+		"excluded tag attached": {
+			excludedTags:  []string{"Environment:Test"},
+			wantEvaluated: false,
+		},
+		"excluded tag not attached": {
+			excludedTags:  []string{"Environment:Production"},
+			wantEvaluated: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			cfg := &config.Config{
+				Log:                    zerolog.Nop(),
+				DatastoreName:          dsName,
+				DatastoreUsageCritical: critical,
+				DatastoreUsageWarning:  warning,
+				IncludedTags:           tt.includedTags,
+				ExcludedTags:           tt.excludedTags,
+			}
+
+			plugin := output.New(&nagios.ExitState{})
+			checkDatastoresSize(ctx, c, cfg, plugin)
+
+			es := plugin.ExitState
+			if es.ExitStatusCode != nagios.StateOKExitCode {
+				t.Fatalf("got exit code %d (%s); want %d", es.ExitStatusCode, es.ServiceOutput, nagios.StateOKExitCode)
+			}
+
+			excluded := strings.Contains(es.ServiceOutput, "excluded by specified tags")
+			if evaluated := len(plugin.Objects) == 1 && !excluded; evaluated != tt.wantEvaluated {
+				t.Errorf("datastore evaluated = %t; want %t (output: %s)", evaluated, tt.wantEvaluated, es.ServiceOutput)
+			}
+		})
+	}
+}
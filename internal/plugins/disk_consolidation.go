@@ -0,0 +1,182 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// DiskConsolidation executes the plugin used to monitor VirtualMachine disk
+// consolidation status. This function does not return; the application exits
+// with the final plugin state.
+func DiskConsolidation() {
+	run(config.PluginType{DiskConsolidation: true}, checkDiskConsolidation)
+}
+
+// checkDiskConsolidation evaluates VirtualMachine disk consolidation status
+// using an established vSphere session and records the results in the
+// provided plugin output.
+func checkDiskConsolidation(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = "Disk consolidation needed for one or more Virtual Machines."
+
+	nagiosExitState.WarningThreshold = config.ThresholdNotUsed
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	// NOTE: This plugin is hard-coded to evaluate powered off and powered
+	// on VMs equally. I'm not sure whether ignoring powered off VMs by
+	// default makes sense for this particular plugin.
+	//
+	// Please share your feedback here if you feel differently:
+	// https://github.com/atc0005/check-vmware/discussions/176
+	//
+	// Please expand on some use cases for ignoring powered off VMs by default.
+	//
+	// log.Debug().Msg("Filter VMs to specified power state")
+	// filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	// here we diverge from other plugins
+
+	log.Debug().Msg("Evaluating disk consolidation needed flag")
+	vmsNeedingConsolidation := make([]mo.VirtualMachine, 0, len(filteredVMs))
+	for _, vm := range filteredVMs {
+		if vm.Runtime.ConsolidationNeeded != nil && *vm.Runtime.ConsolidationNeeded {
+			vmsNeedingConsolidation = append(vmsNeedingConsolidation, vm)
+		}
+	}
+
+	plugin.PerfData = vsphere.VMDiskConsolidationPerfData(filteredVMs, vmsNeedingConsolidation, resourcePools)
+	plugin.Data = vsphere.NewVMDiskConsolidationData(filteredVMs, vmsNeedingConsolidation, resourcePools)
+	plugin.Objects = vmObjects(filteredVMs, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmNeedingConsolidation := intersectVMs(vm, vmsNeedingConsolidation)
+
+		stateLabel, stateExitCode := thresholdState(len(vmNeedingConsolidation) > 0, false)
+
+		return stateExitCode,
+			vsphere.VMDiskConsolidationOneLineCheckSummary(stateLabel, vm, vmNeedingConsolidation, resourcePools),
+			vsphere.VMDiskConsolidationPerfData(vm, vmNeedingConsolidation, resourcePools)
+	})
+
+	switch {
+	case len(vmsNeedingConsolidation) > 0:
+
+		// *ANY* VMs requiring disk consolidation results in a CRITICAL state.
+
+		vmsList := strings.Join(vsphere.VMNames(vmsNeedingConsolidation), ", ")
+
+		log.Error().
+			Int("needing_consolidation", len(vmsNeedingConsolidation)).
+			Str("virtual_machines", vmsList).
+			Msg("Virtual Machines found in need of disk consolidation")
+
+		nagiosExitState.LastError = vsphere.ErrVirtualMachineDiskConsolidationNeeded
+
+		nagiosExitState.ServiceOutput = vsphere.VMDiskConsolidationOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			filteredVMs,
+			vmsNeedingConsolidation,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMDiskConsolidationReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			vmsNeedingConsolidation,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	default:
+
+		// success path
+
+		log.Debug().Msg("VirtualMachine disk consolidation not needed")
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.VMDiskConsolidationOneLineCheckSummary(
+			nagios.StateOKLabel,
+			filteredVMs,
+			vmsNeedingConsolidation,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMDiskConsolidationReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			vmsNeedingConsolidation,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+	}
+
+}
@@ -0,0 +1,11 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+// Package plugins provides the implementation of each plugin provided by
+// this project. Plugins are executed either by the standalone binaries (one
+// per plugin) or by the multi-call check_vmware binary.
+package plugins
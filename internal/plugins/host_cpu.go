@@ -0,0 +1,259 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// HostSystemCPU executes the plugin used to monitor host CPU usage. This
+// function does not return; the application exits with the final plugin
+// state.
+func HostSystemCPU() {
+	run(config.PluginType{HostSystemCPU: true}, checkHostSystemCPU)
+}
+
+// checkHostSystemCPU evaluates host CPU usage using an established vSphere
+// session and records the results in the provided plugin output.
+func checkHostSystemCPU(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s%% CPU usage",
+		cfg.HostSystemCPUUseCritical,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s%% CPU usage",
+		cfg.HostSystemCPUUseWarning,
+	)
+
+	dcName := cfg.DatacenterName
+	if dcName == "" {
+		dcName = "not provided"
+	}
+
+	log := cfg.Log.With().
+		Str("host_system_name", cfg.HostSystemName).
+		Str("datacenter_name", dcName).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("host_system_cpu_critical_usage", cfg.HostSystemCPUUseCritical.String()).
+		Str("host_system_cpu_warning_usage", cfg.HostSystemCPUUseWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve the requested
+	// HostSystem.
+
+	log.Debug().Msg("Retrieving host by name")
+	hostSystem, hsFetchErr := vsphere.GetHostSystemByName(
+		ctx,
+		c.Client,
+		cfg.HostSystemName,
+		cfg.DatacenterName,
+		true,
+	)
+	if hsFetchErr != nil {
+		log.Error().Err(hsFetchErr).Msg(
+			"error retrieving requested host",
+		)
+
+		nagiosExitState.LastError = hsFetchErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving host %q",
+			nagios.StateCRITICALLabel,
+			cfg.HostSystemName,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+	log.Debug().Msg("Successfully retrieved host by name")
+
+	log.Debug().Msg("Validating tags")
+	validateTagsErr := vsphere.ValidateTags(ctx, c.Client, cfg.IncludedTags, cfg.ExcludedTags)
+	if validateTagsErr != nil {
+		log.Error().Err(validateTagsErr).Msg("error validating include/exclude tag lists")
+
+		nagiosExitState.LastError = validateTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating include/exclude tag lists",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Filter host to specified tags")
+	tagged, filterTagsErr := vsphere.FilterHostSystemsByTag(ctx, c.Client, []mo.HostSystem{hostSystem}, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterTagsErr != nil {
+		log.Error().Err(filterTagsErr).Msg(
+			"error retrieving vSphere tags",
+		)
+
+		nagiosExitState.LastError = filterTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving vSphere tags",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	if len(tagged) == 0 {
+		log.Debug().Msg("Host excluded from evaluation by specified tags")
+
+		nagiosExitState.LastError = nil
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Host %q not evaluated; excluded by specified tags",
+			nagios.StateOKLabel,
+			hostSystem.Name,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Generating host CPU usage summary")
+	hsUsage := vsphere.NewHostSystemCPUUsageSummary(
+		hostSystem,
+		cfg.HostSystemCPUUseCritical,
+		cfg.HostSystemCPUUseWarning,
+	)
+
+	log.Debug().
+		Str("host_system_name", hostSystem.Name).
+		Float64("host_system_cpu_used_percentage", hsUsage.CPUUsedPercent).
+		Float64("host_system_cpu_remaining_percentage", hsUsage.CPURemainingPercent).
+		Str("host_system_cpu_total", vsphere.CPUSpeed(hsUsage.CPUTotal).String()).
+		Str("host_system_cpu_used", vsphere.CPUSpeed(hsUsage.CPUUsed).String()).
+		Str("host_system_cpu_remaining", vsphere.CPUSpeed(hsUsage.CPURemaining).String()).
+		Str("host_system_critical_threshold", hsUsage.CriticalThreshold.String()).
+		Str("host_system_warning_threshold", hsUsage.WarningThreshold.String()).
+		Msg("HostSystem CPU usage summary")
+
+	log.Debug().Msg("Retrieving VMs for host")
+	hsVMs, hsVMsFetchErr := vsphere.GetVMsFromContainer(ctx, c.Client, true, hostSystem.ManagedEntity)
+	if hsVMsFetchErr != nil {
+		log.Error().Err(hsVMsFetchErr).Msg(
+			"error retrieving VirtualMachines from host",
+		)
+
+		nagiosExitState.LastError = hsVMsFetchErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving VirtualMachines from host %q",
+			nagios.StateCRITICALLabel,
+			cfg.HostSystemName,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	plugin.PerfData = vsphere.HostSystemCPUUsagePerfData(hsVMs, hsUsage)
+	plugin.Data = vsphere.NewHostSystemCPUUsageData(hsVMs, hsUsage)
+	plugin.Objects = []output.Object{
+		usageObject(hsUsage.HostSystem.Name, hsUsage.IsCriticalState(), hsUsage.IsWarningState(), plugin.PerfData),
+	}
+
+	log.Debug().Msg("Evaluating host CPU usage state")
+	switch {
+	case hsUsage.IsCriticalState():
+
+		log.Error().
+			Str("host_name", hostSystem.Name).
+			Float64("host_system_usage_used_percentage", hsUsage.CPUUsedPercent).
+			Float64("host_system_usage_remaining_percentage", hsUsage.CPURemainingPercent).
+			Str("host_system_cpu_remaining", vsphere.CPUSpeed(hsUsage.CPURemaining).String()).
+			Msg("host CPU usage threshold crossed")
+
+		nagiosExitState.LastError = vsphere.ErrHostSystemCPUUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.HostSystemCPUUsageOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.HostSystemCPUUsageReport(
+			c.Client,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case hsUsage.IsWarningState():
+
+		log.Error().
+			Str("host_name", hostSystem.Name).
+			Float64("host_cpu_usage_used_percentage", hsUsage.CPUUsedPercent).
+			Float64("host_cpu_usage_remaining_percentage", hsUsage.CPURemainingPercent).
+			Str("host_cpu_remaining", vsphere.CPUSpeed(hsUsage.CPURemaining).String()).
+			Msg("host CPU usage threshold crossed")
+
+		nagiosExitState.LastError = vsphere.ErrHostSystemCPUUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.HostSystemCPUUsageOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.HostSystemCPUUsageReport(
+			c.Client,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		log.Debug().Msg("Host CPU usage thresholds not exceeded")
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.HostSystemCPUUsageOneLineCheckSummary(
+			nagios.StateOKLabel,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.HostSystemCPUUsageReport(
+			c.Client,
+			hsVMs,
+			hsUsage,
+		)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
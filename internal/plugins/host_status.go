@@ -0,0 +1,233 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// HostSystemStatus executes the plugin used to monitor the connection, power
+// and maintenance state of ESXi hosts. This function does not return; the
+// application exits with the final plugin state.
+func HostSystemStatus() {
+	run(config.PluginType{HostSystemStatus: true}, checkHostSystemStatus)
+}
+
+// checkHostSystemStatus evaluates the connection, power and maintenance state
+// of ESXi hosts using an established vSphere session and records the results
+// in the provided plugin output.
+func checkHostSystemStatus(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = vsphere.HostStatesThreshold(cfg.HostStateMappings, config.NagiosStateCritical)
+	nagiosExitState.WarningThreshold = vsphere.HostStatesThreshold(cfg.HostStateMappings, config.NagiosStateWarning)
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Interface("host_state_mappings", cfg.HostStateMappings).
+		Logger()
+
+	log.Debug().
+		Int("datacenters_specified", len(cfg.DatacenterNames)).
+		Msg("Validating datacenter names")
+	validateDCsErr := vsphere.ValidateDCs(ctx, c.Client, cfg.DatacenterNames)
+	if validateDCsErr != nil {
+		log.Error().Err(validateDCsErr).Msg("error validating datacenter names")
+
+		nagiosExitState.LastError = validateDCsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating requested datacenter names",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Limit retrieval of inventory objects to the specified datacenters (if
+	// any).
+	containers, containersErr := vsphere.DatacenterContainers(ctx, c.Client, cfg.DatacenterNames)
+	if containersErr != nil {
+		log.Error().Err(containersErr).Msg("error retrieving datacenters")
+
+		nagiosExitState.LastError = containersErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving requested datacenters",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Further limit retrieval of inventory objects to the specified cluster
+	// (if any).
+	containers, containersErr = vsphere.ClusterContainers(ctx, c.Client, cfg.ClusterName, containers...)
+	if containersErr != nil {
+		log.Error().Err(containersErr).Msg("error retrieving cluster")
+
+		nagiosExitState.LastError = containersErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving requested cluster %q",
+			nagios.StateCRITICALLabel,
+			cfg.ClusterName,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Validating tags")
+	validateTagsErr := vsphere.ValidateTags(ctx, c.Client, cfg.IncludedTags, cfg.ExcludedTags)
+	if validateTagsErr != nil {
+		log.Error().Err(validateTagsErr).Msg("error validating include/exclude tag lists")
+
+		nagiosExitState.LastError = validateTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating include/exclude tag lists",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Retrieving hosts")
+	hostSystems, getHostsErr := vsphere.GetHostSystems(ctx, c.Client, true, containers...)
+	if getHostsErr != nil {
+		log.Error().Err(getHostsErr).Msg("error retrieving hosts")
+
+		nagiosExitState.LastError = getHostsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving hosts",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	if len(cfg.HostSystemNames) > 0 {
+		log.Debug().Msg("Validating hosts")
+		hostSystems, getHostsErr = vsphere.GetHostSystemsByName(ctx, c.Client, cfg.HostSystemNames, true, containers...)
+		if getHostsErr != nil {
+			log.Error().Err(getHostsErr).Msg("error validating host names")
+
+			nagiosExitState.LastError = getHostsErr
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Error validating requested host names",
+				nagios.StateCRITICALLabel,
+			)
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+		}
+	}
+
+	log.Debug().Msg("Filter hosts to specified tags")
+	hostSystems, filterTagsErr := vsphere.FilterHostSystemsByTag(ctx, c.Client, hostSystems, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterTagsErr != nil {
+		log.Error().Err(filterTagsErr).Msg(
+			"error retrieving vSphere tags",
+		)
+
+		nagiosExitState.LastError = filterTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving vSphere tags",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Evaluating host states")
+	hostStatuses := vsphere.NewHostSystemStatuses(hostSystems, cfg.HostStateMappings)
+
+	for _, hs := range hostStatuses {
+		log.Debug().
+			Str("host_name", hs.HostSystem.Name).
+			Str("host_state", hs.State).
+			Str("nagios_state", hs.NagiosState).
+			Msg("Evaluated host state")
+	}
+
+	plugin.PerfData = vsphere.HostSystemStatusPerfData(hostStatuses)
+	plugin.Data = vsphere.NewHostSystemStatusData(hostStatuses, cfg.HostStateMappings)
+	plugin.Objects = hostStatusObjects(hostStatuses)
+
+	stateLabel, exitCode := hostStatuses.NagiosState()
+
+	switch {
+	case exitCode != nagios.StateOKExitCode:
+
+		log.Error().
+			Int("hosts_evaluated", len(hostStatuses)).
+			Int("hosts_not_ok", hostStatuses.NumNotOK()).
+			Msg("hosts in a non-OK state detected")
+
+		nagiosExitState.LastError = vsphere.ErrHostSystemStateNotOK
+
+	default:
+
+		log.Debug().Msg("No hosts in a non-OK state detected")
+
+		nagiosExitState.LastError = nil
+
+	}
+
+	nagiosExitState.ServiceOutput = vsphere.HostSystemStatusOneLineCheckSummary(
+		stateLabel,
+		hostStatuses,
+	)
+
+	nagiosExitState.LongServiceOutput = vsphere.HostSystemStatusReport(
+		c.Client,
+		hostStatuses,
+		cfg.HostStateMappings,
+	) + vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) + vsphere.HostSystemsReport(cfg.HostSystemNames)
+
+	nagiosExitState.ExitStatusCode = exitCode
+
+}
+
+// hostStatusObjects generates the evaluated result for each of the given
+// HostSystem states.
+func hostStatusObjects(statuses vsphere.HostSystemStatuses) []output.Object {
+
+	objects := make([]output.Object, 0, len(statuses))
+	for _, hs := range statuses {
+		status := vsphere.HostSystemStatuses{hs}
+		stateLabel, stateExitCode := status.NagiosState()
+
+		objects = append(objects, output.Object{
+			Name:     hs.HostSystem.Name,
+			State:    stateExitCode,
+			PerfData: vsphere.HostSystemStatusPerfData(status),
+			Text:     vsphere.HostSystemStatusOneLineCheckSummary(stateLabel, status),
+		})
+	}
+
+	return objects
+}
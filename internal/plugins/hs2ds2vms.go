@@ -0,0 +1,616 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/textutils"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// Host2Datastores2VMs executes the plugin used to monitor
+// Host/Datastore/VirtualMachine pairings. This function does not return; the
+// application exits with the final plugin state.
+func Host2Datastores2VMs() {
+	run(config.PluginType{Host2Datastores2VMs: true}, checkHost2Datastores2VMs)
+}
+
+// checkHost2Datastores2VMs evaluates Host/Datastore/VirtualMachine pairings
+// using an established vSphere session and records the results in the
+// provided plugin output.
+func checkHost2Datastores2VMs(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = "Any errors encountered or Hosts/Datastores/VMs mismatches."
+	nagiosExitState.WarningThreshold = "Not used by this plugin."
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Bool("eval_powered_off", cfg.PoweredOff).
+		Bool("ignore_missing_ca_on_objects", cfg.IgnoreMissingCustomAttribute).
+		Str("datastore_ca_name", cfg.DatastoreCAName()).
+		Str("datastore_ca_prefix_separator", cfg.DatastoreCASep()).
+		Str("host_ca_name", cfg.HostCAName()).
+		Str("host_ca_prefix_separator", cfg.HostCASep()).
+		Logger()
+
+	// At this point we're logged in. Custom Attributes are provided by vCenter,
+	// so confirm that we're not connected to a standalone ESXi host.
+	log.Debug().Msg("Verifying vCenter connection")
+	if err := vsphere.RequireVCenter(c.Client, "custom attributes"); err != nil {
+		log.Error().Err(err).Msg("plugin requires vCenter connection")
+
+		nagiosExitState.LastError = err
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Plugin requires a vCenter connection; custom attributes are not provided by standalone ESXi hosts",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Ready to retrieve a list of VMs. If specified, we should limit VMs
+	// based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified power state")
+	filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	// here we diverge from other plugins
+
+	dss, dssErr := vsphere.GetDatastores(ctx, c.Client, true, scope.DatacenterContainers...)
+	if dssErr != nil {
+		log.Error().Err(dssErr).Msg(
+			"error retrieving list of datastores",
+		)
+
+		nagiosExitState.LastError = dssErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving list of datastores",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	dsNames := make([]string, 0, len(dss))
+	for _, ds := range dss {
+		dsNames = append(dsNames, ds.Name)
+	}
+
+	// validate the list of ignored datastores
+	if len(cfg.IgnoredDatastores) > 0 {
+		for _, ignDSName := range cfg.IgnoredDatastores {
+			if !textutils.PatternMatchesAny(ignDSName, dsNames) {
+
+				validateIgnoredDSErr := fmt.Errorf(
+					"error validating list of ignored datastores",
+				)
+				validateIgnoredDSErrMsg := fmt.Sprintf(
+					"datastore %s could not be ignored as requested; "+
+						"could not locate datastore within vSphere inventory",
+					ignDSName,
+				)
+				log.Error().Err(validateIgnoredDSErr).
+					Msg(validateIgnoredDSErrMsg)
+
+				nagiosExitState.LastError = validateIgnoredDSErr
+				nagiosExitState.ServiceOutput = fmt.Sprintf(
+					"%s: %s",
+					nagios.StateCRITICALLabel,
+					validateIgnoredDSErrMsg,
+				)
+				nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+				return
+
+			}
+		}
+	}
+
+	hss, hsErr := vsphere.GetHostSystems(ctx, c.Client, true, scope.Containers...)
+	if hsErr != nil {
+		log.Error().Err(hsErr).Msg(
+			"error retrieving list of hosts",
+		)
+
+		nagiosExitState.LastError = hsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving list of hosts",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Filter datastores to specified tags")
+	taggedDSS, filterDSTagsErr := vsphere.FilterDatastoresByTag(ctx, c.Client, dss, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterDSTagsErr != nil {
+		log.Error().Err(filterDSTagsErr).Msg(
+			"error retrieving vSphere tags for datastores",
+		)
+
+		nagiosExitState.LastError = filterDSTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving vSphere tags for datastores",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Datastores excluded by the specified tags are skipped in the same way
+	// as datastores which are explicitly ignored.
+	taggedDSIdx := make(map[string]struct{}, len(taggedDSS))
+	for _, ds := range taggedDSS {
+		taggedDSIdx[ds.Self.Value] = struct{}{}
+	}
+
+	ignoredDatastores := append([]string{}, cfg.IgnoredDatastores...)
+	for _, ds := range dss {
+		if _, ok := taggedDSIdx[ds.Self.Value]; !ok {
+			ignoredDatastores = append(ignoredDatastores, ds.Name)
+		}
+	}
+
+	log.Debug().Msg("Filter hosts to specified tags")
+	hss, filterHSTagsErr := vsphere.FilterHostSystemsByTag(ctx, c.Client, hss, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterHSTagsErr != nil {
+		log.Error().Err(filterHSTagsErr).Msg(
+			"error retrieving vSphere tags for hosts",
+		)
+
+		nagiosExitState.LastError = filterHSTagsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving vSphere tags for hosts",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// VMs running on hosts excluded by the specified tags are not evaluated.
+	if len(cfg.IncludedTags) > 0 || len(cfg.ExcludedTags) > 0 {
+		switch {
+		case len(hss) == 0:
+			filteredVMs = nil
+		default:
+			filteredVMs = vsphere.FilterVMsByHostSystems(filteredVMs, hss)
+		}
+	}
+
+	dsCustomAttributeName := cfg.DatastoreCAName()
+	datastores := make([]vsphere.DatastoreWithCA, 0, len(dss))
+
+	for _, ds := range dss {
+
+		// if user opted to ignore the Datastore, skip attempts to retrieve
+		// Custom Attribute for it.
+		if textutils.InPatternList(ds.Name, ignoredDatastores) {
+			continue
+		}
+
+		caVal, caValErr := vsphere.GetObjectCAVal(dsCustomAttributeName, ds.ManagedEntity)
+		if caValErr != nil {
+			switch {
+
+			case errors.Is(caValErr, vsphere.ErrCustomAttributeNotSet):
+
+				// emit message even if the plugin is allowed to ignore the
+				// problem; this is not shown in the UI or notifications, only
+				// if running the plugin manually.
+				log.Error().Err(caValErr).
+					Str("custom_attribute_name", dsCustomAttributeName).
+					Str("datastore", ds.Name).
+					Msg("specified Custom Attribute not set on datastore")
+
+				if !cfg.IgnoreMissingCustomAttribute {
+					nagiosExitState.LastError = caValErr
+					nagiosExitState.ServiceOutput = fmt.Sprintf(
+						"%s: Custom Attribute %q not set on datastore %q",
+						nagios.StateCRITICALLabel,
+						dsCustomAttributeName,
+						ds.Name,
+					)
+					nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+					return
+				}
+
+				caVal = vsphere.CustomAttributeValNotSet
+
+			// custom attributes are set, but some other error occurred
+			case caValErr != nil:
+
+				log.Error().Err(caValErr).
+					Str("custom_attribute_name", dsCustomAttributeName).
+					Msg("error retrieving value for provided Custom Attribute")
+
+				nagiosExitState.LastError = caValErr
+				nagiosExitState.ServiceOutput = fmt.Sprintf(
+					"%s: Error retrieving value for provided Custom Attribute %q",
+					nagios.StateCRITICALLabel,
+					dsCustomAttributeName,
+				)
+				nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+				return
+
+			}
+		}
+
+		datastores = append(datastores, vsphere.DatastoreWithCA{
+			Datastore: ds,
+			CustomAttribute: vsphere.PairingCustomAttribute{
+				Name:  dsCustomAttributeName,
+				Value: caVal,
+			},
+		})
+	}
+
+	for _, ds := range datastores {
+		log.Debug().
+			Str("datastore", ds.Name).
+			Str("custom_attribute_name", ds.CustomAttribute.Name).
+			Str("custom_attribute_value", ds.CustomAttribute.Value).
+			Msg("")
+	}
+
+	hostCustomAttributeName := cfg.HostCAName()
+	hosts := make([]vsphere.HostWithCA, 0, len(hss))
+	for _, host := range hss {
+		caVal, caValErr := vsphere.GetObjectCAVal(hostCustomAttributeName, host.ManagedEntity)
+		if caValErr != nil {
+			switch {
+
+			case errors.Is(caValErr, vsphere.ErrCustomAttributeNotSet):
+
+				// emit message even if the plugin is allowed to ignore the
+				// problem (reminder: this is not shown in the UI or
+				// notifications, only if running the plugin manually)
+				log.Error().Err(caValErr).
+					Str("custom_attribute_name", hostCustomAttributeName).
+					Str("host", host.Name).
+					Msg("specified Custom Attribute not set on host")
+
+				if !cfg.IgnoreMissingCustomAttribute {
+					nagiosExitState.LastError = caValErr
+					nagiosExitState.ServiceOutput = fmt.Sprintf(
+						"%s: Custom Attribute %q not set on host %q",
+						nagios.StateCRITICALLabel,
+						hostCustomAttributeName,
+						host.Name,
+					)
+					nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+					return
+				}
+
+				caVal = vsphere.CustomAttributeValNotSet
+
+			case caValErr != nil:
+				log.Error().Err(caValErr).
+					Str("custom_attribute_name", hostCustomAttributeName).
+					Str("host", host.Name).
+					Msg("error retrieving value for provided Custom Attribute")
+
+				nagiosExitState.LastError = caValErr
+				nagiosExitState.ServiceOutput = fmt.Sprintf(
+					"%s: Error retrieving value for provided Custom Attribute %q from host %q",
+					nagios.StateCRITICALLabel,
+					hostCustomAttributeName,
+					host.Name,
+				)
+				nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+				return
+			}
+		}
+
+		// here is where you decide on whether we're processing prefixes
+
+		hosts = append(hosts, vsphere.HostWithCA{
+			HostSystem: host,
+			CustomAttribute: vsphere.PairingCustomAttribute{
+				Name:  hostCustomAttributeName,
+				Value: caVal,
+			},
+		})
+
+	}
+
+	for _, host := range hosts {
+		log.Debug().
+			Str("host", host.Name).
+			Str("custom_attribute_name", host.CustomAttribute.Name).
+			Str("custom_attribute_value", host.CustomAttribute.Value).
+			Msg("")
+	}
+
+	h2dIdx, h2dIdxErr := vsphere.NewHostToDatastoreIndex(
+		hosts,
+		datastores,
+		cfg.UsingCAPrefixes(),
+		cfg.HostCASep(),
+		cfg.DatastoreCASep(),
+	)
+
+	// make sure we have at least one pairing, otherwise bail
+	//
+	// TODO: Should this instead be a length check and we explicitly return
+	// the vsphere.ErrHostDatastorePairingFailed error, or keep this like it
+	// is?
+	if h2dIdxErr != nil {
+
+		var errMsg string
+		switch {
+		case errors.Is(h2dIdxErr, vsphere.ErrHostDatastorePairingFailed):
+			errMsg = "no matching datastores and hosts found using provided Custom Attribute"
+
+		default:
+			errMsg = "unknown error encountered while evaluating datastores and hosts for Custom Attribute"
+		}
+
+		log.Error().Err(h2dIdxErr).Msg(errMsg)
+
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: %s [host: %q, datastore: %q]",
+			nagios.StateCRITICALLabel,
+			errMsg,
+			hostCustomAttributeName,
+			dsCustomAttributeName,
+		)
+
+		nagiosExitState.LastError = h2dIdxErr
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	}
+
+	for hostID, pairing := range h2dIdx {
+
+		dsNamesForHost := func(pairings vsphere.HostDatastoresPairing) string {
+			names := make([]string, len(pairing.Datastores))
+			for i := range pairing.Datastores {
+				names[i] = pairing.Datastores[i].Name
+			}
+			return strings.Join(names, ", ")
+		}(pairing)
+
+		log.Debug().
+			Str("host", h2dIdx[hostID].Host.Name).
+			Str("datastores", dsNamesForHost).
+			Msg("host/datastores pairing")
+	}
+
+	// now process VMs
+	vmDatastoresPairingIssues := make(vsphere.VMToMismatchedDatastoreNames)
+	// var vmsWithPairingIssues []mo.VirtualMachine
+	for _, vm := range filteredVMs {
+
+		hostName := h2dIdx[vm.Runtime.Host.Value].Host.Name
+
+		mismatchedDatastores, lookupErr := h2dIdx.ValidateVirtualMachinePairings(
+			vm.Summary.Runtime.Host.Value,
+			dss,
+			vm.Datastore,
+			ignoredDatastores,
+		)
+
+		if lookupErr != nil {
+
+			log.Error().Err(lookupErr).
+				// Str("extended_error_msg", extendedErrMsg).
+				Str("vm", vm.Name).
+				// Str("datastore", datastore.Name).
+				Msg("Error occurred while validating VM/Host/Datastore match")
+
+			nagiosExitState.LastError = lookupErr
+			nagiosExitState.ServiceOutput = fmt.Sprintf(
+				"%s: Error occurred while validating VM/Host/Datastore match for VM %s on host %s",
+				nagios.StateCRITICALLabel,
+				vm.Name,
+				hostName,
+			)
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+		}
+
+		vmDatastoreNames := vsphere.DatastoreIDsToNames(vm.Datastore, dss)
+
+		switch {
+		// expected failure scenario; set LongServiceOutput using report func
+		case len(mismatchedDatastores) > 0:
+
+			// TODO: Should this be error level and shown by default, or debug
+			// level and muted?
+			log.Debug().
+				Str("host", hostName).
+				Str("vm", vm.Name).
+				Bool("validation_failed", true).
+				Str("vm_datastores_all", strings.Join(vmDatastoreNames, ", ")).
+				Str("vm_datastores_mismatched", strings.Join(mismatchedDatastores, ", ")).
+				Msg("VM/Host/Datastore validation failed")
+
+			// collect problem VirtualMachine objects for later review
+			// vmsWithPairingIssues = append(vmsWithPairingIssues, vm)
+
+			// index mismatched datastore names by VirtualMachine name, also for
+			// later review
+			vmDatastoresPairingIssues[vm.Name] = vsphere.VMHostDatastoresPairing{
+				HostName:       hostName,
+				DatastoreNames: mismatchedDatastores,
+			}
+
+		default:
+
+			log.Debug().
+				Str("host", hostName).
+				Str("vm", vm.Name).
+				Bool("validation_failed", false).
+				Str("vm_datastores_all", strings.Join(vmDatastoreNames, ", ")).
+				Msg("all datastores for vm matched to current host")
+
+		}
+	}
+
+	numMismatches := len(vmDatastoresPairingIssues)
+
+	plugin.PerfData = vsphere.H2D2VMsPerfData(filteredVMs, vmDatastoresPairingIssues, resourcePools)
+	plugin.Data = vsphere.NewH2D2VMsData(filteredVMs, vmDatastoresPairingIssues, resourcePools)
+	plugin.Objects = vmObjects(filteredVMs, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmPairingIssues := make(vsphere.VMToMismatchedDatastoreNames)
+		if pairing, ok := vmDatastoresPairingIssues[vm[0].Name]; ok {
+			vmPairingIssues[vm[0].Name] = pairing
+		}
+
+		stateLabel, stateExitCode := thresholdState(len(vmPairingIssues) > 0, false)
+
+		return stateExitCode,
+			vsphere.H2D2VMsOneLineCheckSummary(stateLabel, vm, vmPairingIssues, resourcePools),
+			vsphere.H2D2VMsPerfData(vm, vmPairingIssues, resourcePools)
+	})
+
+	switch {
+	// expected failure scenario; set LongServiceOutput using report func
+	case numMismatches > 0:
+
+		var vmNames []string
+		for vmName := range vmDatastoresPairingIssues {
+			vmNames = append(vmNames, vmName)
+		}
+		sort.Strings(vmNames)
+
+		log.Error().
+			Int("mismatched_vms_count", numMismatches).
+			Str("mismatched_vms_list", strings.Join(vmNames, ", ")).
+			Msg("VM/Host/Datastore validation failed")
+
+		nagiosExitState.LastError = vsphere.ErrVMDatastoreNotInVMHostPairedList
+
+		nagiosExitState.ServiceOutput = vsphere.H2D2VMsOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			filteredVMs,
+			vmDatastoresPairingIssues,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.H2D2VMsReport(
+			c.Client,
+			h2dIdx,
+			vms,
+			filteredVMs,
+			vmDatastoresPairingIssues,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			cfg.IgnoreMissingCustomAttribute,
+			cfg.IgnoredDatastores,
+			cfg.DatastoreCASep(),
+			cfg.HostCASep(),
+			cfg.DatastoreCAName(),
+			cfg.HostCAName(),
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	default:
+		// success if we made it here
+
+		log.Debug().
+			Int("vms_total", len(vms)).
+			Int("vms_filtered", len(filteredVMs)).
+			Msg("No problems with VMware Tools found")
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.H2D2VMsOneLineCheckSummary(
+			nagios.StateOKLabel,
+			filteredVMs,
+			vmDatastoresPairingIssues,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.H2D2VMsReport(
+			c.Client,
+			h2dIdx,
+			vms,
+			filteredVMs,
+			vmDatastoresPairingIssues,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			cfg.IgnoreMissingCustomAttribute,
+			cfg.IgnoredDatastores,
+			cfg.DatastoreCASep(),
+			cfg.HostCASep(),
+			cfg.DatastoreCAName(),
+			cfg.HostCAName(),
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+	}
+
+}
@@ -0,0 +1,134 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// The functions in this file generate the evaluated result for each object
+// evaluated by a plugin for use by output formats which report each object
+// separately (e.g., Checkmk local checks). Each object is evaluated by
+// passing a collection containing only that object to the same state, one-line
+// summary and performance data functions used to evaluate the full
+// collection so that the per-object results match the standard plugin
+// output.
+
+// evaluateVM evaluates a collection containing a single VirtualMachine and
+// returns the Nagios exit code, one-line summary and performance data for
+// the collection.
+type evaluateVM func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData)
+
+// vmObjects generates the evaluated result for each of the given
+// VirtualMachines using the provided evaluation function. Results are sorted
+// by VirtualMachine name.
+func vmObjects(vms []mo.VirtualMachine, evaluate evaluateVM) []output.Object {
+
+	objects := make([]output.Object, 0, len(vms))
+	for _, vm := range vms {
+		exitCode, summary, pd := evaluate([]mo.VirtualMachine{vm})
+		objects = append(objects, output.Object{
+			Name:     vm.Name,
+			State:    exitCode,
+			PerfData: pd,
+			Text:     summary,
+		})
+	}
+
+	sort.Slice(objects, func(i, j int) bool {
+		return strings.ToLower(objects[i].Name) < strings.ToLower(objects[j].Name)
+	})
+
+	return objects
+}
+
+// snapshotObjects generates the evaluated result for each of the given
+// VirtualMachines using the one-line summary and performance data functions
+// of the active snapshots plugin.
+func snapshotObjects(
+	vms []mo.VirtualMachine,
+	snapshotSets vsphere.SnapshotSummarySets,
+	snapshotThresholds vsphere.SnapshotThresholds,
+	rps []mo.ResourcePool,
+	oneLineSummary func(string, vsphere.SnapshotSummarySets, vsphere.SnapshotThresholds, []mo.VirtualMachine, []mo.ResourcePool) string,
+	perfData func(vsphere.SnapshotSummarySets, vsphere.SnapshotThresholds, []mo.VirtualMachine, []mo.ResourcePool) []perfdata.PerformanceData,
+) []output.Object {
+
+	return vmObjects(vms, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmSnapshotSets := make(vsphere.SnapshotSummarySets, 0, 1)
+		for _, set := range snapshotSets {
+			if set.VM.Value == vm[0].Self.Value {
+				vmSnapshotSets = append(vmSnapshotSets, set)
+			}
+		}
+
+		stateLabel, stateExitCode := thresholdState(
+			vmSnapshotSets.IsCriticalState(),
+			vmSnapshotSets.IsWarningState(),
+		)
+
+		return stateExitCode,
+			oneLineSummary(stateLabel, vmSnapshotSets, snapshotThresholds, vm, rps),
+			perfData(vmSnapshotSets, snapshotThresholds, vm, rps)
+	})
+}
+
+// usageObject generates the evaluated result for the single object evaluated
+// by a usage plugin (e.g., Datastore or HostSystem usage). The one-line
+// summary and details for the plugin are used as the summary of the object.
+func usageObject(name string, critical bool, warning bool, pd []perfdata.PerformanceData) output.Object {
+
+	_, stateExitCode := thresholdState(critical, warning)
+
+	return output.Object{
+		Name:     name,
+		State:    stateExitCode,
+		PerfData: pd,
+	}
+}
+
+// intersectVMs returns the VirtualMachines from the given collection which
+// are also present in the subset collection.
+func intersectVMs(vms []mo.VirtualMachine, subset []mo.VirtualMachine) []mo.VirtualMachine {
+
+	index := make(map[string]struct{}, len(subset))
+	for _, vm := range subset {
+		index[vm.Self.Value] = struct{}{}
+	}
+
+	matches := make([]mo.VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if _, ok := index[vm.Self.Value]; ok {
+			matches = append(matches, vm)
+		}
+	}
+
+	return matches
+}
+
+// thresholdState returns the Nagios state label and exit code for an
+// evaluated object based on whether it is considered to be in a CRITICAL or
+// WARNING state.
+func thresholdState(critical bool, warning bool) (string, int) {
+	switch {
+	case critical:
+		return nagios.StateCRITICALLabel, nagios.StateCRITICALExitCode
+	case warning:
+		return nagios.StateWARNINGLabel, nagios.StateWARNINGExitCode
+	default:
+		return nagios.StateOKLabel, nagios.StateOKExitCode
+	}
+}
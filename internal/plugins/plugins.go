@@ -0,0 +1,204 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"strings"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// binaryPrefix is the common prefix for the names of the standalone plugin
+// binaries provided by this project.
+const binaryPrefix string = "check_vmware_"
+
+// Plugin describes a plugin provided by this project.
+type Plugin struct {
+
+	// Name is the plugin type label (e.g., snapshots-age). This value is used
+	// as the subcommand name for the multi-call binary.
+	Name string
+
+	// Binary is the name of the standalone binary for the plugin (e.g.,
+	// check_vmware_snapshots_age). The multi-call binary also executes the
+	// plugin if invoked using this name (e.g., via symlink).
+	Binary string
+
+	// Type is the plugin type used to select the configuration flags and
+	// settings applicable to the plugin.
+	Type config.PluginType
+
+	// Run executes the plugin. This function does not return; the
+	// application exits with the final plugin state.
+	Run func()
+
+	// Check evaluates the plugin using an established vSphere session. This
+	// allows multiple plugins to be evaluated using a single session (e.g.,
+	// batch mode).
+	Check CheckFunc
+}
+
+// All returns the plugins provided by this project in the order they are
+// listed in usage output.
+func All() []Plugin {
+	return []Plugin{
+		{
+			Name:   config.PluginTypeTools,
+			Binary: binaryPrefix + "tools",
+			Type:   config.PluginType{Tools: true},
+			Run:    Tools,
+			Check:  checkTools,
+		},
+		{
+			Name:   config.PluginTypeVirtualCPUsAllocation,
+			Binary: binaryPrefix + "vcpus",
+			Type:   config.PluginType{VirtualCPUsAllocation: true},
+			Run:    VirtualCPUsAllocation,
+			Check:  checkVirtualCPUsAllocation,
+		},
+		{
+			Name:   config.PluginTypeVirtualHardwareVersion,
+			Binary: binaryPrefix + "vhw",
+			Type:   config.PluginType{VirtualHardwareVersion: true},
+			Run:    VirtualHardwareVersion,
+			Check:  checkVirtualHardwareVersion,
+		},
+		{
+			Name:   config.PluginTypeHostDatastoreVMsPairings,
+			Binary: binaryPrefix + "hs2ds2vms",
+			Type:   config.PluginType{Host2Datastores2VMs: true},
+			Run:    Host2Datastores2VMs,
+			Check:  checkHost2Datastores2VMs,
+		},
+		{
+			Name:   config.PluginTypeDatastoresSize,
+			Binary: binaryPrefix + "datastore",
+			Type:   config.PluginType{DatastoresSize: true},
+			Run:    DatastoresSize,
+			Check:  checkDatastoresSize,
+		},
+		{
+			Name:   config.PluginTypeSnapshotsAge,
+			Binary: binaryPrefix + "snapshots_age",
+			Type:   config.PluginType{SnapshotsAge: true},
+			Run:    SnapshotsAge,
+			Check:  checkSnapshotsAge,
+		},
+		{
+			Name:   config.PluginTypeSnapshotsCount,
+			Binary: binaryPrefix + "snapshots_count",
+			Type:   config.PluginType{SnapshotsCount: true},
+			Run:    SnapshotsCount,
+			Check:  checkSnapshotsCount,
+		},
+		{
+			Name:   config.PluginTypeSnapshotsSize,
+			Binary: binaryPrefix + "snapshots_size",
+			Type:   config.PluginType{SnapshotsSize: true},
+			Run:    SnapshotsSize,
+			Check:  checkSnapshotsSize,
+		},
+		{
+			Name:   config.PluginTypeResourcePoolsMemory,
+			Binary: binaryPrefix + "rps_memory",
+			Type:   config.PluginType{ResourcePoolsMemory: true},
+			Run:    ResourcePoolsMemory,
+			Check:  checkResourcePoolsMemory,
+		},
+		{
+			Name:   config.PluginTypeHostSystemMemory,
+			Binary: binaryPrefix + "host_memory",
+			Type:   config.PluginType{HostSystemMemory: true},
+			Run:    HostSystemMemory,
+			Check:  checkHostSystemMemory,
+		},
+		{
+			Name:   config.PluginTypeHostSystemCPU,
+			Binary: binaryPrefix + "host_cpu",
+			Type:   config.PluginType{HostSystemCPU: true},
+			Run:    HostSystemCPU,
+			Check:  checkHostSystemCPU,
+		},
+		{
+			Name:   config.PluginTypeHostSystemStatus,
+			Binary: binaryPrefix + "host_status",
+			Type:   config.PluginType{HostSystemStatus: true},
+			Run:    HostSystemStatus,
+			Check:  checkHostSystemStatus,
+		},
+		{
+			Name:   config.PluginTypeVirtualMachinePowerCycleUptime,
+			Binary: binaryPrefix + "vm_power_uptime",
+			Type:   config.PluginType{VirtualMachinePowerCycleUptime: true},
+			Run:    VirtualMachinePowerCycleUptime,
+			Check:  checkVirtualMachinePowerCycleUptime,
+		},
+		{
+			Name:   config.PluginTypeDiskConsolidation,
+			Binary: binaryPrefix + "disk_consolidation",
+			Type:   config.PluginType{DiskConsolidation: true},
+			Run:    DiskConsolidation,
+			Check:  checkDiskConsolidation,
+		},
+		{
+			Name:   config.PluginTypeInteractiveQuestion,
+			Binary: binaryPrefix + "question",
+			Type:   config.PluginType{InteractiveQuestion: true},
+			Run:    InteractiveQuestion,
+			Check:  checkInteractiveQuestion,
+		},
+		{
+			Name:   config.PluginTypeAlarms,
+			Binary: binaryPrefix + "alarms",
+			Type:   config.PluginType{Alarms: true},
+			Run:    Alarms,
+			Check:  checkAlarms,
+		},
+	}
+}
+
+// Lookup returns the plugin matching the given plugin type label or
+// standalone binary name. A Windows executable extension is ignored when
+// matching binary names. The second return value indicates whether a match
+// was found.
+func Lookup(name string) (Plugin, bool) {
+
+	name = strings.TrimSuffix(strings.ToLower(name), ".exe")
+
+	for _, plugin := range All() {
+		if name == plugin.Name || name == plugin.Binary {
+			return plugin, true
+		}
+	}
+
+	return Plugin{}, false
+}
+
+// footer returns the summary of the inventory scope and VirtualMachine
+// filtering options shared by plugins evaluating VirtualMachines. This
+// summary is appended to the report generated by each plugin for use with
+// the Long Service Output field.
+func footer(cfg *config.Config, vmsExcludedByCA []mo.VirtualMachine) string {
+	return vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) +
+		vmFiltersReport(cfg, vmsExcludedByCA)
+}
+
+// vmFiltersReport returns the summary of the VirtualMachine filtering
+// options, including the VirtualMachines excluded by Custom Attribute
+// filtering and any exclusions loaded from an ignore file. Plugins which do
+// not limit evaluation to specific Datacenters or clusters use this summary
+// in place of the full footer.
+func vmFiltersReport(cfg *config.Config, vmsExcludedByCA []mo.VirtualMachine) string {
+	return vsphere.HostSystemsReport(cfg.HostSystemNames) +
+		vsphere.FoldersReport(cfg.IncludedFolders, cfg.ExcludedFolders, cfg.RecursiveFolders) +
+		vsphere.TagsReport(cfg.IncludedTags, cfg.ExcludedTags) +
+		vsphere.CustomAttributesReport(cfg.IncludedCustomAttributes, cfg.ExcludedCustomAttributes, vmsExcludedByCA) +
+		vsphere.IgnoreFileReport(cfg.IgnoreFile, cfg.IgnoreEntries)
+}
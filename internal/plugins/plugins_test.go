@@ -0,0 +1,57 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import "testing"
+
+func TestLookup(t *testing.T) {
+
+	tests := map[string]string{
+		"snapshots-age":              "snapshots-age",
+		"check_vmware_snapshots_age": "snapshots-age",
+		"check_vmware_datastore.exe": "datastore-size",
+		"CHECK_VMWARE_ALARMS":        "alarms",
+	}
+
+	for name, want := range tests {
+		plugin, ok := Lookup(name)
+		if !ok {
+			t.Errorf("Lookup(%q) did not find plugin", name)
+			continue
+		}
+
+		if plugin.Name != want {
+			t.Errorf("Lookup(%q) = %q; want %q", name, plugin.Name, want)
+		}
+	}
+
+	if _, ok := Lookup("check_vmware"); ok {
+		t.Error("Lookup(\"check_vmware\") unexpectedly found plugin")
+	}
+}
+
+func TestAllUnique(t *testing.T) {
+
+	seen := make(map[string]bool)
+	for _, plugin := range All() {
+		for _, name := range []string{plugin.Name, plugin.Binary} {
+			if seen[name] {
+				t.Errorf("duplicate plugin name %q", name)
+			}
+			seen[name] = true
+		}
+
+		if plugin.Run == nil {
+			t.Errorf("plugin %q has no Run function", plugin.Name)
+		}
+
+		if plugin.Check == nil {
+			t.Errorf("plugin %q has no Check function", plugin.Name)
+		}
+	}
+}
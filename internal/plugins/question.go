@@ -0,0 +1,182 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// InteractiveQuestion executes the plugin used to monitor VirtualMachine
+// interactive question status. This function does not return; the application
+// exits with the final plugin state.
+func InteractiveQuestion() {
+	run(config.PluginType{InteractiveQuestion: true}, checkInteractiveQuestion)
+}
+
+// checkInteractiveQuestion evaluates VirtualMachine interactive question
+// status using an established vSphere session and records the results in the
+// provided plugin output.
+func checkInteractiveQuestion(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = "One or more Virtual Machines blocked by an interactive question"
+
+	nagiosExitState.WarningThreshold = config.ThresholdNotUsed
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	// NOTE: This plugin is used to detect Virtual Machines which are
+	// blocked from execution due to an interactive question.
+	//
+	// At this stage you could argue that they are neither "on" nor "off", but
+	// instead are in an in-between state, though it is likely that vSphere
+	// would considered them to be in an "off" state, transitioning to an "on"
+	// state. Either way, we report here that both powered on and powered off
+	// VMs are evaluated for simplicity.
+	//
+	// log.Debug().Msg("Filter VMs to specified power state")
+	// filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	// here we diverge from other plugins
+
+	log.Debug().Msg("Evaluating interactive question status")
+	vmsWaitingOnInput := make([]mo.VirtualMachine, 0, len(filteredVMs))
+	for _, vm := range filteredVMs {
+		if vm.Summary.Runtime.Question != nil {
+			vmsWaitingOnInput = append(vmsWaitingOnInput, vm)
+		}
+	}
+
+	plugin.PerfData = vsphere.VMInteractiveQuestionPerfData(filteredVMs, vmsWaitingOnInput, resourcePools)
+	plugin.Data = vsphere.NewVMInteractiveQuestionData(filteredVMs, vmsWaitingOnInput, resourcePools)
+	plugin.Objects = vmObjects(filteredVMs, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmWaitingOnInput := intersectVMs(vm, vmsWaitingOnInput)
+
+		stateLabel, stateExitCode := thresholdState(len(vmWaitingOnInput) > 0, false)
+
+		return stateExitCode,
+			vsphere.VMInteractiveQuestionOneLineCheckSummary(stateLabel, vm, vmWaitingOnInput, resourcePools),
+			vsphere.VMInteractiveQuestionPerfData(vm, vmWaitingOnInput, resourcePools)
+	})
+
+	switch {
+	case len(vmsWaitingOnInput) > 0:
+
+		// *ANY* VMs requiring interactive feedback results in a CRITICAL state.
+
+		vmsList := strings.Join(vsphere.VMNames(vmsWaitingOnInput), ", ")
+
+		log.Error().
+			Int("needing_feedback", len(vmsWaitingOnInput)).
+			Str("virtual_machines", vmsList).
+			Msg("Virtual Machines found blocked by interactive question")
+
+		nagiosExitState.LastError = vsphere.ErrVirtualMachineDiskConsolidationNeeded
+
+		nagiosExitState.ServiceOutput = vsphere.VMInteractiveQuestionOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			filteredVMs,
+			vmsWaitingOnInput,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMInteractiveQuestionReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			vmsWaitingOnInput,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	default:
+
+		// success path
+
+		log.Debug().Msg("No Virtual Machines blocked by interactive questions")
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.VMInteractiveQuestionOneLineCheckSummary(
+			nagios.StateOKLabel,
+			filteredVMs,
+			vmsWaitingOnInput,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMInteractiveQuestionReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			vmsWaitingOnInput,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+	}
+
+}
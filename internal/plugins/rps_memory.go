@@ -0,0 +1,455 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/units"
+	"github.com/vmware/govmomi/vim25"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// ResourcePoolsMemory executes the plugin used to monitor Resource Pools
+// memory usage. This function does not return; the application exits with the
+// final plugin state.
+func ResourcePoolsMemory() {
+	runServers(config.PluginType{ResourcePoolsMemory: true}, checkServersResourcePoolsMemory)
+}
+
+// checkResourcePoolsMemory evaluates Resource Pools memory usage using an
+// established vSphere session and records the results in the provided plugin
+// output.
+func checkResourcePoolsMemory(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+	sessions := []vsphere.ServerSession{{Server: cfg.Server(), Client: c}}
+	checkServersResourcePoolsMemory(ctx, sessions, cfg, plugin)
+}
+
+// checkServersResourcePoolsMemory evaluates Resource Pools memory usage using
+// established vSphere sessions with one or more servers and records the
+// combined results in the provided plugin output. The memory usage of
+// Resource Pools on all servers is evaluated against the maximum allowed
+// memory usage and the memory capacity of hosts on all servers.
+func checkServersResourcePoolsMemory(ctx context.Context, sessions []vsphere.ServerSession, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s%% usage of %d GB memory",
+		cfg.ResourcePoolsMemoryUseCritical,
+		cfg.ResourcePoolsMemoryMaxAllowed,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s%% usage of %d GB memory",
+		cfg.ResourcePoolsMemoryUseWarning,
+		cfg.ResourcePoolsMemoryMaxAllowed,
+	)
+
+	// Explicitly ignore the default `Resources` resource pool so that we only
+	// use the Resource Pools specified by the sysadmin.
+	if err := cfg.ExcludedResourcePools.Set(vsphere.ParentResourcePool); err != nil {
+		cfg.Log.Error().Err(err).Msg("Error excluding default Resources Pool from evaluation")
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error excluding default Resources Pool from evaluation",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.LastError = err
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Int("max_memory_usage_allowed", cfg.ResourcePoolsMemoryMaxAllowed).
+		Str("memory_usage_critical", cfg.ResourcePoolsMemoryUseCritical.String()).
+		Str("memory_usage_warning", cfg.ResourcePoolsMemoryUseWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists. First,
+	// we'll make sure that all specified resource pools actually exist in the
+	// vSphere environment.
+
+	log.Debug().
+		Int("datacenters_specified", len(cfg.DatacenterNames)).
+		Msg("Validating datacenter names")
+	validateDCsErr := vsphere.ValidateServersDCs(ctx, sessions, cfg.DatacenterNames)
+	if validateDCsErr != nil {
+		log.Error().Err(validateDCsErr).Msg("error validating datacenter names")
+
+		nagiosExitState.LastError = validateDCsErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating requested datacenter names",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().
+		Str("cluster_name", cfg.ClusterName).
+		Msg("Validating cluster name")
+	validateClusterErr := vsphere.ValidateServersCluster(ctx, sessions, cfg.ClusterName, cfg.DatacenterNames)
+	if validateClusterErr != nil {
+		log.Error().Err(validateClusterErr).Msg("error validating cluster name")
+
+		nagiosExitState.LastError = validateClusterErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating requested cluster name",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Validating resource pools")
+	validateErr := vsphere.ValidateServersRPs(ctx, sessions, cfg.IncludedResourcePools, cfg.ExcludedResourcePools, cfg.DatacenterNames)
+	if validateErr != nil {
+		log.Error().Err(validateErr).Msg("error validating include/exclude lists")
+
+		nagiosExitState.LastError = validateErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error validating include/exclude lists",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().Msg("Retrieving eligible resource pools, hosts and vms")
+	results := vsphere.GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (vsphere.ServerObjects, error) {
+		// Limit retrieval of inventory objects to the specified datacenters
+		// (if any) present on this server.
+		containers, containersErr := vsphere.DatacenterContainers(ctx, c, cfg.DatacenterNames)
+		switch {
+		// none of the specified Datacenters are present on this server
+		case errors.Is(containersErr, vsphere.ErrDatacenterNotFound):
+			return vsphere.ServerObjects{}, nil
+		case containersErr != nil:
+			return vsphere.ServerObjects{}, fmt.Errorf(
+				"error retrieving requested datacenters: %w",
+				containersErr,
+			)
+		}
+
+		// Further limit retrieval to the specified cluster (if any); servers
+		// without the cluster do not contribute any objects.
+		containers, containersErr = vsphere.ClusterContainers(ctx, c, cfg.ClusterName, containers...)
+		switch {
+		case errors.Is(containersErr, vsphere.ErrClusterNotFound):
+			return vsphere.ServerObjects{}, nil
+
+		case containersErr != nil:
+			return vsphere.ServerObjects{}, fmt.Errorf(
+				"error retrieving requested cluster: %w",
+				containersErr,
+			)
+		}
+
+		resourcePools, getRPsErr := vsphere.GetEligibleRPs(
+			ctx,
+			c,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			cfg.RecursiveResourcePools,
+			true,
+			containers...,
+		)
+		if getRPsErr != nil {
+			return vsphere.ServerObjects{}, fmt.Errorf(
+				"error retrieving list of resource pools: %w",
+				getRPsErr,
+			)
+		}
+
+		hosts, getHostsErr := vsphere.GetHostSystems(ctx, c, true, containers...)
+		if getHostsErr != nil {
+			return vsphere.ServerObjects{}, fmt.Errorf(
+				"error retrieving hosts memory capacity: %w",
+				getHostsErr,
+			)
+		}
+
+		vms, getVMsErr := vsphere.GetVMsFromRPs(ctx, c, true, resourcePools...)
+		if getVMsErr != nil {
+			return vsphere.ServerObjects{}, fmt.Errorf(
+				"error retrieving list of VMs from resource pools list: %w",
+				getVMsErr,
+			)
+		}
+
+		return vsphere.ServerObjects{
+			VMs:           vms,
+			ResourcePools: resourcePools,
+			HostSystems:   hosts,
+		}, nil
+	})
+
+	serversErr := vsphere.ServersError(sessions)
+	if len(results) == 0 {
+		log.Error().Err(serversErr).Msg(
+			"error retrieving list of resource pools, hosts and VMs",
+		)
+
+		nagiosExitState.LastError = serversErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving list of resource pools, hosts and VMs from %s",
+			nagios.StateCRITICALLabel,
+			vsphere.ServerNames(sessions),
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Use the first server evaluated when reporting details of the vSphere
+	// environment. Details for each server are also reported if multiple
+	// servers are evaluated.
+	client := vsphere.FirstEvaluatedClient(sessions)
+
+	combined := vsphere.CombineServerObjects(results, cfg.MultipleServers())
+	resourcePools := combined.ResourcePools
+	vms := combined.VMs
+
+	rpNames := make([]string, 0, len(resourcePools))
+	for _, rp := range resourcePools {
+		rpNames = append(rpNames, rp.Name)
+	}
+
+	log.Debug().
+		Str("resource_pools", strings.Join(rpNames, ", ")).
+		Msg("")
+
+	// The memory usage of a Resource Pool includes the memory usage of its
+	// child Resource Pools. When evaluating child Resource Pools, only the
+	// memory usage of the topmost evaluated Resource Pools is aggregated.
+	rpsIdx := make(map[string]struct{}, len(resourcePools))
+	for _, rp := range resourcePools {
+		rpsIdx[rp.Self.Value] = struct{}{}
+	}
+
+	var aggregateMemoryUsage int64
+	for _, rp := range resourcePools {
+		// Per vSphere API docs, `rp.Runtime.Memory.OverallUsage` was
+		// deprecated in v6.5, so we use `hostMemoryUsage` instead.
+		rpMemoryUsage := rp.Summary.GetResourcePoolSummary().QuickStats.HostMemoryUsage * units.MB
+		log.Debug().
+			Str("resource_pool_name", rp.Name).
+			Str("resource_pool_memory_usage", units.ByteSize(rpMemoryUsage).String()).
+			Msg("")
+
+		if cfg.RecursiveResourcePools && rp.Parent != nil {
+			if _, ok := rpsIdx[rp.Parent.Value]; ok {
+				continue
+			}
+		}
+
+		aggregateMemoryUsage += rpMemoryUsage
+	}
+
+	clusterMemory := vsphere.HostSystemsTotalMemory(combined.HostSystems, false)
+
+	clusterMemoryInGB := clusterMemory / units.GB
+	memoryPercentageUsedOfClusterCapacity := vsphere.MemoryUsedPercentage(
+		aggregateMemoryUsage,
+		int(clusterMemoryInGB),
+	)
+
+	log.Debug().
+		Int64("cluster_memory_bytes", clusterMemory).
+		Int64("cluster_memory_gb", clusterMemoryInGB).
+		Str("cluster_memory_hr", units.ByteSize(clusterMemory).String()).
+		Float64("percent_memory_used_from_cluster_raw", memoryPercentageUsedOfClusterCapacity).
+		Str("percent_memory_used_from_cluster_hr", fmt.Sprintf("%0.2f", memoryPercentageUsedOfClusterCapacity)).
+		Msg("")
+
+	log.Debug().
+		Int64("aggregate_memory_usage_raw", aggregateMemoryUsage).
+		Str("aggregate_memory_usage_human_readable", units.ByteSize(aggregateMemoryUsage).String()).
+		Msg("Finished evaluating Resource Pool memory usage")
+
+	memoryPercentageUsedOfAllowed := vsphere.MemoryUsedPercentage(aggregateMemoryUsage, cfg.ResourcePoolsMemoryMaxAllowed)
+	var memoryRemaining int64
+
+	switch {
+	case aggregateMemoryUsage > int64(cfg.ResourcePoolsMemoryMaxAllowed):
+		memoryRemaining = 0
+	default:
+		memoryRemaining = int64(cfg.ResourcePoolsMemoryMaxAllowed) - aggregateMemoryUsage
+	}
+
+	log.Debug().
+		Float64("memory_percent_used", memoryPercentageUsedOfAllowed).
+		Int64("memory_remaining", memoryRemaining).
+		Msg("")
+
+	plugin.PerfData = vsphere.RPMemoryUsagePerfData(
+		aggregateMemoryUsage,
+		cfg.ResourcePoolsMemoryMaxAllowed,
+		clusterMemoryInGB,
+		cfg.ResourcePoolsMemoryUseWarning,
+		cfg.ResourcePoolsMemoryUseCritical,
+		resourcePools,
+	)
+	plugin.Data = vsphere.NewResourcePoolsMemoryUsageData(
+		aggregateMemoryUsage,
+		cfg.ResourcePoolsMemoryMaxAllowed,
+		clusterMemoryInGB,
+		cfg.ResourcePoolsMemoryUseWarning,
+		cfg.ResourcePoolsMemoryUseCritical,
+		resourcePools,
+	)
+
+	switch {
+	case serversErr != nil:
+
+		log.Error().
+			Err(serversErr).
+			Float64("memory_percent_used", memoryPercentageUsedOfAllowed).
+			Int64("memory_remaining", memoryRemaining).
+			Msg("one or more servers could not be evaluated")
+
+		nagiosExitState.LastError = serversErr
+
+		nagiosExitState.ServiceOutput = vsphere.RPMemoryUsageOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			resourcePools,
+		) + vsphere.FailedServersSummary(sessions)
+
+		nagiosExitState.LongServiceOutput = vsphere.ResourcePoolsMemoryReport(
+			client,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			vms,
+		) + vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case cfg.ResourcePoolsMemoryUseCritical.Alert(memoryPercentageUsedOfAllowed):
+
+		log.Error().
+			Float64("memory_percent_used", memoryPercentageUsedOfAllowed).
+			Int64("memory_remaining", memoryRemaining).
+			Msg("memory usage critical")
+
+		nagiosExitState.LastError = vsphere.ErrResourcePoolMemoryUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.RPMemoryUsageOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.ResourcePoolsMemoryReport(
+			client,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			vms,
+		) + vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case cfg.ResourcePoolsMemoryUseWarning.Alert(memoryPercentageUsedOfAllowed):
+
+		log.Error().
+			Float64("memory_percent_used", memoryPercentageUsedOfAllowed).
+			Int64("memory_remaining", memoryRemaining).
+			Msg("memory usage warning")
+
+		nagiosExitState.LastError = vsphere.ErrResourcePoolMemoryUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.RPMemoryUsageOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.ResourcePoolsMemoryReport(
+			client,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			vms,
+		) + vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.RPMemoryUsageOneLineCheckSummary(
+			nagios.StateOKLabel,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.ResourcePoolsMemoryReport(
+			client,
+			aggregateMemoryUsage,
+			cfg.ResourcePoolsMemoryMaxAllowed,
+			clusterMemoryInGB,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+			vms,
+		) + vsphere.InventoryScopeReport(cfg.DatacenterNames, cfg.ClusterName) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
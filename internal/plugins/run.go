@@ -0,0 +1,195 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+
+	zlog "github.com/rs/zerolog/log"
+)
+
+// CheckFunc evaluates a plugin's checks using an established vSphere session
+// and records the results in the provided plugin output. Results are not
+// emitted by this function, allowing the caller to either return them as
+// standard plugin output or submit them elsewhere (e.g., as passive check
+// results).
+type CheckFunc func(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin)
+
+// ServersCheckFunc evaluates a plugin's checks using established vSphere
+// sessions with one or more servers and records the combined results in the
+// provided plugin output. Sessions for servers which could not be logged into
+// record the login error; at least one session is established.
+type ServersCheckFunc func(ctx context.Context, sessions []vsphere.ServerSession, cfg *config.Config, plugin *output.Plugin)
+
+// run executes a standalone plugin of the specified type. Configuration is
+// parsed from command-line flags, a vSphere session is created for the
+// duration of the check and the results are emitted in the requested output
+// format. This function does not return; the application exits with the
+// final plugin state.
+func run(pluginType config.PluginType, check CheckFunc) {
+	runServers(pluginType, func(ctx context.Context, sessions []vsphere.ServerSession, cfg *config.Config, plugin *output.Plugin) {
+		// configuration validation asserts that plugins which do not support
+		// multiple servers are provided exactly one server
+		check(ctx, sessions[0].Client, cfg, plugin)
+	})
+}
+
+// runServers executes a standalone plugin of the specified type which
+// supports evaluating multiple servers. A vSphere session is created with
+// each server concurrently for the duration of the check. This function does
+// not return; the application exits with the final plugin state.
+func runServers(pluginType config.PluginType, check ServersCheckFunc) {
+
+	// Set initial "state" as valid, adjust as we go.
+	var nagiosExitState = nagios.ExitState{
+		LastError:      nil,
+		ExitStatusCode: nagios.StateOKExitCode,
+	}
+
+	// Collect performance data and evaluated data as we go; results are
+	// emitted in the requested output format just before the plugin exits.
+	plugin := output.New(&nagiosExitState)
+
+	// defer this from the start so it is the last deferred function to run
+	defer plugin.ReturnCheckResults()
+
+	// Disable library debug logging output by default
+	// vsphere.EnableLogging()
+	vsphere.DisableLogging()
+
+	// Setup configuration by parsing user-provided flags. Note plugin type so
+	// that only applicable CLI flags are exposed and any plugin-specific
+	// settings are applied.
+	cfg, cfgErr := config.New(pluginType)
+	switch {
+	case errors.Is(cfgErr, config.ErrVersionRequested):
+		fmt.Println(config.Version())
+
+		return
+
+	case cfgErr != nil:
+		// We're using the standalone Err function from rs/zerolog/log as we
+		// do not have a working configuration.
+		zlog.Err(cfgErr).Msg("Error initializing application")
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error initializing application",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.LastError = cfgErr
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	// Record details used when emitting results in the requested format.
+	plugin.App = cfg.App
+	plugin.Format = cfg.OutputFormat
+	plugin.ServiceName = cfg.ServiceName
+
+	// Enable library-level logging if debug logging level is enabled app-wide
+	if cfg.LoggingLevel == config.LogLevelDebug {
+		vsphere.EnableLogging()
+	}
+
+	ctx, cancel := context.WithTimeout(context.Background(), cfg.Timeout())
+	defer cancel()
+
+	if cfg.EmitBranding {
+		// If enabled, show application details at end of notification
+		nagiosExitState.BrandingCallback = config.Branding("Notification generated by ")
+	}
+
+	log := cfg.Log
+
+	loginCtx, loginCancel := context.WithTimeout(ctx, cfg.ConnectTimeout())
+	defer loginCancel()
+
+	log.Debug().Msg("Logging into vSphere environment")
+	sessions := loginServers(loginCtx, cfg)
+
+	defer func() {
+		// cached sessions are left active for reuse by later executions
+		if cfg.SessionCache {
+			log.Debug().Msg("Session caching enabled, skipping logout")
+			return
+		}
+
+		for _, session := range sessions {
+			if session.Client == nil {
+				continue
+			}
+
+			if err := session.Client.Logout(ctx); err != nil {
+				log.Error().
+					Err(err).
+					Str("server", session.Server).
+					Msg("failed to logout")
+			}
+		}
+	}()
+
+	for _, session := range sessions {
+		if session.Err != nil {
+			log.Error().Err(session.Err).Msgf("error logging into %s", session.Server)
+		}
+	}
+
+	if vsphere.FirstEvaluatedClient(sessions) == nil {
+		nagiosExitState.LastError = vsphere.ServersError(sessions)
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error logging into %s",
+			nagios.StateCRITICALLabel,
+			vsphere.ServerNames(sessions),
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+	log.Debug().Msg("Successfully logged into vSphere environment")
+
+	check(ctx, sessions, cfg, plugin)
+}
+
+// loginServers logs into each of the user-specified servers concurrently. A
+// session is returned for each server in the order specified; the sessions
+// for servers which could not be logged into record the login error.
+func loginServers(ctx context.Context, cfg *config.Config) []vsphere.ServerSession {
+
+	sessions := make([]vsphere.ServerSession, len(cfg.Servers))
+
+	var wg sync.WaitGroup
+	for i := range cfg.Servers {
+		sessions[i].Server = cfg.Servers[i]
+
+		wg.Add(1)
+		go func(session *vsphere.ServerSession) {
+			defer wg.Done()
+
+			session.Client, session.Err = vsphere.Login(
+				ctx, session.Server, cfg.Port, cfg.TrustCert,
+				cfg.CAFile, cfg.Thumbprint, cfg.Proxy,
+				cfg.Username, cfg.Domain, cfg.Password,
+				cfg.UserAgent(), cfg.SessionCacheDir(),
+				cfg.Retries, cfg.RetryDelay(), cfg.RetrieveTimeout(),
+			)
+		}(&sessions[i])
+	}
+	wg.Wait()
+
+	return sessions
+}
@@ -0,0 +1,357 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"strings"
+	"sync"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// vmScope is the collection of VirtualMachines (and the inventory objects
+// used to retrieve them) evaluated by a plugin after applying the
+// user-specified datacenter, cluster, host, resource pool, folder, tag, name
+// and custom attribute filters.
+type vmScope struct {
+	// DatacenterContainers are references to the specified Datacenters (or
+	// the root folder if none were specified).
+	DatacenterContainers []types.ManagedObjectReference
+
+	// Containers are references to the specified cluster (if any) or to the
+	// specified Datacenters.
+	Containers []types.ManagedObjectReference
+
+	// ResourcePools are the eligible Resource Pools.
+	ResourcePools []mo.ResourcePool
+
+	// VMs are the VirtualMachines in the eligible Resource Pools after
+	// filtering by folder and tag.
+	VMs []mo.VirtualMachine
+
+	// FilteredVMs are the VirtualMachines remaining after also excluding
+	// ignored VMs and filtering by custom attribute and host. VMs are not
+	// filtered by power state; plugins which evaluate only powered on VMs
+	// apply that filter themselves.
+	FilteredVMs []mo.VirtualMachine
+
+	// VMsExcludedByCA are the VirtualMachines excluded by the custom
+	// attribute filters.
+	VMsExcludedByCA []mo.VirtualMachine
+}
+
+// vmsInScope validates the user-specified inventory filters and retrieves
+// the VirtualMachines to evaluate from the specified server. If an error
+// occurs the provided Nagios exit state is updated to reflect the failure
+// and the error is returned.
+//
+// When multiple servers are evaluated the specified names are validated
+// across all servers by serversVMsInScope. A server which does not have any
+// of the specified Datacenters, the specified cluster or any of the
+// specified hosts does not contribute any VirtualMachines.
+func vmsInScope(ctx context.Context, c *vim25.Client, cfg *config.Config, nagiosExitState *nagios.ExitState) (vmScope, error) {
+
+	log := cfg.Log.With().Str("server", c.URL().Hostname()).Logger()
+
+	fail := func(err error, logMsg string, serviceOutput string) (vmScope, error) {
+		log.Error().Err(err).Msg(logMsg)
+
+		nagiosExitState.LastError = err
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: %s",
+			nagios.StateCRITICALLabel,
+			serviceOutput,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return vmScope{}, err
+	}
+
+	multipleServers := cfg.MultipleServers()
+
+	var scope vmScope
+
+	if !multipleServers {
+		log.Debug().
+			Int("datacenters_specified", len(cfg.DatacenterNames)).
+			Msg("Validating datacenter names")
+		if err := vsphere.ValidateDCs(ctx, c, cfg.DatacenterNames); err != nil {
+			return fail(err, "error validating datacenter names", "Error validating requested datacenter names")
+		}
+	}
+
+	// Limit retrieval of inventory objects to the specified datacenters (if
+	// any).
+	containers, containersErr := vsphere.DatacenterContainers(ctx, c, cfg.DatacenterNames)
+	switch {
+	// none of the specified Datacenters are present on this server
+	case multipleServers && errors.Is(containersErr, vsphere.ErrDatacenterNotFound):
+		return scope, nil
+	case containersErr != nil:
+		return fail(containersErr, "error retrieving datacenters", "Error retrieving requested datacenters")
+	}
+	scope.DatacenterContainers = containers
+
+	var hostSystems []mo.HostSystem
+	if !multipleServers {
+		log.Debug().Msg("Validating folders")
+		if err := vsphere.ValidateFolders(ctx, c, cfg.IncludedFolders, cfg.ExcludedFolders, containers...); err != nil {
+			return fail(err, "error validating include/exclude folder lists", "Error validating include/exclude folder lists")
+		}
+
+		log.Debug().Msg("Validating tags")
+		if err := vsphere.ValidateTags(ctx, c, cfg.IncludedTags, cfg.ExcludedTags); err != nil {
+			return fail(err, "error validating include/exclude tag lists", "Error validating include/exclude tag lists")
+		}
+
+		log.Debug().Msg("Validating custom attributes")
+		if err := vsphere.ValidateCustomAttributes(ctx, c, cfg.IncludedCustomAttributes, cfg.ExcludedCustomAttributes); err != nil {
+			return fail(err, "error validating include/exclude custom attribute lists", "Error validating include/exclude custom attribute lists")
+		}
+
+		log.Debug().Msg("Validating hosts")
+		var getHostsErr error
+		hostSystems, getHostsErr = vsphere.GetHostSystemsByName(ctx, c, cfg.HostSystemNames, true, containers...)
+		if getHostsErr != nil {
+			return fail(getHostsErr, "error validating host names", "Error validating requested host names")
+		}
+	}
+
+	// Further limit retrieval of inventory objects to the specified cluster
+	// (if any).
+	containers, containersErr = vsphere.ClusterContainers(ctx, c, cfg.ClusterName, containers...)
+	switch {
+	// the specified cluster is not present on this server
+	case multipleServers && errors.Is(containersErr, vsphere.ErrClusterNotFound):
+		return vmScope{}, nil
+	case containersErr != nil:
+		return fail(containersErr, "error retrieving cluster", fmt.Sprintf("Error retrieving requested cluster %q", cfg.ClusterName))
+	}
+	scope.Containers = containers
+
+	if !multipleServers {
+		log.Debug().Msg("Validating resource pools")
+		if err := vsphere.ValidateRPs(ctx, c, cfg.IncludedResourcePools, cfg.ExcludedResourcePools, containers...); err != nil {
+			return fail(err, "error validating include/exclude lists", "Error validating include/exclude lists")
+		}
+	}
+
+	// Limit VMs to those running on the specified hosts (if any); servers
+	// without any of the hosts do not contribute any VMs.
+	if multipleServers && len(cfg.HostSystemNames) > 0 {
+		hosts, getHostsErr := vsphere.GetHostSystems(ctx, c, true, containers...)
+		if getHostsErr != nil {
+			return fail(getHostsErr, "error retrieving list of hosts", "Error retrieving list of hosts")
+		}
+
+		hostSystems = vsphere.FilterHostSystemsByName(hosts, cfg.HostSystemNames)
+		if len(hostSystems) == 0 {
+			return vmScope{}, nil
+		}
+	}
+
+	log.Debug().Msg("Retrieving eligible resource pools")
+	resourcePools, getRPsErr := vsphere.GetEligibleRPs(
+		ctx,
+		c,
+		cfg.IncludedResourcePools,
+		cfg.ExcludedResourcePools,
+		cfg.RecursiveResourcePools,
+		true,
+		containers...,
+	)
+	if getRPsErr != nil {
+		return fail(getRPsErr, "error retrieving list of resource pools", fmt.Sprintf("Error retrieving list of resource pools from %q", c.URL().Hostname()))
+	}
+	scope.ResourcePools = resourcePools
+
+	rpNames := make([]string, 0, len(resourcePools))
+	for _, rp := range resourcePools {
+		rpNames = append(rpNames, rp.Name)
+	}
+
+	log.Debug().
+		Str("resource_pools", strings.Join(rpNames, ", ")).
+		Msg("")
+
+	log.Debug().Msg("Retrieving vms from eligible resource pools")
+	vms, getVMsErr := vsphere.GetVMsFromRPs(ctx, c, true, resourcePools...)
+	if getVMsErr != nil {
+		return fail(getVMsErr, "error retrieving list of VMs from resource pools list", "Error retrieving list of VMs from resource pools list")
+	}
+
+	log.Debug().Msg("Filter VMs to specified folders")
+	vms, filterFoldersErr := vsphere.FilterVMsByFolder(ctx, c, vms, cfg.IncludedFolders, cfg.ExcludedFolders, cfg.RecursiveFolders)
+	if filterFoldersErr != nil {
+		return fail(filterFoldersErr, "error retrieving list of VM folders", "Error retrieving list of VM folders")
+	}
+
+	log.Debug().Msg("Filter VMs to specified tags")
+	vms, filterTagsErr := vsphere.FilterVMsByTag(ctx, c, vms, cfg.IncludedTags, cfg.ExcludedTags)
+	if filterTagsErr != nil {
+		return fail(filterTagsErr, "error retrieving vSphere tags", "Error retrieving vSphere tags")
+	}
+	scope.VMs = vms
+
+	log.Debug().Msg("Drop any VMs we've been asked to exclude from checks")
+	filteredVMs := vsphere.ExcludeVMsByName(vms, cfg.IgnoredVMs)
+
+	log.Debug().Msg("Filter VMs to specified custom attributes")
+	filteredVMs, vmsExcludedByCA, filterCAsErr := vsphere.FilterVMsByCustomAttribute(filteredVMs, cfg.IncludedCustomAttributes, cfg.ExcludedCustomAttributes)
+	if filterCAsErr != nil {
+		return fail(filterCAsErr, "error filtering VMs by custom attribute", "Error filtering VMs by custom attribute")
+	}
+	scope.VMsExcludedByCA = vmsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified hosts")
+	scope.FilteredVMs = vsphere.FilterVMsByHostSystems(filteredVMs, hostSystems)
+
+	return scope, nil
+}
+
+// serversVMsInScope is the equivalent of vmsInScope for plugins evaluating
+// multiple servers. The specified names are validated across all servers
+// before the VirtualMachines to evaluate are retrieved from each server using
+// vmsInScope. VirtualMachine and Resource Pool names from each server are
+// qualified with the server name if multiple servers are evaluated. The
+// returned scope does not provide containers. If an error occurs the provided
+// Nagios exit state is updated to reflect the failure and the error is
+// returned.
+func serversVMsInScope(ctx context.Context, sessions []vsphere.ServerSession, cfg *config.Config, nagiosExitState *nagios.ExitState) (vmScope, error) {
+
+	if !cfg.MultipleServers() {
+		return vmsInScope(ctx, sessions[0].Client.Client, cfg, nagiosExitState)
+	}
+
+	log := cfg.Log
+
+	fail := func(err error, logMsg string, serviceOutput string) (vmScope, error) {
+		log.Error().Err(err).Msg(logMsg)
+
+		nagiosExitState.LastError = err
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: %s",
+			nagios.StateCRITICALLabel,
+			serviceOutput,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return vmScope{}, err
+	}
+
+	log.Debug().
+		Int("datacenters_specified", len(cfg.DatacenterNames)).
+		Msg("Validating datacenter names")
+	if err := vsphere.ValidateServersDCs(ctx, sessions, cfg.DatacenterNames); err != nil {
+		return fail(err, "error validating datacenter names", "Error validating requested datacenter names")
+	}
+
+	log.Debug().
+		Str("cluster_name", cfg.ClusterName).
+		Msg("Validating cluster name")
+	if err := vsphere.ValidateServersCluster(ctx, sessions, cfg.ClusterName, cfg.DatacenterNames); err != nil {
+		return fail(err, "error validating cluster name", "Error validating requested cluster name")
+	}
+
+	log.Debug().Msg("Validating folders")
+	if err := vsphere.ValidateServersFolders(ctx, sessions, cfg.IncludedFolders, cfg.ExcludedFolders, cfg.DatacenterNames); err != nil {
+		return fail(err, "error validating include/exclude folder lists", "Error validating include/exclude folder lists")
+	}
+
+	log.Debug().Msg("Validating tags")
+	if err := vsphere.ValidateServersTags(ctx, sessions, cfg.IncludedTags, cfg.ExcludedTags); err != nil {
+		return fail(err, "error validating include/exclude tag lists", "Error validating include/exclude tag lists")
+	}
+
+	log.Debug().Msg("Validating custom attributes")
+	if err := vsphere.ValidateServersCustomAttributes(ctx, sessions, cfg.IncludedCustomAttributes, cfg.ExcludedCustomAttributes); err != nil {
+		return fail(err, "error validating include/exclude custom attribute lists", "Error validating include/exclude custom attribute lists")
+	}
+
+	log.Debug().Msg("Validating hosts")
+	if err := vsphere.ValidateServersHostSystems(ctx, sessions, cfg.HostSystemNames, cfg.DatacenterNames); err != nil {
+		return fail(err, "error validating host names", "Error validating requested host names")
+	}
+
+	log.Debug().Msg("Validating resource pools")
+	if err := vsphere.ValidateServersRPs(ctx, sessions, cfg.IncludedResourcePools, cfg.ExcludedResourcePools, cfg.DatacenterNames); err != nil {
+		return fail(err, "error validating include/exclude lists", "Error validating include/exclude lists")
+	}
+
+	// The full scope for each server is recorded here as GetServerObjects
+	// only collects the objects shared by all plugins.
+	var mu sync.Mutex
+	scopes := make(map[*vim25.Client]vmScope, len(sessions))
+
+	log.Debug().Msg("Retrieving eligible resource pools and vms")
+	vsphere.GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (vsphere.ServerObjects, error) {
+		var serverExitState nagios.ExitState
+		scope, err := vmsInScope(ctx, c, cfg, &serverExitState)
+		if err != nil {
+			return vsphere.ServerObjects{}, err
+		}
+
+		mu.Lock()
+		scopes[c] = scope
+		mu.Unlock()
+
+		return vsphere.ServerObjects{}, nil
+	})
+
+	var vms, filteredVMs, excludedByCA []vsphere.ServerObjects
+	for _, session := range sessions {
+		if session.Err != nil {
+			continue
+		}
+
+		scope := scopes[session.Client.Client]
+		vms = append(vms, vsphere.ServerObjects{
+			Server:        session.Server,
+			VMs:           scope.VMs,
+			ResourcePools: scope.ResourcePools,
+		})
+		filteredVMs = append(filteredVMs, vsphere.ServerObjects{
+			Server: session.Server,
+			VMs:    scope.FilteredVMs,
+		})
+		excludedByCA = append(excludedByCA, vsphere.ServerObjects{
+			Server: session.Server,
+			VMs:    scope.VMsExcludedByCA,
+		})
+	}
+
+	if len(vms) == 0 {
+		serversErr := vsphere.ServersError(sessions)
+
+		return fail(
+			serversErr,
+			"error retrieving list of resource pools and VMs",
+			fmt.Sprintf("Error retrieving list of resource pools and VMs from %s", vsphere.ServerNames(sessions)),
+		)
+	}
+
+	// VMs are filtered before combining results from each server so that
+	// VM names are matched before being qualified with the server name.
+	combined := vsphere.CombineServerObjects(vms, true)
+
+	return vmScope{
+		ResourcePools:   combined.ResourcePools,
+		VMs:             combined.VMs,
+		FilteredVMs:     vsphere.CombineServerObjects(filteredVMs, true).VMs,
+		VMsExcludedByCA: vsphere.CombineServerObjects(excludedByCA, true).VMs,
+	}, nil
+}
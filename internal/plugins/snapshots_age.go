@@ -0,0 +1,240 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// SnapshotsAge executes the plugin used to monitor snapshots age. This
+// function does not return; the application exits with the final plugin
+// state.
+func SnapshotsAge() {
+	run(config.PluginType{SnapshotsAge: true}, checkSnapshotsAge)
+}
+
+// checkSnapshotsAge evaluates snapshots age using an established vSphere
+// session and records the results in the provided plugin output.
+func checkSnapshotsAge(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s day old snapshots present",
+		cfg.SnapshotsAgeCritical,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s day old snapshots present",
+		cfg.SnapshotsAgeWarning,
+	)
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Str("snapshots_age_critical", cfg.SnapshotsAgeCritical.String()).
+		Str("snapshots_age_warning", cfg.SnapshotsAgeWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	// NOTE: This plugin is hard-coded to evaluate powered off and powered
+	// on VMs equally. I'm not sure whether ignoring powered off VMs by
+	// default makes sense for this particular plugin.
+	//
+	// Please share your feedback here if you feel differently:
+	// https://github.com/atc0005/check-vmware/discussions/177
+	//
+	// Please expand on some use cases for ignoring powered off VMs by default.
+	//
+	// log.Debug().Msg("Filter VMs to specified power state")
+	// filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	log.Debug().Msg("Filter VMs to those with snapshots")
+	vmsWithSnapshots := vsphere.FilterVMsWithSnapshots(filteredVMs)
+
+	log.Debug().Msg("Build snapshot sets for bulk processing")
+	snapshotSets := make(vsphere.SnapshotSummarySets, 0, len(vmsWithSnapshots))
+
+	snapshotThresholds := vsphere.SnapshotThresholds{
+		AgeCritical: cfg.SnapshotsAgeCritical,
+		AgeWarning:  cfg.SnapshotsAgeWarning,
+	}
+
+	for _, vm := range vmsWithSnapshots {
+
+		log.Debug().Str("vm", vm.Name).Msg("Evaluating snapshots for VM")
+
+		snapshotSets = append(
+			snapshotSets,
+			vsphere.NewSnapshotSummarySet(
+				vm,
+				snapshotThresholds,
+			),
+		)
+	}
+
+	plugin.PerfData = vsphere.SnapshotsAgePerfData(snapshotSets, snapshotThresholds, filteredVMs, resourcePools)
+	plugin.Data = vsphere.NewSnapshotsData(snapshotSets, snapshotThresholds, filteredVMs, resourcePools)
+	plugin.Objects = snapshotObjects(
+		filteredVMs,
+		snapshotSets,
+		snapshotThresholds,
+		resourcePools,
+		vsphere.SnapshotsAgeOneLineCheckSummary,
+		vsphere.SnapshotsAgePerfData,
+	)
+
+	switch {
+
+	case snapshotSets.IsAgeCriticalState():
+
+		vmsWithOldSnapshots, oldSnapshots := snapshotSets.ExceedsAge(cfg.SnapshotsAgeCritical)
+
+		log.Error().
+			Int("num_vms_with_critical_snapshots", vmsWithOldSnapshots).
+			Int("num_snapshots_age_critical", oldSnapshots).
+			Msg("Snapshot sets contain a snapshot which exceeds specified age in days")
+
+		nagiosExitState.LastError = vsphere.ErrSnapshotAgeThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsAgeOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsAgeReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case snapshotSets.IsAgeWarningState():
+
+		vmsWithOldSnapshots, oldSnapshots := snapshotSets.ExceedsAge(cfg.SnapshotsAgeWarning)
+
+		log.Error().
+			Int("num_vms_with_warning_snapshots", vmsWithOldSnapshots).
+			Int("num_snapshots_age_warning", oldSnapshots).
+			Msg("Snapshot sets contain one or more snapshots which exceed specified age in days")
+
+		nagiosExitState.LastError = vsphere.ErrSnapshotAgeThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsAgeOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsAgeReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsAgeOneLineCheckSummary(
+			nagios.StateOKLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsAgeReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
@@ -0,0 +1,244 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// SnapshotsCount executes the plugin used to monitor snapshots count. This
+// function does not return; the application exits with the final plugin
+// state.
+func SnapshotsCount() {
+	run(config.PluginType{SnapshotsCount: true}, checkSnapshotsCount)
+}
+
+// checkSnapshotsCount evaluates snapshots count using an established vSphere
+// session and records the results in the provided plugin output.
+func checkSnapshotsCount(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s snapshots present",
+		cfg.SnapshotsCountCritical,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s snapshots present",
+		cfg.SnapshotsCountWarning,
+	)
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Str("snapshots_count_critical", cfg.SnapshotsCountCritical.String()).
+		Str("snapshots_count_warning", cfg.SnapshotsCountWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	// NOTE: This plugin is hard-coded to evaluate powered off and powered
+	// on VMs equally. I'm not sure whether ignoring powered off VMs by
+	// default makes sense for this particular plugin.
+	//
+	// Please share your feedback here if you feel differently:
+	// https://github.com/atc0005/check-vmware/discussions/177
+	//
+	// Please expand on some use cases for ignoring powered off VMs by default.
+	//
+	// log.Debug().Msg("Filter VMs to specified power state")
+	// filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	log.Debug().Msg("Filter VMs to those with snapshots")
+	vmsWithSnapshots := vsphere.FilterVMsWithSnapshots(filteredVMs)
+
+	log.Debug().Msg("Build snapshot sets for bulk processing")
+	snapshotSets := make(vsphere.SnapshotSummarySets, 0, len(vmsWithSnapshots))
+
+	snapshotThresholds := vsphere.SnapshotThresholds{
+		CountCritical: cfg.SnapshotsCountCritical,
+		CountWarning:  cfg.SnapshotsCountWarning,
+	}
+
+	for _, vm := range vmsWithSnapshots {
+
+		log.Debug().Str("vm", vm.Name).Msg("Evaluating snapshots for VM")
+
+		snapshotSets = append(
+			snapshotSets,
+			vsphere.NewSnapshotSummarySet(
+				vm,
+				snapshotThresholds,
+			),
+		)
+	}
+
+	plugin.PerfData = vsphere.SnapshotsCountPerfData(snapshotSets, snapshotThresholds, filteredVMs, resourcePools)
+	plugin.Data = vsphere.NewSnapshotsData(snapshotSets, snapshotThresholds, filteredVMs, resourcePools)
+	plugin.Objects = snapshotObjects(
+		filteredVMs,
+		snapshotSets,
+		snapshotThresholds,
+		resourcePools,
+		vsphere.SnapshotsCountOneLineCheckSummary,
+		vsphere.SnapshotsCountPerfData,
+	)
+
+	switch {
+
+	case snapshotSets.IsCountCriticalState():
+
+		numVMsWithExcessSnaps, numExcessSnaps, snapshotsTotal :=
+			snapshotSets.ExcessSnapshots(cfg.SnapshotsCountCritical)
+
+		log.Error().
+			Int("num_vms_with_critical_num_snapshots", numVMsWithExcessSnaps).
+			Int("num_snapshots_in_excess", numExcessSnaps).
+			Int("num_snapshots_total", snapshotsTotal).
+			Msg("Snapshot sets exceed number of specified (permitted) snapshots per VM")
+
+		nagiosExitState.LastError = vsphere.ErrSnapshotCountThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsCountOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsCountReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case snapshotSets.IsCountWarningState():
+
+		numVMsWithExcessSnaps, numExcessSnaps, snapshotsTotal :=
+			snapshotSets.ExcessSnapshots(cfg.SnapshotsCountWarning)
+
+		log.Error().
+			Int("num_vms_with_warning_num_snapshots", numVMsWithExcessSnaps).
+			Int("num_snapshots_in_excess", numExcessSnaps).
+			Int("num_snapshots_total", snapshotsTotal).
+			Msg("Snapshot sets exceed number of specified (permitted) snapshots per VM")
+
+		nagiosExitState.LastError = vsphere.ErrSnapshotCountThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsCountOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsCountReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.SnapshotsCountOneLineCheckSummary(
+			nagios.StateOKLabel,
+			snapshotSets,
+			snapshotThresholds,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.SnapshotsCountReport(
+			c.Client,
+			snapshotSets,
+			snapshotThresholds,
+			vms,
+			filteredVMs,
+			vmsWithSnapshots,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
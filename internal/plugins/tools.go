@@ -0,0 +1,175 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// Tools executes the plugin used to monitor VMware Tools status of
+// VirtualMachines. This function does not return; the application exits with
+// the final plugin state.
+func Tools() {
+	run(config.PluginType{Tools: true}, checkTools)
+}
+
+// checkTools evaluates VMware Tools status of VirtualMachines using an
+// established vSphere session and records the results in the provided plugin
+// output.
+func checkTools(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold =
+		"Tools not running (e.g., crashed, low memory scenario) or installed."
+
+	nagiosExitState.WarningThreshold =
+		"Outdated VMware Tools installation."
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Bool("eval_powered_off", cfg.PoweredOff).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified power state")
+	filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	log.Debug().Msg("Filter VMs to those with VMware Tools issues")
+	vmsWithIssues := vsphere.FilterVMsWithToolsIssues(filteredVMs)
+
+	plugin.PerfData = vsphere.VMToolsPerfData(filteredVMs, vmsWithIssues, resourcePools)
+	plugin.Data = vsphere.NewVMToolsData(filteredVMs, vmsWithIssues, resourcePools)
+	plugin.Objects = vmObjects(filteredVMs, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmWithIssues := vsphere.FilterVMsWithToolsIssues(vm)
+
+		stateLabel, stateExitCode := nagios.StateOKLabel, nagios.StateOKExitCode
+		if len(vmWithIssues) > 0 {
+			stateLabel, stateExitCode = vsphere.GetVMToolsStatusSummary(vmWithIssues)
+		}
+
+		return stateExitCode,
+			vsphere.VMToolsOneLineCheckSummary(stateLabel, vm, vmWithIssues, resourcePools),
+			vsphere.VMToolsPerfData(vm, vmWithIssues, resourcePools)
+	})
+
+	if len(vmsWithIssues) > 0 {
+
+		log.Error().
+			Int("vms_with_issues", len(vmsWithIssues)).
+			Int("vms_total", len(vms)).
+			Int("vms_filtered", len(filteredVMs)).
+			Msg("issues with VMware Tools found")
+
+		stateLabel, stateExitCode := vsphere.GetVMToolsStatusSummary(vmsWithIssues)
+
+		nagiosExitState.LastError = fmt.Errorf(
+			"%d of %d VMs with VMware Tools issues",
+			len(vmsWithIssues),
+			len(filteredVMs),
+		)
+
+		nagiosExitState.ServiceOutput = vsphere.VMToolsOneLineCheckSummary(
+			stateLabel,
+			filteredVMs,
+			vmsWithIssues,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMToolsReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			vmsWithIssues,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = stateExitCode
+
+		return
+
+	}
+
+	// success if we made it here
+
+	log.Debug().
+		Int("vms_total", len(vms)).
+		Int("vms_filtered", len(filteredVMs)).
+		Msg("No problems with VMware Tools found")
+
+	nagiosExitState.LastError = nil
+
+	nagiosExitState.ServiceOutput = vsphere.VMToolsOneLineCheckSummary(
+		nagios.StateOKLabel,
+		filteredVMs,
+		vmsWithIssues,
+		resourcePools,
+	)
+
+	nagiosExitState.LongServiceOutput = vsphere.VMToolsReport(
+		c.Client,
+		vms,
+		filteredVMs,
+		vmsWithIssues,
+		cfg.IgnoredVMs,
+		cfg.PoweredOff,
+		cfg.IncludedResourcePools,
+		cfg.ExcludedResourcePools,
+		resourcePools,
+	) + footer(cfg, vmsExcludedByCA)
+
+	nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+}
@@ -0,0 +1,295 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// VirtualCPUsAllocation executes the plugin used to monitor virtual CPU
+// allocations. This function does not return; the application exits with the
+// final plugin state.
+func VirtualCPUsAllocation() {
+	runServers(config.PluginType{VirtualCPUsAllocation: true}, checkServersVirtualCPUsAllocation)
+}
+
+// checkVirtualCPUsAllocation evaluates virtual CPU allocations using an
+// established vSphere session and records the results in the provided plugin
+// output.
+func checkVirtualCPUsAllocation(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+	sessions := []vsphere.ServerSession{{Server: cfg.Server(), Client: c}}
+	checkServersVirtualCPUsAllocation(ctx, sessions, cfg, plugin)
+}
+
+// checkServersVirtualCPUsAllocation evaluates virtual CPU allocations using
+// established vSphere sessions with one or more servers and records the
+// combined results in the provided plugin output. The vCPUs allocated on all
+// servers are evaluated against the maximum allowed vCPUs.
+func checkServersVirtualCPUsAllocation(ctx context.Context, sessions []vsphere.ServerSession, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s%% of %d vCPUs allocated",
+		cfg.VCPUsAllocatedCritical,
+		cfg.VCPUsMaxAllowed,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s%% of %d vCPUs allocated",
+		cfg.VCPUsAllocatedWarning,
+		cfg.VCPUsMaxAllowed,
+	)
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Bool("eval_powered_off", cfg.PoweredOff).
+		Int("max_vcpus_allowed", cfg.VCPUsMaxAllowed).
+		Str("vcpus_critical_allocation", cfg.VCPUsAllocatedCritical.String()).
+		Str("vcpus_warning_allocation", cfg.VCPUsAllocatedWarning.String()).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := serversVMsInScope(ctx, sessions, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	// Servers which could not be evaluated are reported as a failure along
+	// with the results for the remaining servers.
+	serversErr := vsphere.ServersError(sessions)
+
+	// Use the first server evaluated when reporting details of the vSphere
+	// environment. Details for each server are also reported if multiple
+	// servers are evaluated.
+	client := vsphere.FirstEvaluatedClient(sessions)
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified power state")
+	filteredVMs := vsphere.FilterVMsByPowerState(scope.FilteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	// here we diverge from VMware Tools plugin
+
+	var vCPUsAllocated int32
+	for _, vm := range filteredVMs {
+		vCPUsAllocated += vm.Summary.Config.NumCpu
+		log.Debug().
+			Str("vm_name", vm.Name).
+			Int32("num_vcpu", vm.Summary.Config.NumCpu).
+			Msg("")
+	}
+
+	log.Debug().
+		Int32("vcpus_allocated", vCPUsAllocated).
+		Msg("Finished counting vCPUs")
+
+	vCPUsPercentageUsedOfAllowed := float32(vCPUsAllocated) / float32(cfg.VCPUsMaxAllowed) * 100
+	var vCPUsRemaining int32
+
+	switch {
+	case vCPUsAllocated > int32(cfg.VCPUsMaxAllowed):
+		vCPUsRemaining = 0
+	default:
+		vCPUsRemaining = int32(cfg.VCPUsMaxAllowed) - vCPUsAllocated
+	}
+
+	log.Debug().
+		Float32("vcpus_percent_used", vCPUsPercentageUsedOfAllowed).
+		Int32("vcpus_remaining", vCPUsRemaining).
+		Msg("")
+
+	plugin.PerfData = vsphere.VirtualCPUsPerfData(
+		vCPUsAllocated,
+		cfg.VCPUsMaxAllowed,
+		cfg.VCPUsAllocatedWarning,
+		cfg.VCPUsAllocatedCritical,
+		filteredVMs,
+		resourcePools,
+	)
+	plugin.Data = vsphere.NewVirtualCPUsData(
+		vCPUsAllocated,
+		cfg.VCPUsMaxAllowed,
+		cfg.VCPUsAllocatedWarning,
+		cfg.VCPUsAllocatedCritical,
+		filteredVMs,
+		resourcePools,
+	)
+
+	switch {
+	case serversErr != nil:
+
+		log.Error().
+			Err(serversErr).
+			Float32("vcpus_percent_used", vCPUsPercentageUsedOfAllowed).
+			Int32("vcpus_remaining", vCPUsRemaining).
+			Int("vms_filtered", len(filteredVMs)).
+			Msg("one or more servers could not be evaluated")
+
+		nagiosExitState.LastError = serversErr
+
+		nagiosExitState.ServiceOutput = vsphere.VirtualCPUsOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			filteredVMs,
+			resourcePools,
+		) + vsphere.FailedServersSummary(sessions)
+
+		nagiosExitState.LongServiceOutput = vsphere.VirtualCPUsReport(
+			client,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			vms,
+			filteredVMs,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case cfg.VCPUsAllocatedCritical.Alert(float64(vCPUsPercentageUsedOfAllowed)):
+
+		log.Error().
+			Float32("vcpus_percent_used", vCPUsPercentageUsedOfAllowed).
+			Int32("vcpus_remaining", vCPUsRemaining).
+			Int("vms_filtered", len(filteredVMs)).
+			Msg("vCPUs allocation")
+
+		nagiosExitState.LastError = vsphere.ErrVCPUsUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.VirtualCPUsOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VirtualCPUsReport(
+			client,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			vms,
+			filteredVMs,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case cfg.VCPUsAllocatedWarning.Alert(float64(vCPUsPercentageUsedOfAllowed)):
+
+		log.Error().
+			Float32("vcpus_percent_used", vCPUsPercentageUsedOfAllowed).
+			Int32("vcpus_remaining", vCPUsRemaining).
+			Int("vms_filtered", len(filteredVMs)).
+			Msg("vCPUs allocation warning")
+
+		nagiosExitState.LastError = vsphere.ErrVCPUsUsageThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.VirtualCPUsOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VirtualCPUsReport(
+			client,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			vms,
+			filteredVMs,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.VirtualCPUsOneLineCheckSummary(
+			nagios.StateOKLabel,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			filteredVMs,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VirtualCPUsReport(
+			client,
+			vCPUsAllocated,
+			cfg.VCPUsMaxAllowed,
+			vms,
+			filteredVMs,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA) + vsphere.ServersReport(sessions)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
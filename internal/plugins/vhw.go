@@ -0,0 +1,515 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// VirtualHardwareVersion executes the plugin used to monitor virtual hardware
+// versions. This function does not return; the application exits with the
+// final plugin state.
+func VirtualHardwareVersion() {
+	run(config.PluginType{VirtualHardwareVersion: true}, checkVirtualHardwareVersion)
+}
+
+// checkVirtualHardwareVersion evaluates virtual hardware versions using an
+// established vSphere session and records the results in the provided plugin
+// output.
+func checkVirtualHardwareVersion(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	log := cfg.Log.With().
+		Str("datacenter_name", cfg.DatacenterName).
+		Str("host_system_name", cfg.HostSystemName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Bool("eval_powered_off", cfg.PoweredOff).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+
+	// The datacenter name is also used to obtain the default hardware
+	// version; at most one datacenter is supported by this plugin.
+	var dcNames []string
+	if cfg.DatacenterName != "" {
+		dcNames = append(dcNames, cfg.DatacenterName)
+	}
+
+	// The cluster name is only used to obtain the default hardware version
+	// and does not limit the VMs evaluated.
+	scopeCfg := *cfg
+	scopeCfg.DatacenterNames = dcNames
+	scopeCfg.ClusterName = ""
+
+	scope, scopeErr := vmsInScope(ctx, c.Client, &scopeCfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified power state")
+	filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+		// here we diverge from other plugins
+
+	defaultHardwareVersion, getDefVerErr := vsphere.DefaultHardwareVersion(
+		ctx,
+		c.Client,
+		cfg.HostSystemName,
+		cfg.ClusterName,
+		cfg.DatacenterName,
+	)
+	if getDefVerErr != nil {
+		log.Error().Err(getDefVerErr).Msg(
+			"error retrieving default hardware version",
+		)
+
+		nagiosExitState.LastError = getDefVerErr
+		nagiosExitState.ServiceOutput = fmt.Sprintf(
+			"%s: Error retrieving default hardware version",
+			nagios.StateCRITICALLabel,
+		)
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+	}
+
+	log.Debug().
+		Int("default_hardware_version", defaultHardwareVersion.VersionNumber()).
+		Msg("")
+
+	hardwareVersionsIdx := make(vsphere.HardwareVersionsIndex)
+	for _, vm := range filteredVMs {
+		log.Debug().
+			Str("vm_name", vm.Name).
+			Str("hardware_version", vm.Config.Version).
+			Msg("")
+
+		// record the hardware version and count of that version
+		hardwareVersionsIdx[vm.Config.Version]++
+	}
+
+	plugin.PerfData = vsphere.VirtualHardwarePerfData(hardwareVersionsIdx, filteredVMs, resourcePools)
+	plugin.Data = vsphere.NewVirtualHardwareData(hardwareVersionsIdx, filteredVMs, resourcePools)
+
+	if cfg.VirtualHardwareApplyHomogeneousVersionCheck() {
+
+		// Record thresholds for use as Nagios "Long Service Output" content. This
+		// content is shown in the detailed web UI and in notifications generated
+		// by Nagios.
+		nagiosExitState.CriticalThreshold = config.ThresholdNotUsed
+		nagiosExitState.WarningThreshold = "Non-homogenous hardware versions."
+
+		switch {
+
+		// There are at least two hardware versions present instead of a
+		// uniform version across all VirtualMachines.
+		case hardwareVersionsIdx.Count() > 1:
+
+			log.Error().
+				Int("vms_filtered", len(filteredVMs)).
+				Int("unique_hardware_versions", hardwareVersionsIdx.Count()).
+				Str("newest_hardware", hardwareVersionsIdx.Newest().String()).
+				Str("outdated_hardware_list", strings.Join(
+					hardwareVersionsIdx.Outdated().VersionNames(), ", ")).
+				Msg("Virtual Hardware versions inconsistency detected")
+
+			nagiosExitState.LastError = vsphere.ErrVirtualHardwareOutdatedVersionsFound
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateWARNINGLabel,
+				hardwareVersionsIdx,
+				hardwareVersionsIdx.Newest().VersionNumber(),
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				hardwareVersionsIdx.Newest().VersionNumber(),
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+			return
+
+		default:
+
+			// same hardware version
+
+			nagiosExitState.LastError = nil
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateOKLabel,
+				hardwareVersionsIdx,
+				hardwareVersionsIdx.Newest().VersionNumber(),
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				hardwareVersionsIdx.Newest().VersionNumber(),
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+			return
+
+		}
+
+	}
+
+	if cfg.VirtualHardwareApplyMinVersionCheck() {
+
+		// Record thresholds for use as Nagios "Long Service Output" content. This
+		// content is shown in the detailed web UI and in notifications generated
+		// by Nagios.
+		nagiosExitState.CriticalThreshold = fmt.Sprintf(
+			"Hardware versions older than the minimum (%d) present.",
+			cfg.VirtualHardwareMinimumVersion,
+		)
+		nagiosExitState.WarningThreshold = config.ThresholdNotUsed
+
+		hardwareVersions := hardwareVersionsIdx.Versions()
+
+		switch {
+		case !hardwareVersions.MeetsMinVersion(cfg.VirtualHardwareMinimumVersion):
+
+			log.Error().
+				Int("vms_filtered", len(filteredVMs)).
+				Int("unique_hardware_versions", hardwareVersionsIdx.Count()).
+				Str("newest_hardware", hardwareVersionsIdx.Newest().String()).
+				Str("outdated_hardware_list", strings.Join(
+					hardwareVersionsIdx.Outdated().VersionNames(), ", ")).
+				Msg("Virtual Hardware versions older than the specified minimum version detected")
+
+			nagiosExitState.LastError = vsphere.ErrVirtualHardwareOutdatedVersionsFound
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateCRITICALLabel,
+				hardwareVersionsIdx,
+				cfg.VirtualHardwareMinimumVersion,
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				cfg.VirtualHardwareMinimumVersion,
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+
+		default:
+			nagiosExitState.LastError = nil
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateOKLabel,
+				hardwareVersionsIdx,
+				cfg.VirtualHardwareMinimumVersion,
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				cfg.VirtualHardwareMinimumVersion,
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+			return
+		}
+
+	}
+
+	if cfg.VirtualHardwareApplyDefaultIsMinVersionCheck() {
+
+		// Record thresholds for use as Nagios "Long Service Output" content. This
+		// content is shown in the detailed web UI and in notifications generated
+		// by Nagios.
+		nagiosExitState.CriticalThreshold = config.ThresholdNotUsed
+
+		nagiosExitState.WarningThreshold = fmt.Sprintf(
+			"Hardware versions older than the default host or cluster (%d) present.",
+			defaultHardwareVersion.VersionNumber(),
+		)
+
+		hardwareVersions := hardwareVersionsIdx.Versions()
+
+		switch {
+		case !hardwareVersions.MeetsMinVersion(defaultHardwareVersion.VersionNumber()):
+
+			log.Error().
+				Int("vms_filtered", len(filteredVMs)).
+				Int("unique_hardware_versions", hardwareVersionsIdx.Count()).
+				Str("newest_hardware", hardwareVersionsIdx.Newest().String()).
+				Int("default_hardware_version", defaultHardwareVersion.VersionNumber()).
+				Str("outdated_hardware_list", strings.Join(
+					hardwareVersionsIdx.Outdated().VersionNames(), ", ")).
+				Msg("Virtual Hardware versions older than the host or cluster default version detected")
+
+			nagiosExitState.LastError = vsphere.ErrVirtualHardwareOutdatedVersionsFound
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateWARNINGLabel,
+				hardwareVersionsIdx,
+				defaultHardwareVersion.VersionNumber(),
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				defaultHardwareVersion.VersionNumber(),
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+			return
+
+		default:
+			nagiosExitState.LastError = nil
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateOKLabel,
+				hardwareVersionsIdx,
+				defaultHardwareVersion.VersionNumber(),
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				defaultHardwareVersion.VersionNumber(),
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+			return
+		}
+
+	}
+
+	if cfg.VirtualHardwareApplyOutdatedByVersionCheck() {
+
+		// Record thresholds for use as Nagios "Long Service Output" content. This
+		// content is shown in the detailed web UI and in notifications generated
+		// by Nagios.
+		nagiosExitState.CriticalThreshold = fmt.Sprintf(
+			"Hardware versions outdated by more than %d versions present.",
+			cfg.VirtualHardwareOutdatedByCritical,
+		)
+		nagiosExitState.WarningThreshold = fmt.Sprintf(
+			"Hardware versions outdated by more than %d versions present.",
+			cfg.VirtualHardwareOutdatedByWarning,
+		)
+
+		hardwareVersions := hardwareVersionsIdx.Versions()
+		latestHWVerNum := hardwareVersionsIdx.Newest().VersionNumber()
+		criticalThresholdVerNum := latestHWVerNum - cfg.VirtualHardwareOutdatedByCritical
+		warningThresholdVerNum := latestHWVerNum - cfg.VirtualHardwareOutdatedByWarning
+
+		switch {
+		case !hardwareVersions.MeetsMinVersion(criticalThresholdVerNum):
+
+			log.Error().
+				Int("vms_filtered", len(filteredVMs)).
+				Int("unique_hardware_versions", hardwareVersionsIdx.Count()).
+				Str("newest_hardware", hardwareVersionsIdx.Newest().String()).
+				Str("outdated_hardware_list", strings.Join(
+					hardwareVersionsIdx.Outdated().VersionNames(), ", ")).
+				Msg("Virtual Hardware versions older than the specified minimum version detected")
+
+			nagiosExitState.LastError = vsphere.ErrVirtualHardwareOutdatedVersionsFound
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateCRITICALLabel,
+				hardwareVersionsIdx,
+				criticalThresholdVerNum,
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				criticalThresholdVerNum,
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+			return
+
+		case !hardwareVersions.MeetsMinVersion(warningThresholdVerNum):
+
+			log.Error().
+				Int("vms_filtered", len(filteredVMs)).
+				Int("unique_hardware_versions", hardwareVersionsIdx.Count()).
+				Str("newest_hardware", hardwareVersionsIdx.Newest().String()).
+				Str("outdated_hardware_list", strings.Join(
+					hardwareVersionsIdx.Outdated().VersionNames(), ", ")).
+				Msg("Virtual Hardware versions older than the specified minimum version detected")
+
+			nagiosExitState.LastError = vsphere.ErrVirtualHardwareOutdatedVersionsFound
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateWARNINGLabel,
+				hardwareVersionsIdx,
+				warningThresholdVerNum,
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				warningThresholdVerNum,
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+			return
+
+		default:
+			nagiosExitState.LastError = nil
+
+			nagiosExitState.ServiceOutput = vsphere.VirtualHardwareOneLineCheckSummary(
+				nagios.StateOKLabel,
+				hardwareVersionsIdx,
+				warningThresholdVerNum,
+				filteredVMs,
+				resourcePools,
+			)
+
+			nagiosExitState.LongServiceOutput = vsphere.VirtualHardwareReport(
+				c.Client,
+				hardwareVersionsIdx,
+				warningThresholdVerNum,
+				defaultHardwareVersion,
+				vms,
+				filteredVMs,
+				cfg.IgnoredVMs,
+				cfg.PoweredOff,
+				cfg.IncludedResourcePools,
+				cfg.ExcludedResourcePools,
+				resourcePools,
+			) + vmFiltersReport(cfg, vmsExcludedByCA)
+
+			nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+			return
+		}
+
+	}
+
+}
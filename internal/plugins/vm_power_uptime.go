@@ -0,0 +1,215 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package plugins
+
+import (
+	"context"
+	"fmt"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25/mo"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/output"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/atc0005/check-vmware/internal/vsphere"
+)
+
+// VirtualMachinePowerCycleUptime executes the plugin used to monitor
+// VirtualMachine (power cycle) uptime. This function does not return; the
+// application exits with the final plugin state.
+func VirtualMachinePowerCycleUptime() {
+	run(config.PluginType{VirtualMachinePowerCycleUptime: true}, checkVirtualMachinePowerCycleUptime)
+}
+
+// checkVirtualMachinePowerCycleUptime evaluates VirtualMachine (power cycle)
+// uptime using an established vSphere session and records the results in the
+// provided plugin output.
+func checkVirtualMachinePowerCycleUptime(ctx context.Context, c *govmomi.Client, cfg *config.Config, plugin *output.Plugin) {
+
+	nagiosExitState := plugin.ExitState
+
+	// Record thresholds for use as Nagios "Long Service Output" content. This
+	// content is shown in the detailed web UI and in notifications generated
+	// by Nagios.
+	nagiosExitState.CriticalThreshold = fmt.Sprintf(
+		"%s days Virtual Machine uptime",
+		cfg.VMPowerCycleUptimeCritical,
+	)
+
+	nagiosExitState.WarningThreshold = fmt.Sprintf(
+		"%s days Virtual Machine uptime",
+		cfg.VMPowerCycleUptimeWarning,
+	)
+
+	log := cfg.Log.With().
+		Str("datacenter_names", cfg.DatacenterNames.String()).
+		Str("cluster_name", cfg.ClusterName).
+		Str("host_system_names", cfg.HostSystemNames.String()).
+		Str("included_resource_pools", cfg.IncludedResourcePools.String()).
+		Str("excluded_resource_pools", cfg.ExcludedResourcePools.String()).
+		Bool("recursive_resource_pools", cfg.RecursiveResourcePools).
+		Str("included_folders", cfg.IncludedFolders.String()).
+		Str("excluded_folders", cfg.ExcludedFolders.String()).
+		Bool("recursive_folders", cfg.RecursiveFolders).
+		Str("included_tags", cfg.IncludedTags.String()).
+		Str("excluded_tags", cfg.ExcludedTags.String()).
+		Str("included_custom_attributes", cfg.IncludedCustomAttributes.String()).
+		Str("excluded_custom_attributes", cfg.ExcludedCustomAttributes.String()).
+		Str("ignored_vms", cfg.IgnoredVMs.String()).
+		Str("ignore_file", cfg.IgnoreFile).
+		Bool("eval_powered_off", cfg.PoweredOff).
+		Logger()
+
+	// At this point we're logged in, ready to retrieve a list of VMs. If
+	// specified, we should limit VMs based on include/exclude lists.
+	scope, scopeErr := vmsInScope(ctx, c.Client, cfg, nagiosExitState)
+	if scopeErr != nil {
+		return
+	}
+
+	resourcePools := scope.ResourcePools
+	vms := scope.VMs
+	filteredVMs := scope.FilteredVMs
+	vmsExcludedByCA := scope.VMsExcludedByCA
+
+	log.Debug().Msg("Filter VMs to specified power state")
+	filteredVMs = vsphere.FilterVMsByPowerState(filteredVMs, cfg.PoweredOff)
+
+	log.Debug().
+		Str("virtual_machines", strings.Join(vsphere.VMNames(filteredVMs), ", ")).
+		Msg("Filtered VMs")
+
+	// here we diverge from other plugins
+
+	log.Debug().Msg("Generate VM power cycle uptime summary")
+	uptimeSummary := vsphere.GetVMPowerCycleUptimeStatusSummary(
+		filteredVMs,
+		cfg.VMPowerCycleUptimeWarning,
+		cfg.VMPowerCycleUptimeCritical,
+	)
+
+	plugin.PerfData = vsphere.VMPowerCycleUptimePerfData(filteredVMs, uptimeSummary, resourcePools)
+	plugin.Data = vsphere.NewVMPowerCycleUptimeData(filteredVMs, uptimeSummary, resourcePools)
+	plugin.Objects = vmObjects(filteredVMs, func(vm []mo.VirtualMachine) (int, string, []perfdata.PerformanceData) {
+		vmUptimeSummary := vsphere.GetVMPowerCycleUptimeStatusSummary(
+			vm,
+			cfg.VMPowerCycleUptimeWarning,
+			cfg.VMPowerCycleUptimeCritical,
+		)
+
+		stateLabel, stateExitCode := thresholdState(
+			len(vmUptimeSummary.VMsCritical) > 0,
+			len(vmUptimeSummary.VMsWarning) > 0,
+		)
+
+		return stateExitCode,
+			vsphere.VMPowerCycleUptimeOneLineCheckSummary(stateLabel, vm, vmUptimeSummary, resourcePools),
+			vsphere.VMPowerCycleUptimePerfData(vm, vmUptimeSummary, resourcePools)
+	})
+
+	log.Debug().Msg("Evaluating VM power cycle uptime")
+	switch {
+	case len(uptimeSummary.VMsCritical) > 0:
+
+		log.Error().
+			Str("virtual_machines_with_high_uptime", uptimeSummary.VMNames()).
+			Msg("VMs crossed power cycle uptime thresholds")
+
+		nagiosExitState.LastError = vsphere.ErrVirtualMachinePowerCycleUptimeThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.VMPowerCycleUptimeOneLineCheckSummary(
+			nagios.StateCRITICALLabel,
+			filteredVMs,
+			uptimeSummary,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMPowerCycleUptimeReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			uptimeSummary,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateCRITICALExitCode
+
+		return
+
+	case len(uptimeSummary.VMsWarning) > 0:
+
+		log.Error().
+			Str("virtual_machines_with_high_uptime", uptimeSummary.VMNames()).
+			Msg("VMs crossed power cycle uptime thresholds")
+
+		nagiosExitState.LastError = vsphere.ErrVirtualMachinePowerCycleUptimeThresholdCrossed
+
+		nagiosExitState.ServiceOutput = vsphere.VMPowerCycleUptimeOneLineCheckSummary(
+			nagios.StateWARNINGLabel,
+			filteredVMs,
+			uptimeSummary,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMPowerCycleUptimeReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			uptimeSummary,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateWARNINGExitCode
+
+		return
+
+	default:
+
+		// success path
+
+		log.Debug().Msg("VM Power Cycle uptime thresholds not exceeded")
+
+		nagiosExitState.LastError = nil
+
+		nagiosExitState.ServiceOutput = vsphere.VMPowerCycleUptimeOneLineCheckSummary(
+			nagios.StateOKLabel,
+			filteredVMs,
+			uptimeSummary,
+			resourcePools,
+		)
+
+		nagiosExitState.LongServiceOutput = vsphere.VMPowerCycleUptimeReport(
+			c.Client,
+			vms,
+			filteredVMs,
+			uptimeSummary,
+			cfg.IgnoredVMs,
+			cfg.PoweredOff,
+			cfg.IncludedResourcePools,
+			cfg.ExcludedResourcePools,
+			resourcePools,
+		) + footer(cfg, vmsExcludedByCA)
+
+		nagiosExitState.ExitStatusCode = nagios.StateOKExitCode
+
+		return
+
+	}
+
+}
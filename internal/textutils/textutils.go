@@ -9,10 +9,22 @@ package textutils
 
 import (
 	"fmt"
+	"path"
+	"regexp"
 	"strconv"
 	"strings"
+	"sync"
 )
 
+// RegexPatternPrefix indicates that a name pattern is a regular expression
+// (e.g., re:^citrix-pool-[0-9]+$) instead of a glob pattern (e.g.,
+// citrix-pool-*).
+const RegexPatternPrefix string = "re:"
+
+// compiledPatterns caches the regular expressions compiled for name patterns
+// so that each is compiled only once when matching many names.
+var compiledPatterns sync.Map
+
 // InList is a helper function to emulate Python's `if "x"
 // in list:` functionality. The caller can optionally ignore case of compared
 // items.
@@ -32,6 +44,79 @@ func InList(needle string, haystack []string, ignoreCase bool) bool {
 	return false
 }
 
+// ValidatePattern asserts that the given name pattern is a valid glob
+// pattern or, if prefixed with RegexPatternPrefix, a valid regular
+// expression.
+func ValidatePattern(pattern string) error {
+	if strings.HasPrefix(pattern, RegexPatternPrefix) {
+		_, err := compilePattern(pattern)
+		return err
+	}
+
+	_, err := path.Match(pattern, "")
+	return err
+}
+
+// compilePattern compiles the case-insensitive regular expression for the
+// given name pattern, reusing a previously compiled regular expression if
+// available.
+func compilePattern(pattern string) (*regexp.Regexp, error) {
+	if re, ok := compiledPatterns.Load(pattern); ok {
+		return re.(*regexp.Regexp), nil
+	}
+
+	re, err := regexp.Compile("(?i)" + strings.TrimPrefix(pattern, RegexPatternPrefix))
+	if err != nil {
+		return nil, err
+	}
+	compiledPatterns.Store(pattern, re)
+
+	return re, nil
+}
+
+// MatchPattern indicates whether the given name matches the specified name
+// pattern. Names are matched case-insensitively. Patterns prefixed with
+// RegexPatternPrefix are evaluated as regular expressions, all others as
+// glob patterns. Names identical to the pattern always match so that names
+// containing glob special characters may be specified as-is. Invalid
+// patterns only match identical names.
+func MatchPattern(name string, pattern string) bool {
+	if strings.EqualFold(name, pattern) {
+		return true
+	}
+
+	if strings.HasPrefix(pattern, RegexPatternPrefix) {
+		re, err := compilePattern(pattern)
+		return err == nil && re.MatchString(name)
+	}
+
+	matched, err := path.Match(strings.ToLower(pattern), strings.ToLower(name))
+	return err == nil && matched
+}
+
+// InPatternList is the equivalent of InList for name patterns; the given
+// name is compared case-insensitively against each of the name patterns
+// using MatchPattern.
+func InPatternList(name string, patterns []string) bool {
+	for _, pattern := range patterns {
+		if MatchPattern(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
+// PatternMatchesAny indicates whether the given name pattern matches any of
+// the provided names.
+func PatternMatchesAny(pattern string, names []string) bool {
+	for _, name := range names {
+		if MatchPattern(name, pattern) {
+			return true
+		}
+	}
+	return false
+}
+
 // DedupeList returns a copy of a provided string slice with all duplicate
 // entries removed.
 // FIXME: Is there already a standard library version of this functionality?
@@ -127,7 +212,7 @@ func InsertDelimiter(s string, delimiter string, pos int) string {
 func BytesToDelimitedHexStr(bx []byte, delimiter string) string {
 	hexStr := make([]string, 0, len(bx))
 	for _, v := range bx {
-		hexStr = append(hexStr, fmt.Sprintf("%X", v))
+		hexStr = append(hexStr, fmt.Sprintf("%02X", v))
 	}
 	return strings.Join(hexStr, delimiter)
 }
@@ -0,0 +1,53 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package textutils
+
+import "testing"
+
+func TestMatchPattern(t *testing.T) {
+
+	tests := []struct {
+		name    string
+		pattern string
+		want    bool
+	}{
+		{name: "citrix-pool-01", pattern: "Citrix-Pool-01", want: true},
+		{name: "citrix-pool-01", pattern: "citrix-pool-*", want: true},
+		{name: "Citrix-Pool-01", pattern: "citrix-pool-*", want: true},
+		{name: "citrix-pool", pattern: "citrix-pool-*", want: false},
+		{name: "veeam-proxy-2", pattern: "veeam-proxy-?", want: true},
+		{name: "veeam-proxy-12", pattern: "veeam-proxy-?", want: false},
+		{name: "veeam-proxy-12", pattern: "re:^veeam-proxy-[0-9]+$", want: true},
+		{name: "VEEAM-proxy-12", pattern: "re:^veeam-proxy-[0-9]+$", want: true},
+		{name: "old-veeam-proxy-12", pattern: "re:^veeam-proxy-[0-9]+$", want: false},
+		{name: "vm [old]", pattern: "vm [old]", want: true},
+		{name: "vm [old", pattern: "vm [old", want: true},
+		{name: "vm old", pattern: "vm [old", want: false},
+	}
+
+	for _, tt := range tests {
+		if got := MatchPattern(tt.name, tt.pattern); got != tt.want {
+			t.Errorf("MatchPattern(%q, %q) = %t; want %t", tt.name, tt.pattern, got, tt.want)
+		}
+	}
+}
+
+func TestValidatePattern(t *testing.T) {
+
+	for _, pattern := range []string{"citrix-pool-*", "re:^veeam-proxy-[0-9]+$", "vm01"} {
+		if err := ValidatePattern(pattern); err != nil {
+			t.Errorf("ValidatePattern(%q) returned unexpected error: %v", pattern, err)
+		}
+	}
+
+	for _, pattern := range []string{"vm[01", "re:veeam-(proxy"} {
+		if err := ValidatePattern(pattern); err == nil {
+			t.Errorf("ValidatePattern(%q) did not return expected error", pattern)
+		}
+	}
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/check-vmware/internal/textutils"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi"
@@ -1055,7 +1056,7 @@ func (tas *TriggeredAlarms) filterByEntityResourcePool(include []string, exclude
 					// TriggeredAlarm matches one of the provided Resource
 					// Pool names to compare against mark the TriggeredAlarm
 					// as explicitly included.
-					case textutils.InList((*tas)[i].Entity.ResourcePools[j], include, true):
+					case textutils.InPatternList((*tas)[i].Entity.ResourcePools[j], include):
 
 						// Don't explicitly *include* the TriggeredAlarm if
 						// the TriggeredAlarm has already been explicitly
@@ -1103,7 +1104,7 @@ func (tas *TriggeredAlarms) filterByEntityResourcePool(include []string, exclude
 				// no implicit inclusions are applied for non-matching alarm
 				// types as that could unintentionally flip the results from
 				// earlier filtering stages.
-				if textutils.InList((*tas)[i].Entity.ResourcePools[j], exclude, true) {
+				if textutils.InPatternList((*tas)[i].Entity.ResourcePools[j], exclude) {
 					(*tas)[i].Exclude = true
 					(*tas)[i].ExcludeReason = alarmExcludeReasonEntityResourcePool
 					(*tas)[i].ExplicitlyExcluded = true
@@ -1598,6 +1599,113 @@ func AlarmsOneLineCheckSummary(
 	}
 }
 
+// AlarmsPerfData generates performance data for the specified Triggered
+// Alarms. Excluded alarms are not included in the per-state counts.
+func AlarmsPerfData(
+	triggeredAlarms TriggeredAlarms,
+	datacentersEvaluated []string,
+) []perfdata.PerformanceData {
+
+	return []perfdata.PerformanceData{
+		countPerfData("alarms", len(triggeredAlarms), ""),
+		countPerfData("alarms_excluded", triggeredAlarms.NumExcluded(), ""),
+		countPerfData("alarms_critical", triggeredAlarms.NumCriticalState(false), "0"),
+		countPerfData("alarms_warning", triggeredAlarms.NumWarningState(false), ""),
+		countPerfData("alarms_unknown", triggeredAlarms.NumUnknownState(false), ""),
+		countPerfData("alarms_ok", triggeredAlarms.NumOKState(false), ""),
+		countPerfData("datacenters_evaluated", len(datacentersEvaluated), ""),
+	}
+}
+
+// AlarmsData represents the evaluated Triggered Alarms for a set of
+// Datacenters.
+type AlarmsData struct {
+	DatacentersEvaluated []string             `json:"datacenters_evaluated"`
+	TriggeredAlarms      []TriggeredAlarmData `json:"triggered_alarms"`
+}
+
+// TriggeredAlarmData represents a Triggered Alarm along with the affected
+// entity. The exclude reason is set for alarms excluded from evaluation.
+type TriggeredAlarmData struct {
+	Name               string          `json:"name"`
+	Description        string          `json:"description"`
+	MOID               string          `json:"moid"`
+	Key                string          `json:"key"`
+	Datacenter         string          `json:"datacenter"`
+	Entity             AlarmEntityData `json:"entity"`
+	OverallStatus      string          `json:"overall_status"`
+	State              string          `json:"state"`
+	Time               time.Time       `json:"time"`
+	Acknowledged       bool            `json:"acknowledged"`
+	AcknowledgedTime   *time.Time      `json:"acknowledged_time,omitempty"`
+	AcknowledgedByUser string          `json:"acknowledged_by_user,omitempty"`
+	Excluded           bool            `json:"excluded"`
+	ExcludeReason      string          `json:"exclude_reason,omitempty"`
+}
+
+// AlarmEntityData represents the affected resource associated with a
+// Triggered Alarm.
+type AlarmEntityData struct {
+	Name          string   `json:"name"`
+	Type          string   `json:"type"`
+	MOID          string   `json:"moid"`
+	OverallStatus string   `json:"overall_status"`
+	ResourcePools []string `json:"resource_pools,omitempty"`
+}
+
+// NewAlarmsData generates machine-readable data for the specified Triggered
+// Alarms, including those excluded from evaluation.
+func NewAlarmsData(
+	triggeredAlarms TriggeredAlarms,
+	datacentersEvaluated []string,
+) AlarmsData {
+
+	alarms := make([]TriggeredAlarmData, 0, len(triggeredAlarms))
+	for _, ta := range triggeredAlarms {
+
+		// Use the exit code to determine the state label; the go-nagios
+		// UNKNOWN state label is misspelled.
+		_, exitCode := EntityStatusToNagiosState(ta.OverallStatus)
+
+		var ackTime *time.Time
+		if ta.Acknowledged && !ta.AcknowledgedTime.IsZero() {
+			t := ta.AcknowledgedTime
+			ackTime = &t
+		}
+
+		alarms = append(alarms, TriggeredAlarmData{
+			Name:        ta.Name,
+			Description: ta.Description,
+			MOID:        ta.MOID.Value,
+			Key:         ta.Key,
+			Datacenter:  ta.Datacenter,
+			Entity: AlarmEntityData{
+				Name:          ta.Entity.Name,
+				Type:          ta.Entity.MOID.Type,
+				MOID:          ta.Entity.MOID.Value,
+				OverallStatus: string(ta.Entity.OverallStatus),
+				ResourcePools: ta.Entity.ResourcePools,
+			},
+			OverallStatus:      string(ta.OverallStatus),
+			State:              exitCodeLabel(exitCode),
+			Time:               ta.Time,
+			Acknowledged:       ta.Acknowledged,
+			AcknowledgedTime:   ackTime,
+			AcknowledgedByUser: ta.AcknowledgedByUser,
+			Excluded:           ta.Excluded(),
+			ExcludeReason:      ta.ExcludeReason,
+		})
+	}
+
+	dcs := make([]string, len(datacentersEvaluated))
+	copy(dcs, datacentersEvaluated)
+
+	return AlarmsData{
+		DatacentersEvaluated: dcs,
+		TriggeredAlarms:      alarms,
+	}
+}
+
 // AlarmsReport generates a summary of detected alarms along with various
 // verbose details intended to aid in troubleshooting check results at a
 // glance. This information is provided for use with the Long Service Output
@@ -1719,6 +1827,13 @@ func AlarmsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -1726,6 +1841,13 @@ func AlarmsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Triggered Alarms (evaluated: %d, ignored: %d, total: %d)%s",
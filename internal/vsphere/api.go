@@ -0,0 +1,65 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+
+	"github.com/vmware/govmomi/vim25"
+)
+
+// APITypeVCenter is the API type reported by vCenter Server instances.
+const APITypeVCenter string = "VirtualCenter"
+
+// APITypeStandaloneHost is the API type reported by standalone ESXi hosts
+// (i.e., when connecting directly to an ESXi host instead of vCenter).
+const APITypeStandaloneHost string = "HostAgent"
+
+// StandaloneHostDatacenter is the name of the implicit Datacenter provided by
+// standalone ESXi hosts. This is the only Datacenter available when
+// connecting directly to an ESXi host.
+const StandaloneHostDatacenter string = "ha-datacenter"
+
+// ErrVCenterRequired indicates that the requested functionality is only
+// provided by vCenter and is not available when connecting directly to a
+// standalone ESXi host.
+var ErrVCenterRequired = errors.New("vCenter connection required")
+
+// IsStandaloneHost indicates whether the client is connected directly to a
+// standalone ESXi host instead of a vCenter Server instance.
+func IsStandaloneHost(c *vim25.Client) bool {
+	return c.ServiceContent.About.ApiType == APITypeStandaloneHost
+}
+
+// APIDescription returns the API type (e.g., "VirtualCenter" or "HostAgent")
+// and API version reported by the vSphere environment.
+func APIDescription(c *vim25.Client) string {
+	return fmt.Sprintf(
+		"%s %s",
+		c.ServiceContent.About.ApiType,
+		c.ServiceContent.About.ApiVersion,
+	)
+}
+
+// RequireVCenter returns an error if the client is connected directly to a
+// standalone ESXi host. The given feature is used to explain which
+// functionality requires a vCenter connection.
+func RequireVCenter(c *vim25.Client, feature string) error {
+	if !IsStandaloneHost(c) {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w: %s not supported by standalone ESXi host %s (API type %q)",
+		ErrVCenterRequired,
+		feature,
+		c.URL().Hostname(),
+		c.ServiceContent.About.ApiType,
+	)
+}
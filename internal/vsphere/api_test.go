@@ -0,0 +1,82 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"errors"
+	"net/url"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// newTestClient returns a client reporting the given API type. The client
+// is not connected and is only suitable for functions which do not submit
+// requests.
+func newTestClient(apiType string) *vim25.Client {
+	u := &url.URL{Scheme: "https", Host: "esx1.example.com", Path: "/sdk"}
+
+	return &vim25.Client{
+		Client: soap.NewClient(u, true),
+		ServiceContent: types.ServiceContent{
+			About: types.AboutInfo{ApiType: apiType},
+		},
+	}
+}
+
+func TestRequireVCenter(t *testing.T) {
+
+	tests := map[string]struct {
+		apiType        string
+		wantStandalone bool
+		wantErr        error
+	}{
+		"vCenter": {
+			apiType:        APITypeVCenter,
+			wantStandalone: false,
+			wantErr:        nil,
+		},
+		"standalone ESXi host": {
+			apiType:        APITypeStandaloneHost,
+			wantStandalone: true,
+			wantErr:        ErrVCenterRequired,
+		},
+		"unknown API type": {
+			apiType:        "",
+			wantStandalone: false,
+			wantErr:        nil,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(tt.apiType)
+
+			if got := IsStandaloneHost(c); got != tt.wantStandalone {
+				t.Errorf("IsStandaloneHost() = %t; want %t", got, tt.wantStandalone)
+			}
+
+			err := RequireVCenter(c, "Custom Attributes")
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("RequireVCenter() returned error %v; want %v", err, tt.wantErr)
+			}
+
+			if err != nil {
+				for _, s := range []string{"Custom Attributes", "esx1.example.com", tt.apiType} {
+					if !strings.Contains(err.Error(), s) {
+						t.Errorf("error %q does not mention %q", err, s)
+					}
+				}
+			}
+		})
+	}
+}
@@ -8,13 +8,29 @@
 package vsphere
 
 import (
+	"context"
 	"errors"
+	"fmt"
+	"regexp"
 	"strings"
+	"sync"
+	"time"
 
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
 	"github.com/vmware/govmomi/vim25/types"
 )
 
+// CustomAttributeSeparator separates the name and value of a Custom
+// Attribute filter (e.g., Monitoring=off).
+const CustomAttributeSeparator string = "="
+
+// CustomAttributeRegexPrefix indicates that the value of a Custom Attribute
+// filter is a regular expression (e.g., Environment=re:^prod).
+const CustomAttributeRegexPrefix string = "re:"
+
 // ErrConvertBaseCustomFieldValue is returned when a conversion error occurs
 // or (type assertion failure) for a provided BaseCustomFieldValue.
 //
@@ -132,3 +148,292 @@ func GetObjectCAVal(caName string, obj mo.ManagedEntity) (string, error) {
 	return caValue, nil
 
 }
+
+// customAttributeFilter is a Custom Attribute name and the value (or
+// regular expression matching the value) used to filter VirtualMachines.
+type customAttributeFilter struct {
+	name  string
+	value string
+	regex *regexp.Regexp
+}
+
+// parseCustomAttributeFilters parses the given Custom Attribute filters in
+// name=value format. Values prefixed with CustomAttributeRegexPrefix are
+// compiled as regular expressions.
+func parseCustomAttributeFilters(filters []string) ([]customAttributeFilter, error) {
+
+	parsed := make([]customAttributeFilter, 0, len(filters))
+	for _, filter := range filters {
+		parts := strings.SplitN(filter, CustomAttributeSeparator, 2)
+		if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" {
+			return nil, fmt.Errorf(
+				"invalid Custom Attribute filter %q; expected format is name=value",
+				filter,
+			)
+		}
+
+		caFilter := customAttributeFilter{
+			name:  strings.TrimSpace(parts[0]),
+			value: parts[1],
+		}
+
+		if strings.HasPrefix(parts[1], CustomAttributeRegexPrefix) {
+			regex, err := regexp.Compile(strings.TrimPrefix(parts[1], CustomAttributeRegexPrefix))
+			if err != nil {
+				return nil, fmt.Errorf(
+					"invalid Custom Attribute filter %q: %w",
+					filter,
+					err,
+				)
+			}
+			caFilter.regex = regex
+		}
+
+		parsed = append(parsed, caFilter)
+	}
+
+	return parsed, nil
+}
+
+// matches indicates whether the given Custom Attribute value matches the
+// filter. Values are compared case-insensitively unless a regular expression
+// is used.
+func (f customAttributeFilter) matches(value string) bool {
+	if f.regex != nil {
+		return f.regex.MatchString(value)
+	}
+
+	return strings.EqualFold(f.value, value)
+}
+
+// vmMatchesCustomAttributes indicates whether the Custom Attributes set for
+// the given VirtualMachine match any of the specified filters. Custom
+// Attributes which are not set for the VirtualMachine do not match.
+func vmMatchesCustomAttributes(vm mo.VirtualMachine, filters []customAttributeFilter) bool {
+	for _, filter := range filters {
+		value, err := GetObjectCAVal(filter.name, vm.ManagedEntity)
+		if err != nil {
+			continue
+		}
+
+		if filter.matches(value) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// FilterVMsByCustomAttribute receives a collection of VirtualMachines and two
+// lists of Custom Attribute filters (in name=value format) that should
+// either be explicitly included or excluded. Values prefixed with "re:" are
+// evaluated as regular expressions. If include filters are specified, only
+// VMs matching at least one of them are kept; VMs matching any of the exclude
+// filters are then dropped. The kept VirtualMachines are returned along with
+// the VirtualMachines excluded by these filters.
+func FilterVMsByCustomAttribute(vms []mo.VirtualMachine, includeCAs []string, excludeCAs []string) ([]mo.VirtualMachine, []mo.VirtualMachine, error) {
+
+	funcTimeStart := time.Now()
+
+	defer func(vms []mo.VirtualMachine) {
+		logger.Printf(
+			"It took %v to execute FilterVMsByCustomAttribute func (and filter %d VMs).\n",
+			time.Since(funcTimeStart),
+			len(vms),
+		)
+	}(vms)
+
+	if len(includeCAs) == 0 && len(excludeCAs) == 0 {
+		return vms, nil, nil
+	}
+
+	includeFilters, err := parseCustomAttributeFilters(includeCAs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	excludeFilters, err := parseCustomAttributeFilters(excludeCAs)
+	if err != nil {
+		return nil, nil, err
+	}
+
+	filteredVMs := make([]mo.VirtualMachine, 0, len(vms))
+	var excludedVMs []mo.VirtualMachine
+
+	for _, vm := range vms {
+		switch {
+		case len(includeFilters) > 0 && !vmMatchesCustomAttributes(vm, includeFilters):
+			excludedVMs = append(excludedVMs, vm)
+
+		case vmMatchesCustomAttributes(vm, excludeFilters):
+			excludedVMs = append(excludedVMs, vm)
+
+		default:
+			filteredVMs = append(filteredVMs, vm)
+		}
+	}
+
+	return filteredVMs, excludedVMs, nil
+}
+
+// vmCustomAttributeDefined indicates whether the named Custom Attribute is
+// defined for VirtualMachines (or globally) in the given list of Custom
+// Attribute definitions.
+func vmCustomAttributeDefined(name string, fields []types.CustomFieldDef) bool {
+	for _, field := range fields {
+		if !strings.EqualFold(field.Name, name) {
+			continue
+		}
+
+		switch field.ManagedObjectType {
+		case "", MgObjRefTypeVirtualMachine:
+			return true
+		}
+	}
+
+	return false
+}
+
+// getCustomAttributeDefs retrieves the Custom Attribute definitions for the
+// vSphere environment. Custom Attributes are provided only by vCenter.
+func getCustomAttributeDefs(ctx context.Context, c *vim25.Client) ([]types.CustomFieldDef, error) {
+
+	if err := RequireVCenter(c, "Custom Attributes"); err != nil {
+		return nil, err
+	}
+
+	m, err := object.GetCustomFieldsManager(c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Custom Attributes manager: %w", err)
+	}
+
+	fields, err := m.Field(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Custom Attribute definitions: %w", err)
+	}
+
+	return fields, nil
+}
+
+// validateCustomAttributes verifies that the Custom Attribute named by each
+// of the given include and exclude filters is found using the provided
+// function.
+func validateCustomAttributes(found func(name string) bool, includeCAs []string, excludeCAs []string) error {
+
+	filterFound := func(filter string) bool {
+		parts := strings.SplitN(filter, CustomAttributeSeparator, 2)
+		return found(strings.TrimSpace(parts[0]))
+	}
+
+	if err := validateNames("Custom Attributes", filterFound, includeCAs, nil); err != nil {
+		return err
+	}
+
+	return validateNames("Custom Attributes", filterFound, nil, excludeCAs)
+}
+
+// ValidateCustomAttributes is responsible for receiving two lists of Custom
+// Attribute filters (in name=value format), explicitly "included" (aka,
+// "whitelisted") and explicitly "excluded" (aka, "blacklisted"). If the
+// Custom Attribute named by any list entry is not defined for
+// VirtualMachines in the vSphere environment an error is returned listing
+// which ones. Custom Attributes are provided only by vCenter; an error is
+// returned if filters are specified for a standalone ESXi host.
+func ValidateCustomAttributes(ctx context.Context, c *vim25.Client, includeCAs []string, excludeCAs []string) error {
+
+	funcTimeStart := time.Now()
+
+	defer func(ics []string, ecs []string) {
+		logger.Printf(
+			"It took %v to execute ValidateCustomAttributes func (and validate %d Custom Attribute filters).\n",
+			time.Since(funcTimeStart),
+			len(ics)+len(ecs),
+		)
+	}(includeCAs, excludeCAs)
+
+	if len(includeCAs) == 0 && len(excludeCAs) == 0 {
+		return nil
+	}
+
+	fields, err := getCustomAttributeDefs(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	found := func(name string) bool {
+		return vmCustomAttributeDefined(name, fields)
+	}
+
+	return validateCustomAttributes(found, includeCAs, excludeCAs)
+}
+
+// ValidateServersCustomAttributes is the equivalent of
+// ValidateCustomAttributes for plugins evaluating multiple servers. An error
+// is returned if the Custom Attribute named by any list entry is not defined
+// on at least one of the servers. If the Custom Attribute definitions for a
+// server cannot be retrieved, the error is recorded for the session and the
+// server is excluded from further evaluation.
+func ValidateServersCustomAttributes(ctx context.Context, sessions []ServerSession, includeCAs []string, excludeCAs []string) error {
+
+	if len(includeCAs) == 0 && len(excludeCAs) == 0 {
+		return nil
+	}
+
+	var mu sync.Mutex
+	var allFields []types.CustomFieldDef
+
+	GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		fields, err := getCustomAttributeDefs(ctx, c)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude Custom Attribute lists: %w", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		allFields = append(allFields, fields...)
+
+		return ServerObjects{}, nil
+	})
+
+	found := func(name string) bool {
+		return vmCustomAttributeDefined(name, allFields)
+	}
+
+	return validateCustomAttributes(found, includeCAs, excludeCAs)
+}
+
+// CustomAttributesReport generates a summary of the Custom Attribute filters
+// used to limit evaluation of VirtualMachines, along with the
+// VirtualMachines excluded by these filters, for use with the Long Service
+// Output field. This summary is intended to be appended to the report
+// generated by plugins supporting these options.
+func CustomAttributesReport(includeCAs []string, excludeCAs []string, excludedVMs []mo.VirtualMachine) string {
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"* Specified Custom Attributes to explicitly include (%d): [%v]%s",
+		len(includeCAs),
+		strings.Join(includeCAs, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Specified Custom Attributes to explicitly exclude (%d): [%v]%s",
+		len(excludeCAs),
+		strings.Join(excludeCAs, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* VMs excluded by Custom Attribute (%d): [%v]%s",
+		len(excludedVMs),
+		strings.Join(VMNames(excludedVMs), ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	return report.String()
+}
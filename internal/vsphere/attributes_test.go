@@ -0,0 +1,83 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFilterVMsByCustomAttribute(t *testing.T) {
+
+	availableField := []types.CustomFieldDef{
+		{Key: 1, Name: "Environment", ManagedObjectType: MgObjRefTypeVirtualMachine},
+		{Key: 2, Name: "Monitoring", ManagedObjectType: MgObjRefTypeVirtualMachine},
+	}
+
+	newVM := func(name string, values map[int32]string) mo.VirtualMachine {
+		var vm mo.VirtualMachine
+		vm.Name = name
+		vm.AvailableField = availableField
+		for key, value := range values {
+			vm.CustomValue = append(vm.CustomValue, &types.CustomFieldStringValue{
+				CustomFieldValue: types.CustomFieldValue{Key: key},
+				Value:            value,
+			})
+		}
+
+		return vm
+	}
+
+	vms := []mo.VirtualMachine{
+		newVM("web1", map[int32]string{1: "prod"}),
+		newVM("web2", map[int32]string{1: "Production", 2: "off"}),
+		newVM("test1", map[int32]string{1: "test"}),
+		newVM("unset1", nil),
+	}
+
+	tests := []struct {
+		name         string
+		include      []string
+		exclude      []string
+		want         []string
+		wantExcluded []string
+	}{
+		{name: "no filters", want: []string{"web1", "web2", "test1", "unset1"}, wantExcluded: []string{}},
+		{name: "include by value", include: []string{"environment=PROD"}, want: []string{"web1"}, wantExcluded: []string{"web2", "test1", "unset1"}},
+		{name: "include by regex", include: []string{"Environment=re:^[Pp]rod"}, want: []string{"web1", "web2"}, wantExcluded: []string{"test1", "unset1"}},
+		{name: "exclude by value", exclude: []string{"Monitoring=off"}, want: []string{"web1", "test1", "unset1"}, wantExcluded: []string{"web2"}},
+		{name: "include and exclude", include: []string{"Environment=re:^[Pp]rod"}, exclude: []string{"Monitoring=off"}, want: []string{"web1"}, wantExcluded: []string{"web2", "test1", "unset1"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got, excluded, err := FilterVMsByCustomAttribute(vms, tt.include, tt.exclude)
+			if err != nil {
+				t.Fatalf("unexpected error: %v", err)
+			}
+
+			if gotNames := VMNames(got); !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("got VMs %v; want %v", gotNames, tt.want)
+			}
+
+			if gotExcluded := VMNames(excluded); !reflect.DeepEqual(gotExcluded, tt.wantExcluded) {
+				t.Errorf("got excluded VMs %v; want %v", gotExcluded, tt.wantExcluded)
+			}
+		})
+	}
+
+	t.Run("invalid filter", func(t *testing.T) {
+		if _, _, err := FilterVMsByCustomAttribute(vms, []string{"Environment"}, nil); err == nil {
+			t.Error("expected error for filter without value")
+		}
+	})
+}
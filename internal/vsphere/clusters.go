@@ -0,0 +1,229 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// ErrClusterNotFound indicates that a specified cluster was not located.
+var ErrClusterNotFound = errors.New("specified cluster not found")
+
+// ErrClusterNotUnique indicates that multiple clusters with the specified
+// name were located (e.g., within different Datacenters).
+var ErrClusterNotUnique = errors.New("specified cluster name is not unique")
+
+// GetClusters accepts a context, a connected client and a boolean value
+// indicating whether a subset of properties per ClusterComputeResource are
+// retrieved. A collection of ClusterComputeResources with requested
+// properties is returned. If requested, a subset of all available properties
+// will be retrieved (faster) instead of recursively fetching all properties
+// (about 2x as slow). If specified, clusters are only retrieved from the
+// given containers (e.g., Datacenters).
+func GetClusters(ctx context.Context, c *vim25.Client, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.ClusterComputeResource, error) {
+
+	funcTimeStart := time.Now()
+
+	// declare this early so that we can grab a pointer to it in order to
+	// access the entries later
+	var clusters []mo.ClusterComputeResource
+
+	defer func(clusters *[]mo.ClusterComputeResource) {
+		logger.Printf(
+			"It took %v to execute GetClusters func (and retrieve %d clusters).\n",
+			time.Since(funcTimeStart),
+			len(*clusters),
+		)
+	}(&clusters)
+
+	err := getObjectsFromContainers(ctx, c, &clusters, containers, propsSubset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve ClusterComputeResources: %w", err)
+	}
+
+	sort.Slice(clusters, func(i, j int) bool {
+		return strings.ToLower(clusters[i].Name) < strings.ToLower(clusters[j].Name)
+	})
+
+	return clusters, nil
+}
+
+// getClustersByName retrieves all clusters with the specified name within
+// the given containers.
+func getClustersByName(ctx context.Context, c *vim25.Client, clusterName string, containers []types.ManagedObjectReference) ([]mo.ClusterComputeResource, error) {
+
+	clusters, err := GetClusters(ctx, c, true, containers...)
+	if err != nil {
+		return nil, err
+	}
+
+	return filterClustersByName(clusters, clusterName), nil
+}
+
+// filterClustersByName returns the clusters matching the specified name.
+// Cluster names are compared case-insensitively.
+func filterClustersByName(clusters []mo.ClusterComputeResource, clusterName string) []mo.ClusterComputeResource {
+
+	var matches []mo.ClusterComputeResource
+	for _, cluster := range clusters {
+		if strings.EqualFold(cluster.Name, clusterName) {
+			matches = append(matches, cluster)
+		}
+	}
+
+	return matches
+}
+
+// clusterReference returns a reference to the cluster with the specified name
+// from the given clusters. An error is returned if the cluster is not found
+// or if multiple clusters with the same name are found.
+func clusterReference(clusters []mo.ClusterComputeResource, clusterName string) (types.ManagedObjectReference, error) {
+
+	matches := filterClustersByName(clusters, clusterName)
+
+	switch {
+	case len(matches) == 0:
+		return types.ManagedObjectReference{}, fmt.Errorf("%w: %s", ErrClusterNotFound, clusterName)
+
+	case len(matches) > 1:
+		return types.ManagedObjectReference{}, fmt.Errorf(
+			"%w: %d clusters named %s found; specify datacenter name",
+			ErrClusterNotUnique,
+			len(matches),
+			clusterName,
+		)
+
+	default:
+		return matches[0].Reference(), nil
+	}
+}
+
+// ClusterContainers receives the name of a cluster and returns a reference to
+// the matching ClusterComputeResource for use as a container when retrieving
+// VirtualMachines, HostSystems or Resource Pools. This limits retrieval to
+// objects within the specified cluster. The cluster is located within the
+// given containers (e.g., Datacenters). If the cluster name is empty or the
+// client is connected to a standalone ESXi host (where clusters are not
+// applicable) the given containers are returned as-is.
+//
+// An error is returned if the cluster is not found or if multiple clusters
+// with the same name are found; in that case the Datacenter name should be
+// specified in order to select the intended cluster.
+func ClusterContainers(ctx context.Context, c *vim25.Client, clusterName string, containers ...types.ManagedObjectReference) ([]types.ManagedObjectReference, error) {
+
+	if clusterName == "" {
+		return containers, nil
+	}
+
+	if IsStandaloneHost(c) {
+		logger.Printf(
+			"Standalone ESXi host detected, ignoring cluster name %q\n",
+			clusterName,
+		)
+
+		return containers, nil
+	}
+
+	clusters, err := GetClusters(ctx, c, true, containers...)
+	if err != nil {
+		return nil, err
+	}
+
+	cluster, err := clusterReference(clusters, clusterName)
+	if err != nil {
+		return nil, err
+	}
+
+	return []types.ManagedObjectReference{cluster}, nil
+}
+
+// ValidateServersCluster is used by plugins evaluating multiple servers to
+// assert that the specified cluster is found within the specified
+// Datacenters (if any) on at least one of the servers. The cluster name is
+// ignored for standalone ESXi hosts. If the clusters for a server cannot be
+// retrieved, the error is recorded for the session and the server is
+// excluded from further evaluation.
+func ValidateServersCluster(ctx context.Context, sessions []ServerSession, clusterName string, dcNames []string) error {
+
+	if clusterName == "" {
+		return nil
+	}
+
+	results := GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		containers, err := DatacenterContainers(ctx, c, dcNames)
+		switch {
+		// none of the specified Datacenters are present on this server
+		case errors.Is(err, ErrDatacenterNotFound):
+			return ServerObjects{}, nil
+		case err != nil:
+			return ServerObjects{}, fmt.Errorf("error validating cluster name: %w", err)
+		}
+
+		clusters, err := getClustersByName(ctx, c, clusterName, containers)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating cluster name: %w", err)
+		}
+
+		return ServerObjects{Clusters: clusters}, nil
+	})
+
+	for _, result := range results {
+		if len(result.Clusters) > 0 {
+			return nil
+		}
+	}
+
+	// the cluster name is ignored for standalone ESXi hosts
+	for _, session := range sessions {
+		if session.Err == nil && IsStandaloneHost(session.Client.Client) {
+			return nil
+		}
+	}
+
+	return fmt.Errorf("%w: %s", ErrClusterNotFound, clusterName)
+}
+
+// InventoryScopeReport generates a summary of the Datacenters and cluster
+// used to limit evaluation of inventory objects for use with the Long
+// Service Output field. This summary is intended to be appended to the report
+// generated by plugins supporting these options.
+func InventoryScopeReport(dcNames []string, clusterName string) string {
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"* Specified Datacenters to evaluate (%d): [%v]%s",
+		len(dcNames),
+		strings.Join(dcNames, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	if clusterName == "" {
+		clusterName = "not specified (all clusters evaluated)"
+	}
+
+	fmt.Fprintf(
+		&report,
+		"* Specified cluster to evaluate: %s%s",
+		clusterName,
+		nagios.CheckOutputEOL,
+	)
+
+	return report.String()
+}
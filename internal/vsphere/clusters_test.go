@@ -0,0 +1,109 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestClusterReference(t *testing.T) {
+
+	newCluster := func(name string, moid string) mo.ClusterComputeResource {
+		var cluster mo.ClusterComputeResource
+		cluster.Name = name
+		cluster.Self = types.ManagedObjectReference{Type: MgObjRefTypeClusterComputeResource, Value: moid}
+
+		return cluster
+	}
+
+	clusters := []mo.ClusterComputeResource{
+		newCluster("Cluster1", "domain-c1"),
+		newCluster("Cluster2", "domain-c2"),
+		newCluster("Cluster2", "domain-c3"),
+	}
+
+	tests := map[string]struct {
+		clusterName string
+		want        string
+		wantErr     error
+	}{
+		"found": {
+			clusterName: "Cluster1",
+			want:        "domain-c1",
+		},
+		"found mixed case": {
+			clusterName: "CLUSTER1",
+			want:        "domain-c1",
+		},
+		"unknown cluster": {
+			clusterName: "Cluster3",
+			wantErr:     ErrClusterNotFound,
+		},
+		"duplicate name": {
+			clusterName: "Cluster2",
+			wantErr:     ErrClusterNotUnique,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, err := clusterReference(clusters, tt.clusterName)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("clusterReference(%q) returned error %v; want %v", tt.clusterName, err, tt.wantErr)
+			}
+
+			if got.Value != tt.want {
+				t.Errorf("clusterReference(%q) = %q; want %q", tt.clusterName, got.Value, tt.want)
+			}
+		})
+	}
+}
+
+func TestClusterContainersPassthrough(t *testing.T) {
+
+	containers := []types.ManagedObjectReference{
+		{Type: MgObjRefTypeDatacenter, Value: "datacenter-2"},
+	}
+
+	tests := map[string]struct {
+		apiType     string
+		clusterName string
+	}{
+		"no cluster specified": {
+			apiType:     APITypeVCenter,
+			clusterName: "",
+		},
+		"standalone ESXi host": {
+			apiType:     APITypeStandaloneHost,
+			clusterName: "Cluster1",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(tt.apiType)
+
+			got, err := ClusterContainers(context.Background(), c, tt.clusterName, containers...)
+			if err != nil {
+				t.Fatalf("ClusterContainers returned unexpected error: %v", err)
+			}
+
+			if !reflect.DeepEqual(got, containers) {
+				t.Errorf("got containers %v; want %v", got, containers)
+			}
+		})
+	}
+}
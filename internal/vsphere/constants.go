@@ -32,12 +32,14 @@ const CustomAttributeValNotSet string = "NotSet"
 
 // Managed Object Reference types
 const (
-	MgObjRefTypeFolder          string = "Folder"
-	MgObjRefTypeDatacenter      string = "Datacenter"
-	MgObjRefTypeComputeResource string = "ComputeResource"
-	MgObjRefTypeResourcePool    string = "ResourcePool"
-	MgObjRefTypeHostSystem      string = "HostSystem"
-	MgObjRefTypeVirtualMachine  string = "VirtualMachine"
+	MgObjRefTypeFolder                 string = "Folder"
+	MgObjRefTypeDatacenter             string = "Datacenter"
+	MgObjRefTypeComputeResource        string = "ComputeResource"
+	MgObjRefTypeResourcePool           string = "ResourcePool"
+	MgObjRefTypeVirtualApp             string = "VirtualApp"
+	MgObjRefTypeHostSystem             string = "HostSystem"
+	MgObjRefTypeVirtualMachine         string = "VirtualMachine"
+	MgObjRefTypeClusterComputeResource string = "ClusterComputeResource"
 )
 
 // used with snapshots reports that provide Long Service Output
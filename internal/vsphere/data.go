@@ -0,0 +1,112 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"sort"
+	"strings"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// The *Data types provided by this package represent the evaluated data for
+// each plugin in a form suitable for machine-readable (e.g., JSON) output.
+// Field names used when serializing these types are part of the versioned
+// JSON output schema; see docs/json-output.md.
+
+// VirtualMachineData represents a VirtualMachine evaluated by a plugin.
+type VirtualMachineData struct {
+	Name       string `json:"name"`
+	MOID       string `json:"moid"`
+	PowerState string `json:"power_state"`
+}
+
+// EvaluatedVMsData records the number of VirtualMachines and the names of the
+// Resource Pools evaluated by plugins which evaluate VirtualMachines from a
+// set of Resource Pools.
+type EvaluatedVMsData struct {
+	VMsEvaluated           int      `json:"vms_evaluated"`
+	ResourcePoolsEvaluated []string `json:"resource_pools_evaluated"`
+}
+
+// newVirtualMachineData converts the given VirtualMachine to a
+// VirtualMachineData value.
+func newVirtualMachineData(vm mo.VirtualMachine) VirtualMachineData {
+	return VirtualMachineData{
+		Name:       vm.Name,
+		MOID:       vm.Self.Value,
+		PowerState: string(vm.Runtime.PowerState),
+	}
+}
+
+// newVirtualMachinesData converts the given VirtualMachines to a collection
+// of VirtualMachineData values sorted by name. An empty (non-nil) collection
+// is returned if no VirtualMachines are provided.
+func newVirtualMachinesData(vms []mo.VirtualMachine) []VirtualMachineData {
+
+	vmsData := make([]VirtualMachineData, 0, len(vms))
+	for _, vm := range vms {
+		vmsData = append(vmsData, newVirtualMachineData(vm))
+	}
+
+	sort.Slice(vmsData, func(i, j int) bool {
+		return strings.ToLower(vmsData[i].Name) < strings.ToLower(vmsData[j].Name)
+	})
+
+	return vmsData
+}
+
+// newEvaluatedVMsData generates data common to all plugins which evaluate
+// VirtualMachines from a set of Resource Pools.
+func newEvaluatedVMsData(evaluatedVMs []mo.VirtualMachine, rps []mo.ResourcePool) EvaluatedVMsData {
+
+	rpNames := make([]string, 0, len(rps))
+	for _, rp := range rps {
+		rpNames = append(rpNames, rp.Name)
+	}
+
+	sort.Slice(rpNames, func(i, j int) bool {
+		return strings.ToLower(rpNames[i]) < strings.ToLower(rpNames[j])
+	})
+
+	return EvaluatedVMsData{
+		VMsEvaluated:           len(evaluatedVMs),
+		ResourcePoolsEvaluated: rpNames,
+	}
+}
+
+// stateLabel returns the Nagios state label for an evaluated item based on
+// whether it is considered to be in a CRITICAL or WARNING state.
+func stateLabel(critical bool, warning bool) string {
+	switch {
+	case critical:
+		return nagios.StateCRITICALLabel
+	case warning:
+		return nagios.StateWARNINGLabel
+	default:
+		return nagios.StateOKLabel
+	}
+}
+
+// exitCodeLabel returns the Nagios state label for the given exit code. The
+// go-nagios UNKNOWN state label is misspelled, so it is not used here.
+func exitCodeLabel(exitCode int) string {
+	switch exitCode {
+	case nagios.StateOKExitCode:
+		return nagios.StateOKLabel
+	case nagios.StateWARNINGExitCode:
+		return nagios.StateWARNINGLabel
+	case nagios.StateCRITICALExitCode:
+		return nagios.StateCRITICALLabel
+	case nagios.StateDEPENDENTExitCode:
+		return nagios.StateDEPENDENTLabel
+	default:
+		return "UNKNOWN"
+	}
+}
@@ -17,6 +17,7 @@ import (
 	"github.com/vmware/govmomi/view"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
 )
 
 // ErrDatacenterNotFound indicates that one or more specified datacenters were
@@ -26,7 +27,8 @@ var ErrDatacenterNotFound = errors.New("specified Datacenters not found")
 // ValidateDCs receives a list of Datacenter names and compares against all
 // visible Datacenter objects within the vSphere environment. If any are not
 // found an error is returned listing which ones. If an empty list of
-// Datacenter names is provided validation is considered successful.
+// Datacenter names is provided validation is considered successful. For
+// standalone ESXi hosts only the implicit "ha-datacenter" Datacenter is valid.
 func ValidateDCs(ctx context.Context, c *vim25.Client, datacenters []string) error {
 
 	funcTimeStart := time.Now()
@@ -45,6 +47,29 @@ func ValidateDCs(ctx context.Context, c *vim25.Client, datacenters []string) err
 		return nil
 	}
 
+	// Standalone ESXi hosts provide only the implicit "ha-datacenter"
+	// Datacenter; call out this specifically instead of listing the names
+	// as not found without further explanation.
+	if IsStandaloneHost(c) {
+		var notFound []string
+		for _, dc := range datacenters {
+			if !textutils.InList(dc, []string{StandaloneHostDatacenter}, true) {
+				notFound = append(notFound, dc)
+			}
+		}
+
+		if len(notFound) > 0 {
+			return fmt.Errorf(
+				"%w: %v (standalone ESXi host provides only the %q Datacenter)",
+				ErrDatacenterNotFound,
+				notFound,
+				StandaloneHostDatacenter,
+			)
+		}
+
+		return nil
+	}
+
 	m := view.NewManager(c)
 
 	// Create a view of Datacenter objects
@@ -113,6 +138,88 @@ func ValidateDCs(ctx context.Context, c *vim25.Client, datacenters []string) err
 
 }
 
+// ValidateServersDCs is the equivalent of ValidateDCs for plugins evaluating
+// multiple servers. An error is returned if any specified Datacenter names
+// are not found on at least one of the servers. If the Datacenters for a
+// server cannot be retrieved, the error is recorded for the session and the
+// server is excluded from further evaluation.
+func ValidateServersDCs(ctx context.Context, sessions []ServerSession, datacenters []string) error {
+
+	if len(datacenters) == 0 {
+		return nil
+	}
+
+	results := GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		dcs, err := GetDatacenters(ctx, c, datacenters, true)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating datacenter names: %w", err)
+		}
+
+		return ServerObjects{Datacenters: dcs}, nil
+	})
+
+	var dcNamesFound []string
+	for _, result := range results {
+		for _, dc := range result.Datacenters {
+			dcNamesFound = append(dcNamesFound, dc.Name)
+		}
+	}
+
+	var notFound []string
+	for _, dc := range datacenters {
+		if !textutils.InList(dc, dcNamesFound, true) {
+			notFound = append(notFound, dc)
+		}
+	}
+
+	if len(notFound) > 0 {
+		return fmt.Errorf(
+			"%w: %v",
+			ErrDatacenterNotFound,
+			notFound,
+		)
+	}
+
+	return nil
+}
+
+// DatacenterContainers receives a list of Datacenter names and returns
+// references to the matching Datacenters for use as containers when
+// retrieving VirtualMachines, HostSystems, Resource Pools or Datastores. This
+// limits retrieval to objects within the specified Datacenters. If an empty
+// list of Datacenter names is provided, a reference to the root folder is
+// returned so that objects within all Datacenters are retrieved. Datacenter
+// names which are not found are skipped; use ValidateDCs to assert that all
+// specified Datacenters exist. If none of the specified Datacenters are
+// found, ErrDatacenterNotFound is returned instead of an empty list so that
+// callers do not fall back to retrieving objects from all Datacenters.
+func DatacenterContainers(ctx context.Context, c *vim25.Client, dcNames []string) ([]types.ManagedObjectReference, error) {
+
+	if len(dcNames) == 0 {
+		return []types.ManagedObjectReference{c.ServiceContent.RootFolder}, nil
+	}
+
+	dcs, err := GetDatacenters(ctx, c, dcNames, true)
+	if err != nil {
+		return nil, err
+	}
+
+	containers := make([]types.ManagedObjectReference, 0, len(dcs))
+	for _, dc := range dcs {
+		containers = append(containers, dc.Reference())
+	}
+
+	if len(containers) == 0 {
+		return nil, fmt.Errorf(
+			"%w: %v",
+			ErrDatacenterNotFound,
+			dcNames,
+		)
+	}
+
+	return containers, nil
+}
+
 // GetDatacenters receives a list of Datacenter names along with a boolean
 // value indicating whether only a subset of properties for the Datacenters
 // should be returned. If requested, a subset of all available properties will
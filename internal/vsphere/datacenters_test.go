@@ -0,0 +1,223 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"strings"
+	"testing"
+
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/object"
+	"github.com/vmware/govmomi/simulator"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// newTestServer starts a simulated vCenter server with Datacenter DC0 in the
+// root folder and Datacenter DC1 in folder Site2. Two sessions are returned:
+// the first sees the full inventory and the second is limited to the Site2
+// folder, standing in for a second server which only provides DC1. The
+// simulator is stopped when the test completes.
+func newTestServer(t *testing.T) []ServerSession {
+	t.Helper()
+
+	ctx := context.Background()
+
+	model := simulator.VPX()
+	if err := model.Create(); err != nil {
+		t.Fatalf("failed to create simulator model: %v", err)
+	}
+
+	server := model.Service.NewServer()
+	t.Cleanup(func() {
+		server.Close()
+		model.Remove()
+	})
+
+	login := func() *govmomi.Client {
+		c, err := govmomi.NewClient(ctx, server.URL, true)
+		if err != nil {
+			t.Fatalf("failed to log in to simulator: %v", err)
+		}
+
+		return c
+	}
+
+	c1 := login()
+	site2, err := object.NewRootFolder(c1.Client).CreateFolder(ctx, "Site2")
+	if err != nil {
+		t.Fatalf("failed to create folder: %v", err)
+	}
+
+	if _, err := site2.CreateDatacenter(ctx, "DC1"); err != nil {
+		t.Fatalf("failed to create datacenter: %v", err)
+	}
+
+	c2 := login()
+	c2.ServiceContent.RootFolder = site2.Reference()
+
+	return []ServerSession{
+		{Server: "vc1.example.com", Client: c1},
+		{Server: "vc2.example.com", Client: c2},
+	}
+}
+
+func TestValidateDCsStandaloneHost(t *testing.T) {
+
+	tests := map[string]struct {
+		datacenters []string
+		wantErr     error
+	}{
+		"none specified": {
+			datacenters: nil,
+			wantErr:     nil,
+		},
+		"implicit datacenter": {
+			datacenters: []string{StandaloneHostDatacenter},
+			wantErr:     nil,
+		},
+		"implicit datacenter mixed case": {
+			datacenters: []string{"HA-Datacenter"},
+			wantErr:     nil,
+		},
+		"other datacenter": {
+			datacenters: []string{"DC1"},
+			wantErr:     ErrDatacenterNotFound,
+		},
+		"implicit and other datacenter": {
+			datacenters: []string{StandaloneHostDatacenter, "DC1"},
+			wantErr:     ErrDatacenterNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			c := newTestClient(APITypeStandaloneHost)
+
+			err := ValidateDCs(context.Background(), c, tt.datacenters)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateDCs(%v) returned error %v; want %v", tt.datacenters, err, tt.wantErr)
+			}
+
+			if err != nil && strings.Contains(err.Error(), "["+StandaloneHostDatacenter) {
+				t.Errorf("error %q lists %q as not found", err, StandaloneHostDatacenter)
+			}
+		})
+	}
+}
+
+func TestValidateServersDCs(t *testing.T) {
+
+	sessions := newTestServer(t)
+
+	// Assert that the second server does not provide DC0 so that the
+	// "present on one server" case below is meaningful.
+	err := ValidateServersDCs(context.Background(), sessions[1:], []string{"DC0"})
+	if !errors.Is(err, ErrDatacenterNotFound) {
+		t.Fatalf("ValidateServersDCs([DC0]) for second server returned error %v; want %v", err, ErrDatacenterNotFound)
+	}
+
+	tests := map[string]struct {
+		datacenters  []string
+		wantErr      error
+		wantNotFound string
+	}{
+		"none specified": {
+			datacenters: nil,
+		},
+		"present on all servers": {
+			datacenters: []string{"DC1"},
+		},
+		"present on one server": {
+			datacenters: []string{"DC0"},
+		},
+		"mixed case": {
+			datacenters: []string{"dc0", "dc1"},
+		},
+		"unknown datacenter": {
+			datacenters:  []string{"DC0", "DC2"},
+			wantErr:      ErrDatacenterNotFound,
+			wantNotFound: "DC2",
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			err := ValidateServersDCs(context.Background(), sessions, tt.datacenters)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("ValidateServersDCs(%v) returned error %v; want %v", tt.datacenters, err, tt.wantErr)
+			}
+
+			if err != nil && !strings.Contains(err.Error(), tt.wantNotFound) {
+				t.Errorf("error %q does not list %q as not found", err, tt.wantNotFound)
+			}
+
+			for _, session := range sessions {
+				if session.Err != nil {
+					t.Errorf("session with %s recorded unexpected error: %v", session.Server, session.Err)
+				}
+			}
+		})
+	}
+}
+
+func TestDatacenterContainers(t *testing.T) {
+
+	c := newTestServer(t)[0].Client.Client
+
+	dcs, err := GetDatacenters(context.Background(), c, nil, true)
+	if err != nil {
+		t.Fatalf("failed to retrieve datacenters: %v", err)
+	}
+
+	dcRefs := make(map[string]types.ManagedObjectReference, len(dcs))
+	for _, dc := range dcs {
+		dcRefs[dc.Name] = dc.Reference()
+	}
+
+	tests := map[string]struct {
+		datacenters []string
+		want        []types.ManagedObjectReference
+		wantErr     error
+	}{
+		"none specified": {
+			datacenters: nil,
+			want:        []types.ManagedObjectReference{c.ServiceContent.RootFolder},
+		},
+		"single datacenter": {
+			datacenters: []string{"DC1"},
+			want:        []types.ManagedObjectReference{dcRefs["DC1"]},
+		},
+		"unknown datacenter skipped": {
+			datacenters: []string{"DC0", "DC2"},
+			want:        []types.ManagedObjectReference{dcRefs["DC0"]},
+		},
+		"unknown datacenter": {
+			datacenters: []string{"DC2"},
+			wantErr:     ErrDatacenterNotFound,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			got, err := DatacenterContainers(context.Background(), c, tt.datacenters)
+			if !errors.Is(err, tt.wantErr) {
+				t.Fatalf("DatacenterContainers(%v) returned error %v; want %v", tt.datacenters, err, tt.wantErr)
+			}
+
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("DatacenterContainers(%v) = %v; want %v", tt.datacenters, got, tt.want)
+			}
+		})
+	}
+}
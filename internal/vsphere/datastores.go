@@ -15,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/check-vmware/internal/textutils"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/units"
@@ -38,14 +40,14 @@ type DatastoreUsageSummary struct {
 	StorageTotal            int64
 	StorageUsed             int64
 	StorageRemaining        int64
-	CriticalThreshold       int
-	WarningThreshold        int
+	CriticalThreshold       config.Range
+	WarningThreshold        config.Range
 }
 
 // NewDatastoreUsageSummary receives a Datastore and generates summary
 // information used to determine if usage levels have crossed user-specified
 // thresholds.
-func NewDatastoreUsageSummary(ds mo.Datastore, criticalThreshold int, warningThreshold int) DatastoreUsageSummary {
+func NewDatastoreUsageSummary(ds mo.Datastore, criticalThreshold config.Range, warningThreshold config.Range) DatastoreUsageSummary {
 
 	storageRemainingPercentage := float64(ds.Summary.FreeSpace) / float64(ds.Summary.Capacity) * 100
 	storageUsedPercentage := 100 - storageRemainingPercentage
@@ -71,22 +73,24 @@ func NewDatastoreUsageSummary(ds mo.Datastore, criticalThreshold int, warningThr
 // IsWarningState indicates whether Datastore usage has crossed the WARNING
 // level threshold.
 func (dus DatastoreUsageSummary) IsWarningState() bool {
-	return dus.StorageUsedPercent < float64(dus.CriticalThreshold) &&
-		dus.StorageUsedPercent >= float64(dus.WarningThreshold)
+	return !dus.CriticalThreshold.Alert(dus.StorageUsedPercent) &&
+		dus.WarningThreshold.Alert(dus.StorageUsedPercent)
 }
 
 // IsCriticalState indicates whether Datastore usage has crossed the CRITICAL
 // level threshold.
 func (dus DatastoreUsageSummary) IsCriticalState() bool {
-	return dus.StorageUsedPercent >= float64(dus.CriticalThreshold)
+	return dus.CriticalThreshold.Alert(dus.StorageUsedPercent)
 }
 
 // GetDatastores accepts a context, a connected client and a boolean value
 // indicating whether a subset of properties per Datastore are retrieved. A
 // collection of Datastores with requested properties is returned. If
 // requested, a subset of all available properties will be retrieved (faster)
-// instead of recursively fetching all properties (about 2x as slow).
-func GetDatastores(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo.Datastore, error) {
+// instead of recursively fetching all properties (about 2x as slow). If
+// specified, Datastores are only retrieved from the given containers (e.g.,
+// Datacenters).
+func GetDatastores(ctx context.Context, c *vim25.Client, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.Datastore, error) {
 
 	funcTimeStart := time.Now()
 
@@ -102,7 +106,7 @@ func GetDatastores(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo
 		)
 	}(&dss)
 
-	err := getObjects(ctx, c, &dss, c.ServiceContent.RootFolder, propsSubset)
+	err := getObjectsFromContainers(ctx, c, &dss, containers, propsSubset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve Datastores: %w", err)
 	}
@@ -246,7 +250,7 @@ func DatastoreUsageOneLineCheckSummary(
 	}()
 
 	return fmt.Sprintf(
-		"%s: Datastore %s usage is %.2f%% of %s with %s remaining [WARNING: %d%% , CRITICAL: %d%%]",
+		"%s: Datastore %s usage is %.2f%% of %s with %s remaining [WARNING: %s%% , CRITICAL: %s%%]",
 		stateLabel,
 		dsUsageSummary.Datastore.Name,
 		dsUsageSummary.StorageUsedPercent,
@@ -258,6 +262,80 @@ func DatastoreUsageOneLineCheckSummary(
 
 }
 
+// DatastoreUsagePerfData generates performance data for the specified
+// Datastore usage summary and VirtualMachines associated with the Datastore.
+func DatastoreUsagePerfData(
+	dsUsageSummary DatastoreUsageSummary,
+	dsVMs []mo.VirtualMachine,
+) []perfdata.PerformanceData {
+
+	return []perfdata.PerformanceData{
+		{
+			Label:             "datastore_usage",
+			Value:             perfdata.Float(dsUsageSummary.StorageUsedPercent),
+			UnitOfMeasurement: perfdata.UOMPercentage,
+			Warn:              dsUsageSummary.WarningThreshold.String(),
+			Crit:              dsUsageSummary.CriticalThreshold.String(),
+			Min:               "0",
+			Max:               "100",
+		},
+		{
+			Label:             "datastore_storage_used",
+			Value:             perfdata.Int(dsUsageSummary.StorageUsed),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+			Max:               perfdata.Int(dsUsageSummary.StorageTotal),
+		},
+		{
+			Label:             "datastore_storage_remaining",
+			Value:             perfdata.Int(dsUsageSummary.StorageRemaining),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+			Max:               perfdata.Int(dsUsageSummary.StorageTotal),
+		},
+		{
+			Label: "vms",
+			Value: perfdata.Int(int64(len(dsVMs))),
+		},
+	}
+}
+
+// DatastoreUsageData represents the evaluated usage of a Datastore along
+// with the VirtualMachines associated with the Datastore.
+type DatastoreUsageData struct {
+	Datastore               string               `json:"datastore"`
+	MOID                    string               `json:"moid"`
+	StorageTotal            int64                `json:"storage_total_bytes"`
+	StorageUsed             int64                `json:"storage_used_bytes"`
+	StorageRemaining        int64                `json:"storage_remaining_bytes"`
+	StorageUsedPercent      float64              `json:"storage_used_percent"`
+	StorageRemainingPercent float64              `json:"storage_remaining_percent"`
+	WarningThreshold        string               `json:"warning_threshold"`
+	CriticalThreshold       string               `json:"critical_threshold"`
+	VMs                     []VirtualMachineData `json:"vms"`
+}
+
+// NewDatastoreUsageData generates machine-readable data for the specified
+// Datastore usage summary and VirtualMachines associated with the Datastore.
+func NewDatastoreUsageData(
+	dsUsageSummary DatastoreUsageSummary,
+	dsVMs []mo.VirtualMachine,
+) DatastoreUsageData {
+
+	return DatastoreUsageData{
+		Datastore:               dsUsageSummary.Datastore.Name,
+		MOID:                    dsUsageSummary.Datastore.Self.Value,
+		StorageTotal:            dsUsageSummary.StorageTotal,
+		StorageUsed:             dsUsageSummary.StorageUsed,
+		StorageRemaining:        dsUsageSummary.StorageRemaining,
+		StorageUsedPercent:      dsUsageSummary.StorageUsedPercent,
+		StorageRemainingPercent: dsUsageSummary.StorageRemainingPercent,
+		WarningThreshold:        dsUsageSummary.WarningThreshold.String(),
+		CriticalThreshold:       dsUsageSummary.CriticalThreshold.String(),
+		VMs:                     newVirtualMachinesData(dsVMs),
+	}
+}
+
 // DatastoreUsageReport generates a summary of Datastore usage along with
 // various verbose details intended to aid in troubleshooting check results at
 // a glance. This information is provided for use with the Long Service Output
@@ -341,6 +419,13 @@ func DatastoreUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -348,5 +433,12 @@ func DatastoreUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	return report.String()
 }
@@ -0,0 +1,102 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+
+	"github.com/atc0005/check-vmware/internal/config"
+)
+
+// IgnoreFileReport generates a summary of the exclusions loaded from the
+// specified ignore file for use as part of the Long Service Output. Active
+// exclusions are listed along with the reason recorded for each. Expired
+// entries, which no longer suppress results, are flagged separately so that
+// they may be reviewed and removed. An empty string is returned if an
+// ignore file was not specified.
+func IgnoreFileReport(path string, entries []config.IgnoreEntry) string {
+
+	if path == "" {
+		return ""
+	}
+
+	now := time.Now()
+
+	active := make([]config.IgnoreEntry, 0, len(entries))
+	expired := make([]config.IgnoreEntry, 0, len(entries))
+	for _, entry := range entries {
+		switch {
+		case entry.Expired(now):
+			expired = append(expired, entry)
+		default:
+			active = append(active, entry)
+		}
+	}
+
+	entryDetails := func(entry config.IgnoreEntry) string {
+		var details strings.Builder
+
+		details.WriteString(entry.String())
+
+		if !entry.Until.IsZero() {
+			fmt.Fprintf(&details, " (until %s)", entry.Until.Format("2006-01-02"))
+		}
+
+		reason := entry.Reason
+		if reason == "" {
+			reason = "no reason recorded"
+		}
+		fmt.Fprintf(&details, ": %s", reason)
+
+		return details.String()
+	}
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"* Active exclusions from ignore file %s (%d)%s",
+		path,
+		len(active),
+		nagios.CheckOutputEOL,
+	)
+
+	for _, entry := range active {
+		fmt.Fprintf(
+			&report,
+			"** %s%s",
+			entryDetails(entry),
+			nagios.CheckOutputEOL,
+		)
+	}
+
+	if len(expired) > 0 {
+		fmt.Fprintf(
+			&report,
+			"* EXPIRED exclusions no longer applied; review and remove from ignore file (%d)%s",
+			len(expired),
+			nagios.CheckOutputEOL,
+		)
+
+		for _, entry := range expired {
+			fmt.Fprintf(
+				&report,
+				"** [EXPIRED] line %d: %s%s",
+				entry.Line,
+				entryDetails(entry),
+				nagios.CheckOutputEOL,
+			)
+		}
+	}
+
+	return report.String()
+}
@@ -0,0 +1,479 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atc0005/check-vmware/internal/textutils"
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// folderPathSeparator separates the elements of an inventory path (e.g.,
+// /Datacenter/vm/Team1/Web).
+const folderPathSeparator string = "/"
+
+// inventoryNode records the name and parent of a Folder or Datacenter for
+// use when resolving the inventory path of a Folder.
+type inventoryNode struct {
+	name       string
+	parent     string
+	datacenter bool
+	vmFolder   bool
+}
+
+// folderTree indexes Folders and Datacenters by MOID.
+type folderTree map[string]inventoryNode
+
+// newFolderTree builds an index of the given Folders and Datacenters. The
+// root folder is not included; inventory paths are resolved relative to it.
+func newFolderTree(folders []mo.Folder, dcs []mo.Datacenter) folderTree {
+
+	tree := make(folderTree, len(folders)+len(dcs))
+
+	for _, folder := range folders {
+		node := inventoryNode{
+			name:     folder.Name,
+			vmFolder: textutils.InList(MgObjRefTypeVirtualMachine, folder.ChildType, false),
+		}
+		if folder.Parent != nil {
+			node.parent = folder.Parent.Value
+		}
+		tree[folder.Self.Value] = node
+	}
+
+	for _, dc := range dcs {
+		node := inventoryNode{
+			name:       dc.Name,
+			datacenter: true,
+		}
+		if dc.Parent != nil {
+			node.parent = dc.Parent.Value
+		}
+		tree[dc.Self.Value] = node
+	}
+
+	return tree
+}
+
+// path returns the inventory path (e.g., /Datacenter/vm/Team1) of the
+// Folder or Datacenter with the given MOID.
+func (t folderTree) path(moid string) string {
+	node, ok := t[moid]
+	if !ok {
+		return ""
+	}
+
+	return t.path(node.parent) + folderPathSeparator + node.name
+}
+
+// datacenter returns the MOID of the Datacenter containing the Folder with
+// the given MOID, or an empty string if not found.
+func (t folderTree) datacenter(moid string) string {
+	for {
+		node, ok := t[moid]
+		switch {
+		case !ok:
+			return ""
+		case node.datacenter:
+			return moid
+		}
+		moid = node.parent
+	}
+}
+
+// matches indicates whether the Folder with the given MOID matches any of
+// the specified folder names or inventory paths. If requested, parent
+// Folders (up to the containing Datacenter) are also evaluated so that
+// objects within subfolders of a specified folder also match.
+func (t folderTree) matches(moid string, folders []string, recursive bool) bool {
+	for {
+		node, ok := t[moid]
+		if !ok || node.datacenter {
+			return false
+		}
+
+		if folderMatches(node.name, t.path(moid), folders) {
+			return true
+		}
+
+		if !recursive {
+			return false
+		}
+
+		moid = node.parent
+	}
+}
+
+// found indicates whether a VM folder matching the given folder name or
+// inventory path is present within the given Datacenters. If the list of
+// Datacenter MOIDs is nil, all VM folders are considered.
+func (t folderTree) found(folder string, dcs map[string]bool) bool {
+	for moid, node := range t {
+		if !node.vmFolder {
+			continue
+		}
+
+		if dcs != nil && !dcs[t.datacenter(moid)] {
+			continue
+		}
+
+		if folderMatches(node.name, t.path(moid), []string{folder}) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// folderMatches indicates whether the given folder name or inventory path
+// matches any of the specified folder patterns. Entries containing a path
+// separator are compared against the inventory path, all others against the
+// name.
+func folderMatches(name string, path string, folders []string) bool {
+	for _, folder := range folders {
+		target := name
+		if strings.Contains(folder, folderPathSeparator) {
+			target = path
+
+			// regular expressions are used as-is
+			if !strings.HasPrefix(folder, textutils.RegexPatternPrefix) {
+				folder = folderPathSeparator + strings.Trim(folder, folderPathSeparator)
+			}
+		}
+
+		if textutils.MatchPattern(target, folder) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// folderScope returns the set of Datacenter MOIDs from the given containers
+// used to limit folder validation, or nil if all Datacenters are in scope.
+func folderScope(c *vim25.Client, containers []types.ManagedObjectReference) map[string]bool {
+
+	if len(containers) == 0 {
+		return nil
+	}
+
+	dcs := make(map[string]bool, len(containers))
+	for _, container := range containers {
+		if container == c.ServiceContent.RootFolder {
+			return nil
+		}
+		dcs[container.Value] = true
+	}
+
+	return dcs
+}
+
+// GetFolders accepts a context, a connected client and a boolean value
+// indicating whether a subset of properties per Folder are retrieved. A
+// collection of Folders with requested properties is returned. If requested,
+// a subset of all available properties will be retrieved (faster) instead of
+// recursively fetching all properties (about 2x as slow). If specified,
+// Folders are only retrieved from the given containers (e.g., Datacenters).
+func GetFolders(ctx context.Context, c *vim25.Client, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.Folder, error) {
+
+	funcTimeStart := time.Now()
+
+	// declare this early so that we can grab a pointer to it in order to
+	// access the entries later
+	var folders []mo.Folder
+
+	defer func(folders *[]mo.Folder) {
+		logger.Printf(
+			"It took %v to execute GetFolders func (and retrieve %d Folders).\n",
+			time.Since(funcTimeStart),
+			len(*folders),
+		)
+	}(&folders)
+
+	err := getObjectsFromContainers(ctx, c, &folders, containers, propsSubset)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve Folders: %w", err)
+	}
+
+	sort.Slice(folders, func(i, j int) bool {
+		return strings.ToLower(folders[i].Name) < strings.ToLower(folders[j].Name)
+	})
+
+	return folders, nil
+}
+
+// getFolderTree retrieves all Folders and Datacenters in the vSphere
+// environment and indexes them for resolving inventory paths.
+func getFolderTree(ctx context.Context, c *vim25.Client) (folderTree, error) {
+
+	folders, err := GetFolders(ctx, c, true)
+	if err != nil {
+		return nil, err
+	}
+
+	dcs, err := GetDatacenters(ctx, c, nil, true)
+	if err != nil {
+		return nil, err
+	}
+
+	return newFolderTree(folders, dcs), nil
+}
+
+// ValidateFolders is responsible for receiving two lists of VM folders,
+// explicitly "included" (aka, "whitelisted") and explicitly "excluded" (aka,
+// "blacklisted"). Folders are specified by name (e.g., Team1) or by
+// inventory path (e.g., /Datacenter/vm/Team1), either of which may be a glob
+// or "re:" prefixed regular expression pattern. If any list entries do not
+// match a folder in the vSphere environment an error is returned listing
+// which ones.
+// If specified, only Folders within the given Datacenters are considered.
+func ValidateFolders(ctx context.Context, c *vim25.Client, includeFolders []string, excludeFolders []string, containers ...types.ManagedObjectReference) error {
+
+	funcTimeStart := time.Now()
+
+	defer func(ifs []string, efs []string) {
+		logger.Printf(
+			"It took %v to execute ValidateFolders func (and validate %d Folders).\n",
+			time.Since(funcTimeStart),
+			len(ifs)+len(efs),
+		)
+	}(includeFolders, excludeFolders)
+
+	if len(includeFolders) == 0 && len(excludeFolders) == 0 {
+		return nil
+	}
+
+	tree, err := getFolderTree(ctx, c)
+	if err != nil {
+		return err
+	}
+
+	scope := folderScope(c, containers)
+	found := func(folder string) bool {
+		return tree.found(folder, scope)
+	}
+
+	return validateNames("Folders", found, includeFolders, excludeFolders)
+}
+
+// ValidateServersFolders is the equivalent of ValidateFolders for plugins
+// evaluating multiple servers. An error is returned if any list entries are
+// not found on at least one of the servers. If the Folders for a server
+// cannot be retrieved, the error is recorded for the session and the server
+// is excluded from further evaluation. If Datacenter names are specified,
+// only Folders within those Datacenters are considered.
+func ValidateServersFolders(ctx context.Context, sessions []ServerSession, includeFolders []string, excludeFolders []string, dcNames []string) error {
+
+	if len(includeFolders) == 0 && len(excludeFolders) == 0 {
+		return nil
+	}
+
+	folders := append(append([]string{}, includeFolders...), excludeFolders...)
+
+	var mu sync.Mutex
+	foundFolders := make(map[string]bool, len(folders))
+
+	GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		containers, err := DatacenterContainers(ctx, c, dcNames)
+		switch {
+		// none of the specified Datacenters are present on this server
+		case errors.Is(err, ErrDatacenterNotFound):
+			return ServerObjects{}, nil
+		case err != nil:
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude folder lists: %w", err)
+		}
+
+		tree, err := getFolderTree(ctx, c)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude folder lists: %w", err)
+		}
+
+		scope := folderScope(c, containers)
+
+		mu.Lock()
+		defer mu.Unlock()
+		for _, folder := range folders {
+			if tree.found(folder, scope) {
+				foundFolders[folder] = true
+			}
+		}
+
+		return ServerObjects{}, nil
+	})
+
+	found := func(folder string) bool {
+		return foundFolders[folder]
+	}
+
+	return validateNames("Folders", found, includeFolders, excludeFolders)
+}
+
+// validateNames verifies that all entries in the given include or exclude
+// lists for the specified kind of filter (e.g., "Folders") are found using
+// the provided function.
+func validateNames(kind string, found func(name string) bool, include []string, exclude []string) error {
+
+	// If any specified entries are not found, note that so we can provide the
+	// full list of invalid entries together as a convenience for the user.
+	var notFound []string
+	switch {
+	case len(include) > 0:
+		for _, name := range include {
+			if !found(name) {
+				notFound = append(notFound, name)
+			}
+		}
+
+		if len(notFound) > 0 {
+			return fmt.Errorf(
+				"specified %s (to include) not found: %v",
+				kind,
+				notFound,
+			)
+		}
+
+		// all listed entries were found
+		return nil
+
+	case len(exclude) > 0:
+		for _, name := range exclude {
+			if !found(name) {
+				notFound = append(notFound, name)
+			}
+		}
+
+		if len(notFound) > 0 {
+			return fmt.Errorf(
+				"specified %s (to exclude) not found: %v",
+				kind,
+				notFound,
+			)
+		}
+
+		// all listed entries were found
+		return nil
+
+	default:
+
+		// no restrictions specified by user; all objects are "eligible" for
+		// evaluation
+		return nil
+	}
+
+}
+
+// FilterVMsByFolder receives a collection of VirtualMachines and two lists
+// of VM folders (by name or inventory path) that should either be explicitly
+// included or excluded. If requested, VMs within subfolders of the specified
+// folders are also matched. A new collection of VirtualMachines is returned.
+// VMs which are not placed directly within a folder (e.g., VMs within a
+// vApp) are considered to be outside of all folders. If both lists are
+// empty, the received collection is returned as-is.
+func FilterVMsByFolder(ctx context.Context, c *vim25.Client, vms []mo.VirtualMachine, includeFolders []string, excludeFolders []string, recursive bool) ([]mo.VirtualMachine, error) {
+
+	funcTimeStart := time.Now()
+
+	defer func(vms []mo.VirtualMachine) {
+		logger.Printf(
+			"It took %v to execute FilterVMsByFolder func (and filter %d VMs).\n",
+			time.Since(funcTimeStart),
+			len(vms),
+		)
+	}(vms)
+
+	if len(includeFolders) == 0 && len(excludeFolders) == 0 {
+		return vms, nil
+	}
+
+	tree, err := getFolderTree(ctx, c)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve VM folders: %w", err)
+	}
+
+	return filterVMsByFolder(vms, tree, includeFolders, excludeFolders, recursive), nil
+}
+
+// filterVMsByFolder filters the given VirtualMachines using the specified
+// folder include or exclude lists.
+func filterVMsByFolder(vms []mo.VirtualMachine, tree folderTree, includeFolders []string, excludeFolders []string, recursive bool) []mo.VirtualMachine {
+
+	filteredVMs := make([]mo.VirtualMachine, 0, len(vms))
+
+	for _, vm := range vms {
+
+		inFolder := func(folders []string) bool {
+			if vm.Parent == nil || vm.Parent.Type != MgObjRefTypeFolder {
+				return false
+			}
+			return tree.matches(vm.Parent.Value, folders, recursive)
+		}
+
+		// config validation asserts that only one of include/exclude folder
+		// flags are specified
+		switch {
+		case len(includeFolders) > 0:
+			if inFolder(includeFolders) {
+				filteredVMs = append(filteredVMs, vm)
+			}
+
+		case len(excludeFolders) > 0:
+			if !inFolder(excludeFolders) {
+				filteredVMs = append(filteredVMs, vm)
+			}
+
+		default:
+			filteredVMs = append(filteredVMs, vm)
+		}
+	}
+
+	return filteredVMs
+}
+
+// FoldersReport generates a summary of the VM folders used to limit
+// evaluation of VirtualMachines for use with the Long Service Output field.
+// This summary is intended to be appended to the report generated by plugins
+// supporting these options.
+func FoldersReport(includeFolders []string, excludeFolders []string, recursive bool) string {
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"* Specified Folders to explicitly include (%d): [%v]%s",
+		len(includeFolders),
+		strings.Join(includeFolders, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Specified Folders to explicitly exclude (%d): [%v]%s",
+		len(excludeFolders),
+		strings.Join(excludeFolders, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Subfolders of specified Folders evaluated: %t%s",
+		recursive,
+		nagios.CheckOutputEOL,
+	)
+
+	return report.String()
+}
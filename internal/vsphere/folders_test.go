@@ -0,0 +1,96 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFilterVMsByFolder(t *testing.T) {
+
+	newFolder := func(name string, moid string, parent types.ManagedObjectReference) mo.Folder {
+		var folder mo.Folder
+		folder.Name = name
+		folder.Self = types.ManagedObjectReference{Type: MgObjRefTypeFolder, Value: moid}
+		folder.Parent = &parent
+		folder.ChildType = []string{MgObjRefTypeFolder, MgObjRefTypeVirtualMachine}
+
+		return folder
+	}
+
+	newVM := func(name string, parent *types.ManagedObjectReference) mo.VirtualMachine {
+		var vm mo.VirtualMachine
+		vm.Name = name
+		vm.Parent = parent
+
+		return vm
+	}
+
+	rootFolder := types.ManagedObjectReference{Type: MgObjRefTypeFolder, Value: "group-d1"}
+
+	var dc mo.Datacenter
+	dc.Name = "DC1"
+	dc.Self = types.ManagedObjectReference{Type: MgObjRefTypeDatacenter, Value: "datacenter-1"}
+	dc.Parent = &rootFolder
+
+	vmFolder := newFolder("vm", "group-v1", dc.Self)
+	team1 := newFolder("Team1", "group-v2", vmFolder.Self)
+	web := newFolder("Web", "group-v3", team1.Self)
+	team2 := newFolder("Team2", "group-v4", vmFolder.Self)
+
+	tree := newFolderTree([]mo.Folder{vmFolder, team1, web, team2}, []mo.Datacenter{dc})
+
+	vms := []mo.VirtualMachine{
+		newVM("app1", &team1.Self),
+		newVM("web1", &web.Self),
+		newVM("db1", &team2.Self),
+		newVM("vapp1", nil),
+	}
+
+	tests := []struct {
+		name      string
+		include   []string
+		exclude   []string
+		recursive bool
+		want      []string
+	}{
+		{name: "no filters", want: []string{"app1", "web1", "db1", "vapp1"}},
+		{name: "include by name", include: []string{"team1"}, want: []string{"app1"}},
+		{name: "include by name recursive", include: []string{"Team1"}, recursive: true, want: []string{"app1", "web1"}},
+		{name: "include by path", include: []string{"/DC1/vm/Team1/Web/"}, want: []string{"web1"}},
+		{name: "exclude by name", exclude: []string{"Team2"}, want: []string{"app1", "web1", "vapp1"}},
+		{name: "exclude by path recursive", exclude: []string{"/DC1/vm/Team1"}, recursive: true, want: []string{"db1", "vapp1"}},
+		{name: "include by name pattern", include: []string{"team*"}, want: []string{"app1", "db1"}},
+		{name: "include by path pattern", include: []string{"/DC1/vm/*/Web"}, want: []string{"web1"}},
+		{name: "exclude by regex", exclude: []string{"re:^team[0-9]$"}, want: []string{"web1", "vapp1"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := VMNames(filterVMsByFolder(vms, tree, tt.include, tt.exclude, tt.recursive))
+			if !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("got VMs %v; want %v", got, tt.want)
+			}
+		})
+	}
+
+	t.Run("found within datacenter", func(t *testing.T) {
+		if !tree.found("/DC1/vm/Team2", map[string]bool{dc.Self.Value: true}) {
+			t.Error("folder not found within datacenter")
+		}
+
+		if tree.found("Team2", map[string]bool{"datacenter-2": true}) {
+			t.Error("folder found outside of specified datacenter")
+		}
+	})
+}
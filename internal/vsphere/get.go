@@ -30,6 +30,7 @@ func getVirtualMachinePropsSubset() []string {
 		"name",
 		"network",
 		"runtime", // Host system is listed here
+		"parent",  // Folder containing the VM (if any)
 		"customValue",
 		"availableField",
 	}
@@ -52,6 +53,7 @@ func getResourcePoolPropsSubset() []string {
 		"resourcePool", // potential child resource pools
 		"config",
 		"name",
+		"parent", // parent resource pool (if any)
 		"runtime",
 	}
 }
@@ -87,10 +89,31 @@ func getDatacenterPropsSubset() []string {
 	// https://vdc-download.vmware.com/vmwb-repository/dcr-public/a5f4000f-1ea8-48a9-9221-586adff3c557/7ff50256-2cf2-45ea-aacd-87d231ab1ac7/vim.Datacenter.html
 	return []string{
 		"name",
+		"parent",
 		"overallStatus",
 		"triggeredAlarmState",
 	}
 }
+func getFolderPropsSubset() []string {
+	// https://code.vmware.com/apis/1067/vsphere
+	// https://vdc-download.vmware.com/vmwb-repository/dcr-public/a5f4000f-1ea8-48a9-9221-586adff3c557/7ff50256-2cf2-45ea-aacd-87d231ab1ac7/vim.Folder.html
+	return []string{
+		"name",
+		"parent",
+		"childType",
+	}
+}
+func getClusterComputeResourcePropsSubset() []string {
+	// https://code.vmware.com/apis/1067/vsphere
+	// https://vdc-download.vmware.com/vmwb-repository/dcr-public/a5f4000f-1ea8-48a9-9221-586adff3c557/7ff50256-2cf2-45ea-aacd-87d231ab1ac7/vim.ClusterComputeResource.html
+	return []string{
+		"name",
+		"parent",
+		"host",
+		"datastore",
+		"overallStatus",
+	}
+}
 func getAlarmPropsSubset() []string {
 	// https://code.vmware.com/apis/1067/vsphere
 	// https://vdc-download.vmware.com/vmwb-repository/dcr-public/a5f4000f-1ea8-48a9-9221-586adff3c557/7ff50256-2cf2-45ea-aacd-87d231ab1ac7/vim.alarm.Alarm.html
@@ -131,7 +154,9 @@ func getObjects(ctx context.Context, c *vim25.Client, dst interface{}, objRef ty
 	case MgObjRefTypeFolder:
 	case MgObjRefTypeDatacenter:
 	case MgObjRefTypeComputeResource:
+	case MgObjRefTypeClusterComputeResource:
 	case MgObjRefTypeResourcePool:
+	case MgObjRefTypeVirtualApp:
 	case MgObjRefTypeHostSystem:
 	default:
 		return fmt.Errorf(
@@ -152,6 +177,28 @@ func getObjects(ctx context.Context, c *vim25.Client, dst interface{}, objRef ty
 			props = getDatacenterPropsSubset()
 		}
 
+	case *[]mo.Folder:
+		defer func() {
+			objCount = len(*u)
+		}()
+
+		objKind = "Folder"
+
+		if propsSubset {
+			props = getFolderPropsSubset()
+		}
+
+	case *[]mo.ClusterComputeResource:
+		defer func() {
+			objCount = len(*u)
+		}()
+
+		objKind = "ClusterComputeResource"
+
+		if propsSubset {
+			props = getClusterComputeResourcePropsSubset()
+		}
+
 	case *[]mo.Alarm:
 		defer func() {
 			objCount = len(*u)
@@ -220,7 +267,9 @@ func getObjects(ctx context.Context, c *vim25.Client, dst interface{}, objRef ty
 
 	}
 
-	// FIXME: Should this filter to a specific datacenter? See GH-219.
+	// Objects are retrieved recursively from the specified container;
+	// callers scope retrieval to specific Datacenters by providing the
+	// Datacenter as the container. See GH-219.
 	v, err := m.CreateContainerView(
 		ctx,
 		objRef,
@@ -252,6 +301,24 @@ func getObjects(ctx context.Context, c *vim25.Client, dst interface{}, objRef ty
 
 }
 
+// getObjectsFromContainers retrieves objects from each of the provided
+// containers, appending the results to dst. If no containers are provided,
+// objects are retrieved from the root folder (i.e., all Datacenters).
+func getObjectsFromContainers(ctx context.Context, c *vim25.Client, dst interface{}, containers []types.ManagedObjectReference, propsSubset bool) error {
+
+	if len(containers) == 0 {
+		containers = []types.ManagedObjectReference{c.ServiceContent.RootFolder}
+	}
+
+	for _, container := range containers {
+		if err := getObjects(ctx, c, dst, container, propsSubset); err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
 func getObjectByName(ctx context.Context, c *vim25.Client, dst interface{}, objName string, datacenter string, propsSubset bool) error {
 
 	funcTimeStart := time.Now()
@@ -266,6 +333,16 @@ func getObjectByName(ctx context.Context, c *vim25.Client, dst interface{}, objN
 		)
 	}(&objKind)
 
+	// Standalone ESXi hosts provide only the implicit "ha-datacenter"
+	// Datacenter; use it regardless of the specified name.
+	if IsStandaloneHost(c) && datacenter != "" {
+		logger.Printf(
+			"Standalone ESXi host detected, ignoring datacenter name %q\n",
+			datacenter,
+		)
+		datacenter = ""
+	}
+
 	finder := find.NewFinder(c, true)
 
 	switch {
@@ -16,6 +16,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/find"
 	"github.com/vmware/govmomi/property"
@@ -70,7 +71,8 @@ func NewHardwareVersion(verStr string) HardwareVersion {
 // be made to use the default Datacenter and default ComputeResource (obtained
 // using cluster name). If a host name is supplied, it will be used to obtain
 // the default hardware version. If a host name and a cluster name are
-// provided, an error will be returned.
+// provided, an error will be returned. Cluster and datacenter names are
+// ignored for standalone ESXi hosts.
 //
 // The default version may not be the very latest version supported in the
 // cluster (e.g., v14 is the default, but v15 is the latest supported).
@@ -85,6 +87,17 @@ func DefaultHardwareVersion(ctx context.Context, c *vim25.Client, hostName strin
 		)
 	}()
 
+	// Standalone ESXi hosts provide a single implicit Datacenter and
+	// ComputeResource; Datacenter and cluster names are not applicable.
+	if IsStandaloneHost(c) && (clusterName != "" || datacenterName != "") {
+		logger.Printf(
+			"Standalone ESXi host detected, ignoring cluster name %q and datacenter name %q\n",
+			clusterName,
+			datacenterName,
+		)
+		clusterName, datacenterName = "", ""
+	}
+
 	if hostName != "" && clusterName != "" {
 		return HardwareVersion{}, fmt.Errorf(
 			"func DefaultHardwareVersion: only one of cluster or host name supported",
@@ -236,6 +249,11 @@ func (hvi HardwareVersionsIndex) Outdated() HardwareVersions {
 // formatted string in addition to the actual version number.
 func (hvi HardwareVersionsIndex) Newest() HardwareVersion {
 
+	// no VMs were indexed (e.g., all VMs excluded from evaluation)
+	if len(hvi) == 0 {
+		return HardwareVersion{}
+	}
+
 	keys := make([]string, 0, len(hvi))
 	for k := range hvi {
 		keys = append(keys, k)
@@ -454,6 +472,77 @@ func VirtualHardwareOneLineCheckSummary(
 	}
 }
 
+// VirtualHardwarePerfData generates performance data for the virtual
+// hardware versions used by the specified VirtualMachines.
+func VirtualHardwarePerfData(
+	hwvIndex HardwareVersionsIndex,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("hardware_versions", hwvIndex.Count(), ""),
+	}
+
+	// The index is empty if there were no VirtualMachines to evaluate.
+	if hwvIndex.Count() > 0 {
+		pd = append(pd,
+			countPerfData("vms_outdated", hwvIndex.Outdated().Sum(), ""),
+			perfdata.PerformanceData{
+				Label: "hardware_version_newest",
+				Value: perfdata.Int(int64(hwvIndex.Newest().VersionNumber())),
+			},
+			perfdata.PerformanceData{
+				Label: "hardware_version_oldest",
+				Value: perfdata.Int(int64(hwvIndex.Oldest().VersionNumber())),
+			},
+		)
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VirtualHardwareData represents the evaluated virtual hardware versions for
+// a set of VirtualMachines.
+type VirtualHardwareData struct {
+	EvaluatedVMsData
+	Versions []HardwareVersionData `json:"versions"`
+}
+
+// HardwareVersionData represents a virtual hardware version along with the
+// number of VirtualMachines using the version.
+type HardwareVersionData struct {
+	Version       string `json:"version"`
+	VersionNumber int    `json:"version_number"`
+	VMs           int    `json:"vms"`
+	Newest        bool   `json:"newest"`
+}
+
+// NewVirtualHardwareData generates machine-readable data for the virtual
+// hardware versions used by the specified VirtualMachines.
+func NewVirtualHardwareData(
+	hwvIndex HardwareVersionsIndex,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) VirtualHardwareData {
+
+	hwVersions := hwvIndex.Versions()
+	versions := make([]HardwareVersionData, 0, len(hwVersions))
+	for _, hwv := range hwVersions {
+		versions = append(versions, HardwareVersionData{
+			Version:       hwv.String(),
+			VersionNumber: hwv.VersionNumber(),
+			VMs:           hwv.Count(),
+			Newest:        hwv.IsHighest(),
+		})
+	}
+
+	return VirtualHardwareData{
+		EvaluatedVMsData: newEvaluatedVMsData(evaluatedVMs, rps),
+		Versions:         versions,
+	}
+}
+
 // VirtualHardwareReport generates a summary of virtual hardware details
 // intended to aid in troubleshooting check results at a glance. This
 // information is provided for use with the Long Service Output field commonly
@@ -591,6 +680,13 @@ func VirtualHardwareReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -598,6 +694,13 @@ func VirtualHardwareReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Default Virtual Hardware Version: %d (%s) %s",
@@ -0,0 +1,473 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
+)
+
+// ErrHostSystemStateNotOK indicates that one or more evaluated hosts are in
+// a state mapped to a non-OK Nagios state.
+var ErrHostSystemStateNotOK = errors.New("one or more hosts in a non-OK state")
+
+// hostStates returns the supported host state keywords in the order used
+// when listing hosts by state.
+func hostStates() []string {
+	return []string{
+		config.HostStateNotResponding,
+		config.HostStateDisconnected,
+		config.HostStatePoweredOff,
+		config.HostStateStandby,
+		config.HostStateQuarantine,
+		config.HostStateMaintenance,
+		config.HostStateUnknown,
+		config.HostStateConnected,
+	}
+}
+
+// HostSystemState returns the keyword (e.g., maintenance) describing the
+// connection, power and maintenance state of the given HostSystem. Problems
+// with the connection to the host take precedence over the power state of
+// the host, which in turn takes precedence over maintenance or quarantine
+// mode; a disconnected host in maintenance mode is reported as disconnected.
+// This classification is also used to determine which hosts are available
+// when calculating memory capacity.
+func HostSystemState(hs mo.HostSystem) string {
+
+	switch {
+	case hs.Runtime.ConnectionState == types.HostSystemConnectionStateDisconnected:
+		return config.HostStateDisconnected
+
+	case hs.Runtime.ConnectionState == types.HostSystemConnectionStateNotResponding:
+		return config.HostStateNotResponding
+
+	case hs.Runtime.PowerState == types.HostSystemPowerStatePoweredOff:
+		return config.HostStatePoweredOff
+
+	case hs.Runtime.PowerState == types.HostSystemPowerStateStandBy:
+		return config.HostStateStandby
+
+	case hs.Runtime.InMaintenanceMode:
+		return config.HostStateMaintenance
+
+	case hs.Runtime.InQuarantineMode != nil && *hs.Runtime.InQuarantineMode:
+		return config.HostStateQuarantine
+
+	case hs.Runtime.PowerState == types.HostSystemPowerStatePoweredOn &&
+		hs.Runtime.ConnectionState == types.HostSystemConnectionStateConnected:
+		return config.HostStateConnected
+
+	default:
+		return config.HostStateUnknown
+	}
+}
+
+// nagiosStateLabelExitCode converts a Nagios state keyword (e.g., warning)
+// to a Nagios state label and exit code. Unrecognized keywords are treated
+// as an UNKNOWN state.
+func nagiosStateLabelExitCode(nagiosState string) (string, int) {
+
+	switch nagiosState {
+	case config.NagiosStateOK:
+		return nagios.StateOKLabel, nagios.StateOKExitCode
+
+	case config.NagiosStateWarning:
+		return nagios.StateWARNINGLabel, nagios.StateWARNINGExitCode
+
+	case config.NagiosStateCritical:
+		return nagios.StateCRITICALLabel, nagios.StateCRITICALExitCode
+
+	default:
+		return nagios.StateUNKNOWNLabel, nagios.StateUNKNOWNExitCode
+	}
+}
+
+// HostStatesThreshold returns a description of the host states mapped to the
+// given Nagios state keyword for display as a plugin threshold.
+func HostStatesThreshold(mappings map[string]string, nagiosState string) string {
+
+	var states []string
+	for _, state := range hostStates() {
+		if mappings[state] == nagiosState {
+			states = append(states, state)
+		}
+	}
+
+	if len(states) == 0 {
+		return config.ThresholdNotUsed
+	}
+
+	return fmt.Sprintf("Hosts in state: %s", strings.Join(states, ", "))
+}
+
+// HostSystemStatus records the evaluated state of a HostSystem along with
+// the Nagios state mapped to it.
+type HostSystemStatus struct {
+	HostSystem mo.HostSystem
+
+	// State is the host state keyword (e.g., maintenance).
+	State string
+
+	// NagiosState is the Nagios state keyword (e.g., warning) mapped to the
+	// host state.
+	NagiosState string
+}
+
+// HostSystemStatuses is a collection of evaluated HostSystem states.
+type HostSystemStatuses []HostSystemStatus
+
+// NewHostSystemStatuses evaluates the state of each given HostSystem using
+// the provided host state to Nagios state mappings. The results are sorted
+// by host name.
+func NewHostSystemStatuses(hss []mo.HostSystem, mappings map[string]string) HostSystemStatuses {
+
+	statuses := make(HostSystemStatuses, 0, len(hss))
+	for _, hs := range hss {
+		state := HostSystemState(hs)
+		statuses = append(statuses, HostSystemStatus{
+			HostSystem:  hs,
+			State:       state,
+			NagiosState: mappings[state],
+		})
+	}
+
+	sort.Slice(statuses, func(i, j int) bool {
+		return strings.ToLower(statuses[i].HostSystem.Name) < strings.ToLower(statuses[j].HostSystem.Name)
+	})
+
+	return statuses
+}
+
+// NumNagiosState indicates how many hosts in the collection are mapped to
+// the given Nagios state keyword.
+func (hss HostSystemStatuses) NumNagiosState(nagiosState string) int {
+	var num int
+	for _, hs := range hss {
+		if hs.NagiosState == nagiosState {
+			num++
+		}
+	}
+
+	return num
+}
+
+// NumState indicates how many hosts in the collection are in the given host
+// state.
+func (hss HostSystemStatuses) NumState(state string) int {
+	var num int
+	for _, hs := range hss {
+		if hs.State == state {
+			num++
+		}
+	}
+
+	return num
+}
+
+// NumNotOK indicates how many hosts in the collection are mapped to a
+// non-OK Nagios state.
+func (hss HostSystemStatuses) NumNotOK() int {
+	return len(hss) - hss.NumNagiosState(config.NagiosStateOK)
+}
+
+// NagiosState returns the Nagios state label and exit code for the most
+// severe Nagios state mapped to a host in the collection. CRITICAL takes
+// precedence over WARNING, which takes precedence over UNKNOWN.
+func (hss HostSystemStatuses) NagiosState() (string, int) {
+
+	switch {
+	case hss.NumNagiosState(config.NagiosStateCritical) > 0:
+		return nagiosStateLabelExitCode(config.NagiosStateCritical)
+
+	case hss.NumNagiosState(config.NagiosStateWarning) > 0:
+		return nagiosStateLabelExitCode(config.NagiosStateWarning)
+
+	case hss.NumNotOK() > 0:
+		return nagiosStateLabelExitCode(config.NagiosStateUnknown)
+
+	default:
+		return nagiosStateLabelExitCode(config.NagiosStateOK)
+	}
+}
+
+// stateCounts returns a summary of the number of hosts in each host state
+// found in the collection (e.g., "connected: 3, maintenance: 1").
+func (hss HostSystemStatuses) stateCounts() string {
+
+	counts := make([]string, 0, len(hostStates()))
+	for _, state := range hostStates() {
+		if num := hss.NumState(state); num > 0 {
+			counts = append(counts, fmt.Sprintf("%s: %d", state, num))
+		}
+	}
+
+	return strings.Join(counts, ", ")
+}
+
+// HostSystemStatusOneLineCheckSummary is used to generate a one-line Nagios
+// service check results summary. This is the line most prominent in
+// notifications.
+func HostSystemStatusOneLineCheckSummary(
+	stateLabel string,
+	statuses HostSystemStatuses,
+) string {
+
+	funcTimeStart := time.Now()
+
+	defer func() {
+		logger.Printf(
+			"It took %v to execute HostSystemStatusOneLineCheckSummary func.\n",
+			time.Since(funcTimeStart),
+		)
+	}()
+
+	switch {
+	case len(statuses) == 0:
+		return fmt.Sprintf(
+			"%s: No hosts found to evaluate",
+			stateLabel,
+		)
+
+	case statuses.NumNotOK() > 0:
+		return fmt.Sprintf(
+			"%s: %d of %d hosts in a non-OK state (%s)",
+			stateLabel,
+			statuses.NumNotOK(),
+			len(statuses),
+			statuses.stateCounts(),
+		)
+
+	default:
+		return fmt.Sprintf(
+			"%s: All %d hosts in an OK state (%s)",
+			stateLabel,
+			len(statuses),
+			statuses.stateCounts(),
+		)
+	}
+}
+
+// HostSystemStatusPerfData generates performance data for the evaluated
+// host states. A count of hosts is provided for each supported host state.
+func HostSystemStatusPerfData(statuses HostSystemStatuses) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("hosts_evaluated", len(statuses), ""),
+		countPerfData("hosts_not_ok", statuses.NumNotOK(), ""),
+	}
+
+	for _, state := range hostStates() {
+		pd = append(pd, countPerfData(
+			"hosts_"+strings.ReplaceAll(state, "-", "_"),
+			statuses.NumState(state),
+			"",
+		))
+	}
+
+	return pd
+}
+
+// HostSystemStatusHostData represents the evaluated state of a HostSystem.
+type HostSystemStatusHostData struct {
+	Name            string `json:"name"`
+	MOID            string `json:"moid"`
+	State           string `json:"state"`
+	NagiosState     string `json:"nagios_state"`
+	ConnectionState string `json:"connection_state"`
+	PowerState      string `json:"power_state"`
+	MaintenanceMode bool   `json:"maintenance_mode"`
+}
+
+// HostSystemStatusData represents the evaluated state of a set of
+// HostSystems.
+type HostSystemStatusData struct {
+	HostsEvaluated int                        `json:"hosts_evaluated"`
+	StateMappings  map[string]string          `json:"state_mappings"`
+	Hosts          []HostSystemStatusHostData `json:"hosts"`
+}
+
+// NewHostSystemStatusData generates machine-readable data for the evaluated
+// host states.
+func NewHostSystemStatusData(statuses HostSystemStatuses, mappings map[string]string) HostSystemStatusData {
+
+	hosts := make([]HostSystemStatusHostData, 0, len(statuses))
+	for _, hs := range statuses {
+
+		// Use the exit code to determine the state label; the go-nagios
+		// UNKNOWN state label is misspelled.
+		_, exitCode := nagiosStateLabelExitCode(hs.NagiosState)
+
+		hosts = append(hosts, HostSystemStatusHostData{
+			Name:            hs.HostSystem.Name,
+			MOID:            hs.HostSystem.Self.Value,
+			State:           hs.State,
+			NagiosState:     exitCodeLabel(exitCode),
+			ConnectionState: string(hs.HostSystem.Runtime.ConnectionState),
+			PowerState:      string(hs.HostSystem.Runtime.PowerState),
+			MaintenanceMode: hs.HostSystem.Runtime.InMaintenanceMode,
+		})
+	}
+
+	return HostSystemStatusData{
+		HostsEvaluated: len(statuses),
+		StateMappings:  mappings,
+		Hosts:          hosts,
+	}
+}
+
+// HostSystemStatusReport generates a summary of evaluated hosts grouped by
+// host state along with various verbose details intended to aid in
+// troubleshooting check results at a glance. This information is provided
+// for use with the Long Service Output field commonly displayed on the
+// detailed service check results display in the web UI or in the body of
+// many notifications.
+func HostSystemStatusReport(
+	c *vim25.Client,
+	statuses HostSystemStatuses,
+	mappings map[string]string,
+) string {
+
+	funcTimeStart := time.Now()
+
+	defer func() {
+		logger.Printf(
+			"It took %v to execute HostSystemStatusReport func.\n",
+			time.Since(funcTimeStart),
+		)
+	}()
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"Hosts by state:%s%s",
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+	)
+
+	if len(statuses) == 0 {
+		fmt.Fprintf(&report, "* None %s", nagios.CheckOutputEOL)
+	}
+
+	// List states mapped to the most severe Nagios states first.
+	nagiosStates := []string{
+		config.NagiosStateCritical,
+		config.NagiosStateWarning,
+		config.NagiosStateUnknown,
+		config.NagiosStateOK,
+	}
+
+	for _, nagiosState := range nagiosStates {
+		for _, state := range hostStates() {
+			if mappings[state] != nagiosState {
+				continue
+			}
+
+			var hostNames []string
+			for _, hs := range statuses {
+				if hs.State == state {
+					hostNames = append(hostNames, hs.HostSystem.Name)
+				}
+			}
+
+			if len(hostNames) == 0 {
+				continue
+			}
+
+			_, exitCode := nagiosStateLabelExitCode(nagiosState)
+
+			fmt.Fprintf(
+				&report,
+				"* %s [%s] (%d)%s",
+				state,
+				exitCodeLabel(exitCode),
+				len(hostNames),
+				nagios.CheckOutputEOL,
+			)
+
+			for _, name := range hostNames {
+				fmt.Fprintf(
+					&report,
+					"** %s%s",
+					name,
+					nagios.CheckOutputEOL,
+				)
+			}
+		}
+	}
+
+	fmt.Fprintf(
+		&report,
+		"%s---%s%s",
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* vSphere environment: %s%s",
+		c.URL().String(),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Plugin User Agent: %s%s",
+		c.Client.UserAgent,
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Hosts (evaluated: %d, non-OK: %d)%s",
+		len(statuses),
+		statuses.NumNotOK(),
+		nagios.CheckOutputEOL,
+	)
+
+	stateMappings := make([]string, 0, len(mappings))
+	for _, state := range hostStates() {
+		stateMappings = append(stateMappings, state+"="+mappings[state])
+	}
+
+	fmt.Fprintf(
+		&report,
+		"* Host state mappings: [%s]%s",
+		strings.Join(stateMappings, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	return report.String()
+}
@@ -15,6 +15,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/check-vmware/internal/textutils"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/vim25"
@@ -323,7 +324,7 @@ func (hdi HostToDatastoreIndex) ValidateVirtualMachinePairings(
 						)
 					}
 
-					if textutils.InList(datastore.Name, dsNamesToIgnore, true) {
+					if textutils.InPatternList(datastore.Name, dsNamesToIgnore) {
 
 						// TODO: Switch this off after sufficient testing
 						// has been completed. For now, explicitly send to
@@ -346,7 +347,7 @@ func (hdi HostToDatastoreIndex) ValidateVirtualMachinePairings(
 			}
 
 			switch {
-			case textutils.InList(dsName, dsNamesToIgnore, true):
+			case textutils.InPatternList(dsName, dsNamesToIgnore):
 				// if datastore name is in the ignore list, don't report
 				// the mismatch, move on and check the next datastore
 				continue
@@ -404,6 +405,63 @@ func H2D2VMsOneLineCheckSummary(
 	}
 }
 
+// H2D2VMsPerfData generates performance data for VirtualMachines with
+// Host/Datastore pairing issues.
+func H2D2VMsPerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmDatastoresPairingIssues VMToMismatchedDatastoreNames,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("vms_with_pairing_issues", len(vmDatastoresPairingIssues), "0"),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// H2D2VMsData represents the evaluated Host/Datastore pairings for a set of
+// VirtualMachines.
+type H2D2VMsData struct {
+	EvaluatedVMsData
+	PairingIssues []VMDatastoresPairingData `json:"pairing_issues"`
+}
+
+// VMDatastoresPairingData represents a VirtualMachine with Datastores that
+// are not associated with its current HostSystem.
+type VMDatastoresPairingData struct {
+	VM                   string   `json:"vm"`
+	HostSystem           string   `json:"host_system"`
+	MismatchedDatastores []string `json:"mismatched_datastores"`
+}
+
+// NewH2D2VMsData generates machine-readable data for VirtualMachines with
+// Host/Datastore pairing issues.
+func NewH2D2VMsData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmDatastoresPairingIssues VMToMismatchedDatastoreNames,
+	rps []mo.ResourcePool,
+) H2D2VMsData {
+
+	issues := make([]VMDatastoresPairingData, 0, len(vmDatastoresPairingIssues))
+	for vmName, pairing := range vmDatastoresPairingIssues {
+		issues = append(issues, VMDatastoresPairingData{
+			VM:                   vmName,
+			HostSystem:           pairing.HostName,
+			MismatchedDatastores: pairing.DatastoreNames,
+		})
+	}
+
+	sort.Slice(issues, func(i, j int) bool {
+		return strings.ToLower(issues[i].VM) < strings.ToLower(issues[j].VM)
+	})
+
+	return H2D2VMsData{
+		EvaluatedVMsData: newEvaluatedVMsData(evaluatedVMs, rps),
+		PairingIssues:    issues,
+	}
+}
+
 // H2D2VMsReport generates a summary of host/datastore/vms pairings along with
 // additional details intended to aid in troubleshooting check results at a
 // glance. This information is provided for use with the Long Service Output
@@ -621,6 +679,13 @@ func H2D2VMsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -628,6 +693,13 @@ func H2D2VMsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -15,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/units"
 	"github.com/vmware/govmomi/vim25"
@@ -45,8 +47,8 @@ type HostSystemMemorySummary struct {
 
 	// MemoryTotal is the total amount of memory for the host in bytes.
 	MemoryTotal       int64
-	CriticalThreshold int
-	WarningThreshold  int
+	CriticalThreshold config.Range
+	WarningThreshold  config.Range
 }
 
 // HostSystemCPUSummary tracks CPU usage details for a specific HostSystem.
@@ -63,14 +65,14 @@ type HostSystemCPUSummary struct {
 
 	// CPUTotal is the total amount of CPU capacity for the host in Hz.
 	CPUTotal          float64
-	CriticalThreshold int
-	WarningThreshold  int
+	CriticalThreshold config.Range
+	WarningThreshold  config.Range
 }
 
 // NewHostSystemMemoryUsageSummary receives a HostSystem and generates summary
 // information used to determine if usage levels have crossed user-specified
 // thresholds.
-func NewHostSystemMemoryUsageSummary(hs mo.HostSystem, criticalThreshold int, warningThreshold int) HostSystemMemorySummary {
+func NewHostSystemMemoryUsageSummary(hs mo.HostSystem, criticalThreshold config.Range, warningThreshold config.Range) HostSystemMemorySummary {
 
 	// total memory in bytes
 	memoryTotal := hs.Hardware.MemorySize
@@ -102,7 +104,7 @@ func NewHostSystemMemoryUsageSummary(hs mo.HostSystem, criticalThreshold int, wa
 // NewHostSystemCPUUsageSummary receives a HostSystem and generates summary
 // information used to determine if usage levels have crossed user-specified
 // thresholds.
-func NewHostSystemCPUUsageSummary(hs mo.HostSystem, criticalThreshold int, warningThreshold int) HostSystemCPUSummary {
+func NewHostSystemCPUUsageSummary(hs mo.HostSystem, criticalThreshold config.Range, warningThreshold config.Range) HostSystemCPUSummary {
 
 	numCPUCores := hs.Summary.Hardware.NumCpuCores
 
@@ -135,35 +137,37 @@ func NewHostSystemCPUUsageSummary(hs mo.HostSystem, criticalThreshold int, warni
 // IsWarningState indicates whether HostSystem memory usage has crossed the
 // WARNING level threshold.
 func (hss HostSystemMemorySummary) IsWarningState() bool {
-	return hss.MemoryUsedPercent < float64(hss.CriticalThreshold) &&
-		hss.MemoryUsedPercent >= float64(hss.WarningThreshold)
+	return !hss.CriticalThreshold.Alert(hss.MemoryUsedPercent) &&
+		hss.WarningThreshold.Alert(hss.MemoryUsedPercent)
 }
 
 // IsCriticalState indicates whether HostSystem memory usage has crossed the
 // CRITICAL level threshold.
 func (hss HostSystemMemorySummary) IsCriticalState() bool {
-	return hss.MemoryUsedPercent >= float64(hss.CriticalThreshold)
+	return hss.CriticalThreshold.Alert(hss.MemoryUsedPercent)
 }
 
 // IsWarningState indicates whether HostSystem CPU usage has crossed the
 // WARNING level threshold.
 func (hss HostSystemCPUSummary) IsWarningState() bool {
-	return hss.CPUUsedPercent < float64(hss.CriticalThreshold) &&
-		hss.CPUUsedPercent >= float64(hss.WarningThreshold)
+	return !hss.CriticalThreshold.Alert(hss.CPUUsedPercent) &&
+		hss.WarningThreshold.Alert(hss.CPUUsedPercent)
 }
 
 // IsCriticalState indicates whether HostSystem CPU usage has crossed the
 // CRITICAL level threshold.
 func (hss HostSystemCPUSummary) IsCriticalState() bool {
-	return hss.CPUUsedPercent >= float64(hss.CriticalThreshold)
+	return hss.CriticalThreshold.Alert(hss.CPUUsedPercent)
 }
 
 // GetHostSystems accepts a context, a connected client and a boolean value
 // indicating whether a subset of properties per HostSystem are retrieved. A
 // collection of HostSystems with requested properties is returned. If
 // requested, a subset of all available properties will be retrieved (faster)
-// instead of recursively fetching all properties (about 2x as slow).
-func GetHostSystems(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo.HostSystem, error) {
+// instead of recursively fetching all properties (about 2x as slow). If
+// specified, HostSystems are only retrieved from the given containers (e.g.,
+// Datacenters).
+func GetHostSystems(ctx context.Context, c *vim25.Client, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.HostSystem, error) {
 
 	funcTimeStart := time.Now()
 
@@ -179,7 +183,7 @@ func GetHostSystems(ctx context.Context, c *vim25.Client, propsSubset bool) ([]m
 		)
 	}(&hss)
 
-	err := getObjects(ctx, c, &hss, c.ServiceContent.RootFolder, propsSubset)
+	err := getObjectsFromContainers(ctx, c, &hss, containers, propsSubset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve HostSystems: %w", err)
 	}
@@ -283,12 +287,169 @@ func FilterHostSystemByID(hss []mo.HostSystem, hsID string) (mo.HostSystem, erro
 
 }
 
+// FilterHostSystemsByName receives a collection of HostSystems and a list of
+// HostSystem names and returns the HostSystems matching any of the names
+// (case-insensitive).
+func FilterHostSystemsByName(hss []mo.HostSystem, hostNames []string) []mo.HostSystem {
+
+	filtered := make([]mo.HostSystem, 0, len(hostNames))
+	for _, hs := range hss {
+		for _, hostName := range hostNames {
+			if strings.EqualFold(hs.Name, hostName) {
+				filtered = append(filtered, hs)
+				break
+			}
+		}
+	}
+
+	return filtered
+}
+
+// validateHostSystemNames verifies that all of the specified HostSystem
+// names are found in the given list of HostSystem names.
+func validateHostSystemNames(hostNamesFound []string, hostNames []string) error {
+
+	var notFound []string
+	for _, hostName := range hostNames {
+		var found bool
+		for _, name := range hostNamesFound {
+			if strings.EqualFold(name, hostName) {
+				found = true
+				break
+			}
+		}
+
+		if !found {
+			notFound = append(notFound, hostName)
+		}
+	}
+
+	if len(notFound) > 0 {
+		return fmt.Errorf("specified hosts not found: %v", notFound)
+	}
+
+	return nil
+}
+
+// GetHostSystemsByName retrieves the HostSystems matching the given list of
+// HostSystem names (case-insensitive). The propsSubset boolean value
+// indicates whether a subset of properties per HostSystem are retrieved. If
+// specified, HostSystems are only retrieved from the given containers (e.g.,
+// Datacenters or a cluster). An error is returned listing any names which do
+// not match a HostSystem. If no names are specified, nil is returned.
+func GetHostSystemsByName(ctx context.Context, c *vim25.Client, hostNames []string, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.HostSystem, error) {
+
+	if len(hostNames) == 0 {
+		return nil, nil
+	}
+
+	hss, err := GetHostSystems(ctx, c, propsSubset, containers...)
+	if err != nil {
+		return nil, err
+	}
+
+	hostNamesFound := make([]string, 0, len(hss))
+	for _, hs := range hss {
+		hostNamesFound = append(hostNamesFound, hs.Name)
+	}
+
+	if err := validateHostSystemNames(hostNamesFound, hostNames); err != nil {
+		return nil, err
+	}
+
+	return FilterHostSystemsByName(hss, hostNames), nil
+}
+
+// ValidateServersHostSystems is the equivalent of GetHostSystemsByName for
+// plugins evaluating multiple servers. An error is returned if any of the
+// specified HostSystem names are not found on at least one of the servers.
+// If the HostSystems for a server cannot be retrieved, the error is recorded
+// for the session and the server is excluded from further evaluation. If
+// Datacenter names are specified, only HostSystems within those Datacenters
+// are considered.
+func ValidateServersHostSystems(ctx context.Context, sessions []ServerSession, hostNames []string, dcNames []string) error {
+
+	if len(hostNames) == 0 {
+		return nil
+	}
+
+	results := GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		containers, err := DatacenterContainers(ctx, c, dcNames)
+		switch {
+		// none of the specified Datacenters are present on this server
+		case errors.Is(err, ErrDatacenterNotFound):
+			return ServerObjects{}, nil
+		case err != nil:
+			return ServerObjects{}, fmt.Errorf("error validating host names: %w", err)
+		}
+
+		hss, err := GetHostSystems(ctx, c, true, containers...)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating host names: %w", err)
+		}
+
+		return ServerObjects{HostSystems: hss}, nil
+	})
+
+	var hostNamesFound []string
+	for _, result := range results {
+		for _, hs := range result.HostSystems {
+			hostNamesFound = append(hostNamesFound, hs.Name)
+		}
+	}
+
+	return validateHostSystemNames(hostNamesFound, hostNames)
+}
+
+// FilterVMsByHostSystems receives a collection of VirtualMachines and a
+// collection of HostSystems and returns the VirtualMachines running on any
+// of the HostSystems. If no HostSystems are specified, the collection of
+// VirtualMachines is returned unmodified.
+func FilterVMsByHostSystems(vms []mo.VirtualMachine, hss []mo.HostSystem) []mo.VirtualMachine {
+
+	if len(hss) == 0 {
+		return vms
+	}
+
+	hostsIdx := make(map[string]struct{}, len(hss))
+	for _, hs := range hss {
+		hostsIdx[hs.Self.Value] = struct{}{}
+	}
+
+	filtered := make([]mo.VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if vm.Runtime.Host == nil {
+			continue
+		}
+
+		if _, ok := hostsIdx[vm.Runtime.Host.Value]; ok {
+			filtered = append(filtered, vm)
+		}
+	}
+
+	return filtered
+}
+
+// HostSystemsReport generates a summary of the HostSystems used to limit
+// evaluation of VirtualMachines for use with the Long Service Output field.
+// This summary is intended to be appended to the report generated by plugins
+// supporting this option.
+func HostSystemsReport(hostNames []string) string {
+	return fmt.Sprintf(
+		"* Specified hosts to evaluate (%d): [%v]%s",
+		len(hostNames),
+		strings.Join(hostNames, ", "),
+		nagios.CheckOutputEOL,
+	)
+}
+
 // GetHostSystemsTotalMemory returns the total memory capacity for all
 // HostSystems. Unless requested, offline or otherwise unavailable hosts are
 // included for evaluation based on the assumption that offline hosts are
 // offline for only a brief time and should still be considered part of
-// overall cluster capacity.
-func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffline bool) (int64, error) {
+// overall cluster capacity. If specified, only HostSystems within the given
+// containers (e.g., Datacenters) are evaluated.
+func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffline bool, containers ...types.ManagedObjectReference) (int64, error) {
 
 	funcTimeStart := time.Now()
 
@@ -299,7 +460,7 @@ func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffl
 		)
 	}()
 
-	clusterHosts, err := GetHostSystems(ctx, c, true)
+	clusterHosts, err := GetHostSystems(ctx, c, true, containers...)
 	if err != nil {
 		return 0, fmt.Errorf(
 			"failed to gather total memory capacity for host systems: %w",
@@ -307,6 +468,16 @@ func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffl
 		)
 	}
 
+	return HostSystemsTotalMemory(clusterHosts, excludeOffline), nil
+
+}
+
+// HostSystemsTotalMemory returns the total memory capacity for the given
+// HostSystems. Unless requested, offline or otherwise unavailable hosts are
+// included for evaluation. If requested, only hosts classified as connected
+// by HostSystemState are evaluated.
+func HostSystemsTotalMemory(clusterHosts []mo.HostSystem, excludeOffline bool) int64 {
+
 	var clusterMemory int64
 	for _, host := range clusterHosts {
 
@@ -315,40 +486,9 @@ func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffl
 
 			logger.Printf("Checking host %s availability ... \n", host.Name)
 
-			switch {
-
-			case host.Runtime.PowerState == types.HostSystemPowerStatePoweredOn &&
-				host.Runtime.ConnectionState == types.HostSystemConnectionStateConnected:
-				// desired state, no other limiting factors detected
-
-			case host.Runtime.InMaintenanceMode:
-				logger.Printf("Host %s is in maintenance mode, skipping evaluation ...\n", host.Name)
-				continue
-
-			case host.Runtime.InQuarantineMode != nil && *host.Runtime.InQuarantineMode:
-				logger.Printf("Host %s is in quarantine mode, skipping evaluation ...\n", host.Name)
-				continue
-
-			case host.Runtime.PowerState == types.HostSystemPowerStatePoweredOff:
-				logger.Printf("Host %s is powered off, skipping evaluation ...\n", host.Name)
-				continue
-
-			case host.Runtime.PowerState == types.HostSystemPowerStateStandBy:
-				logger.Printf("Host %s is in standby, skipping evaluation ...\n", host.Name)
-				continue
-
-			case host.Runtime.ConnectionState == types.HostSystemConnectionStateDisconnected:
-				logger.Printf("Host %s is disconnected, skipping evaluation ...\n", host.Name)
-				continue
-
-			case host.Runtime.ConnectionState == types.HostSystemConnectionStateNotResponding:
-				logger.Printf("Host %s is not responding, skipping evaluation ...\n", host.Name)
+			if state := HostSystemState(host); state != config.HostStateConnected {
+				logger.Printf("Host %s is in state %s, skipping evaluation ...\n", host.Name, state)
 				continue
-
-			default:
-				logger.Printf("Host %s is in an UNKNOWN state, skipping evaluation ...\n", host.Name)
-				continue
-
 			}
 		}
 
@@ -361,7 +501,7 @@ func GetHostSystemsTotalMemory(ctx context.Context, c *vim25.Client, excludeOffl
 		clusterMemory += host.Hardware.MemorySize
 	}
 
-	return clusterMemory, nil
+	return clusterMemory
 
 }
 
@@ -415,6 +555,81 @@ func HostSystemMemoryUsageOneLineCheckSummary(
 
 }
 
+// HostSystemMemoryUsagePerfData generates performance data for the specified
+// HostSystem memory usage summary and VirtualMachines running on the HostSystem.
+func HostSystemMemoryUsagePerfData(
+	hsVMs []mo.VirtualMachine,
+	hsUsageSummary HostSystemMemorySummary,
+) []perfdata.PerformanceData {
+
+	return []perfdata.PerformanceData{
+		{
+			Label:             "memory_usage",
+			Value:             perfdata.Float(hsUsageSummary.MemoryUsedPercent),
+			UnitOfMeasurement: perfdata.UOMPercentage,
+			Warn:              hsUsageSummary.WarningThreshold.String(),
+			Crit:              hsUsageSummary.CriticalThreshold.String(),
+			Min:               "0",
+			Max:               "100",
+		},
+		{
+			Label:             "memory_used",
+			Value:             perfdata.Int(hsUsageSummary.MemoryUsed),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+			Max:               perfdata.Int(hsUsageSummary.MemoryTotal),
+		},
+		{
+			Label:             "memory_remaining",
+			Value:             perfdata.Int(hsUsageSummary.MemoryRemaining),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+			Max:               perfdata.Int(hsUsageSummary.MemoryTotal),
+		},
+		{
+			Label: "vms",
+			Value: perfdata.Int(int64(len(hsVMs))),
+		},
+	}
+}
+
+// HostSystemMemoryUsageData represents the evaluated memory usage of a
+// HostSystem along with the VirtualMachines running on the HostSystem.
+type HostSystemMemoryUsageData struct {
+	HostSystem             string               `json:"host_system"`
+	MOID                   string               `json:"moid"`
+	MemoryTotal            int64                `json:"memory_total_bytes"`
+	MemoryUsed             int64                `json:"memory_used_bytes"`
+	MemoryRemaining        int64                `json:"memory_remaining_bytes"`
+	MemoryUsedPercent      float64              `json:"memory_used_percent"`
+	MemoryRemainingPercent float64              `json:"memory_remaining_percent"`
+	WarningThreshold       string               `json:"warning_threshold"`
+	CriticalThreshold      string               `json:"critical_threshold"`
+	VMs                    []VirtualMachineData `json:"vms"`
+}
+
+// NewHostSystemMemoryUsageData generates machine-readable data for the
+// specified HostSystem memory usage summary and VirtualMachines running on
+// the HostSystem.
+func NewHostSystemMemoryUsageData(
+	hsVMs []mo.VirtualMachine,
+	hsUsageSummary HostSystemMemorySummary,
+) HostSystemMemoryUsageData {
+
+	return HostSystemMemoryUsageData{
+		HostSystem:             hsUsageSummary.HostSystem.Name,
+		MOID:                   hsUsageSummary.HostSystem.Self.Value,
+		MemoryTotal:            hsUsageSummary.MemoryTotal,
+		MemoryUsed:             hsUsageSummary.MemoryUsed,
+		MemoryRemaining:        hsUsageSummary.MemoryRemaining,
+		MemoryUsedPercent:      hsUsageSummary.MemoryUsedPercent,
+		MemoryRemainingPercent: hsUsageSummary.MemoryRemainingPercent,
+		WarningThreshold:       hsUsageSummary.WarningThreshold.String(),
+		CriticalThreshold:      hsUsageSummary.CriticalThreshold.String(),
+		VMs:                    newVirtualMachinesData(hsVMs),
+	}
+}
+
 // HostSystemMemoryUsageReport generates a summary of HostSystem memory usage
 // along with various verbose details intended to aid in troubleshooting check
 // results at a glance. This information is provided for use with the Long
@@ -572,6 +787,13 @@ func HostSystemMemoryUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -579,6 +801,13 @@ func HostSystemMemoryUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	return report.String()
 }
 
@@ -630,6 +859,82 @@ func HostSystemCPUUsageOneLineCheckSummary(
 
 }
 
+// HostSystemCPUUsagePerfData generates performance data for the specified
+// HostSystem CPU usage summary and VirtualMachines running on the HostSystem.
+func HostSystemCPUUsagePerfData(
+	hsVMs []mo.VirtualMachine,
+	hsUsageSummary HostSystemCPUSummary,
+) []perfdata.PerformanceData {
+
+	return []perfdata.PerformanceData{
+		{
+			Label:             "cpu_usage",
+			Value:             perfdata.Float(hsUsageSummary.CPUUsedPercent),
+			UnitOfMeasurement: perfdata.UOMPercentage,
+			Warn:              hsUsageSummary.WarningThreshold.String(),
+			Crit:              hsUsageSummary.CriticalThreshold.String(),
+			Min:               "0",
+			Max:               "100",
+		},
+		{
+			// Nagios does not provide a UOM for frequency; values are
+			// recorded in MHz to match the units used by vSphere.
+			Label: "cpu_used_mhz",
+			Value: perfdata.Float(hsUsageSummary.CPUUsed / MHz),
+			Min:   "0",
+			Max:   perfdata.Float(hsUsageSummary.CPUTotal / MHz),
+		},
+		{
+			Label: "cpu_remaining_mhz",
+			Value: perfdata.Float(hsUsageSummary.CPURemaining / MHz),
+			Min:   "0",
+			Max:   perfdata.Float(hsUsageSummary.CPUTotal / MHz),
+		},
+		{
+			Label: "vms",
+			Value: perfdata.Int(int64(len(hsVMs))),
+		},
+	}
+}
+
+// HostSystemCPUUsageData represents the evaluated CPU usage of a HostSystem
+// along with the VirtualMachines running on the HostSystem. CPU values are
+// recorded in MHz to match the units used by vSphere.
+type HostSystemCPUUsageData struct {
+	HostSystem          string               `json:"host_system"`
+	MOID                string               `json:"moid"`
+	CPUTotal            float64              `json:"cpu_total_mhz"`
+	CPUUsed             float64              `json:"cpu_used_mhz"`
+	CPURemaining        float64              `json:"cpu_remaining_mhz"`
+	CPUUsedPercent      float64              `json:"cpu_used_percent"`
+	CPURemainingPercent float64              `json:"cpu_remaining_percent"`
+	WarningThreshold    string               `json:"warning_threshold"`
+	CriticalThreshold   string               `json:"critical_threshold"`
+	VMs                 []VirtualMachineData `json:"vms"`
+}
+
+// NewHostSystemCPUUsageData generates machine-readable data for the
+// specified HostSystem CPU usage summary and VirtualMachines running on the
+// HostSystem.
+func NewHostSystemCPUUsageData(
+	hsVMs []mo.VirtualMachine,
+	hsUsageSummary HostSystemCPUSummary,
+) HostSystemCPUUsageData {
+
+	return HostSystemCPUUsageData{
+		HostSystem:          hsUsageSummary.HostSystem.Name,
+		MOID:                hsUsageSummary.HostSystem.Self.Value,
+		CPUTotal:            hsUsageSummary.CPUTotal / MHz,
+		CPUUsed:             hsUsageSummary.CPUUsed / MHz,
+		CPURemaining:        hsUsageSummary.CPURemaining / MHz,
+		CPUUsedPercent:      hsUsageSummary.CPUUsedPercent,
+		CPURemainingPercent: hsUsageSummary.CPURemainingPercent,
+		WarningThreshold:    hsUsageSummary.WarningThreshold.String(),
+		CriticalThreshold:   hsUsageSummary.CriticalThreshold.String(),
+		VMs:                 newVirtualMachinesData(hsVMs),
+	}
+}
+
 // HostSystemCPUUsageReport generates a summary of HostSystem CPU usage along
 // with various verbose details intended to aid in troubleshooting check
 // results at a glance. This information is provided for use with the Long
@@ -789,6 +1094,13 @@ func HostSystemCPUUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -796,5 +1108,12 @@ func HostSystemCPUUsageReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	return report.String()
 }
@@ -0,0 +1,75 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestFilterVMsByHostSystems(t *testing.T) {
+
+	newHost := func(name string, moid string) mo.HostSystem {
+		var hs mo.HostSystem
+		hs.Name = name
+		hs.Self = types.ManagedObjectReference{Type: MgObjRefTypeHostSystem, Value: moid}
+
+		return hs
+	}
+
+	newVM := func(name string, host *mo.HostSystem) mo.VirtualMachine {
+		var vm mo.VirtualMachine
+		vm.Name = name
+		if host != nil {
+			hostRef := host.Self
+			vm.Runtime.Host = &hostRef
+		}
+
+		return vm
+	}
+
+	esx11 := newHost("esx11.example.com", "host-11")
+	esx12 := newHost("esx12.example.com", "host-12")
+	hosts := []mo.HostSystem{esx11, esx12}
+
+	vms := []mo.VirtualMachine{
+		newVM("vm1", &esx11),
+		newVM("vm2", &esx12),
+		newVM("vm3", &esx12),
+		newVM("vm4", nil),
+	}
+
+	tests := []struct {
+		name      string
+		hostNames []string
+		want      []string
+	}{
+		{name: "no hosts", want: []string{"vm1", "vm2", "vm3", "vm4"}},
+		{name: "single host", hostNames: []string{"ESX12.example.com"}, want: []string{"vm2", "vm3"}},
+		{name: "multiple hosts", hostNames: []string{"esx11.example.com", "esx12.example.com"}, want: []string{"vm1", "vm2", "vm3"}},
+	}
+
+	for _, tt := range tests {
+		tt := tt
+		t.Run(tt.name, func(t *testing.T) {
+			got := FilterVMsByHostSystems(vms, FilterHostSystemsByName(hosts, tt.hostNames))
+			if gotNames := VMNames(got); !reflect.DeepEqual(gotNames, tt.want) {
+				t.Errorf("got VMs %v; want %v", gotNames, tt.want)
+			}
+		})
+	}
+
+	t.Run("host not found", func(t *testing.T) {
+		if err := validateHostSystemNames([]string{esx11.Name, esx12.Name}, []string{"esx13.example.com"}); err == nil {
+			t.Error("expected error for unknown host name")
+		}
+	})
+}
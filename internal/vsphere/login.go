@@ -10,24 +10,46 @@ package vsphere
 import (
 	"context"
 	"fmt"
+	"net/http"
 	"net/url"
 	"strings"
+	"time"
 
 	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/session"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
 )
 
 // Login receives credentials and related settings used to handle creating a
 // new client and logging into a specified vSphere environment. The
-// initialized and logged-in client is returned for further use.
+// initialized and logged-in client is returned for further use. If a session
+// cache directory is specified, a cached session for the server, port and
+// user is reused if still valid and new sessions are cached for later use.
+// The server certificate is validated using the CA certificates from the
+// specified file (or the system certificate store) unless a thumbprint is
+// specified to pin the certificate or trustCert is set to skip validation.
+// If a proxy URL is specified it is used to connect to the server, otherwise
+// the proxy (if any) is determined from the environment. Requests failing
+// with a transient error are retried up to the specified number of times,
+// doubling the retry delay after each attempt. If specified, the request
+// timeout limits each request attempt made after logging in.
 func Login(
 	ctx context.Context,
 	server string,
 	port int,
 	trustCert bool,
+	caFile string,
+	thumbprint string,
+	proxy string,
 	username string,
 	domain string,
 	password string,
 	userAgent string,
+	sessionCacheDir string,
+	retries int,
+	retryDelay time.Duration,
+	requestTimeout time.Duration,
 ) (*govmomi.Client, error) {
 
 	// TODO: Do we really need to support user domains?
@@ -45,24 +67,119 @@ func Login(
 		username = strings.Join([]string{username, domain}, "@")
 	}
 
-	c, err := govmomi.NewClient(ctx, u, trustCert)
+	soapClient, err := newSOAPClient(u, trustCert, caFile, thumbprint)
 	if err != nil {
 		return nil, err
 	}
 
+	if err := setProxy(soapClient, u, proxy); err != nil {
+		return nil, err
+	}
+
 	// Override default user agent
-	c.Client.UserAgent = userAgent
+	soapClient.UserAgent = userAgent
+
+	rt := newRetryRoundTripper(soapClient, retries, retryDelay, requestTimeout)
+
+	// The service content is retrieved directly using the SOAP client when
+	// creating the vim25 client, so this request is retried explicitly.
+	var vimClient *vim25.Client
+	err = rt.retry(ctx, "RetrieveServiceContent", isRetryable, func(ctx context.Context) error {
+		var err error
+		vimClient, err = vim25.NewClient(ctx, soapClient)
+		return err
+	})
+	if err != nil {
+		return nil, loginError(err, rt)
+	}
+
+	// all later requests are retried as needed
+	vimClient.RoundTripper = rt
+
+	c := &govmomi.Client{
+		Client:         vimClient,
+		SessionManager: session.NewManager(vimClient),
+	}
 
 	// provide credentials *after* we create the client so that the desired
 	// User Agent value can be set before logging in.
-	u.User = url.UserPassword(username, password)
+	userInfo := url.UserPassword(username, password)
+
+	var authErr error
+	var afterRelogin func()
+	switch {
+	case sessionCacheDir != "":
+		authErr = loginWithSessionCache(ctx, c, u, server, port, userInfo, sessionCacheDir)
+
+		// record any new session created after the cached session expires
+		afterRelogin = func() {
+			saveSession(c, u, server, port, userInfo.Username(), sessionCacheDir)
+		}
+
+	default:
+		// Login, supplying our custom user agent in place of the default
+		authErr = c.Login(ctx, userInfo)
+	}
 
-	// Login, supplying our custom user agent in place of the default
-	authErr := c.Login(ctx, u.User)
 	if authErr != nil {
-		return nil, authErr
+		return nil, loginError(authErr, rt)
 	}
 
+	rt.enableRelogin(*vimClient.ServiceContent.SessionManager, userInfo, afterRelogin)
+
 	return c, nil
 
 }
+
+// loginError annotates the given login error with the number of retries
+// attempted, if any.
+func loginError(err error, rt *retryRoundTripper) error {
+	if retries := rt.Count(); retries > 0 {
+		return fmt.Errorf("%w (after %d retries)", err, retries)
+	}
+
+	return err
+}
+
+// proxyFromEnvironment determines the proxy used for a request from the
+// HTTPS_PROXY and NO_PROXY environment variables. The environment variables
+// are only read once per process, so tests replace this function to
+// simulate different environments.
+var proxyFromEnvironment = http.ProxyFromEnvironment
+
+// setProxy configures the proxy used by the SOAP client to connect to the
+// specified URL. If a proxy URL is specified it is used for all requests,
+// otherwise the proxy is determined from the HTTPS_PROXY and NO_PROXY
+// environment variables. Proxy credentials are taken from the proxy URL. The
+// route used to connect to the server is logged.
+func setProxy(soapClient *soap.Client, u *url.URL, proxy string) error {
+
+	proxyFunc := proxyFromEnvironment
+	source := "environment"
+
+	if proxy != "" {
+		proxyURL, err := url.Parse(proxy)
+		if err != nil {
+			return fmt.Errorf("invalid proxy URL: %w", err)
+		}
+
+		proxyFunc = http.ProxyURL(proxyURL)
+		source = "proxy flag"
+	}
+
+	soapClient.DefaultTransport().Proxy = proxyFunc
+
+	route, err := proxyFunc(&http.Request{URL: u})
+	switch {
+	case err != nil:
+		return fmt.Errorf("failed to determine proxy for %s: %w", u.Host, err)
+
+	case route == nil:
+		logger.Printf("connecting to %s directly (no proxy)", u.Host)
+
+	default:
+		logger.Printf("connecting to %s via proxy %s (from %s)", u.Host, route.Redacted(), source)
+	}
+
+	return nil
+}
@@ -0,0 +1,44 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"github.com/atc0005/check-vmware/internal/perfdata"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// vmsPerfData generates performance data common to all plugins which
+// evaluate VirtualMachines from a set of Resource Pools.
+func vmsPerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	return []perfdata.PerformanceData{
+		{
+			Label: "vms_evaluated",
+			Value: perfdata.Int(int64(len(evaluatedVMs))),
+		},
+		{
+			Label: "resource_pools_evaluated",
+			Value: perfdata.Int(int64(len(rps))),
+		},
+	}
+}
+
+// countPerfData is a helper function used to generate performance data for a
+// simple count of items (e.g., VMs with issues). If specified, the critical
+// threshold is recorded alongside the count.
+func countPerfData(label string, count int, crit string) perfdata.PerformanceData {
+	return perfdata.PerformanceData{
+		Label: label,
+		Value: perfdata.Int(int64(count)),
+		Crit:  crit,
+		Min:   "0",
+	}
+}
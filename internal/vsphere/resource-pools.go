@@ -15,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/check-vmware/internal/textutils"
 	"github.com/atc0005/go-nagios"
 
@@ -29,11 +31,18 @@ import (
 // resource pools have exceeded a given threshold
 var ErrResourcePoolMemoryUsageThresholdCrossed = errors.New("memory usage exceeds specified threshold")
 
+// rpPathSeparator separates the elements of a Resource Pool path (e.g.,
+// Production/Web).
+const rpPathSeparator string = "/"
+
 // ValidateRPs is responsible for receiving two lists of resource pools,
 // explicitly "included" (aka, "whitelisted") and explicitly "excluded" (aka,
-// "blacklisted"). If any list entries are not found in the vSphere
-// environment an error is returned listing which ones.
-func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excludeRPs []string) error {
+// "blacklisted"). List entries may be glob patterns or regular expressions
+// (if prefixed with "re:"). If any list entries do not match a Resource Pool
+// in the vSphere environment an error is returned listing which ones. If
+// specified, only Resource Pools within the given containers (e.g.,
+// Datacenters) are considered.
+func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excludeRPs []string, containers ...types.ManagedObjectReference) error {
 
 	funcTimeStart := time.Now()
 
@@ -45,19 +54,93 @@ func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excl
 		)
 	}(includeRPs, excludeRPs)
 
+	poolNamesFound, err := getRPNames(ctx, c, containers)
+	if err != nil {
+		return err
+	}
+
+	return validateRPNames(poolNamesFound, includeRPs, excludeRPs)
+}
+
+// ValidateServersRPs is the equivalent of ValidateRPs for plugins evaluating
+// multiple servers. An error is returned if any list entries are not found
+// on at least one of the servers. If the Resource Pools for a server cannot
+// be retrieved, the error is recorded for the session and the server is
+// excluded from further evaluation. If Datacenter names are specified, only
+// Resource Pools within those Datacenters are considered.
+func ValidateServersRPs(ctx context.Context, sessions []ServerSession, includeRPs []string, excludeRPs []string, dcNames []string) error {
+
+	// Only the name of each Resource Pool is retrieved.
+	results := GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		containers, err := DatacenterContainers(ctx, c, dcNames)
+		switch {
+		// none of the specified Datacenters are present on this server
+		case errors.Is(err, ErrDatacenterNotFound):
+			return ServerObjects{}, nil
+		case err != nil:
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude lists: %w", err)
+		}
+
+		names, err := getRPNames(ctx, c, containers)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude lists: %w", err)
+		}
+
+		rps := make([]mo.ResourcePool, len(names))
+		for i := range names {
+			rps[i].Name = names[i]
+		}
+
+		return ServerObjects{ResourcePools: rps}, nil
+	})
+
+	var poolNamesFound []string
+	for _, result := range results {
+		for _, rp := range result.ResourcePools {
+			poolNamesFound = append(poolNamesFound, rp.Name)
+		}
+	}
+
+	return validateRPNames(poolNamesFound, includeRPs, excludeRPs)
+}
+
+// getRPNames retrieves the names of all Resource Pools in the given
+// containers, or in the vSphere environment if no containers are specified.
+func getRPNames(ctx context.Context, c *vim25.Client, containers []types.ManagedObjectReference) ([]string, error) {
+
+	if len(containers) == 0 {
+		containers = []types.ManagedObjectReference{c.ServiceContent.RootFolder}
+	}
+
+	var poolNamesFound []string
+	for _, container := range containers {
+		names, err := getContainerRPNames(ctx, c, container)
+		if err != nil {
+			return nil, err
+		}
+		poolNamesFound = append(poolNamesFound, names...)
+	}
+
+	return poolNamesFound, nil
+}
+
+// getContainerRPNames retrieves the names of all Resource Pools in the given
+// container.
+func getContainerRPNames(ctx context.Context, c *vim25.Client, container types.ManagedObjectReference) ([]string, error) {
+
 	m := view.NewManager(c)
 
 	// Create a view of Resource Pool objects
 	v, createViewErr := m.CreateContainerView(
 		ctx,
-		c.ServiceContent.RootFolder,
+		container,
 		[]string{
 			"ResourcePool",
 		},
 		true,
 	)
 	if createViewErr != nil {
-		return fmt.Errorf("failed to create ResourcePool view: %w", createViewErr)
+		return nil, fmt.Errorf("failed to create ResourcePool view: %w", createViewErr)
 	}
 
 	defer func() {
@@ -72,23 +155,37 @@ func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excl
 		}
 	}()
 
-	// Retrieve name property for all resource pools.
-	props := []string{"name"}
+	// Retrieve name and parent properties for all resource pools; the parent
+	// is used to determine the path of nested resource pools.
+	props := []string{"name", "parent"}
 	var rpsSearchResults []mo.ResourcePool
 	retrieveErr := v.Retrieve(ctx, []string{"ResourcePool"}, props, &rpsSearchResults)
 	if retrieveErr != nil {
-		return fmt.Errorf(
+		return nil, fmt.Errorf(
 			"failed to retrieve ResourcePool properties: %w",
 			retrieveErr,
 		)
 	}
 
-	// We're only interested in working with resource pool names
+	// We're only interested in working with resource pool names and the
+	// paths of nested resource pools.
+	paths := rpPaths(rpsSearchResults)
 	poolNamesFound := make([]string, 0, len(rpsSearchResults))
 	for _, rp := range rpsSearchResults {
 		poolNamesFound = append(poolNamesFound, rp.Name)
+		if path := paths[rp.Self.Value]; path != rp.Name {
+			poolNamesFound = append(poolNamesFound, path)
+		}
 	}
 
+	return poolNamesFound, nil
+}
+
+// validateRPNames verifies that all Resource Pool patterns in the given
+// include or exclude lists match at least one of the Resource Pool names (or
+// paths of nested Resource Pools) found in the vSphere environment.
+func validateRPNames(poolNamesFound []string, includeRPs []string, excludeRPs []string) error {
+
 	// If any specified resource pool names are not found, note that so we can
 	// provide the full list of invalid pool names together as a convenience
 	// for the user.
@@ -96,7 +193,7 @@ func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excl
 	switch {
 	case len(includeRPs) > 0:
 		for _, iRP := range includeRPs {
-			if !textutils.InList(iRP, poolNamesFound, true) {
+			if !textutils.PatternMatchesAny(rpPattern(iRP), poolNamesFound) {
 				notFound = append(notFound, iRP)
 			}
 		}
@@ -113,7 +210,7 @@ func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excl
 
 	case len(excludeRPs) > 0:
 		for _, eRP := range excludeRPs {
-			if !textutils.InList(eRP, poolNamesFound, true) {
+			if !textutils.PatternMatchesAny(rpPattern(eRP), poolNamesFound) {
 				notFound = append(notFound, eRP)
 			}
 		}
@@ -137,14 +234,100 @@ func ValidateRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excl
 
 }
 
-// GetEligibleRPs receives a list of Resource Pool names that should either be
-// explicitly included or excluded along with a boolean value indicating
-// whether only a subset of properties for the Resource Pools should be
-// returned. If requested, a subset of all available properties will be
-// retrieved (faster) instead of recursively fetching all properties (about 2x
-// as slow). The filtered list of Resource Pools is returned, or an error if
-// one occurs.
-func GetEligibleRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excludeRPs []string, propsSubset bool) ([]mo.ResourcePool, error) {
+// rpPaths returns the path of each of the given Resource Pools (and vApps)
+// indexed by MOID. The path is made up of the names of all parent Resource
+// Pools below the hidden Resources pool (e.g., Production/Web). The path of
+// a Resource Pool whose parent is not among the given Resource Pools is its
+// name.
+func rpPaths(rps []mo.ResourcePool) map[string]string {
+
+	rpsIdx := make(map[string]int, len(rps))
+	for i := range rps {
+		rpsIdx[rps[i].Self.Value] = i
+	}
+
+	paths := make(map[string]string, len(rps))
+	for i := range rps {
+		path := rps[i].Name
+		parent := rps[i].Parent
+
+		// the number of parents is limited to guard against cycles
+		for depth := 0; parent != nil && depth < len(rps); depth++ {
+			j, ok := rpsIdx[parent.Value]
+			if !ok || isRootRP(rps[j]) {
+				break
+			}
+
+			path = rps[j].Name + rpPathSeparator + path
+			parent = rps[j].Parent
+		}
+
+		paths[rps[i].Self.Value] = path
+	}
+
+	return paths
+}
+
+// isRootRP indicates whether the given Resource Pool is the hidden Resources
+// pool of a host or cluster; the parent of the root Resource Pool is not a
+// Resource Pool or vApp.
+func isRootRP(rp mo.ResourcePool) bool {
+	if rp.Parent == nil {
+		return true
+	}
+
+	switch rp.Parent.Type {
+	case MgObjRefTypeResourcePool, MgObjRefTypeVirtualApp:
+		return false
+	default:
+		return true
+	}
+}
+
+// rpPattern normalizes the given Resource Pool pattern. Leading and trailing
+// path separators are removed from patterns matching a Resource Pool path;
+// regular expressions are used as-is.
+func rpPattern(pattern string) string {
+	if strings.Contains(pattern, rpPathSeparator) &&
+		!strings.HasPrefix(pattern, textutils.RegexPatternPrefix) {
+		return strings.Trim(pattern, rpPathSeparator)
+	}
+
+	return pattern
+}
+
+// rpMatches indicates whether the given Resource Pool name or path matches
+// any of the specified Resource Pool patterns. Entries containing a path
+// separator are compared against the path, all others against the name.
+func rpMatches(name string, path string, patterns []string) bool {
+	for _, pattern := range patterns {
+		target := name
+		if strings.Contains(pattern, rpPathSeparator) {
+			target = path
+		}
+
+		if textutils.MatchPattern(target, rpPattern(pattern)) {
+			return true
+		}
+	}
+
+	return false
+}
+
+// GetEligibleRPs receives a list of Resource Pool names (or glob and "re:"
+// prefixed regular expression patterns) that should either be explicitly
+// included or excluded along with a boolean value indicating whether child
+// Resource Pools (and vApps) of matching Resource Pools are also matched and
+// a boolean value indicating whether only a subset of properties for the
+// Resource Pools should be returned. If requested, a subset of all available
+// properties will be retrieved (faster) instead of recursively fetching all
+// properties (about 2x as slow). If specified, Resource Pools are only
+// retrieved from the given containers (e.g., Datacenters). List entries
+// containing a path separator are matched against the path of nested
+// Resource Pools (e.g., Production/Web). If child Resource Pools are matched,
+// the name of each returned Resource Pool is replaced by its path. The
+// filtered list of Resource Pools is returned, or an error if one occurs.
+func GetEligibleRPs(ctx context.Context, c *vim25.Client, includeRPs []string, excludeRPs []string, recursive bool, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.ResourcePool, error) {
 
 	funcTimeStart := time.Now()
 
@@ -164,11 +347,46 @@ func GetEligibleRPs(ctx context.Context, c *vim25.Client, includeRPs []string, e
 	// return a trimmed list.
 	var rpsSearchResults []mo.ResourcePool
 
-	err := getObjects(ctx, c, &rpsSearchResults, c.ServiceContent.RootFolder, propsSubset)
+	err := getObjectsFromContainers(ctx, c, &rpsSearchResults, containers, propsSubset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve ResourcePools: %w", err)
 	}
 
+	paths := rpPaths(rpsSearchResults)
+
+	rpsIdx := make(map[string]int, len(rpsSearchResults))
+	for i := range rpsSearchResults {
+		rpsIdx[rpsSearchResults[i].Self.Value] = i
+	}
+
+	// matches indicates whether the Resource Pool (or, if requested, one of
+	// its parent Resource Pools) matches any of the given patterns.
+	matches := func(rp mo.ResourcePool, patterns []string) bool {
+		if rpMatches(rp.Name, paths[rp.Self.Value], patterns) {
+			return true
+		}
+
+		if !recursive {
+			return false
+		}
+
+		parent := rp.Parent
+		for depth := 0; parent != nil && depth < len(rpsSearchResults); depth++ {
+			i, ok := rpsIdx[parent.Value]
+			if !ok {
+				break
+			}
+
+			ancestor := rpsSearchResults[i]
+			if rpMatches(ancestor.Name, paths[ancestor.Self.Value], patterns) {
+				return true
+			}
+			parent = ancestor.Parent
+		}
+
+		return false
+	}
+
 	for _, rp := range rpsSearchResults {
 
 		// Virtual machine hosts have a hidden resource pool named Resources,
@@ -185,28 +403,37 @@ func GetEligibleRPs(ctx context.Context, c *vim25.Client, includeRPs []string, e
 
 		// config validation asserts that only one of include/exclude resource
 		// pools flags are specified
+		var eligible bool
 		switch {
 
 		// if specified, only include resource pools that have been
 		// intentionally included (aka, "whitelisted")
 		case len(includeRPs) > 0:
-			if textutils.InList(rp.Name, includeRPs, true) {
-				rps = append(rps, rp)
-			}
+			eligible = matches(rp, includeRPs)
 
 		// if specified, don't include resource pools that have been
 		// intentionally excluded (aka, "blacklisted")
 		case len(excludeRPs) > 0:
-			if !textutils.InList(rp.Name, excludeRPs, true) {
-				rps = append(rps, rp)
-			}
+			eligible = !matches(rp, excludeRPs)
 
 		// if we are not explicitly excluding or including pools, then we are
 		// working with all pools
 		default:
-			rps = append(rps, rp)
+			eligible = true
 		}
 
+		if !eligible {
+			continue
+		}
+
+		// nested resource pools are listed by path when evaluating child
+		// resource pools
+		if recursive {
+			rp.Name = paths[rp.Self.Value]
+		}
+
+		rps = append(rps, rp)
+
 	}
 
 	sort.Slice(rps, func(i, j int) bool {
@@ -320,6 +547,83 @@ func RPMemoryUsageOneLineCheckSummary(
 	}
 }
 
+// RPMemoryUsagePerfData generates performance data for the aggregate memory
+// usage of the specified Resource Pools. The given WARNING and CRITICAL
+// threshold values are percentages of the maximum allowed memory usage.
+func RPMemoryUsagePerfData(
+	aggregateMemoryUsage int64,
+	maxMemoryUsageInGB int,
+	clusterMemoryInGB int64,
+	warningThreshold config.Range,
+	criticalThreshold config.Range,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	memoryUsageMax := int64(maxMemoryUsageInGB) * units.GB
+
+	return []perfdata.PerformanceData{
+		{
+			Label:             "memory_usage",
+			Value:             perfdata.Float(MemoryUsedPercentage(aggregateMemoryUsage, maxMemoryUsageInGB)),
+			UnitOfMeasurement: perfdata.UOMPercentage,
+			Warn:              warningThreshold.String(),
+			Crit:              criticalThreshold.String(),
+			Min:               "0",
+			Max:               "100",
+		},
+		{
+			Label:             "memory_used",
+			Value:             perfdata.Int(aggregateMemoryUsage),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+			Max:               perfdata.Int(memoryUsageMax),
+		},
+		{
+			Label:             "memory_cluster_total",
+			Value:             perfdata.Int(clusterMemoryInGB * units.GB),
+			UnitOfMeasurement: perfdata.UOMBytes,
+		},
+		{
+			Label: "resource_pools_evaluated",
+			Value: perfdata.Int(int64(len(rps))),
+		},
+	}
+}
+
+// ResourcePoolsMemoryUsageData represents the evaluated aggregate memory
+// usage of a set of Resource Pools.
+type ResourcePoolsMemoryUsageData struct {
+	MemoryUsed             int64    `json:"memory_used_bytes"`
+	MemoryMaxAllowed       int64    `json:"memory_max_allowed_bytes"`
+	MemoryClusterTotal     int64    `json:"memory_cluster_total_bytes"`
+	MemoryUsedPercent      float64  `json:"memory_used_percent"`
+	WarningThreshold       string   `json:"warning_threshold"`
+	CriticalThreshold      string   `json:"critical_threshold"`
+	ResourcePoolsEvaluated []string `json:"resource_pools_evaluated"`
+}
+
+// NewResourcePoolsMemoryUsageData generates machine-readable data for the
+// aggregate memory usage of the specified Resource Pools.
+func NewResourcePoolsMemoryUsageData(
+	aggregateMemoryUsage int64,
+	maxMemoryUsageInGB int,
+	clusterMemoryInGB int64,
+	warningThreshold config.Range,
+	criticalThreshold config.Range,
+	rps []mo.ResourcePool,
+) ResourcePoolsMemoryUsageData {
+
+	return ResourcePoolsMemoryUsageData{
+		MemoryUsed:             aggregateMemoryUsage,
+		MemoryMaxAllowed:       int64(maxMemoryUsageInGB) * units.GB,
+		MemoryClusterTotal:     clusterMemoryInGB * units.GB,
+		MemoryUsedPercent:      MemoryUsedPercentage(aggregateMemoryUsage, maxMemoryUsageInGB),
+		WarningThreshold:       warningThreshold.String(),
+		CriticalThreshold:      criticalThreshold.String(),
+		ResourcePoolsEvaluated: newEvaluatedVMsData(nil, rps).ResourcePoolsEvaluated,
+	}
+}
+
 // ResourcePoolsMemoryReport generates a summary of memory usage associated
 // with specified Resource Pools along with various verbose details intended
 // to aid in troubleshooting check results at a glance. This information is
@@ -501,6 +805,13 @@ func ResourcePoolsMemoryReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -508,6 +819,13 @@ func ResourcePoolsMemoryReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Specified Resource Pools to explicitly include (%d): [%v]%s",
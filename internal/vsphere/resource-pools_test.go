@@ -0,0 +1,79 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"reflect"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestRPPaths(t *testing.T) {
+
+	newRP := func(name string, ref types.ManagedObjectReference, parent types.ManagedObjectReference) mo.ResourcePool {
+		var rp mo.ResourcePool
+		rp.Name = name
+		rp.Self = ref
+		rp.Parent = &parent
+
+		return rp
+	}
+
+	cluster := types.ManagedObjectReference{Type: MgObjRefTypeClusterComputeResource, Value: "domain-c7"}
+	root := types.ManagedObjectReference{Type: MgObjRefTypeResourcePool, Value: "resgroup-8"}
+	prod := types.ManagedObjectReference{Type: MgObjRefTypeResourcePool, Value: "resgroup-10"}
+	web := types.ManagedObjectReference{Type: MgObjRefTypeResourcePool, Value: "resgroup-11"}
+	app := types.ManagedObjectReference{Type: MgObjRefTypeVirtualApp, Value: "resgroup-v12"}
+	db := types.ManagedObjectReference{Type: MgObjRefTypeResourcePool, Value: "resgroup-13"}
+
+	rps := []mo.ResourcePool{
+		newRP(ParentResourcePool, root, cluster),
+		newRP("Production", prod, root),
+		newRP("Web", web, prod),
+		newRP("App", app, prod),
+		newRP("DB", db, app),
+	}
+
+	want := map[string]string{
+		root.Value: "Resources",
+		prod.Value: "Production",
+		web.Value:  "Production/Web",
+		app.Value:  "Production/App",
+		db.Value:   "Production/App/DB",
+	}
+
+	if got := rpPaths(rps); !reflect.DeepEqual(got, want) {
+		t.Errorf("got paths %v; want %v", got, want)
+	}
+}
+
+func TestRPMatches(t *testing.T) {
+
+	tests := []struct {
+		name     string
+		path     string
+		patterns []string
+		want     bool
+	}{
+		{name: "Web", path: "Production/Web", patterns: []string{"web"}, want: true},
+		{name: "Web", path: "Production/Web", patterns: []string{"production/web"}, want: true},
+		{name: "Web", path: "Production/Web", patterns: []string{"/Production/Web/"}, want: true},
+		{name: "Web", path: "Production/Web", patterns: []string{"Production/*"}, want: true},
+		{name: "Web", path: "Test/Web", patterns: []string{"Production/*"}, want: false},
+		{name: "Web", path: "Production/Web", patterns: []string{"re:^production/"}, want: true},
+		{name: "Production", path: "Production", patterns: []string{"Production/*"}, want: false},
+	}
+
+	for _, tt := range tests {
+		if got := rpMatches(tt.name, tt.path, tt.patterns); got != tt.want {
+			t.Errorf("rpMatches(%q, %q, %v) = %t; want %t", tt.name, tt.path, tt.patterns, got, tt.want)
+		}
+	}
+}
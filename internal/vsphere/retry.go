@@ -0,0 +1,315 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"net/url"
+	"reflect"
+	"regexp"
+	"strings"
+	"sync"
+	"syscall"
+	"time"
+
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// maxRetryDelay is the maximum delay between retry attempts.
+const maxRetryDelay = 30 * time.Second
+
+// retryableHTTPStatus matches the status text of HTTP responses which are
+// retried (e.g., "503 Service Unavailable").
+var retryableHTTPStatus = regexp.MustCompile(`^(502|503|504) `)
+
+// retryRoundTripper is a soap.RoundTripper which retries vSphere API
+// requests failing with a retryable fault using exponential backoff. If the
+// session is no longer authenticated, a new session is created using the
+// recorded credentials before retrying the request.
+type retryRoundTripper struct {
+	roundTripper soap.RoundTripper
+
+	// retries is the maximum number of retries for each request.
+	retries int
+
+	// delay is the delay before the first retry attempt; the delay is
+	// doubled for each later attempt.
+	delay time.Duration
+
+	// requestTimeout is the timeout for each request attempt made after
+	// logging in. If zero, requests are limited only by the context.
+	requestTimeout time.Duration
+
+	mu sync.Mutex
+
+	// count is the number of retry attempts made.
+	count int
+
+	// sessionManager and userInfo are recorded after logging in so that
+	// a new session can be created if the session is no longer
+	// authenticated.
+	sessionManager *types.ManagedObjectReference
+	userInfo       *url.Userinfo
+
+	// afterRelogin, if set, is called after a new session is created (e.g.,
+	// to record the new session in the session cache).
+	afterRelogin func()
+}
+
+// newRetryRoundTripper wraps the given soap.RoundTripper, retrying requests
+// up to the specified number of times.
+func newRetryRoundTripper(rt soap.RoundTripper, retries int, delay time.Duration, requestTimeout time.Duration) *retryRoundTripper {
+	return &retryRoundTripper{
+		roundTripper:   rt,
+		retries:        retries,
+		delay:          delay,
+		requestTimeout: requestTimeout,
+	}
+}
+
+// RoundTrip implements the soap.RoundTripper interface.
+func (r *retryRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+
+	r.mu.Lock()
+	loggedIn := r.userInfo != nil
+	r.mu.Unlock()
+
+	// A session which is not authenticated is only retried after logging
+	// in; this fault is otherwise expected (e.g., when validating a cached
+	// session).
+	retryable := func(err error) bool {
+		if isNotAuthenticated(err) {
+			return loggedIn
+		}
+
+		return isRetryable(err)
+	}
+
+	return r.retry(ctx, fmt.Sprintf("%T", req), retryable, func(ctx context.Context) error {
+
+		if loggedIn && r.requestTimeout > 0 {
+			var cancel context.CancelFunc
+			ctx, cancel = context.WithTimeout(ctx, r.requestTimeout)
+			defer cancel()
+		}
+
+		// Discard any response (or fault) decoded by an earlier attempt.
+		resetResponse(res)
+
+		err := r.roundTripper.RoundTrip(ctx, req, res)
+		if err != nil && isNotAuthenticated(err) && loggedIn {
+			if _, isLogout := req.(*methods.LogoutBody); !isLogout {
+				if loginErr := r.relogin(ctx); loginErr != nil {
+					logger.Printf("failed to create new session: %v", loginErr)
+				}
+			}
+		}
+
+		return err
+	})
+}
+
+// retry calls fn until it succeeds, a non-retryable error occurs, the
+// maximum number of retries is reached or the context is done. The delay
+// between attempts is doubled after each attempt.
+func (r *retryRoundTripper) retry(
+	ctx context.Context,
+	op string,
+	retryable func(err error) bool,
+	fn func(ctx context.Context) error,
+) error {
+
+	delay := r.delay
+
+	for attempt := 0; ; attempt++ {
+		err := fn(ctx)
+		if err == nil || attempt >= r.retries || ctx.Err() != nil || !retryable(err) {
+			return err
+		}
+
+		// give up early if the context would expire before the next attempt
+		if deadline, ok := ctx.Deadline(); ok && time.Until(deadline) < delay {
+			return err
+		}
+
+		logger.Printf(
+			"retrying %s in %v (retry %d of %d) after error: %v",
+			strings.TrimPrefix(op, "*methods."),
+			delay,
+			attempt+1,
+			r.retries,
+			err,
+		)
+
+		r.mu.Lock()
+		r.count++
+		r.mu.Unlock()
+
+		timer := time.NewTimer(delay)
+		select {
+		case <-ctx.Done():
+			timer.Stop()
+			return err
+		case <-timer.C:
+		}
+
+		delay *= 2
+		if delay > maxRetryDelay {
+			delay = maxRetryDelay
+		}
+	}
+}
+
+// enableRelogin records the credentials used to create a new session if
+// the session is no longer authenticated. This also enables the per-request
+// timeout for later requests. If specified, afterRelogin is called after
+// each new session is created.
+func (r *retryRoundTripper) enableRelogin(sessionManager types.ManagedObjectReference, userInfo *url.Userinfo, afterRelogin func()) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.sessionManager = &sessionManager
+	r.userInfo = userInfo
+	r.afterRelogin = afterRelogin
+}
+
+// relogin creates a new session using the recorded credentials.
+func (r *retryRoundTripper) relogin(ctx context.Context) error {
+
+	r.mu.Lock()
+	sessionManager := r.sessionManager
+	userInfo := r.userInfo
+	afterRelogin := r.afterRelogin
+	r.mu.Unlock()
+
+	password, _ := userInfo.Password()
+	req := types.Login{
+		This:     *sessionManager,
+		UserName: userInfo.Username(),
+		Password: password,
+	}
+
+	logger.Printf("session is no longer authenticated, creating new session")
+
+	if _, err := methods.Login(ctx, r.roundTripper, &req); err != nil {
+		return err
+	}
+
+	if afterRelogin != nil {
+		afterRelogin()
+	}
+
+	return nil
+}
+
+// Count returns the number of retry attempts made.
+func (r *retryRoundTripper) Count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.count
+}
+
+// Retries returns the number of vSphere API request retry attempts made
+// using the given client.
+func Retries(c *vim25.Client) int {
+	if r, ok := c.RoundTripper.(*retryRoundTripper); ok {
+		return r.Count()
+	}
+
+	return 0
+}
+
+// WrapRoundTripper wraps the soap.RoundTripper used by the given client
+// (e.g., with a keep alive handler). If the client was created using Login,
+// the wrapped soap.RoundTripper is placed beneath the retry handling so that
+// retry attempts and recorded credentials remain available.
+func WrapRoundTripper(c *vim25.Client, wrap func(rt soap.RoundTripper) soap.RoundTripper) {
+	if r, ok := c.RoundTripper.(*retryRoundTripper); ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		r.roundTripper = wrap(r.roundTripper)
+
+		return
+	}
+
+	c.RoundTripper = wrap(c.RoundTripper)
+}
+
+// credentials returns the credentials used to log in with the given client,
+// or nil if the client was not created using Login.
+func credentials(c *vim25.Client) *url.Userinfo {
+	if r, ok := c.RoundTripper.(*retryRoundTripper); ok {
+		r.mu.Lock()
+		defer r.mu.Unlock()
+
+		return r.userInfo
+	}
+
+	return nil
+}
+
+// resetResponse sets the given response to its zero value.
+func resetResponse(res soap.HasFault) {
+	v := reflect.ValueOf(res)
+	if v.Kind() == reflect.Ptr && !v.IsNil() {
+		v.Elem().Set(reflect.Zero(v.Elem().Type()))
+	}
+}
+
+// isNotAuthenticated indicates whether the given error is a fault indicating
+// that the session is not authenticated.
+func isNotAuthenticated(err error) bool {
+	if !soap.IsSoapFault(err) {
+		return false
+	}
+
+	fault := soap.ToSoapFault(err)
+	switch fault.VimFault().(type) {
+	case types.NotAuthenticated, *types.NotAuthenticated:
+		return true
+	}
+
+	return strings.Contains(strings.ToLower(fault.String), "session is not authenticated")
+}
+
+// isRetryable indicates whether the given error is a transient network or
+// server error for which the request should be retried.
+func isRetryable(err error) bool {
+
+	var urlErr *url.Error
+
+	switch {
+	case vim25.IsTemporaryNetworkError(err):
+		return true
+
+	// a request attempt exceeded the per-request timeout
+	case errors.Is(err, context.DeadlineExceeded):
+		return true
+
+	case errors.Is(err, syscall.ECONNRESET),
+		errors.Is(err, syscall.ECONNABORTED),
+		errors.Is(err, syscall.EPIPE),
+		errors.Is(err, io.EOF),
+		errors.Is(err, io.ErrUnexpectedEOF):
+		return true
+
+	case errors.As(err, &urlErr) && urlErr.Err != nil:
+		return retryableHTTPStatus.MatchString(urlErr.Err.Error())
+
+	default:
+		return false
+	}
+}
@@ -0,0 +1,167 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"io"
+	"net/url"
+	"syscall"
+	"testing"
+	"time"
+
+	"github.com/vmware/govmomi/vim25/methods"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// fakeRoundTripper returns the recorded errors in order, one per request.
+type fakeRoundTripper struct {
+	errs  []error
+	calls int
+}
+
+func (f *fakeRoundTripper) RoundTrip(ctx context.Context, req, res soap.HasFault) error {
+	f.calls++
+	if len(f.errs) == 0 {
+		return nil
+	}
+
+	err := f.errs[0]
+	f.errs = f.errs[1:]
+
+	return err
+}
+
+func TestIsRetryable(t *testing.T) {
+
+	tests := map[string]struct {
+		err  error
+		want bool
+	}{
+		"connection reset": {
+			err:  &url.Error{Op: "Post", URL: "https://vc1", Err: syscall.ECONNRESET},
+			want: true,
+		},
+		"unexpected EOF": {
+			err:  io.ErrUnexpectedEOF,
+			want: true,
+		},
+		"deadline exceeded": {
+			err:  context.DeadlineExceeded,
+			want: true,
+		},
+		"service unavailable": {
+			err:  &url.Error{Op: "POST", URL: "https://vc1", Err: errors.New("503 Service Unavailable")},
+			want: true,
+		},
+		"internal server error": {
+			err:  &url.Error{Op: "POST", URL: "https://vc1", Err: errors.New("500 Internal Server Error")},
+			want: false,
+		},
+		"connection refused": {
+			err:  &url.Error{Op: "Post", URL: "https://vc1", Err: syscall.ECONNREFUSED},
+			want: false,
+		},
+		"canceled": {
+			err:  context.Canceled,
+			want: false,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			if got := isRetryable(tt.err); got != tt.want {
+				t.Errorf("isRetryable(%v) = %t; want %t", tt.err, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestRetryRoundTripper(t *testing.T) {
+
+	t.Run("retries until success", func(t *testing.T) {
+		fake := &fakeRoundTripper{errs: []error{io.EOF, io.EOF}}
+		rt := newRetryRoundTripper(fake, 2, time.Millisecond, 0)
+
+		if err := rt.RoundTrip(context.Background(), nil, nil); err != nil {
+			t.Fatalf("unexpected error: %v", err)
+		}
+
+		if fake.calls != 3 {
+			t.Errorf("got %d requests; want 3", fake.calls)
+		}
+
+		if rt.Count() != 2 {
+			t.Errorf("got %d retries; want 2", rt.Count())
+		}
+	})
+
+	t.Run("stops after maximum retries", func(t *testing.T) {
+		fake := &fakeRoundTripper{errs: []error{io.EOF, io.EOF, io.EOF}}
+		rt := newRetryRoundTripper(fake, 1, time.Millisecond, 0)
+
+		if err := rt.RoundTrip(context.Background(), nil, nil); !errors.Is(err, io.EOF) {
+			t.Fatalf("got error %v; want %v", err, io.EOF)
+		}
+
+		if fake.calls != 2 {
+			t.Errorf("got %d requests; want 2", fake.calls)
+		}
+	})
+
+	t.Run("does not retry non-retryable errors", func(t *testing.T) {
+		errFatal := errors.New("fatal")
+		fake := &fakeRoundTripper{errs: []error{errFatal}}
+		rt := newRetryRoundTripper(fake, 2, time.Millisecond, 0)
+
+		if err := rt.RoundTrip(context.Background(), nil, nil); !errors.Is(err, errFatal) {
+			t.Fatalf("got error %v; want %v", err, errFatal)
+		}
+
+		if fake.calls != 1 {
+			t.Errorf("got %d requests; want 1", fake.calls)
+		}
+
+		if rt.Count() != 0 {
+			t.Errorf("got %d retries; want 0", rt.Count())
+		}
+	})
+}
+
+func TestRetryRoundTripperRelogin(t *testing.T) {
+
+	notAuthenticated := soap.WrapSoapFault(&soap.Fault{String: "The session is not authenticated."})
+
+	// The first request fails as the session is not authenticated; the
+	// second request creates a new session and the third request retries
+	// the original request.
+	fake := &fakeRoundTripper{errs: []error{notAuthenticated}}
+	rt := newRetryRoundTripper(fake, 1, time.Millisecond, 0)
+
+	var relogins int
+	rt.enableRelogin(
+		types.ManagedObjectReference{Type: "SessionManager", Value: "SessionManager"},
+		url.UserPassword("user", "pass"),
+		func() { relogins++ },
+	)
+
+	if err := rt.RoundTrip(context.Background(), &methods.RetrievePropertiesBody{}, &methods.RetrievePropertiesBody{}); err != nil {
+		t.Fatalf("unexpected error: %v", err)
+	}
+
+	if fake.calls != 3 {
+		t.Errorf("got %d requests; want 3", fake.calls)
+	}
+
+	if relogins != 1 {
+		t.Errorf("got %d calls after creating new session; want 1", relogins)
+	}
+}
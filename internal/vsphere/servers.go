@@ -0,0 +1,296 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+)
+
+// ErrServerEvaluationFailed indicates that one or more of the servers
+// evaluated by a plugin could not be evaluated.
+var ErrServerEvaluationFailed = errors.New("failed to evaluate servers")
+
+// ServerSession is a vSphere session established with one of the ESXi hosts
+// or vCenter instances evaluated by a plugin. If the server could not be
+// evaluated (e.g., logging in or retrieving objects failed), Err records the
+// error and the server is excluded from further evaluation.
+type ServerSession struct {
+	Server string
+	Client *govmomi.Client
+	Err    error
+}
+
+// ServerObjects records the objects retrieved from a specific server.
+type ServerObjects struct {
+	Server        string
+	VMs           []mo.VirtualMachine
+	ResourcePools []mo.ResourcePool
+	HostSystems   []mo.HostSystem
+	Datacenters   []mo.Datacenter
+	Clusters      []mo.ClusterComputeResource
+}
+
+// GetServerObjects uses the provided function to retrieve objects from each
+// server concurrently. Servers which previously failed evaluation are
+// skipped. If retrieving objects from a server fails, the error is recorded
+// for the session and the server is excluded from the results. Results are
+// returned in the same order as the sessions.
+func GetServerObjects(
+	ctx context.Context,
+	sessions []ServerSession,
+	get func(ctx context.Context, c *vim25.Client) (ServerObjects, error),
+) []ServerObjects {
+
+	funcTimeStart := time.Now()
+
+	defer func() {
+		logger.Printf(
+			"It took %v to execute GetServerObjects func (and evaluate %d servers).\n",
+			time.Since(funcTimeStart),
+			len(sessions),
+		)
+	}()
+
+	results := make([]ServerObjects, len(sessions))
+
+	var wg sync.WaitGroup
+	for i := range sessions {
+		if sessions[i].Err != nil {
+			continue
+		}
+
+		wg.Add(1)
+		go func(i int) {
+			defer wg.Done()
+
+			objects, err := get(ctx, sessions[i].Client.Client)
+			if err != nil {
+				sessions[i].Err = err
+				return
+			}
+
+			objects.Server = sessions[i].Server
+			results[i] = objects
+		}(i)
+	}
+	wg.Wait()
+
+	evaluated := make([]ServerObjects, 0, len(sessions))
+	for i := range sessions {
+		if sessions[i].Err == nil {
+			evaluated = append(evaluated, results[i])
+		}
+	}
+
+	return evaluated
+}
+
+// QualifiedName returns the given object name (or MOID) qualified with the
+// name of the server the object was retrieved from.
+func QualifiedName(server string, name string) string {
+	return server + "/" + name
+}
+
+// CombineServerObjects combines the objects retrieved from multiple servers.
+// If requested, the name and managed object reference of each object (along
+// with the Resource Pool reference of each VirtualMachine) are qualified with
+// the name of the server it was retrieved from so that objects with the same
+// name or MOID on different servers can be told apart. VirtualMachines and
+// Resource Pools are sorted by name.
+func CombineServerObjects(results []ServerObjects, qualify bool) ServerObjects {
+
+	var combined ServerObjects
+
+	servers := make([]string, 0, len(results))
+	for _, result := range results {
+		servers = append(servers, result.Server)
+
+		for _, vm := range result.VMs {
+			if qualify {
+				vm.Name = QualifiedName(result.Server, vm.Name)
+				vm.Self.Value = QualifiedName(result.Server, vm.Self.Value)
+
+				if vm.ResourcePool != nil {
+					rpRef := *vm.ResourcePool
+					rpRef.Value = QualifiedName(result.Server, rpRef.Value)
+					vm.ResourcePool = &rpRef
+				}
+			}
+			combined.VMs = append(combined.VMs, vm)
+		}
+
+		for _, rp := range result.ResourcePools {
+			if qualify {
+				rp.Name = QualifiedName(result.Server, rp.Name)
+				rp.Self.Value = QualifiedName(result.Server, rp.Self.Value)
+
+				if rp.Parent != nil {
+					parentRef := *rp.Parent
+					parentRef.Value = QualifiedName(result.Server, parentRef.Value)
+					rp.Parent = &parentRef
+				}
+			}
+			combined.ResourcePools = append(combined.ResourcePools, rp)
+		}
+
+		for _, host := range result.HostSystems {
+			if qualify {
+				host.Name = QualifiedName(result.Server, host.Name)
+				host.Self.Value = QualifiedName(result.Server, host.Self.Value)
+			}
+			combined.HostSystems = append(combined.HostSystems, host)
+		}
+	}
+
+	combined.Server = strings.Join(servers, ", ")
+
+	sort.Slice(combined.VMs, func(i, j int) bool {
+		return strings.ToLower(combined.VMs[i].Name) < strings.ToLower(combined.VMs[j].Name)
+	})
+
+	sort.Slice(combined.ResourcePools, func(i, j int) bool {
+		return strings.ToLower(combined.ResourcePools[i].Name) < strings.ToLower(combined.ResourcePools[j].Name)
+	})
+
+	return combined
+}
+
+// FirstEvaluatedClient returns the client for the first server which has not
+// failed evaluation, or nil if all servers failed evaluation.
+func FirstEvaluatedClient(sessions []ServerSession) *vim25.Client {
+	for _, session := range sessions {
+		if session.Err == nil {
+			return session.Client.Client
+		}
+	}
+
+	return nil
+}
+
+// ServerNames returns the names of the given servers as a comma-separated
+// list of quoted names.
+func ServerNames(sessions []ServerSession) string {
+	names := make([]string, 0, len(sessions))
+	for _, session := range sessions {
+		names = append(names, fmt.Sprintf("%q", session.Server))
+	}
+
+	return strings.Join(names, ", ")
+}
+
+// ServersError returns an error describing each server which failed
+// evaluation, or nil if all servers were evaluated. If only one server is
+// evaluated, the error recorded for that server is returned as-is.
+func ServersError(sessions []ServerSession) error {
+
+	if len(sessions) == 1 {
+		return sessions[0].Err
+	}
+
+	var failed []string
+	for _, session := range sessions {
+		if session.Err != nil {
+			failed = append(failed, fmt.Sprintf("%s: %v", session.Server, session.Err))
+		}
+	}
+
+	if len(failed) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf(
+		"%w (%d of %d): %s",
+		ErrServerEvaluationFailed,
+		len(failed),
+		len(sessions),
+		strings.Join(failed, "; "),
+	)
+}
+
+// FailedServersSummary returns a summary of the servers which failed
+// evaluation suitable for appending to a one-line Nagios service check
+// results summary, or an empty string if all servers were evaluated.
+func FailedServersSummary(sessions []ServerSession) string {
+
+	var failed []string
+	for _, session := range sessions {
+		if session.Err != nil {
+			failed = append(failed, session.Server)
+		}
+	}
+
+	if len(failed) == 0 {
+		return ""
+	}
+
+	return fmt.Sprintf(
+		"; %d of %d servers not evaluated (%s)",
+		len(failed),
+		len(sessions),
+		strings.Join(failed, ", "),
+	)
+}
+
+// ServersReport generates a summary of the evaluation of each server for
+// use with the Long Service Output field. This summary is intended to be
+// appended to the report generated by plugins evaluating multiple servers; an
+// empty string is returned if only one server is evaluated.
+func ServersReport(sessions []ServerSession) string {
+
+	if len(sessions) < 2 {
+		return ""
+	}
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"%sServers evaluated:%s%s",
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+		nagios.CheckOutputEOL,
+	)
+
+	for _, session := range sessions {
+		switch {
+		case session.Err != nil:
+			fmt.Fprintf(
+				&report,
+				"* %s: %s (%v)%s",
+				session.Server,
+				nagios.StateCRITICALLabel,
+				session.Err,
+				nagios.CheckOutputEOL,
+			)
+
+		default:
+			fmt.Fprintf(
+				&report,
+				"* %s: %s (%s, %d vSphere API request retries)%s",
+				session.Server,
+				nagios.StateOKLabel,
+				APIDescription(session.Client.Client),
+				Retries(session.Client.Client),
+				nagios.CheckOutputEOL,
+			)
+		}
+	}
+
+	return report.String()
+}
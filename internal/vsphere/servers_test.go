@@ -0,0 +1,133 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"errors"
+	"testing"
+
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestCombineServerObjects(t *testing.T) {
+
+	newVM := func(name string, moid string, rpMOID string) mo.VirtualMachine {
+		var vm mo.VirtualMachine
+		vm.Name = name
+		vm.Self = types.ManagedObjectReference{Type: "VirtualMachine", Value: moid}
+		vm.ResourcePool = &types.ManagedObjectReference{Type: "ResourcePool", Value: rpMOID}
+
+		return vm
+	}
+
+	newRP := func(name string, moid string) mo.ResourcePool {
+		var rp mo.ResourcePool
+		rp.Name = name
+		rp.Self = types.ManagedObjectReference{Type: "ResourcePool", Value: moid}
+
+		return rp
+	}
+
+	results := []ServerObjects{
+		{
+			Server:        "vc2.example.com",
+			VMs:           []mo.VirtualMachine{newVM("web1", "vm-10", "resgroup-1")},
+			ResourcePools: []mo.ResourcePool{newRP("Production", "resgroup-1")},
+		},
+		{
+			Server:        "vc1.example.com",
+			VMs:           []mo.VirtualMachine{newVM("web1", "vm-10", "resgroup-1")},
+			ResourcePools: []mo.ResourcePool{newRP("Production", "resgroup-1")},
+		},
+	}
+
+	t.Run("single server", func(t *testing.T) {
+		combined := CombineServerObjects(results[:1], false)
+
+		if got := combined.VMs[0].Name; got != "web1" {
+			t.Errorf("got VM name %q; want %q", got, "web1")
+		}
+
+		if got := combined.Server; got != "vc2.example.com" {
+			t.Errorf("got server %q; want %q", got, "vc2.example.com")
+		}
+	})
+
+	t.Run("multiple servers", func(t *testing.T) {
+		combined := CombineServerObjects(results, true)
+
+		if len(combined.VMs) != 2 || len(combined.ResourcePools) != 2 {
+			t.Fatalf(
+				"got %d VMs and %d Resource Pools; want 2 of each",
+				len(combined.VMs),
+				len(combined.ResourcePools),
+			)
+		}
+
+		vm := combined.VMs[0]
+		if want := "vc1.example.com/web1"; vm.Name != want {
+			t.Errorf("got VM name %q; want %q", vm.Name, want)
+		}
+
+		if want := "vc1.example.com/vm-10"; vm.Self.Value != want {
+			t.Errorf("got VM MOID %q; want %q", vm.Self.Value, want)
+		}
+
+		if want := combined.ResourcePools[0].Self.Value; vm.ResourcePool.Value != want {
+			t.Errorf("got VM Resource Pool %q; want %q", vm.ResourcePool.Value, want)
+		}
+
+		// results from each server are left unmodified
+		if got := results[1].VMs[0].ResourcePool.Value; got != "resgroup-1" {
+			t.Errorf("source VM Resource Pool modified: got %q", got)
+		}
+	})
+}
+
+func TestServersError(t *testing.T) {
+
+	errLogin := errors.New("login failed")
+
+	t.Run("single server", func(t *testing.T) {
+		sessions := []ServerSession{{Server: "vc1.example.com", Err: errLogin}}
+
+		if err := ServersError(sessions); !errors.Is(err, errLogin) || errors.Is(err, ErrServerEvaluationFailed) {
+			t.Errorf("got error %v; want %v", err, errLogin)
+		}
+	})
+
+	t.Run("multiple servers", func(t *testing.T) {
+		sessions := []ServerSession{
+			{Server: "vc1.example.com"},
+			{Server: "vc2.example.com", Err: errLogin},
+		}
+
+		err := ServersError(sessions)
+		if !errors.Is(err, ErrServerEvaluationFailed) {
+			t.Fatalf("got error %v; want %v", err, ErrServerEvaluationFailed)
+		}
+
+		want := "failed to evaluate servers (1 of 2): vc2.example.com: login failed"
+		if err.Error() != want {
+			t.Errorf("got error %q; want %q", err, want)
+		}
+
+		if got, want := FailedServersSummary(sessions), "; 1 of 2 servers not evaluated (vc2.example.com)"; got != want {
+			t.Errorf("got summary %q; want %q", got, want)
+		}
+	})
+
+	t.Run("no failures", func(t *testing.T) {
+		sessions := []ServerSession{{Server: "vc1.example.com"}, {Server: "vc2.example.com"}}
+
+		if err := ServersError(sessions); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+}
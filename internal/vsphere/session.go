@@ -0,0 +1,231 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"net/url"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+
+	"github.com/vmware/govmomi"
+)
+
+// ErrSessionCacheInsecure indicates that a session cache file or directory
+// is accessible by users other than the owner.
+var ErrSessionCacheInsecure = errors.New("session cache has insecure permissions")
+
+// cachedSession is the content of a session cache file. The server, port and
+// username values are recorded to help identify cache files; the cookies
+// are used to resume the authenticated session.
+type cachedSession struct {
+	Server   string         `json:"server"`
+	Port     int            `json:"port"`
+	Username string         `json:"username"`
+	Cookies  []*http.Cookie `json:"cookies"`
+}
+
+// sessionCacheFile returns the path to the session cache file within the
+// specified directory for the given server, port and username.
+func sessionCacheFile(dir string, server string, port int, username string) string {
+	key := fmt.Sprintf("%s:%d:%s", strings.ToLower(server), port, username)
+	sum := sha256.Sum256([]byte(key))
+
+	return filepath.Join(dir, hex.EncodeToString(sum[:])+".json")
+}
+
+// checkSessionCachePerms asserts that the session cache file or directory at
+// the specified path is accessible only by the owner. This check is skipped
+// on Windows where file mode permission bits are not meaningful.
+func checkSessionCachePerms(path string, info os.FileInfo) error {
+	if runtime.GOOS != "windows" && info.Mode().Perm()&0o077 != 0 {
+		return fmt.Errorf(
+			"%w: %s has mode %#o",
+			ErrSessionCacheInsecure,
+			path,
+			info.Mode().Perm(),
+		)
+	}
+
+	return nil
+}
+
+// loadCachedSession reads the session cache file at the specified path. A
+// nil value is returned without error if the file does not exist. Files
+// accessible by users other than the owner, or within a directory accessible
+// by users other than the owner, are rejected.
+func loadCachedSession(path string) (*cachedSession, error) {
+
+	path = filepath.Clean(path)
+
+	info, err := os.Stat(path)
+	switch {
+	case errors.Is(err, os.ErrNotExist):
+		return nil, nil
+	case err != nil:
+		return nil, err
+	}
+
+	if err := checkSessionCachePerms(path, info); err != nil {
+		return nil, err
+	}
+
+	dir := filepath.Dir(path)
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := checkSessionCachePerms(dir, dirInfo); err != nil {
+		return nil, err
+	}
+
+	content, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var cs cachedSession
+	if err := json.Unmarshal(content, &cs); err != nil {
+		return nil, fmt.Errorf("failed to decode session cache file %s: %w", path, err)
+	}
+
+	return &cs, nil
+}
+
+// saveCachedSession writes the session cache file at the specified path. The
+// file is written to a temporary file (accessible only by the owner) within
+// the same directory and then renamed so that concurrent plugin executions
+// do not read a partially written file. The directory is created (accessible
+// only by the owner) if it does not exist; an existing directory accessible
+// by users other than the owner is rejected.
+func saveCachedSession(path string, cs cachedSession) error {
+
+	content, err := json.Marshal(cs)
+	if err != nil {
+		return err
+	}
+
+	dir := filepath.Dir(path)
+	if err := os.MkdirAll(dir, 0700); err != nil {
+		return err
+	}
+
+	dirInfo, err := os.Stat(dir)
+	if err != nil {
+		return err
+	}
+
+	if err := checkSessionCachePerms(dir, dirInfo); err != nil {
+		return err
+	}
+
+	tmp, err := ioutil.TempFile(dir, ".session-*")
+	if err != nil {
+		return err
+	}
+
+	// remove the temporary file if it was not renamed due to an error
+	defer func() {
+		_ = os.Remove(tmp.Name())
+	}()
+
+	if _, err := tmp.Write(content); err != nil {
+		_ = tmp.Close()
+		return err
+	}
+
+	if err := tmp.Close(); err != nil {
+		return err
+	}
+
+	return os.Rename(tmp.Name(), path)
+}
+
+// loginWithSessionCache resumes the authenticated session recorded in the
+// session cache file for the specified server, port and user. If a cached
+// session is not available or has expired, a new session is created and
+// recorded for later use. Errors encountered reading or writing the cache
+// file are logged and otherwise ignored.
+func loginWithSessionCache(
+	ctx context.Context,
+	c *govmomi.Client,
+	u *url.URL,
+	server string,
+	port int,
+	userInfo *url.Userinfo,
+	cacheDir string,
+) error {
+
+	cacheFile := sessionCacheFile(cacheDir, server, port, userInfo.Username())
+
+	soapClient := c.Client.Client
+
+	cached, err := loadCachedSession(cacheFile)
+	if err != nil {
+		logger.Printf("failed to load cached session: %v", err)
+	}
+
+	if cached != nil {
+		soapClient.Jar.SetCookies(u, cached.Cookies)
+
+		userSession, err := c.SessionManager.UserSession(ctx)
+		switch {
+		case err != nil:
+			logger.Printf("failed to validate cached session: %v", err)
+		case userSession == nil:
+			logger.Printf("cached session from %s has expired", cacheFile)
+		default:
+			logger.Printf("reusing cached session from %s", cacheFile)
+			return nil
+		}
+	}
+
+	if err := c.Login(ctx, userInfo); err != nil {
+		return err
+	}
+
+	saveSession(c, u, server, port, userInfo.Username(), cacheDir)
+
+	return nil
+}
+
+// saveSession records the current session of the given client in the
+// session cache file for the specified server, port and user. Errors
+// encountered writing the cache file are logged and otherwise ignored.
+func saveSession(
+	c *govmomi.Client,
+	u *url.URL,
+	server string,
+	port int,
+	username string,
+	cacheDir string,
+) {
+
+	cacheFile := sessionCacheFile(cacheDir, server, port, username)
+
+	cs := cachedSession{
+		Server:   server,
+		Port:     port,
+		Username: username,
+		Cookies:  c.Client.Client.Jar.Cookies(u),
+	}
+
+	if err := saveCachedSession(cacheFile, cs); err != nil {
+		logger.Printf("failed to save session to cache: %v", err)
+	}
+}
@@ -0,0 +1,132 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"errors"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"reflect"
+	"runtime"
+	"testing"
+)
+
+func TestSessionCacheSaveLoad(t *testing.T) {
+
+	dir, err := ioutil.TempDir("", "check-vmware-session")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cacheDir := filepath.Join(dir, "cache")
+	cacheFile := sessionCacheFile(cacheDir, "VC1.example.com", 443, "user@example.com")
+
+	if got, want := cacheFile, sessionCacheFile(cacheDir, "vc1.example.com", 443, "user@example.com"); got != want {
+		t.Errorf("cache file for mixed case server name: got %q; want %q", got, want)
+	}
+
+	if got := sessionCacheFile(cacheDir, "vc1.example.com", 8443, "user@example.com"); got == cacheFile {
+		t.Errorf("cache file for different port matches %q", cacheFile)
+	}
+
+	cs, err := loadCachedSession(cacheFile)
+	if err != nil || cs != nil {
+		t.Fatalf("loading missing cache file: got (%v, %v); want (nil, nil)", cs, err)
+	}
+
+	want := cachedSession{
+		Server:   "vc1.example.com",
+		Port:     443,
+		Username: "user@example.com",
+		Cookies: []*http.Cookie{
+			{Name: "vmware_soap_session", Value: "52a3a6c4"},
+		},
+	}
+
+	if err := saveCachedSession(cacheFile, want); err != nil {
+		t.Fatalf("saveCachedSession returned unexpected error: %v", err)
+	}
+
+	if runtime.GOOS != "windows" {
+		for path, mode := range map[string]os.FileMode{cacheDir: 0700, cacheFile: 0600} {
+			info, err := os.Stat(path)
+			if err != nil {
+				t.Fatalf("failed to stat %s: %v", path, err)
+			}
+
+			if info.Mode().Perm() != mode {
+				t.Errorf("%s has mode %#o; want %#o", path, info.Mode().Perm(), mode)
+			}
+		}
+	}
+
+	got, err := loadCachedSession(cacheFile)
+	if err != nil {
+		t.Fatalf("loadCachedSession returned unexpected error: %v", err)
+	}
+
+	if got == nil || !reflect.DeepEqual(*got, want) {
+		t.Errorf("got cached session %+v; want %+v", got, want)
+	}
+}
+
+func TestSessionCachePermissions(t *testing.T) {
+
+	if runtime.GOOS == "windows" {
+		t.Skip("file mode permission bits are not checked on Windows")
+	}
+
+	dir, err := ioutil.TempDir("", "check-vmware-session")
+	if err != nil {
+		t.Fatalf("failed to create temporary directory: %v", err)
+	}
+	defer os.RemoveAll(dir)
+
+	cs := cachedSession{Server: "vc1.example.com", Port: 443, Username: "user"}
+
+	t.Run("insecure file", func(t *testing.T) {
+		cacheDir := filepath.Join(dir, "file")
+		cacheFile := sessionCacheFile(cacheDir, cs.Server, cs.Port, cs.Username)
+
+		if err := saveCachedSession(cacheFile, cs); err != nil {
+			t.Fatalf("saveCachedSession returned unexpected error: %v", err)
+		}
+
+		if err := os.Chmod(cacheFile, 0644); err != nil {
+			t.Fatalf("failed to change mode of %s: %v", cacheFile, err)
+		}
+
+		if _, err := loadCachedSession(cacheFile); !errors.Is(err, ErrSessionCacheInsecure) {
+			t.Errorf("got error %v; want %v", err, ErrSessionCacheInsecure)
+		}
+	})
+
+	t.Run("insecure existing directory", func(t *testing.T) {
+		cacheDir := filepath.Join(dir, "dir")
+		cacheFile := sessionCacheFile(cacheDir, cs.Server, cs.Port, cs.Username)
+
+		if err := saveCachedSession(cacheFile, cs); err != nil {
+			t.Fatalf("saveCachedSession returned unexpected error: %v", err)
+		}
+
+		if err := os.Chmod(cacheDir, 0777); err != nil {
+			t.Fatalf("failed to change mode of %s: %v", cacheDir, err)
+		}
+
+		if _, err := loadCachedSession(cacheFile); !errors.Is(err, ErrSessionCacheInsecure) {
+			t.Errorf("load: got error %v; want %v", err, ErrSessionCacheInsecure)
+		}
+
+		if err := saveCachedSession(cacheFile, cs); !errors.Is(err, ErrSessionCacheInsecure) {
+			t.Errorf("save: got error %v; want %v", err, ErrSessionCacheInsecure)
+		}
+	})
+}
@@ -4,9 +4,13 @@ import (
 	"errors"
 	"fmt"
 	"io"
+	"math"
+	"sort"
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/object"
 	"github.com/vmware/govmomi/units"
@@ -27,38 +31,16 @@ var ErrSnapshotCountThresholdCrossed = errors.New("snapshots exceed specified co
 // specified size threshold
 var ErrSnapshotSizeThresholdCrossed = errors.New("snapshot exceeds specified size threshold")
 
-// ExceedsSize indicates whether a given snapshot size is greater than the
-// specified value in GB.
-func ExceedsSize(snapshotSize int64, thresholdSize int64) bool {
-	return snapshotSize > (thresholdSize * units.GB)
+// ExceedsSize indicates whether a given snapshot size (converted to GB)
+// crosses the specified threshold range.
+func ExceedsSize(snapshotSize int64, thresholdSize config.Range) bool {
+	return thresholdSize.Alert(float64(snapshotSize) / float64(units.GB))
 }
 
-// ExceedsAge indicates whether a given snapshot creation date is older than
-// the specified number of days.
-func ExceedsAge(snapshotCreated time.Time, days int) bool {
-
-	now := time.Now()
-
-	// Flip user specified number of days negative so that we can wind
-	// back that many days from the file modification time. This gives
-	// us our threshold to compare file modification times against.
-	daysBack := -(days)
-	ageThreshold := now.AddDate(0, 0, daysBack)
-
-	switch {
-	case snapshotCreated.Before(ageThreshold):
-		return true
-	case snapshotCreated.Equal(ageThreshold):
-		return false
-	case snapshotCreated.After(ageThreshold):
-		return false
-
-	// TODO: Is there any other state than Before, Equal and After?
-	// TODO: Perhaps remove 'After' and use this instead?
-	default:
-		return false
-	}
-
+// ExceedsAge indicates whether the age (in days) of a given snapshot creation
+// date crosses the specified threshold range.
+func ExceedsAge(snapshotCreated time.Time, days config.Range) bool {
+	return days.Alert(time.Since(snapshotCreated).Hours() / 24)
 }
 
 // FilterVMsWithSnapshots filters the provided collection of VirtualMachines
@@ -95,12 +77,12 @@ func FilterVMsWithSnapshots(vms []mo.VirtualMachine) []mo.VirtualMachine {
 // whether one or many snapshots are considered to be in a CRITICAL or WARNING
 // state.
 type SnapshotThresholds struct {
-	AgeCritical   int
-	AgeWarning    int
-	SizeCritical  int
-	SizeWarning   int
-	CountCritical int
-	CountWarning  int
+	AgeCritical   config.Range
+	AgeWarning    config.Range
+	SizeCritical  config.Range
+	SizeWarning   config.Range
+	CountCritical config.Range
+	CountWarning  config.Range
 }
 
 // SnapshotSummary is intended to be a summary of the most commonly used
@@ -207,11 +189,11 @@ func (sss SnapshotSummarySet) SizeHR() string {
 	return units.ByteSize(sss.Size()).String()
 }
 
-// ExceedsAge indicates how many snapshots in the set are older than the
-// specified number of days. Unlike the ExceedsAge method for
+// ExceedsAge indicates how many snapshots in the set cross the specified age
+// threshold range (in days). Unlike the ExceedsAge method for
 // SnapshotSummarySets, this method focuses specifically on individual
 // snapshots.
-func (sss SnapshotSummarySet) ExceedsAge(days int) int {
+func (sss SnapshotSummarySet) ExceedsAge(days config.Range) int {
 
 	var numExceeded int
 	for _, snap := range sss.Snapshots {
@@ -223,11 +205,11 @@ func (sss SnapshotSummarySet) ExceedsAge(days int) int {
 	return numExceeded
 }
 
-// ExceedsSize indicates how many snapshots in the set are larger than the
-// specified size in GB. Unlike the ExceedsSize method for
+// ExceedsSize indicates how many snapshots in the set cross the specified
+// size threshold range (in GB). Unlike the ExceedsSize method for
 // SnapshotSummarySets, this method focuses specifically on individual
 // snapshot size.
-func (sss SnapshotSummarySet) ExceedsSize(sizeGB int) int {
+func (sss SnapshotSummarySet) ExceedsSize(sizeGB config.Range) int {
 
 	var numSnapshotsExceeded int
 	for _, snap := range sss.Snapshots {
@@ -251,8 +233,8 @@ func (sss SnapshotSummarySets) Snapshots() int {
 }
 
 // ExceedsAge indicates how many sets and number of snapshots from all of
-// those sets are older than the specified number of days.
-func (sss SnapshotSummarySets) ExceedsAge(days int) (int, int) {
+// those sets cross the specified age threshold range (in days).
+func (sss SnapshotSummarySets) ExceedsAge(days config.Range) (int, int) {
 
 	var setsExceeded int
 	var snapshotsExceeded int
@@ -267,22 +249,26 @@ func (sss SnapshotSummarySets) ExceedsAge(days int) (int, int) {
 }
 
 // ExcessSnapshots indicates how many sets have excess snapshots, how many excess
-// snapshots there are and how many total snapshots there are.
-func (sss SnapshotSummarySets) ExcessSnapshots(count int) (int, int, int) {
+// snapshots there are and how many total snapshots there are. Excess
+// snapshots are only counted if the specified threshold range has an upper
+// bound.
+func (sss SnapshotSummarySets) ExcessSnapshots(count config.Range) (int, int, int) {
 
 	var setsExceeded int
 	var snapshotsExceeded int
 	var snapshotsTotal int
 	for _, set := range sss {
-		if len(set.Snapshots) > count {
+		if count.Alert(float64(len(set.Snapshots))) {
 			setsExceeded++
 			snapshotsTotal += len(set.Snapshots)
 
 			// Excess snapshots calculated from number of snapshots minus the
-			// number permitted/specified, if positive.
-			exceeded := len(set.Snapshots) - count
-			if exceeded > 0 {
-				snapshotsExceeded += exceeded
+			// upper bound of the threshold range, if positive.
+			if !math.IsInf(count.End(), 1) {
+				exceeded := len(set.Snapshots) - int(count.End())
+				if exceeded > 0 {
+					snapshotsExceeded += exceeded
+				}
 			}
 		}
 	}
@@ -291,13 +277,13 @@ func (sss SnapshotSummarySets) ExcessSnapshots(count int) (int, int, int) {
 }
 
 // FilterByCount returns a SnapshotSummarySets value containing only sets
-// which have snapshots in excess of the specified number.
-func (sss SnapshotSummarySets) FilterByCount(count int) SnapshotSummarySets {
+// with a number of snapshots crossing the specified threshold range.
+func (sss SnapshotSummarySets) FilterByCount(count config.Range) SnapshotSummarySets {
 
 	var sets SnapshotSummarySets
 
 	for _, set := range sss {
-		if len(set.Snapshots) > count {
+		if count.Alert(float64(len(set.Snapshots))) {
 			sets = append(sets, set)
 		}
 	}
@@ -306,13 +292,14 @@ func (sss SnapshotSummarySets) FilterByCount(count int) SnapshotSummarySets {
 }
 
 // ExceedsSize indicates how many sets and number of snapshots from all of
-// those sets have cumulative snapshots larger than the specified size in GB.
-func (sss SnapshotSummarySets) ExceedsSize(sizeGB int) (int, int) {
+// those sets have cumulative snapshots crossing the specified size threshold
+// range (in GB).
+func (sss SnapshotSummarySets) ExceedsSize(sizeGB config.Range) (int, int) {
 
 	var numSetsExceeded int
 	var numSnapshotsExceeded int
 	for _, set := range sss {
-		if set.Size() > (int64(sizeGB) * units.GB) {
+		if ExceedsSize(set.Size(), sizeGB) {
 			numSetsExceeded++
 			numSnapshotsExceeded += len(set.Snapshots)
 		}
@@ -322,8 +309,8 @@ func (sss SnapshotSummarySets) ExceedsSize(sizeGB int) (int, int) {
 }
 
 // HasNotYetExceededAge indicates whether any of the snapshots in any of the
-// sets have yet to exceed the threshold for the specified number of days.
-func (sss SnapshotSummarySets) HasNotYetExceededAge(days int) bool {
+// sets have yet to cross the specified age threshold range (in days).
+func (sss SnapshotSummarySets) HasNotYetExceededAge(days config.Range) bool {
 
 	for _, set := range sss {
 		for _, snapSummary := range set.Snapshots {
@@ -336,9 +323,9 @@ func (sss SnapshotSummarySets) HasNotYetExceededAge(days int) bool {
 	return false
 }
 
-// HasNotYetExceededCount indicates whether any of the sets have yet to exceed
-// the threshold for the specified number of snapshots.
-func (sss SnapshotSummarySets) HasNotYetExceededCount(count int) bool {
+// HasNotYetExceededCount indicates whether any of the sets have yet to cross
+// the specified snapshot count threshold range.
+func (sss SnapshotSummarySets) HasNotYetExceededCount(count config.Range) bool {
 
 	for _, set := range sss {
 		switch {
@@ -351,7 +338,7 @@ func (sss SnapshotSummarySets) HasNotYetExceededCount(count int) bool {
 		// handles cases where there is just one snapshot and the threshold is
 		// 1 and more common cases where snapshots are present and the
 		// threshold is something more realistic such as 4 or more snapshots
-		case len(set.Snapshots) <= count:
+		case !count.Alert(float64(len(set.Snapshots))):
 			return true
 		}
 	}
@@ -360,12 +347,12 @@ func (sss SnapshotSummarySets) HasNotYetExceededCount(count int) bool {
 }
 
 // HasNotYetExceededSize indicates whether any snapshot set (all snapshots for
-// a specific VM) has yet to exceed the threshold for the specified size in
-// GB.
-func (sss SnapshotSummarySets) HasNotYetExceededSize(sizeGB int) bool {
+// a specific VM) has yet to cross the specified size threshold range (in
+// GB).
+func (sss SnapshotSummarySets) HasNotYetExceededSize(sizeGB config.Range) bool {
 
 	for _, set := range sss {
-		if !ExceedsSize(set.Size(), int64(sizeGB)) {
+		if !ExceedsSize(set.Size(), sizeGB) {
 			return true
 		}
 	}
@@ -397,16 +384,16 @@ func (ss SnapshotSummary) Age() string {
 
 }
 
-// IsAgeExceeded indicates whether the snapshot is older than the specified
-// number of days.
-func (ss SnapshotSummary) IsAgeExceeded(days int) bool {
+// IsAgeExceeded indicates whether the snapshot age crosses the specified
+// threshold range (in days).
+func (ss SnapshotSummary) IsAgeExceeded(days config.Range) bool {
 	return ExceedsAge(ss.createTime, days)
 }
 
-// IsSizeExceeded indicates whether the snapshot is larger than the specified
-// size in GB.
-func (ss SnapshotSummary) IsSizeExceeded(sizeGB int) bool {
-	return ExceedsSize(ss.Size, int64(sizeGB))
+// IsSizeExceeded indicates whether the snapshot size crosses the specified
+// threshold range (in GB).
+func (ss SnapshotSummary) IsSizeExceeded(sizeGB config.Range) bool {
+	return ExceedsSize(ss.Size, sizeGB)
 }
 
 // IsWarningState indicates whether the snapshot has exceeded age or size
@@ -930,8 +917,8 @@ func NewSnapshotSummarySet(
 				createTime:        snapTree.CreateTime,
 				ageWarningState:   ExceedsAge(snapTree.CreateTime, snapshotThresholds.AgeWarning),
 				ageCriticalState:  ExceedsAge(snapTree.CreateTime, snapshotThresholds.AgeCritical),
-				sizeWarningState:  ExceedsSize(snapshotSize, int64(snapshotThresholds.SizeWarning)),
-				sizeCriticalState: ExceedsSize(snapshotSize, int64(snapshotThresholds.SizeCritical)),
+				sizeWarningState:  ExceedsSize(snapshotSize, snapshotThresholds.SizeWarning),
+				sizeCriticalState: ExceedsSize(snapshotSize, snapshotThresholds.SizeCritical),
 			})
 
 			if snapTree.ChildSnapshotList != nil {
@@ -950,17 +937,17 @@ func NewSnapshotSummarySet(
 	}
 
 	logger.Println("setSize for VM ", vm.Name, ":", setSize)
-	logger.Println("setSizeWarningState for VM ", vm.Name, ":", ExceedsSize(setSize, int64(snapshotThresholds.SizeWarning)))
-	logger.Println("setSizeCriticalState for VM ", vm.Name, ":", ExceedsSize(setSize, int64(snapshotThresholds.SizeCritical)))
+	logger.Println("setSizeWarningState for VM ", vm.Name, ":", ExceedsSize(setSize, snapshotThresholds.SizeWarning))
+	logger.Println("setSizeCriticalState for VM ", vm.Name, ":", ExceedsSize(setSize, snapshotThresholds.SizeCritical))
 
 	return SnapshotSummarySet{
 		VM:                    vm.Self,
 		VMName:                vm.Name,
 		Snapshots:             snapshots,
-		setSizeWarningState:   ExceedsSize(setSize, int64(snapshotThresholds.SizeWarning)),
-		setSizeCriticalState:  ExceedsSize(setSize, int64(snapshotThresholds.SizeCritical)),
-		setCountWarningState:  len(snapshots) > snapshotThresholds.CountWarning,
-		setCountCriticalState: len(snapshots) > snapshotThresholds.CountCritical,
+		setSizeWarningState:   ExceedsSize(setSize, snapshotThresholds.SizeWarning),
+		setSizeCriticalState:  ExceedsSize(setSize, snapshotThresholds.SizeCritical),
+		setCountWarningState:  snapshotThresholds.CountWarning.Alert(float64(len(snapshots))),
+		setCountCriticalState: snapshotThresholds.CountCritical.Alert(float64(len(snapshots))),
 	}
 
 }
@@ -992,7 +979,7 @@ func SnapshotsAgeOneLineCheckSummary(
 		vms, snapshots := snapshotSets.ExceedsAge(snapshotThresholds.AgeCritical)
 
 		return fmt.Sprintf(
-			"%s: %d VMs with %d snapshots older than %d days detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with %d snapshots crossing age threshold (%s days) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshots,
@@ -1007,7 +994,7 @@ func SnapshotsAgeOneLineCheckSummary(
 		vms, snapshots := snapshotSets.ExceedsAge(snapshotThresholds.AgeWarning)
 
 		return fmt.Sprintf(
-			"%s: %d VMs with %d snapshots older than %d days detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with %d snapshots crossing age threshold (%s days) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshots,
@@ -1020,7 +1007,7 @@ func SnapshotsAgeOneLineCheckSummary(
 	default:
 
 		return fmt.Sprintf(
-			"%s: No snapshots older than %d days detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: No snapshots crossing age threshold (%s days) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			snapshotThresholds.AgeWarning,
 			len(evaluatedVMs),
@@ -1031,6 +1018,44 @@ func SnapshotsAgeOneLineCheckSummary(
 	}
 }
 
+// SnapshotsAgePerfData generates performance data for the age of snapshots
+// in the specified snapshot sets.
+func SnapshotsAgePerfData(
+	snapshotSets SnapshotSummarySets,
+	snapshotThresholds SnapshotThresholds,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	var maxAgeDays float64
+	for _, set := range snapshotSets {
+		for _, snap := range set.Snapshots {
+			if snap.AgeDays() > maxAgeDays {
+				maxAgeDays = snap.AgeDays()
+			}
+		}
+	}
+
+	_, snapshotsCritical := snapshotSets.ExceedsAge(snapshotThresholds.AgeCritical)
+	_, snapshotsWarning := snapshotSets.ExceedsAge(snapshotThresholds.AgeWarning)
+
+	pd := []perfdata.PerformanceData{
+		{
+			Label: "snapshot_age_max_days",
+			Value: perfdata.Float(maxAgeDays),
+			Warn:  snapshotThresholds.AgeWarning.String(),
+			Crit:  snapshotThresholds.AgeCritical.String(),
+			Min:   "0",
+		},
+		countPerfData("snapshots", snapshotSets.Snapshots(), ""),
+		countPerfData("snapshots_age_critical", snapshotsCritical, ""),
+		countPerfData("snapshots_age_warning", snapshotsWarning, ""),
+		countPerfData("vms_with_snapshots", len(snapshotSets), ""),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
 // SnapshotsCountOneLineCheckSummary is used to generate a one-line Nagios
 // service check results summary. This is the line most prominent in
 // notifications.
@@ -1058,7 +1083,7 @@ func SnapshotsCountOneLineCheckSummary(
 		vms, snapsExcess, _ := snapshotSets.ExcessSnapshots(snapshotThresholds.CountCritical)
 
 		return fmt.Sprintf(
-			"%s: %d VMs with snapshots count greater than %d; %d excess snapshots detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with snapshots count crossing threshold (%s); %d excess snapshots detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshotThresholds.CountCritical,
@@ -1073,7 +1098,7 @@ func SnapshotsCountOneLineCheckSummary(
 		vms, snapsExcess, _ := snapshotSets.ExcessSnapshots(snapshotThresholds.CountWarning)
 
 		return fmt.Sprintf(
-			"%s: %d VMs with snapshots count greater than %d; %d excess snapshots detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with snapshots count crossing threshold (%s); %d excess snapshots detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshotThresholds.CountWarning,
@@ -1086,7 +1111,7 @@ func SnapshotsCountOneLineCheckSummary(
 	default:
 
 		return fmt.Sprintf(
-			"%s: No VMs with snapshots count greater than %d detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: No VMs with snapshots count crossing threshold (%s) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			snapshotThresholds.CountWarning,
 			len(evaluatedVMs),
@@ -1097,6 +1122,42 @@ func SnapshotsCountOneLineCheckSummary(
 	}
 }
 
+// SnapshotsCountPerfData generates performance data for the number of
+// snapshots in the specified snapshot sets.
+func SnapshotsCountPerfData(
+	snapshotSets SnapshotSummarySets,
+	snapshotThresholds SnapshotThresholds,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	var maxCount int
+	for _, set := range snapshotSets {
+		if len(set.Snapshots) > maxCount {
+			maxCount = len(set.Snapshots)
+		}
+	}
+
+	setsCritical, _, _ := snapshotSets.ExcessSnapshots(snapshotThresholds.CountCritical)
+	setsWarning, _, _ := snapshotSets.ExcessSnapshots(snapshotThresholds.CountWarning)
+
+	pd := []perfdata.PerformanceData{
+		{
+			Label: "snapshot_count_max",
+			Value: perfdata.Int(int64(maxCount)),
+			Warn:  snapshotThresholds.CountWarning.String(),
+			Crit:  snapshotThresholds.CountCritical.String(),
+			Min:   "0",
+		},
+		countPerfData("snapshots", snapshotSets.Snapshots(), ""),
+		countPerfData("vms_snapshot_count_critical", setsCritical, ""),
+		countPerfData("vms_snapshot_count_warning", setsWarning, ""),
+		countPerfData("vms_with_snapshots", len(snapshotSets), ""),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
 // SnapshotsSizeOneLineCheckSummary is used to generate a one-line Nagios
 // service check results summary. This is the line most prominent in
 // notifications.
@@ -1123,7 +1184,7 @@ func SnapshotsSizeOneLineCheckSummary(
 
 		vms, snapshots := snapshotSets.ExceedsSize(snapshotThresholds.SizeCritical)
 		return fmt.Sprintf(
-			"%s: %d VMs with combined snapshots (%d) exceeding %d %s detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with combined snapshots (%d) crossing size threshold (%s %s) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshots,
@@ -1139,7 +1200,7 @@ func SnapshotsSizeOneLineCheckSummary(
 		vms, snapshots := snapshotSets.ExceedsSize(snapshotThresholds.SizeWarning)
 
 		return fmt.Sprintf(
-			"%s: %d VMs with combined snapshots (%d) exceeding %d %s detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: %d VMs with combined snapshots (%d) crossing size threshold (%s %s) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			vms,
 			snapshots,
@@ -1153,7 +1214,7 @@ func SnapshotsSizeOneLineCheckSummary(
 	default:
 
 		return fmt.Sprintf(
-			"%s: No VMs, each with combined snapshots exceeding %d %s detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
+			"%s: No VMs, each with combined snapshots crossing size threshold (%s %s) detected (evaluated %d VMs, %d Snapshots, %d Resource Pools)",
 			stateLabel,
 			snapshotThresholds.SizeWarning,
 			snapshotThresholdTypeSizeSuffix,
@@ -1165,13 +1226,158 @@ func SnapshotsSizeOneLineCheckSummary(
 	}
 }
 
+// SnapshotsSizePerfData generates performance data for the cumulative size
+// of snapshots in the specified snapshot sets.
+func SnapshotsSizePerfData(
+	snapshotSets SnapshotSummarySets,
+	snapshotThresholds SnapshotThresholds,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	var sizeTotal int64
+	var sizeMax int64
+	for _, set := range snapshotSets {
+		sizeTotal += set.Size()
+		if set.Size() > sizeMax {
+			sizeMax = set.Size()
+		}
+	}
+
+	setsCritical, _ := snapshotSets.ExceedsSize(snapshotThresholds.SizeCritical)
+	setsWarning, _ := snapshotSets.ExceedsSize(snapshotThresholds.SizeWarning)
+
+	pd := []perfdata.PerformanceData{
+		{
+			// Thresholds are specified in GB.
+			Label:             "snapshot_size_max",
+			Value:             perfdata.Float(float64(sizeMax) / float64(units.GB)),
+			UnitOfMeasurement: perfdata.UOMGigabytes,
+			Warn:              snapshotThresholds.SizeWarning.String(),
+			Crit:              snapshotThresholds.SizeCritical.String(),
+			Min:               "0",
+		},
+		{
+			Label:             "snapshot_size_total",
+			Value:             perfdata.Int(sizeTotal),
+			UnitOfMeasurement: perfdata.UOMBytes,
+			Min:               "0",
+		},
+		countPerfData("snapshots", snapshotSets.Snapshots(), ""),
+		countPerfData("vms_snapshot_size_critical", setsCritical, ""),
+		countPerfData("vms_snapshot_size_warning", setsWarning, ""),
+		countPerfData("vms_with_snapshots", len(snapshotSets), ""),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// SnapshotsData represents the evaluated snapshots for a set of
+// VirtualMachines. This type is shared by the snapshots age, count and size
+// plugins; only the thresholds used by the active plugin are recorded.
+type SnapshotsData struct {
+	EvaluatedVMsData
+	Thresholds   SnapshotThresholdsData   `json:"thresholds"`
+	SnapshotSets []SnapshotSummarySetData `json:"snapshot_sets"`
+}
+
+// SnapshotThresholdsData represents the snapshot thresholds used by a
+// plugin. Thresholds not used by the active plugin are omitted.
+type SnapshotThresholdsData struct {
+	AgeCritical   string `json:"age_critical_days,omitempty"`
+	AgeWarning    string `json:"age_warning_days,omitempty"`
+	CountCritical string `json:"count_critical,omitempty"`
+	CountWarning  string `json:"count_warning,omitempty"`
+	SizeCritical  string `json:"size_critical_gb,omitempty"`
+	SizeWarning   string `json:"size_warning_gb,omitempty"`
+}
+
+// SnapshotSummarySetData represents the snapshots associated with a
+// VirtualMachine.
+type SnapshotSummarySetData struct {
+	VM        string                `json:"vm"`
+	MOID      string                `json:"moid"`
+	State     string                `json:"state"`
+	Count     int                   `json:"count"`
+	SizeBytes int64                 `json:"size_bytes"`
+	Snapshots []SnapshotSummaryData `json:"snapshots"`
+}
+
+// SnapshotSummaryData represents a single VirtualMachine snapshot.
+type SnapshotSummaryData struct {
+	Name        string    `json:"name"`
+	MOID        string    `json:"moid"`
+	ID          int32     `json:"id"`
+	Description string    `json:"description"`
+	Datastore   string    `json:"datastore"`
+	State       string    `json:"state"`
+	SizeBytes   int64     `json:"size_bytes"`
+	Created     time.Time `json:"created"`
+	AgeDays     float64   `json:"age_days"`
+}
+
+// NewSnapshotsData generates machine-readable data for the specified
+// snapshot sets.
+func NewSnapshotsData(
+	snapshotSets SnapshotSummarySets,
+	snapshotThresholds SnapshotThresholds,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) SnapshotsData {
+
+	sets := make([]SnapshotSummarySetData, 0, len(snapshotSets))
+	for _, set := range snapshotSets {
+
+		snapshots := make([]SnapshotSummaryData, 0, len(set.Snapshots))
+		for _, snap := range set.Snapshots {
+			snapshots = append(snapshots, SnapshotSummaryData{
+				Name:        snap.Name,
+				MOID:        snap.MOID,
+				ID:          snap.ID,
+				Description: snap.Description,
+				Datastore:   snap.DatastoreName,
+				State:       stateLabel(snap.IsCriticalState(), snap.IsWarningState()),
+				SizeBytes:   snap.Size,
+				Created:     snap.createTime,
+				AgeDays:     snap.AgeDays(),
+			})
+		}
+
+		sets = append(sets, SnapshotSummarySetData{
+			VM:        set.VMName,
+			MOID:      set.VM.Value,
+			State:     stateLabel(set.IsCriticalState(), set.IsWarningState()),
+			Count:     len(set.Snapshots),
+			SizeBytes: set.Size(),
+			Snapshots: snapshots,
+		})
+	}
+
+	sort.Slice(sets, func(i, j int) bool {
+		return strings.ToLower(sets[i].VM) < strings.ToLower(sets[j].VM)
+	})
+
+	return SnapshotsData{
+		EvaluatedVMsData: newEvaluatedVMsData(evaluatedVMs, rps),
+		Thresholds: SnapshotThresholdsData{
+			AgeCritical:   snapshotThresholds.AgeCritical.String(),
+			AgeWarning:    snapshotThresholds.AgeWarning.String(),
+			CountCritical: snapshotThresholds.CountCritical.String(),
+			CountWarning:  snapshotThresholds.CountWarning.String(),
+			SizeCritical:  snapshotThresholds.SizeCritical.String(),
+			SizeWarning:   snapshotThresholds.SizeWarning.String(),
+		},
+		SnapshotSets: sets,
+	}
+}
+
 // writeSnapshotsListEntries generates a common snapshots report for both age
 // and size checks listing any snapshots which have exceeded thresholds along
 // with any snapshots which have not yet exceeded them.
 func writeSnapshotsListEntries(
 	w io.Writer,
-	snapshotCriticalThreshold int,
-	snapshotWarningThreshold int,
+	snapshotCriticalThreshold config.Range,
+	snapshotWarningThreshold config.Range,
 	unitSuffix string,
 	unitName string,
 	snapshotSummarySets SnapshotSummarySets,
@@ -1181,7 +1387,7 @@ func writeSnapshotsListEntries(
 
 	fmt.Fprintf(
 		w,
-		"Snapshots exceeding WARNING (%d %s) or CRITICAL (%d %s) %s thresholds:%s%s",
+		"Snapshots exceeding WARNING (%s %s) or CRITICAL (%s %s) %s thresholds:%s%s",
 		snapshotWarningThreshold,
 		unitSuffix,
 		snapshotCriticalThreshold,
@@ -1377,6 +1583,13 @@ func writeSnapshotsReportFooter(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		w,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		w,
 		"* Plugin User Agent: %s%s",
@@ -1384,6 +1597,13 @@ func writeSnapshotsReportFooter(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		w,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		w,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -0,0 +1,424 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"fmt"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/atc0005/go-nagios"
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/mo"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+// tagSeparator separates the category and tag names used to specify a
+// vSphere tag (e.g., Environment:Production).
+const tagSeparator string = ":"
+
+// tagLookupBatchSize is the maximum number of objects for which attached
+// tags are requested in a single vSphere Automation API request.
+const tagLookupBatchSize int = 1000
+
+// splitTag splits the given category:tag value into the category and tag
+// names. An error is returned if either name is missing.
+func splitTag(tag string) (string, string, error) {
+	parts := strings.SplitN(tag, tagSeparator, 2)
+	if len(parts) != 2 || strings.TrimSpace(parts[0]) == "" || strings.TrimSpace(parts[1]) == "" {
+		return "", "", fmt.Errorf(
+			"invalid tag %q; expected format is category%stag",
+			tag,
+			tagSeparator,
+		)
+	}
+
+	return strings.TrimSpace(parts[0]), strings.TrimSpace(parts[1]), nil
+}
+
+// tagSession is a vSphere Automation API (REST) session used to retrieve
+// vSphere tags. Tags are provided only by vCenter.
+type tagSession struct {
+	*tags.Manager
+}
+
+// newTagSession creates a vSphere Automation API session using the
+// credentials (or the session) of the given client.
+func newTagSession(ctx context.Context, c *vim25.Client) (*tagSession, error) {
+
+	if err := RequireVCenter(c, "vSphere tags"); err != nil {
+		return nil, err
+	}
+
+	rc := rest.NewClient(c)
+
+	// The TLS settings of the SOAP client are copied to the new client, but
+	// the proxy settings are not.
+	rc.DefaultTransport().Proxy = c.DefaultTransport().Proxy
+
+	if err := rc.Login(ctx, credentials(c)); err != nil {
+		return nil, fmt.Errorf("failed to login to vSphere Automation API: %w", err)
+	}
+
+	return &tagSession{Manager: tags.NewManager(rc)}, nil
+}
+
+// logout ends the vSphere Automation API session.
+func (s *tagSession) logout(ctx context.Context) {
+	if err := s.Logout(ctx); err != nil {
+		logger.Printf("failed to logout from vSphere Automation API: %v", err)
+	}
+}
+
+// resolveTags retrieves the IDs of the specified category:tag values. Values
+// which are not found are omitted from the results. Categories and tags are
+// matched case-insensitively. Only the tags of the categories referenced by
+// the specified values are retrieved.
+func (s *tagSession) resolveTags(ctx context.Context, tagNames []string) (map[string]string, error) {
+
+	categories, err := s.GetCategories(ctx)
+	if err != nil {
+		return nil, fmt.Errorf("failed to retrieve tag categories: %w", err)
+	}
+
+	categoryIDs := make(map[string]string, len(categories))
+	for _, category := range categories {
+		categoryIDs[strings.ToLower(category.Name)] = category.ID
+	}
+
+	categoryTags := make(map[string][]tags.Tag)
+	tagIDs := make(map[string]string, len(tagNames))
+
+	for _, tagName := range tagNames {
+		categoryName, name, err := splitTag(tagName)
+		if err != nil {
+			return nil, err
+		}
+
+		categoryID, ok := categoryIDs[strings.ToLower(categoryName)]
+		if !ok {
+			continue
+		}
+
+		if _, ok := categoryTags[categoryID]; !ok {
+			categoryTags[categoryID], err = s.GetTagsForCategory(ctx, categoryID)
+			if err != nil {
+				return nil, fmt.Errorf(
+					"failed to retrieve tags for category %s: %w",
+					categoryName,
+					err,
+				)
+			}
+		}
+
+		for _, tag := range categoryTags[categoryID] {
+			if strings.EqualFold(tag.Name, name) {
+				tagIDs[tagName] = tag.ID
+				break
+			}
+		}
+	}
+
+	return tagIDs, nil
+}
+
+// taggedObjects returns the given objects which have at least one of the
+// specified tag IDs attached. Attached tags are retrieved in batches instead
+// of one request per object.
+func (s *tagSession) taggedObjects(ctx context.Context, objs []types.ManagedObjectReference, tagIDs map[string]bool) (map[types.ManagedObjectReference]bool, error) {
+
+	tagged := make(map[types.ManagedObjectReference]bool)
+
+	for start := 0; start < len(objs); start += tagLookupBatchSize {
+		end := start + tagLookupBatchSize
+		if end > len(objs) {
+			end = len(objs)
+		}
+
+		refs := make([]mo.Reference, 0, end-start)
+		for _, obj := range objs[start:end] {
+			refs = append(refs, obj)
+		}
+
+		attached, err := s.ListAttachedTagsOnObjects(ctx, refs)
+		if err != nil {
+			return nil, fmt.Errorf("failed to retrieve attached tags: %w", err)
+		}
+
+		for _, objTags := range attached {
+			for _, tagID := range objTags.TagIDs {
+				if tagIDs[tagID] {
+					tagged[objTags.ObjectID.Reference()] = true
+					break
+				}
+			}
+		}
+	}
+
+	return tagged, nil
+}
+
+// ValidateTags is responsible for receiving two lists of vSphere tags (in
+// category:tag format), explicitly "included" (aka, "whitelisted") and
+// explicitly "excluded" (aka, "blacklisted"). If any list entries are not
+// found in the vSphere environment an error is returned listing which ones.
+// Tags are provided only by vCenter; an error is returned if tags are
+// specified for a standalone ESXi host.
+func ValidateTags(ctx context.Context, c *vim25.Client, includeTags []string, excludeTags []string) error {
+
+	funcTimeStart := time.Now()
+
+	defer func(its []string, ets []string) {
+		logger.Printf(
+			"It took %v to execute ValidateTags func (and validate %d tags).\n",
+			time.Since(funcTimeStart),
+			len(its)+len(ets),
+		)
+	}(includeTags, excludeTags)
+
+	if len(includeTags) == 0 && len(excludeTags) == 0 {
+		return nil
+	}
+
+	s, err := newTagSession(ctx, c)
+	if err != nil {
+		return err
+	}
+	defer s.logout(ctx)
+
+	tagIDs, err := s.resolveTags(ctx, append(append([]string{}, includeTags...), excludeTags...))
+	if err != nil {
+		return err
+	}
+
+	found := func(tag string) bool {
+		_, ok := tagIDs[tag]
+		return ok
+	}
+
+	return validateNames("tags", found, includeTags, excludeTags)
+}
+
+// ValidateServersTags is the equivalent of ValidateTags for plugins
+// evaluating multiple servers. An error is returned if any list entries are
+// not found on at least one of the servers. If the tags for a server cannot
+// be retrieved, the error is recorded for the session and the server is
+// excluded from further evaluation.
+func ValidateServersTags(ctx context.Context, sessions []ServerSession, includeTags []string, excludeTags []string) error {
+
+	if len(includeTags) == 0 && len(excludeTags) == 0 {
+		return nil
+	}
+
+	tagNames := append(append([]string{}, includeTags...), excludeTags...)
+
+	var mu sync.Mutex
+	foundTags := make(map[string]bool, len(tagNames))
+
+	GetServerObjects(ctx, sessions, func(ctx context.Context, c *vim25.Client) (ServerObjects, error) {
+		s, err := newTagSession(ctx, c)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude tag lists: %w", err)
+		}
+		defer s.logout(ctx)
+
+		tagIDs, err := s.resolveTags(ctx, tagNames)
+		if err != nil {
+			return ServerObjects{}, fmt.Errorf("error validating include/exclude tag lists: %w", err)
+		}
+
+		mu.Lock()
+		defer mu.Unlock()
+		for tag := range tagIDs {
+			foundTags[tag] = true
+		}
+
+		return ServerObjects{}, nil
+	})
+
+	found := func(tag string) bool {
+		return foundTags[tag]
+	}
+
+	return validateNames("tags", found, includeTags, excludeTags)
+}
+
+// eligibleByTag evaluates the given objects against the specified include or
+// exclude tag lists, returning the objects eligible for evaluation.
+func eligibleByTag(ctx context.Context, c *vim25.Client, objs []types.ManagedObjectReference, includeTags []string, excludeTags []string) (map[types.ManagedObjectReference]bool, error) {
+
+	s, err := newTagSession(ctx, c)
+	if err != nil {
+		return nil, err
+	}
+	defer s.logout(ctx)
+
+	// config validation asserts that only one of include/exclude tag flags
+	// are specified
+	tagNames := includeTags
+	if len(excludeTags) > 0 {
+		tagNames = excludeTags
+	}
+
+	resolved, err := s.resolveTags(ctx, tagNames)
+	if err != nil {
+		return nil, err
+	}
+
+	tagIDs := make(map[string]bool, len(resolved))
+	for _, id := range resolved {
+		tagIDs[id] = true
+	}
+
+	tagged := make(map[types.ManagedObjectReference]bool)
+	if len(tagIDs) > 0 && len(objs) > 0 {
+		tagged, err = s.taggedObjects(ctx, objs, tagIDs)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	eligible := make(map[types.ManagedObjectReference]bool, len(objs))
+	for _, obj := range objs {
+		switch {
+		case len(includeTags) > 0:
+			eligible[obj] = tagged[obj]
+		default:
+			eligible[obj] = !tagged[obj]
+		}
+	}
+
+	return eligible, nil
+}
+
+// FilterVMsByTag receives a collection of VirtualMachines and two lists of
+// vSphere tags (in category:tag format) that should either be explicitly
+// included or excluded. A new collection of VirtualMachines with at least one
+// of the included tags (or none of the excluded tags) attached is returned.
+// If both lists are empty, the received collection is returned as-is.
+func FilterVMsByTag(ctx context.Context, c *vim25.Client, vms []mo.VirtualMachine, includeTags []string, excludeTags []string) ([]mo.VirtualMachine, error) {
+
+	funcTimeStart := time.Now()
+
+	defer func(vms []mo.VirtualMachine) {
+		logger.Printf(
+			"It took %v to execute FilterVMsByTag func (and filter %d VMs).\n",
+			time.Since(funcTimeStart),
+			len(vms),
+		)
+	}(vms)
+
+	if len(includeTags) == 0 && len(excludeTags) == 0 {
+		return vms, nil
+	}
+
+	refs := make([]types.ManagedObjectReference, 0, len(vms))
+	for _, vm := range vms {
+		refs = append(refs, vm.Self)
+	}
+
+	eligible, err := eligibleByTag(ctx, c, refs, includeTags, excludeTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter VMs by tag: %w", err)
+	}
+
+	filteredVMs := make([]mo.VirtualMachine, 0, len(vms))
+	for _, vm := range vms {
+		if eligible[vm.Self] {
+			filteredVMs = append(filteredVMs, vm)
+		}
+	}
+
+	return filteredVMs, nil
+}
+
+// FilterHostSystemsByTag is the equivalent of FilterVMsByTag for
+// HostSystems.
+func FilterHostSystemsByTag(ctx context.Context, c *vim25.Client, hss []mo.HostSystem, includeTags []string, excludeTags []string) ([]mo.HostSystem, error) {
+
+	if len(includeTags) == 0 && len(excludeTags) == 0 {
+		return hss, nil
+	}
+
+	refs := make([]types.ManagedObjectReference, 0, len(hss))
+	for _, hs := range hss {
+		refs = append(refs, hs.Self)
+	}
+
+	eligible, err := eligibleByTag(ctx, c, refs, includeTags, excludeTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter hosts by tag: %w", err)
+	}
+
+	filteredHSs := make([]mo.HostSystem, 0, len(hss))
+	for _, hs := range hss {
+		if eligible[hs.Self] {
+			filteredHSs = append(filteredHSs, hs)
+		}
+	}
+
+	return filteredHSs, nil
+}
+
+// FilterDatastoresByTag is the equivalent of FilterVMsByTag for Datastores.
+func FilterDatastoresByTag(ctx context.Context, c *vim25.Client, dss []mo.Datastore, includeTags []string, excludeTags []string) ([]mo.Datastore, error) {
+
+	if len(includeTags) == 0 && len(excludeTags) == 0 {
+		return dss, nil
+	}
+
+	refs := make([]types.ManagedObjectReference, 0, len(dss))
+	for _, ds := range dss {
+		refs = append(refs, ds.Self)
+	}
+
+	eligible, err := eligibleByTag(ctx, c, refs, includeTags, excludeTags)
+	if err != nil {
+		return nil, fmt.Errorf("failed to filter datastores by tag: %w", err)
+	}
+
+	filteredDSs := make([]mo.Datastore, 0, len(dss))
+	for _, ds := range dss {
+		if eligible[ds.Self] {
+			filteredDSs = append(filteredDSs, ds)
+		}
+	}
+
+	return filteredDSs, nil
+}
+
+// TagsReport generates a summary of the vSphere tags used to limit
+// evaluation of objects for use with the Long Service Output field. This
+// summary is intended to be appended to the report generated by plugins
+// supporting these options.
+func TagsReport(includeTags []string, excludeTags []string) string {
+
+	var report strings.Builder
+
+	fmt.Fprintf(
+		&report,
+		"* Specified tags to explicitly include (%d): [%v]%s",
+		len(includeTags),
+		strings.Join(includeTags, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	fmt.Fprintf(
+		&report,
+		"* Specified tags to explicitly exclude (%d): [%v]%s",
+		len(excludeTags),
+		strings.Join(excludeTags, ", "),
+		nagios.CheckOutputEOL,
+	)
+
+	return report.String()
+}
@@ -0,0 +1,189 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"net/url"
+	"reflect"
+	"strings"
+	"sync"
+	"testing"
+
+	"github.com/vmware/govmomi/vapi/rest"
+	"github.com/vmware/govmomi/vapi/tags"
+	"github.com/vmware/govmomi/vim25"
+	"github.com/vmware/govmomi/vim25/soap"
+	"github.com/vmware/govmomi/vim25/types"
+)
+
+func TestSplitTag(t *testing.T) {
+
+	tests := map[string]struct {
+		tag          string
+		wantCategory string
+		wantName     string
+		wantErr      bool
+	}{
+		"category and tag": {
+			tag:          "Environment:Production",
+			wantCategory: "Environment",
+			wantName:     "Production",
+		},
+		"surrounding whitespace": {
+			tag:          " Environment : Production ",
+			wantCategory: "Environment",
+			wantName:     "Production",
+		},
+		"separator in tag name": {
+			tag:          "Backup:daily:02:00",
+			wantCategory: "Backup",
+			wantName:     "daily:02:00",
+		},
+		"missing separator": {
+			tag:     "Production",
+			wantErr: true,
+		},
+		"missing category": {
+			tag:     ":Production",
+			wantErr: true,
+		},
+		"missing tag": {
+			tag:     "Environment: ",
+			wantErr: true,
+		},
+	}
+
+	for name, tt := range tests {
+		tt := tt
+		t.Run(name, func(t *testing.T) {
+			category, tagName, err := splitTag(tt.tag)
+			switch {
+			case tt.wantErr && err == nil:
+				t.Fatalf("splitTag(%q) did not return expected error", tt.tag)
+			case !tt.wantErr && err != nil:
+				t.Fatalf("splitTag(%q) returned unexpected error: %v", tt.tag, err)
+			}
+
+			if category != tt.wantCategory || tagName != tt.wantName {
+				t.Errorf(
+					"splitTag(%q) = (%q, %q); want (%q, %q)",
+					tt.tag,
+					category,
+					tagName,
+					tt.wantCategory,
+					tt.wantName,
+				)
+			}
+		})
+	}
+}
+
+func TestTaggedObjectsBatching(t *testing.T) {
+
+	const prodTagID string = "urn:vmomi:InventoryServiceTag:prod:GLOBAL"
+
+	var mu sync.Mutex
+	var batchSizes []int
+
+	handler := func(w http.ResponseWriter, r *http.Request) {
+		if !strings.HasSuffix(r.URL.Path, "/com/vmware/cis/tagging/tag-association") ||
+			r.URL.Query().Get("~action") != "list-attached-tags-on-objects" {
+			http.NotFound(w, r)
+			return
+		}
+
+		var req struct {
+			ObjectIDs []struct {
+				Type string `json:"type"`
+				ID   string `json:"id"`
+			} `json:"object_ids"`
+		}
+		if err := json.NewDecoder(r.Body).Decode(&req); err != nil {
+			http.Error(w, err.Error(), http.StatusBadRequest)
+			return
+		}
+
+		mu.Lock()
+		batchSizes = append(batchSizes, len(req.ObjectIDs))
+		mu.Unlock()
+
+		type attachedTags struct {
+			ObjectID interface{} `json:"object_id"`
+			TagIDs   []string    `json:"tag_ids"`
+		}
+
+		res := struct {
+			Value []attachedTags `json:"value"`
+		}{Value: make([]attachedTags, 0, len(req.ObjectIDs))}
+
+		// Objects with an even MOID number have the tag attached.
+		for _, obj := range req.ObjectIDs {
+			var n int
+			_, _ = fmt.Sscanf(obj.ID, "vm-%d", &n)
+
+			var tagIDs []string
+			if n%2 == 0 {
+				tagIDs = []string{prodTagID}
+			}
+
+			res.Value = append(res.Value, attachedTags{ObjectID: obj, TagIDs: tagIDs})
+		}
+
+		w.Header().Set("Content-Type", "application/json")
+		_ = json.NewEncoder(w).Encode(res)
+	}
+
+	server := httptest.NewServer(http.HandlerFunc(handler))
+	defer server.Close()
+
+	u, err := url.Parse(server.URL + "/sdk")
+	if err != nil {
+		t.Fatalf("failed to parse server URL: %v", err)
+	}
+
+	vimClient := &vim25.Client{Client: soap.NewClient(u, true)}
+	s := &tagSession{Manager: tags.NewManager(rest.NewClient(vimClient))}
+
+	objs := make([]types.ManagedObjectReference, 2*tagLookupBatchSize+1)
+	want := make(map[types.ManagedObjectReference]bool)
+	for i := range objs {
+		objs[i] = types.ManagedObjectReference{
+			Type:  MgObjRefTypeVirtualMachine,
+			Value: fmt.Sprintf("vm-%d", i),
+		}
+
+		if i%2 == 0 {
+			want[objs[i]] = true
+		}
+	}
+
+	got, err := s.taggedObjects(context.Background(), objs, map[string]bool{prodTagID: true})
+	if err != nil {
+		t.Fatalf("taggedObjects returned unexpected error: %v", err)
+	}
+
+	wantBatchSizes := []int{tagLookupBatchSize, tagLookupBatchSize, 1}
+	if !reflect.DeepEqual(batchSizes, wantBatchSizes) {
+		t.Errorf("got batch sizes %v; want %v", batchSizes, wantBatchSizes)
+	}
+
+	if len(got) != len(want) {
+		t.Fatalf("got %d tagged objects; want %d", len(got), len(want))
+	}
+
+	for obj := range want {
+		if !got[obj] {
+			t.Errorf("object %s not reported as tagged", obj.Value)
+		}
+	}
+}
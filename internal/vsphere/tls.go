@@ -0,0 +1,149 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"bytes"
+	"crypto/sha1" // #nosec G505 -- SHA-1 fingerprints are supported for pinning only
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/hex"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/url"
+	"path/filepath"
+	"strings"
+
+	"github.com/vmware/govmomi/vim25/soap"
+
+	"github.com/atc0005/check-vmware/internal/textutils"
+)
+
+// ErrCertificateThumbprintMismatch indicates that the fingerprint of the
+// certificate presented by the server does not match the expected
+// thumbprint.
+var ErrCertificateThumbprintMismatch = errors.New("certificate thumbprint mismatch")
+
+// ErrCertificateVerificationFailed indicates that the certificate presented
+// by the server could not be verified.
+var ErrCertificateVerificationFailed = errors.New("certificate verification failed")
+
+// newSOAPClient creates a SOAP client for the specified URL using the
+// requested certificate validation behavior. If trustCert is set, the server
+// certificate is accepted without validation. If a thumbprint is specified,
+// the server certificate is accepted if its fingerprint matches. Otherwise
+// the certificate chain is validated using the CA certificates from the
+// specified file or the system certificate store if a file is not
+// specified.
+func newSOAPClient(u *url.URL, trustCert bool, caFile string, thumbprint string) (*soap.Client, error) {
+
+	if trustCert {
+		return soap.NewClient(u, true), nil
+	}
+
+	var roots *x509.CertPool
+	if caFile != "" {
+		pem, err := ioutil.ReadFile(filepath.Clean(caFile))
+		if err != nil {
+			return nil, fmt.Errorf("failed to read CA file: %w", err)
+		}
+
+		roots = x509.NewCertPool()
+		if ok := roots.AppendCertsFromPEM(pem); !ok {
+			return nil, fmt.Errorf("no PEM-encoded certificates found in CA file %s", caFile)
+		}
+	}
+
+	var pinned []byte
+	if thumbprint != "" {
+		var err error
+		pinned, err = hex.DecodeString(strings.ReplaceAll(thumbprint, ":", ""))
+		if err != nil {
+			return nil, fmt.Errorf("invalid certificate thumbprint %q: %w", thumbprint, err)
+		}
+	}
+
+	// Certificate validation is performed by our own verification function
+	// instead of the TLS defaults so that the fingerprint of the certificate
+	// presented by the server can be reported if validation fails.
+	soapClient := soap.NewClient(u, true)
+	soapClient.DefaultTransport().TLSClientConfig.VerifyPeerCertificate =
+		func(rawCerts [][]byte, _ [][]*x509.Certificate) error {
+			return verifyPeerCertificate(rawCerts, u.Hostname(), roots, pinned)
+		}
+
+	return soapClient, nil
+}
+
+// verifyPeerCertificate validates the certificate chain presented by the
+// server. If a pinned fingerprint is provided, the server certificate is
+// accepted only if its SHA-1 or SHA-256 fingerprint (based on the length of
+// the pinned fingerprint) matches. Otherwise the certificate chain is
+// verified for the specified host using the given root CA certificates or
+// the system certificate store if not provided.
+func verifyPeerCertificate(rawCerts [][]byte, host string, roots *x509.CertPool, pinned []byte) error {
+
+	certs := make([]*x509.Certificate, 0, len(rawCerts))
+	for _, rawCert := range rawCerts {
+		cert, err := x509.ParseCertificate(rawCert)
+		if err != nil {
+			return fmt.Errorf("%w: failed to parse certificate: %v", ErrCertificateVerificationFailed, err)
+		}
+		certs = append(certs, cert)
+	}
+
+	if len(certs) == 0 {
+		return fmt.Errorf("%w: no certificate presented by server", ErrCertificateVerificationFailed)
+	}
+
+	leaf := certs[0]
+	sha1Sum := sha1.Sum(leaf.Raw) // #nosec G401 -- used for fingerprint comparison only
+	sha256Sum := sha256.Sum256(leaf.Raw)
+
+	if pinned != nil {
+		presented := sha256Sum[:]
+		if len(pinned) == sha1.Size {
+			presented = sha1Sum[:]
+		}
+
+		if !bytes.Equal(pinned, presented) {
+			return fmt.Errorf(
+				"%w: expected %s, server presented certificate with fingerprint %s",
+				ErrCertificateThumbprintMismatch,
+				textutils.BytesToDelimitedHexStr(pinned, ":"),
+				textutils.BytesToDelimitedHexStr(presented, ":"),
+			)
+		}
+
+		return nil
+	}
+
+	intermediates := x509.NewCertPool()
+	for _, cert := range certs[1:] {
+		intermediates.AddCert(cert)
+	}
+
+	opts := x509.VerifyOptions{
+		DNSName:       host,
+		Roots:         roots,
+		Intermediates: intermediates,
+	}
+
+	if _, err := leaf.Verify(opts); err != nil {
+		return fmt.Errorf(
+			"%w: %v; server presented certificate with SHA-256 fingerprint %s (SHA-1 fingerprint %s)",
+			ErrCertificateVerificationFailed,
+			err,
+			textutils.BytesToDelimitedHexStr(sha256Sum[:], ":"),
+			textutils.BytesToDelimitedHexStr(sha1Sum[:], ":"),
+		)
+	}
+
+	return nil
+}
@@ -0,0 +1,93 @@
+// Copyright 2021 Adam Chalkley
+//
+// https://github.com/atc0005/check-vmware
+//
+// Licensed under the MIT License. See LICENSE file in the project root for
+// full license information.
+
+package vsphere
+
+import (
+	"crypto/sha1" // #nosec G505 -- SHA-1 fingerprints are supported for pinning only
+	"crypto/sha256"
+	"crypto/x509"
+	"errors"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+)
+
+func TestVerifyPeerCertificate(t *testing.T) {
+
+	server := httptest.NewTLSServer(http.NotFoundHandler())
+	defer server.Close()
+
+	cert := server.Certificate()
+	rawCerts := [][]byte{cert.Raw}
+
+	roots := x509.NewCertPool()
+	roots.AddCert(cert)
+
+	sha1Sum := sha1.Sum(cert.Raw) // #nosec G401 -- used for fingerprint comparison only
+	sha256Sum := sha256.Sum256(cert.Raw)
+	wrongSum := sha256.Sum256([]byte("not the certificate"))
+
+	t.Run("trusted CA", func(t *testing.T) {
+		if err := verifyPeerCertificate(rawCerts, "127.0.0.1", roots, nil); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("untrusted CA reports fingerprint", func(t *testing.T) {
+		err := verifyPeerCertificate(rawCerts, "127.0.0.1", x509.NewCertPool(), nil)
+		if !errors.Is(err, ErrCertificateVerificationFailed) {
+			t.Fatalf("got error %v; want %v", err, ErrCertificateVerificationFailed)
+		}
+
+		if want := fingerprint(sha256Sum[:]); !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain fingerprint %q", err, want)
+		}
+	})
+
+	t.Run("hostname mismatch", func(t *testing.T) {
+		err := verifyPeerCertificate(rawCerts, "vc1.invalid", roots, nil)
+		if !errors.Is(err, ErrCertificateVerificationFailed) {
+			t.Errorf("got error %v; want %v", err, ErrCertificateVerificationFailed)
+		}
+	})
+
+	t.Run("pinned SHA-1 fingerprint", func(t *testing.T) {
+		if err := verifyPeerCertificate(rawCerts, "vc1.example.com", nil, sha1Sum[:]); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("pinned SHA-256 fingerprint", func(t *testing.T) {
+		if err := verifyPeerCertificate(rawCerts, "vc1.example.com", nil, sha256Sum[:]); err != nil {
+			t.Errorf("unexpected error: %v", err)
+		}
+	})
+
+	t.Run("pinned fingerprint mismatch", func(t *testing.T) {
+		err := verifyPeerCertificate(rawCerts, "127.0.0.1", roots, wrongSum[:])
+		if !errors.Is(err, ErrCertificateThumbprintMismatch) {
+			t.Fatalf("got error %v; want %v", err, ErrCertificateThumbprintMismatch)
+		}
+
+		if want := fingerprint(sha256Sum[:]); !strings.Contains(err.Error(), want) {
+			t.Errorf("error %q does not contain fingerprint %q", err, want)
+		}
+	})
+}
+
+// fingerprint formats the given checksum as colon-delimited hex digits.
+func fingerprint(sum []byte) string {
+	hexStr := make([]string, 0, len(sum))
+	for _, b := range sum {
+		hexStr = append(hexStr, fmt.Sprintf("%02X", b))
+	}
+
+	return strings.Join(hexStr, ":")
+}
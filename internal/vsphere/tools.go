@@ -13,6 +13,7 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -144,6 +145,60 @@ func VMToolsOneLineCheckSummary(
 	}
 }
 
+// VMToolsPerfData generates performance data for VirtualMachines with VMware
+// Tools issues.
+func VMToolsPerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsWithIssues []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("vms_with_tools_issues", len(vmsWithIssues), ""),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VMToolsData represents the evaluated VMware Tools status for a set of
+// VirtualMachines.
+type VMToolsData struct {
+	EvaluatedVMsData
+	VMsWithIssues []VMToolsStatusData `json:"vms_with_issues"`
+}
+
+// VMToolsStatusData represents the VMware Tools status for a VirtualMachine.
+type VMToolsStatusData struct {
+	VirtualMachineData
+	ToolsStatus string `json:"tools_status"`
+}
+
+// NewVMToolsData generates machine-readable data for the VMware Tools status
+// of the specified VirtualMachines.
+func NewVMToolsData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsWithIssues []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) VMToolsData {
+
+	vms := make([]VMToolsStatusData, 0, len(vmsWithIssues))
+	for _, vm := range vmsWithIssues {
+		vms = append(vms, VMToolsStatusData{
+			VirtualMachineData: newVirtualMachineData(vm),
+			ToolsStatus:        string(vm.Guest.ToolsStatus),
+		})
+	}
+
+	sort.Slice(vms, func(i, j int) bool {
+		return strings.ToLower(vms[i].Name) < strings.ToLower(vms[j].Name)
+	})
+
+	return VMToolsData{
+		EvaluatedVMsData: newEvaluatedVMsData(evaluatedVMs, rps),
+		VMsWithIssues:    vms,
+	}
+}
+
 // VMToolsReport generates a comprehensive summary including any active issues
 // along with various verbose details intended to aid in troubleshooting check
 // results at a glance. This information is provided for use with the Long
@@ -219,6 +274,13 @@ func VMToolsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&vmsReport,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&vmsReport,
 		"* Plugin User Agent: %s%s",
@@ -226,6 +288,13 @@ func VMToolsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&vmsReport,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&vmsReport,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -14,6 +14,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/vim25"
 	"github.com/vmware/govmomi/vim25/mo"
@@ -78,6 +80,72 @@ func VirtualCPUsOneLineCheckSummary(
 	}
 }
 
+// VirtualCPUsPerfData generates performance data for the vCPUs allocated to
+// the specified VirtualMachines. The given WARNING and CRITICAL threshold
+// values are percentages of the maximum allowed vCPUs.
+func VirtualCPUsPerfData(
+	vCPUsAllocated int32,
+	vCPUsMax int,
+	warningThreshold config.Range,
+	criticalThreshold config.Range,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	vCPUsPercentageUsed := float64(vCPUsAllocated) / float64(vCPUsMax) * 100
+
+	pd := []perfdata.PerformanceData{
+		{
+			Label:             "vcpus_usage",
+			Value:             perfdata.Float(vCPUsPercentageUsed),
+			UnitOfMeasurement: perfdata.UOMPercentage,
+			Warn:              warningThreshold.String(),
+			Crit:              criticalThreshold.String(),
+			Min:               "0",
+		},
+		{
+			Label: "vcpus_allocated",
+			Value: perfdata.Int(int64(vCPUsAllocated)),
+			Min:   "0",
+			Max:   perfdata.Int(int64(vCPUsMax)),
+		},
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VirtualCPUsData represents the evaluated vCPUs allocation for a set of
+// VirtualMachines.
+type VirtualCPUsData struct {
+	EvaluatedVMsData
+	VCPUsAllocated        int32   `json:"vcpus_allocated"`
+	VCPUsMaxAllowed       int     `json:"vcpus_max_allowed"`
+	VCPUsAllocatedPercent float64 `json:"vcpus_allocated_percent"`
+	WarningThreshold      string  `json:"warning_threshold"`
+	CriticalThreshold     string  `json:"critical_threshold"`
+}
+
+// NewVirtualCPUsData generates machine-readable data for the vCPUs allocated
+// to the specified VirtualMachines.
+func NewVirtualCPUsData(
+	vCPUsAllocated int32,
+	vCPUsMax int,
+	warningThreshold config.Range,
+	criticalThreshold config.Range,
+	evaluatedVMs []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) VirtualCPUsData {
+
+	return VirtualCPUsData{
+		EvaluatedVMsData:      newEvaluatedVMsData(evaluatedVMs, rps),
+		VCPUsAllocated:        vCPUsAllocated,
+		VCPUsMaxAllowed:       vCPUsMax,
+		VCPUsAllocatedPercent: float64(vCPUsAllocated) / float64(vCPUsMax) * 100,
+		WarningThreshold:      warningThreshold.String(),
+		CriticalThreshold:     criticalThreshold.String(),
+	}
+}
+
 // VirtualCPUsReport generates a summary of vCPU usage along with various
 // verbose details intended to aid in troubleshooting check results at a
 // glance. This information is provided for use with the Long Service Output
@@ -220,6 +288,13 @@ func VirtualCPUsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&vmsReport,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&vmsReport,
 		"* Plugin User Agent: %s%s",
@@ -227,6 +302,13 @@ func VirtualCPUsReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&vmsReport,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&vmsReport,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -15,6 +15,8 @@ import (
 	"strings"
 	"time"
 
+	"github.com/atc0005/check-vmware/internal/config"
+	"github.com/atc0005/check-vmware/internal/perfdata"
 	"github.com/atc0005/check-vmware/internal/textutils"
 	"github.com/atc0005/go-nagios"
 	"github.com/vmware/govmomi/vim25"
@@ -41,8 +43,8 @@ type VirtualMachinePowerCycleUptimeStatus struct {
 	VMsCritical       []mo.VirtualMachine
 	VMsWarning        []mo.VirtualMachine
 	VMsOK             []mo.VirtualMachine
-	WarningThreshold  int
-	CriticalThreshold int
+	WarningThreshold  config.Range
+	CriticalThreshold config.Range
 }
 
 // VMNames returns a list of sorted VirtualMachine names which have exceeded
@@ -125,8 +127,9 @@ func (vpcs VirtualMachinePowerCycleUptimeStatus) BottomTenOK() []mo.VirtualMachi
 // requested, a subset of all available properties will be retrieved (faster)
 // instead of recursively fetching all properties (about 2x as slow) A
 // collection of VirtualMachines with requested properties is returned or nil
-// and an error, if one occurs.
-func GetVMs(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo.VirtualMachine, error) {
+// and an error, if one occurs. If specified, VirtualMachines are only
+// retrieved from the given containers (e.g., Datacenters).
+func GetVMs(ctx context.Context, c *vim25.Client, propsSubset bool, containers ...types.ManagedObjectReference) ([]mo.VirtualMachine, error) {
 
 	funcTimeStart := time.Now()
 
@@ -143,7 +146,7 @@ func GetVMs(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo.Virtua
 		)
 	}(&vms)
 
-	err := getObjects(ctx, c, &vms, c.ServiceContent.RootFolder, propsSubset)
+	err := getObjectsFromContainers(ctx, c, &vms, containers, propsSubset)
 	if err != nil {
 		return nil, fmt.Errorf("failed to retrieve VirtualMachines: %w", err)
 	}
@@ -156,7 +159,8 @@ func GetVMs(ctx context.Context, c *vim25.Client, propsSubset bool) ([]mo.Virtua
 }
 
 // GetVMsFromContainer receives one or many ManagedEntity values for Folder,
-// Datacenter, ComputeResource, ResourcePool, or HostSystem types and returns
+// Datacenter, ComputeResource, ResourcePool, VirtualApp or HostSystem types
+// and returns
 // a list of VirtualMachine object references.
 //
 // The propsSubset boolean value indicates whether a subset of properties per
@@ -204,6 +208,58 @@ func GetVMsFromContainer(ctx context.Context, c *vim25.Client, propsSubset bool,
 
 }
 
+// GetVMsFromRPs receives one or many Resource Pools and a boolean value
+// indicating whether a subset of properties per VirtualMachine are
+// retrieved. Only VirtualMachines placed directly within the given Resource
+// Pools (or vApps) are returned; VirtualMachines within child Resource Pools
+// are returned only if the child Resource Pools are also given. A collection
+// of VirtualMachines with requested properties is returned or nil and an
+// error, if one occurs.
+func GetVMsFromRPs(ctx context.Context, c *vim25.Client, propsSubset bool, rps ...mo.ResourcePool) ([]mo.VirtualMachine, error) {
+
+	funcTimeStart := time.Now()
+
+	// declare this early so that we can grab a pointer to it in order to
+	// access the entries later
+	var vms []mo.VirtualMachine
+
+	defer func(vms *[]mo.VirtualMachine) {
+		logger.Printf(
+			"It took %v to execute GetVMsFromRPs func (and retrieve %d VMs).\n",
+			time.Since(funcTimeStart),
+			len(*vms),
+		)
+	}(&vms)
+
+	rpsIdx := make(map[string]struct{}, len(rps))
+	rpEntityVals := make([]mo.ManagedEntity, 0, len(rps))
+	for i := range rps {
+		rpsIdx[rps[i].Self.Value] = struct{}{}
+		rpEntityVals = append(rpEntityVals, rps[i].ManagedEntity)
+	}
+
+	// VirtualMachines are retrieved recursively from each Resource Pool,
+	// which includes VirtualMachines within child Resource Pools.
+	rpsVMs, err := GetVMsFromContainer(ctx, c, propsSubset, rpEntityVals...)
+	if err != nil {
+		return nil, err
+	}
+
+	vms = make([]mo.VirtualMachine, 0, len(rpsVMs))
+	for _, vm := range rpsVMs {
+		if vm.ResourcePool == nil {
+			continue
+		}
+
+		if _, ok := rpsIdx[vm.ResourcePool.Value]; ok {
+			vms = append(vms, vm)
+		}
+	}
+
+	return vms, nil
+
+}
+
 // GetVMsFromDatastore receives a Datastore object reference and returns a
 // list of VirtualMachine object references. The propsSubset boolean value
 // indicates whether a subset of properties per VirtualMachine are retrieved.
@@ -350,13 +406,15 @@ func FilterVMByID(vms []mo.VirtualMachine, vmID string) (mo.VirtualMachine, erro
 }
 
 // ExcludeVMsByName receives a collection of VirtualMachines and a list of VMs
-// that should be ignored. A new collection minus ignored VirtualMachines is
-// returned. If the collection of VirtualMachine is empty, an empty collection
-// is returned. If the list of ignored VirtualMachines is empty, the same
-// items from the received collection of VirtualMachines is returned. If the
-// list of ignored VirtualMachines is greater than the list of received
-// VirtualMachines, then only matching VirtualMachines will be excluded and
-// any others silently skipped.
+// that should be ignored. VMs may be listed by name or by glob (e.g.,
+// citrix-pool-*) or "re:" prefixed regular expression patterns. A new
+// collection minus ignored VirtualMachines is returned. If the collection of
+// VirtualMachine is empty, an empty collection is returned. If the list of
+// ignored VirtualMachines is empty, the same items from the received
+// collection of VirtualMachines is returned. If the list of ignored
+// VirtualMachines is greater than the list of received VirtualMachines, then
+// only matching VirtualMachines will be excluded and any others silently
+// skipped.
 func ExcludeVMsByName(allVMs []mo.VirtualMachine, ignoreList []string) []mo.VirtualMachine {
 
 	if len(allVMs) == 0 || len(ignoreList) == 0 {
@@ -366,7 +424,7 @@ func ExcludeVMsByName(allVMs []mo.VirtualMachine, ignoreList []string) []mo.Virt
 	vmsToKeep := make([]mo.VirtualMachine, 0, len(allVMs))
 
 	for _, vm := range allVMs {
-		if textutils.InList(vm.Name, ignoreList, true) {
+		if textutils.InPatternList(vm.Name, ignoreList) {
 			continue
 		}
 		vmsToKeep = append(vmsToKeep, vm)
@@ -426,7 +484,7 @@ func FilterVMsByPowerState(vms []mo.VirtualMachine, includePoweredOff bool) []mo
 // FilterVMsByPowerCycleUptime filters the provided collection of
 // VirtualMachines to just those with WARNING or CRITICAL values based on
 // provided thresholds.
-func FilterVMsByPowerCycleUptime(vms []mo.VirtualMachine, warningThreshold int, criticalThreshold int) []mo.VirtualMachine {
+func FilterVMsByPowerCycleUptime(vms []mo.VirtualMachine, warningThreshold config.Range, criticalThreshold config.Range) []mo.VirtualMachine {
 
 	// setup early so we can reference it from deferred stats output
 	var vmsWithIssues []mo.VirtualMachine
@@ -446,9 +504,7 @@ func FilterVMsByPowerCycleUptime(vms []mo.VirtualMachine, warningThreshold int,
 		uptime := time.Duration(vm.Summary.QuickStats.UptimeSeconds) * time.Second
 		uptimeDays := uptime.Hours() / 24
 
-		// compare against the WARNING threshold as that will net VMs with
-		// CRITICAL state as well.
-		if uptimeDays > float64(warningThreshold) {
+		if warningThreshold.Alert(uptimeDays) || criticalThreshold.Alert(uptimeDays) {
 			vmsWithIssues = append(vmsWithIssues, vm)
 		}
 	}
@@ -507,8 +563,8 @@ func VMNames(vmsList []mo.VirtualMachine) []string {
 // given thresholds along with those given thresholds.
 func GetVMPowerCycleUptimeStatusSummary(
 	vms []mo.VirtualMachine,
-	warningThreshold int,
-	criticalThreshold int,
+	warningThreshold config.Range,
+	criticalThreshold config.Range,
 ) VirtualMachinePowerCycleUptimeStatus {
 
 	funcTimeStart := time.Now()
@@ -530,10 +586,10 @@ func GetVMPowerCycleUptimeStatusSummary(
 		uptimeDays := uptime.Hours() / 24
 
 		switch {
-		case uptimeDays > float64(criticalThreshold):
+		case criticalThreshold.Alert(uptimeDays):
 			vmsCritical = append(vmsCritical, vm)
 
-		case uptimeDays > float64(warningThreshold):
+		case warningThreshold.Alert(uptimeDays):
 			vmsWarning = append(vmsWarning, vm)
 
 		default:
@@ -575,7 +631,7 @@ func VMPowerCycleUptimeOneLineCheckSummary(
 	switch {
 	case len(uptimeSummary.VMsCritical) > 0:
 		return fmt.Sprintf(
-			"%s: %d VMs with power cycle uptime exceeding %d days detected (evaluated %d VMs, %d Resource Pools)",
+			"%s: %d VMs with power cycle uptime crossing threshold (%s days) detected (evaluated %d VMs, %d Resource Pools)",
 			stateLabel,
 			len(uptimeSummary.VMsCritical),
 			uptimeSummary.CriticalThreshold,
@@ -585,7 +641,7 @@ func VMPowerCycleUptimeOneLineCheckSummary(
 
 	case len(uptimeSummary.VMsWarning) > 0:
 		return fmt.Sprintf(
-			"%s: %d VMs with power cycle uptime exceeding %d days detected (evaluated %d VMs, %d Resource Pools)",
+			"%s: %d VMs with power cycle uptime crossing threshold (%s days) detected (evaluated %d VMs, %d Resource Pools)",
 			stateLabel,
 			len(uptimeSummary.VMsWarning),
 			uptimeSummary.WarningThreshold,
@@ -596,7 +652,7 @@ func VMPowerCycleUptimeOneLineCheckSummary(
 	default:
 
 		return fmt.Sprintf(
-			"%s: No VMs with power cycle uptime exceeding %d days detected (evaluated %d VMs, %d Resource Pools)",
+			"%s: No VMs with power cycle uptime crossing threshold (%s days) detected (evaluated %d VMs, %d Resource Pools)",
 			stateLabel,
 			uptimeSummary.WarningThreshold,
 			len(evaluatedVMs),
@@ -605,6 +661,94 @@ func VMPowerCycleUptimeOneLineCheckSummary(
 	}
 }
 
+// VMPowerCycleUptimePerfData generates performance data for the power cycle
+// uptime of the specified VirtualMachines.
+func VMPowerCycleUptimePerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	uptimeSummary VirtualMachinePowerCycleUptimeStatus,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	var maxUptime time.Duration
+	for _, vm := range evaluatedVMs {
+		uptime := time.Duration(vm.Summary.QuickStats.UptimeSeconds) * time.Second
+		if uptime > maxUptime {
+			maxUptime = uptime
+		}
+	}
+
+	pd := []perfdata.PerformanceData{
+		{
+			Label: "uptime_max_days",
+			Value: perfdata.Float(maxUptime.Hours() / 24),
+			Warn:  uptimeSummary.WarningThreshold.String(),
+			Crit:  uptimeSummary.CriticalThreshold.String(),
+			Min:   "0",
+		},
+		countPerfData("vms_uptime_critical", len(uptimeSummary.VMsCritical), ""),
+		countPerfData("vms_uptime_warning", len(uptimeSummary.VMsWarning), ""),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VMPowerCycleUptimeData represents the evaluated power cycle uptime for a
+// set of VirtualMachines.
+type VMPowerCycleUptimeData struct {
+	EvaluatedVMsData
+	WarningThreshold  string                     `json:"warning_threshold"`
+	CriticalThreshold string                     `json:"critical_threshold"`
+	VMsCritical       []VMPowerCycleUptimeVMData `json:"vms_critical"`
+	VMsWarning        []VMPowerCycleUptimeVMData `json:"vms_warning"`
+	VMsOK             []VMPowerCycleUptimeVMData `json:"vms_ok"`
+}
+
+// VMPowerCycleUptimeVMData represents the power cycle uptime for a
+// VirtualMachine.
+type VMPowerCycleUptimeVMData struct {
+	VirtualMachineData
+	UptimeDays float64 `json:"uptime_days"`
+}
+
+// newVMPowerCycleUptimeVMsData converts the given VirtualMachines to a
+// collection of VMPowerCycleUptimeVMData values sorted by uptime (highest
+// first).
+func newVMPowerCycleUptimeVMsData(vms []mo.VirtualMachine) []VMPowerCycleUptimeVMData {
+
+	vmsData := make([]VMPowerCycleUptimeVMData, 0, len(vms))
+	for _, vm := range vms {
+		uptime := time.Duration(vm.Summary.QuickStats.UptimeSeconds) * time.Second
+		vmsData = append(vmsData, VMPowerCycleUptimeVMData{
+			VirtualMachineData: newVirtualMachineData(vm),
+			UptimeDays:         uptime.Hours() / 24,
+		})
+	}
+
+	sort.Slice(vmsData, func(i, j int) bool {
+		return vmsData[i].UptimeDays > vmsData[j].UptimeDays
+	})
+
+	return vmsData
+}
+
+// NewVMPowerCycleUptimeData generates machine-readable data for the power
+// cycle uptime of the specified VirtualMachines.
+func NewVMPowerCycleUptimeData(
+	evaluatedVMs []mo.VirtualMachine,
+	uptimeSummary VirtualMachinePowerCycleUptimeStatus,
+	rps []mo.ResourcePool,
+) VMPowerCycleUptimeData {
+
+	return VMPowerCycleUptimeData{
+		EvaluatedVMsData:  newEvaluatedVMsData(evaluatedVMs, rps),
+		WarningThreshold:  uptimeSummary.WarningThreshold.String(),
+		CriticalThreshold: uptimeSummary.CriticalThreshold.String(),
+		VMsCritical:       newVMPowerCycleUptimeVMsData(uptimeSummary.VMsCritical),
+		VMsWarning:        newVMPowerCycleUptimeVMsData(uptimeSummary.VMsWarning),
+		VMsOK:             newVMPowerCycleUptimeVMsData(uptimeSummary.VMsOK),
+	}
+}
+
 // VMPowerCycleUptimeReport generates a summary of VMs which exceed power
 // cycle uptime thresholds along with various verbose details intended to aid
 // in troubleshooting check results at a glance. This information is provided
@@ -750,6 +894,13 @@ func VMPowerCycleUptimeReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -757,6 +908,13 @@ func VMPowerCycleUptimeReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -847,6 +1005,42 @@ func VMDiskConsolidationOneLineCheckSummary(
 	}
 }
 
+// VMDiskConsolidationPerfData generates performance data for VirtualMachines
+// requiring disk consolidation.
+func VMDiskConsolidationPerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsNeedingConsolidation []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("vms_needing_consolidation", len(vmsNeedingConsolidation), "0"),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VMDiskConsolidationData represents the evaluated disk consolidation state
+// for a set of VirtualMachines.
+type VMDiskConsolidationData struct {
+	EvaluatedVMsData
+	VMsNeedingConsolidation []VirtualMachineData `json:"vms_needing_consolidation"`
+}
+
+// NewVMDiskConsolidationData generates machine-readable data for
+// VirtualMachines requiring disk consolidation.
+func NewVMDiskConsolidationData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsNeedingConsolidation []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) VMDiskConsolidationData {
+
+	return VMDiskConsolidationData{
+		EvaluatedVMsData:        newEvaluatedVMsData(evaluatedVMs, rps),
+		VMsNeedingConsolidation: newVirtualMachinesData(vmsNeedingConsolidation),
+	}
+}
+
 // VMDiskConsolidationReport generates a summary of VMs which require disk
 // consolidation along with various verbose details intended to aid in
 // troubleshooting check results at a glance. This information is provided for
@@ -926,6 +1120,13 @@ func VMDiskConsolidationReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -933,6 +1134,13 @@ func VMDiskConsolidationReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* VMs (evaluated: %d, total: %d)%s",
@@ -1031,6 +1239,66 @@ func VMInteractiveQuestionOneLineCheckSummary(
 	}
 }
 
+// VMInteractiveQuestionPerfData generates performance data for
+// VirtualMachines blocked waiting on a response to an interactive question.
+func VMInteractiveQuestionPerfData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsNeedingResponse []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) []perfdata.PerformanceData {
+
+	pd := []perfdata.PerformanceData{
+		countPerfData("vms_needing_response", len(vmsNeedingResponse), "0"),
+	}
+
+	return append(pd, vmsPerfData(evaluatedVMs, rps)...)
+}
+
+// VMInteractiveQuestionData represents the evaluated interactive question
+// state for a set of VirtualMachines.
+type VMInteractiveQuestionData struct {
+	EvaluatedVMsData
+	VMsNeedingResponse []VMInteractiveQuestionVMData `json:"vms_needing_response"`
+}
+
+// VMInteractiveQuestionVMData represents a VirtualMachine blocked on an
+// interactive question.
+type VMInteractiveQuestionVMData struct {
+	VirtualMachineData
+	Question string `json:"question"`
+}
+
+// NewVMInteractiveQuestionData generates machine-readable data for
+// VirtualMachines requiring an interactive response.
+func NewVMInteractiveQuestionData(
+	evaluatedVMs []mo.VirtualMachine,
+	vmsNeedingResponse []mo.VirtualMachine,
+	rps []mo.ResourcePool,
+) VMInteractiveQuestionData {
+
+	vms := make([]VMInteractiveQuestionVMData, 0, len(vmsNeedingResponse))
+	for _, vm := range vmsNeedingResponse {
+		var question string
+		if vm.Summary.Runtime.Question != nil {
+			question = vm.Summary.Runtime.Question.Text
+		}
+
+		vms = append(vms, VMInteractiveQuestionVMData{
+			VirtualMachineData: newVirtualMachineData(vm),
+			Question:           question,
+		})
+	}
+
+	sort.Slice(vms, func(i, j int) bool {
+		return strings.ToLower(vms[i].Name) < strings.ToLower(vms[j].Name)
+	})
+
+	return VMInteractiveQuestionData{
+		EvaluatedVMsData:   newEvaluatedVMsData(evaluatedVMs, rps),
+		VMsNeedingResponse: vms,
+	}
+}
+
 // VMInteractiveQuestionReport generates a summary of VMs which require an
 // interactive response along with various verbose details intended to aid in
 // troubleshooting check results at a glance. This information is provided for
@@ -1119,6 +1387,13 @@ func VMInteractiveQuestionReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API: %s%s",
+		APIDescription(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* Plugin User Agent: %s%s",
@@ -1126,6 +1401,13 @@ func VMInteractiveQuestionReport(
 		nagios.CheckOutputEOL,
 	)
 
+	fmt.Fprintf(
+		&report,
+		"* vSphere API request retries: %d%s",
+		Retries(c),
+		nagios.CheckOutputEOL,
+	)
+
 	fmt.Fprintf(
 		&report,
 		"* VMs (evaluated: %d, total: %d)%s",